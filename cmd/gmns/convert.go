@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// runConvert converts macroscopic network between CSV tables and GeoJSON. Formats are defined by paths: GeoJSON file or directory with CSV tables
+func runConvert(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("convert", stderr)
+	input := fs.String("in", "", "macroscopic network: directory with node.csv and link.csv or GeoJSON file")
+	output := fs.String("out", "", "output: directory for CSV tables or GeoJSON file (.geojson, .json)")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *input == "" || *output == "" {
+		fmt.Fprintln(stderr, "gmns convert: flags -in and -out are required")
+		fs.Usage()
+		return EXIT_USAGE
+	}
+	macroNet, err := readMacroNet(*input)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	err = writeMacroNet(*output, macroNet)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	return EXIT_OK
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/LdDl/go-gmns/diff"
+	"github.com/LdDl/go-gmns/generators"
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/LdDl/go-gmns/movement"
+	"github.com/pkg/errors"
+)
+
+// runDiff compares two versions of macroscopic network and writes changes as GeoJSON and JSON patch
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("diff", stderr)
+	oldInput := fs.String("old", "", "old macroscopic network: directory with node.csv and link.csv or GeoJSON file")
+	newInput := fs.String("new", "", "new macroscopic network: directory with node.csv and link.csv or GeoJSON file")
+	withMovements := fs.Bool("movements", false, "generate movements of both networks and compare them too")
+	drivingSide := fs.String("driving-side", types.DRIVING_SIDE_RIGHT.String(), "side of the road which vehicles keep to: right or left")
+	geojsonPath := fs.String("geojson", "", "path of GeoJSON file with changes for map review")
+	patchPath := fs.String("patch", "", "path of JSON patch with changes")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *oldInput == "" || *newInput == "" {
+		fmt.Fprintln(stderr, "gmns diff: flags -old and -new are required")
+		fs.Usage()
+		return EXIT_USAGE
+	}
+	side := types.NewDrivingSideFrom(*drivingSide)
+	if side.String() != *drivingSide {
+		fmt.Fprintf(stderr, "gmns diff: invalid driving side '%s'\n", *drivingSide)
+		return EXIT_USAGE
+	}
+	oldNet, err := readMacroNet(*oldInput)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	newNet, err := readMacroNet(*newInput)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	var oldMovements, newMovements movement.MovementsStorage
+	if *withMovements {
+		opts := generators.DefaultMovementsGenOptions()
+		opts.DrivingSide = side
+		oldMovements, err = generateMovements(oldNet, opts)
+		if err != nil {
+			return fail(stderr, err)
+		}
+		newMovements, err = generateMovements(newNet, opts)
+		if err != nil {
+			return fail(stderr, err)
+		}
+	}
+	d := diff.Compare(oldNet, newNet, oldMovements, newMovements)
+	if *geojsonPath != "" {
+		err = writeGeoJSON(*geojsonPath, d.GeoFeatureCollection())
+		if err != nil {
+			return fail(stderr, err)
+		}
+	}
+	if *patchPath != "" {
+		err = writeFile(*patchPath, func(w io.Writer) error {
+			return diff.WritePatch(w, d)
+		})
+		if err != nil {
+			return fail(stderr, err)
+		}
+	}
+	for _, element := range []diff.ElementType{diff.ELEMENT_NODE, diff.ELEMENT_LINK, diff.ELEMENT_MOVEMENT} {
+		fmt.Fprintf(stdout, "%ss: %d added, %d removed, %d modified\n", element, d.Count(element, diff.CHANGE_ADDED), d.Count(element, diff.CHANGE_REMOVED), d.Count(element, diff.CHANGE_MODIFIED))
+	}
+	return EXIT_OK
+}
+
+// generateMovements generates movements of the network for comparison
+func generateMovements(net *macro.Net, opts generators.MovementsGenOptions) (movement.MovementsStorage, error) {
+	movements, err := generators.GenerateMovements(net, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't generate movements")
+	}
+	return movements, nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/LdDl/go-gmns/generators"
+	"github.com/LdDl/go-gmns/gmns/types"
+)
+
+// newFlagSet returns flag set of the subcommand which prints errors and usage to stderr
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("gmns "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// parseFlags parses arguments of the subcommand. Returns false and exit code if the command should stop (help requested or invalid flags)
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	err := fs.Parse(args)
+	if err == flag.ErrHelp {
+		return EXIT_OK, false
+	}
+	if err != nil {
+		return EXIT_USAGE, false
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "%s: unexpected arguments: %s\n", fs.Name(), strings.Join(fs.Args(), " "))
+		return EXIT_USAGE, false
+	}
+	return EXIT_OK, true
+}
+
+// floatsFlag is comma-separated list of floats
+type floatsFlag []float64
+
+func (f *floatsFlag) String() string {
+	strs := make([]string, len(*f))
+	for i, value := range *f {
+		strs[i] = strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return strings.Join(strs, ",")
+}
+
+func (f *floatsFlag) Set(value string) error {
+	values := []float64{}
+	for _, str := range strings.Split(value, ",") {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+		if err != nil {
+			return err
+		}
+		values = append(values, parsed)
+	}
+	*f = values
+	return nil
+}
+
+// genFlags are flags of generators options
+type genFlags struct {
+	drivingSide string
+	workers     int
+	verbose     bool
+	reportPath  string
+	stableIDs   bool
+
+	cutLengths     floatsFlag
+	shortcutLength float64
+	minCutLength   float64
+	mesoLaneWidth  float64
+
+	micro      generators.MicroGenOptions
+	cellSizing string
+}
+
+// addGenFlags registers flags of generators options with default values of generators
+func addGenFlags(fs *flag.FlagSet) *genFlags {
+	mesoDefaults := generators.DefaultMesoGenOptions()
+	flags := &genFlags{
+		cutLengths: floatsFlag(mesoDefaults.CutLengths[:10]),
+		micro:      generators.DefaultMicroGenOptions(),
+	}
+	fs.StringVar(&flags.drivingSide, "driving-side", types.DRIVING_SIDE_RIGHT.String(), "side of the road which vehicles keep to: right or left")
+	fs.IntVar(&flags.workers, "workers", 1, "number of goroutines for meso and micro generation, non-positive value means number of CPUs")
+	fs.BoolVar(&flags.verbose, "verbose", false, "print progress and diagnostics of generators to stderr")
+	fs.StringVar(&flags.reportPath, "report", "", "path of JSON generation report")
+	fs.BoolVar(&flags.stableIDs, "stable-ids", false, "derive identifiers of movements, meso and micro elements from their content, so unchanged parts keep identifiers across generations")
+
+	fs.Var(&flags.cutLengths, "cut-lengths", "comma-separated cut lengths [meters] at the ends of links by number of lanes starting from 0 lanes, the last value is used for greater numbers")
+	fs.Float64Var(&flags.shortcutLength, "shortcut-length", mesoDefaults.ShortcutLength, "cut length [meters] at the ends of links where movements are not needed")
+	fs.Float64Var(&flags.minCutLength, "min-cut-length", mesoDefaults.MinCutLength, "minimum length [meters] of the link remaining after cuts")
+	fs.Float64Var(&flags.mesoLaneWidth, "meso-lane-width", mesoDefaults.LaneWidth, "lane width [meters] used for offset of bidirectional links in mesoscopic network")
+
+	fs.Float64Var(&flags.micro.CellLength, "cell-length", flags.micro.CellLength, "cell length [meters] of microscopic network")
+	fs.Float64Var(&flags.micro.LaneWidth, "lane-width", flags.micro.LaneWidth, "lane width [meters] of microscopic network")
+	fs.Float64Var(&flags.micro.BikeLaneWidth, "bike-lane-width", flags.micro.BikeLaneWidth, "bike lane width [meters] of microscopic network")
+	fs.Float64Var(&flags.micro.WalkLaneWidth, "walk-lane-width", flags.micro.WalkLaneWidth, "walk lane width [meters] of microscopic network")
+	fs.BoolVar(&flags.micro.SeparateBikeWalk, "separate-bike-walk", flags.micro.SeparateBikeWalk, "generate separate bike and walk lanes in microscopic network")
+	fs.StringVar(&flags.cellSizing, "cell-sizing", flags.micro.CellSizing.String(), "the way meso links are divided into cells: fixed, speed or adaptive")
+	fs.Float64Var(&flags.micro.CellTimeStep, "cell-time-step", flags.micro.CellTimeStep, "simulation time step [seconds] for 'speed' cell sizing")
+	fs.Float64Var(&flags.micro.IntersectionCellLength, "intersection-cell-length", flags.micro.IntersectionCellLength, "cell length [meters] near intersections for 'adaptive' cell sizing")
+	fs.Float64Var(&flags.micro.IntersectionZoneLength, "intersection-zone-length", flags.micro.IntersectionZoneLength, "distance [meters] from intersections where shorter cells are used for 'adaptive' cell sizing")
+	fs.Float64Var(&flags.micro.NoLaneChangeZone, "no-lane-change-zone", flags.micro.NoLaneChangeZone, "distance [meters] before intersections where lane changes are prohibited, non-positive value disables the zone")
+	return flags
+}
+
+// options returns generators options built from flags. Error is returned for invalid values
+func (flags *genFlags) options(stderr io.Writer) (generators.MovementsGenOptions, generators.MesoGenOptions, generators.MicroGenOptions, error) {
+	drivingSide := types.NewDrivingSideFrom(flags.drivingSide)
+	if drivingSide.String() != strings.ToLower(strings.TrimSpace(flags.drivingSide)) {
+		return generators.MovementsGenOptions{}, generators.MesoGenOptions{}, generators.MicroGenOptions{}, fmt.Errorf("invalid driving side '%s'", flags.drivingSide)
+	}
+	cellSizing := generators.NewCellSizingPolicyFrom(flags.cellSizing)
+	if cellSizing.String() != strings.ToLower(strings.TrimSpace(flags.cellSizing)) {
+		return generators.MovementsGenOptions{}, generators.MesoGenOptions{}, generators.MicroGenOptions{}, fmt.Errorf("invalid cell sizing '%s'", flags.cellSizing)
+	}
+	var logger generators.Logger
+	if flags.verbose {
+		logger = generators.NewSlogLogger(slog.New(slog.NewTextHandler(stderr, nil)))
+	}
+	var report *generators.GenerationReport
+	if flags.reportPath != "" {
+		report = generators.NewGenerationReport()
+	}
+
+	movementsOpts := generators.DefaultMovementsGenOptions()
+	movementsOpts.DrivingSide = drivingSide
+	movementsOpts.Logger = logger
+	movementsOpts.Report = report
+	movementsOpts.StableIDs = flags.stableIDs
+
+	mesoOpts := generators.DefaultMesoGenOptions()
+	mesoOpts.CutLengths = append([]float64{}, flags.cutLengths...)
+	mesoOpts.ShortcutLength = flags.shortcutLength
+	mesoOpts.MinCutLength = flags.minCutLength
+	mesoOpts.LaneWidth = flags.mesoLaneWidth
+	mesoOpts.DrivingSide = drivingSide
+	mesoOpts.Workers = flags.workers
+	mesoOpts.Logger = logger
+	mesoOpts.Report = report
+	mesoOpts.Verbose = flags.verbose
+	mesoOpts.StableIDs = flags.stableIDs
+
+	microOpts := flags.micro
+	microOpts.CellSizing = cellSizing
+	microOpts.DrivingSide = drivingSide
+	microOpts.Workers = flags.workers
+	microOpts.Logger = logger
+	microOpts.Report = report
+	microOpts.Verbose = flags.verbose
+	microOpts.StableIDs = flags.stableIDs
+	return movementsOpts, mesoOpts, microOpts, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/LdDl/go-gmns/generators"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/LdDl/go-gmns/meso"
+	"github.com/LdDl/go-gmns/micro"
+	"github.com/LdDl/go-gmns/movement"
+	"github.com/pkg/errors"
+)
+
+// networks are outputs of the generation pipeline
+type networks struct {
+	macroNet  *macro.Net
+	movements movement.MovementsStorage
+	mesoNet   *meso.Net
+	microNet  *micro.Net
+}
+
+// runGenerate reads macroscopic network and writes movements, mesoscopic and microscopic networks as CSV tables:
+// <out>/movement.csv, <out>/meso/{node,link}.csv and <out>/micro/{node,link}.csv
+func runGenerate(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("generate", stderr)
+	input := fs.String("in", "", "macroscopic network: directory with node.csv and link.csv or GeoJSON file")
+	output := fs.String("out", "", "output directory")
+	skipMicro := fs.Bool("skip-micro", false, "do not generate microscopic network")
+	genFlags := addGenFlags(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *input == "" || *output == "" {
+		fmt.Fprintln(stderr, "gmns generate: flags -in and -out are required")
+		fs.Usage()
+		return EXIT_USAGE
+	}
+	movementsOpts, mesoOpts, microOpts, err := genFlags.options(stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "gmns generate: %v\n", err)
+		return EXIT_USAGE
+	}
+	macroNet, err := readMacroNet(*input)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	nets, err := generate(ctx, macroNet, movementsOpts, mesoOpts, microOpts, !*skipMicro)
+	if err != nil {
+		// Report is useful even if the generation has failed
+		if reportErr := writeReport(genFlags.reportPath, movementsOpts.Report); reportErr != nil {
+			fmt.Fprintf(stderr, "gmns: %v\n", reportErr)
+		}
+		return fail(stderr, err)
+	}
+	err = writeNetworks(*output, nets)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	err = writeReport(genFlags.reportPath, movementsOpts.Report)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	fmt.Fprintf(stdout, "movements: %d, meso nodes: %d, meso links: %d", len(nets.movements), len(nets.mesoNet.Nodes), len(nets.mesoNet.Links))
+	if nets.microNet != nil {
+		fmt.Fprintf(stdout, ", micro nodes: %d, micro links: %d", len(nets.microNet.Nodes), len(nets.microNet.Links))
+	}
+	fmt.Fprintln(stdout)
+	return EXIT_OK
+}
+
+// generate runs the generation pipeline. Microscopic network is generated only if withMicro is set
+func generate(ctx context.Context, macroNet *macro.Net, movementsOpts generators.MovementsGenOptions, mesoOpts generators.MesoGenOptions, microOpts generators.MicroGenOptions, withMicro bool) (*networks, error) {
+	nets := &networks{macroNet: macroNet}
+	var err error
+	nets.movements, err = generators.GenerateMovementsContext(ctx, macroNet, movementsOpts)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't generate movements")
+	}
+	nets.mesoNet, err = generators.GenerateMesoscopicContext(ctx, macroNet, nets.movements, mesoOpts)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't generate mesoscopic network")
+	}
+	if !withMicro {
+		return nets, nil
+	}
+	nets.microNet, err = generators.GenerateMicroscopicContext(ctx, macroNet, nets.mesoNet, nets.movements, microOpts)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't generate microscopic network")
+	}
+	return nets, nil
+}
+
+// writeNetworks writes generated networks as CSV tables into the directory
+func writeNetworks(dir string, nets *networks) error {
+	mesoDir := filepath.Join(dir, MESO_DIR)
+	err := os.MkdirAll(mesoDir, 0o755)
+	if err != nil {
+		return errors.Wrap(err, "Can't create output directory")
+	}
+	err = writeFile(filepath.Join(dir, MOVEMENTS_FILE), func(w io.Writer) error {
+		return movement.WriteMovementsCSV(w, nets.movements)
+	})
+	if err != nil {
+		return err
+	}
+	err = writeFile(filepath.Join(mesoDir, NODES_FILE), func(w io.Writer) error {
+		return meso.WriteNodesCSV(w, nets.mesoNet)
+	})
+	if err != nil {
+		return err
+	}
+	err = writeFile(filepath.Join(mesoDir, LINKS_FILE), func(w io.Writer) error {
+		return meso.WriteLinksCSV(w, nets.mesoNet)
+	})
+	if err != nil {
+		return err
+	}
+	if nets.microNet == nil {
+		return nil
+	}
+	microDir := filepath.Join(dir, MICRO_DIR)
+	err = os.MkdirAll(microDir, 0o755)
+	if err != nil {
+		return errors.Wrap(err, "Can't create output directory")
+	}
+	err = writeFile(filepath.Join(microDir, NODES_FILE), func(w io.Writer) error {
+		return micro.WriteNodesCSV(w, nets.microNet)
+	})
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(microDir, LINKS_FILE), func(w io.Writer) error {
+		return micro.WriteLinksCSV(w, nets.microNet)
+	})
+}
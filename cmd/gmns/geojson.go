@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/paulmach/orb/geojson"
+	"github.com/pkg/errors"
+)
+
+// Layers exported by geojson command
+const (
+	LAYER_MACRO     = "macro"
+	LAYER_MOVEMENTS = "movements"
+	LAYER_MESO      = "meso"
+	LAYER_MICRO     = "micro"
+)
+
+var layers = []string{LAYER_MACRO, LAYER_MOVEMENTS, LAYER_MESO, LAYER_MICRO}
+
+// runGeoJSON generates networks and writes requested layers as <out>/<layer>.geojson
+func runGeoJSON(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("geojson", stderr)
+	input := fs.String("in", "", "macroscopic network: directory with node.csv and link.csv or GeoJSON file")
+	output := fs.String("out", "", "output directory")
+	layersStr := fs.String("layers", strings.Join(layers, ","), "comma-separated layers to export: "+strings.Join(layers, ", "))
+	genFlags := addGenFlags(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *input == "" || *output == "" {
+		fmt.Fprintln(stderr, "gmns geojson: flags -in and -out are required")
+		fs.Usage()
+		return EXIT_USAGE
+	}
+	requested := map[string]bool{}
+	for _, layer := range strings.Split(*layersStr, ",") {
+		layer = strings.ToLower(strings.TrimSpace(layer))
+		if !isLayer(layer) {
+			fmt.Fprintf(stderr, "gmns geojson: unknown layer '%s'\n", layer)
+			return EXIT_USAGE
+		}
+		requested[layer] = true
+	}
+	movementsOpts, mesoOpts, microOpts, err := genFlags.options(stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "gmns geojson: %v\n", err)
+		return EXIT_USAGE
+	}
+	macroNet, err := readMacroNet(*input)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	nets := &networks{macroNet: macroNet}
+	if requested[LAYER_MOVEMENTS] || requested[LAYER_MESO] || requested[LAYER_MICRO] {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		nets, err = generate(ctx, macroNet, movementsOpts, mesoOpts, microOpts, requested[LAYER_MICRO])
+		if err != nil {
+			return fail(stderr, err)
+		}
+		err = writeReport(genFlags.reportPath, movementsOpts.Report)
+		if err != nil {
+			return fail(stderr, err)
+		}
+	}
+	err = os.MkdirAll(*output, 0o755)
+	if err != nil {
+		return fail(stderr, errors.Wrap(err, "Can't create output directory"))
+	}
+	for _, layer := range layers {
+		if !requested[layer] {
+			continue
+		}
+		var fc *geojson.FeatureCollection
+		switch layer {
+		case LAYER_MACRO:
+			fc = nets.macroNet.GeoFeatureCollection()
+		case LAYER_MOVEMENTS:
+			fc = nets.movements.GeoFeatureCollection()
+		case LAYER_MESO:
+			fc = nets.mesoNet.GeoFeatureCollection()
+		case LAYER_MICRO:
+			fc = nets.microNet.GeoFeatureCollection()
+		}
+		err = writeGeoJSON(filepath.Join(*output, layer+GEOJSON_EXT), fc)
+		if err != nil {
+			return fail(stderr, err)
+		}
+	}
+	return EXIT_OK
+}
+
+// isLayer checks if the layer is known
+func isLayer(layer string) bool {
+	for _, known := range layers {
+		if known == layer {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/LdDl/go-gmns/generators"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/paulmach/orb/geojson"
+	"github.com/pkg/errors"
+)
+
+// Names of GMNS tables in network directories
+const (
+	NODES_FILE      = "node.csv"
+	LINKS_FILE      = "link.csv"
+	LANES_FILE      = "lane.csv"
+	MOVEMENTS_FILE  = "movement.csv"
+	MESO_DIR        = "meso"
+	MICRO_DIR       = "micro"
+	GEOJSON_EXT     = ".geojson"
+	GEOJSON_EXT_ALT = ".json"
+)
+
+// isGeoJSONPath checks if the path refers to GeoJSON file by extension
+func isGeoJSONPath(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == GEOJSON_EXT || ext == GEOJSON_EXT_ALT
+}
+
+// readMacroNet reads macroscopic network from GeoJSON file or from directory with GMNS tables
+func readMacroNet(path string) (*macro.Net, error) {
+	if path == "" {
+		return nil, fmt.Errorf("input is not set")
+	}
+	if isGeoJSONPath(path) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "Can't read GeoJSON")
+		}
+		fc, err := geojson.UnmarshalFeatureCollection(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Can't parse GeoJSON '%s'", path)
+		}
+		return macro.NewNetFromGeoFeatureCollection(fc)
+	}
+	net := macro.NewNet()
+	err := readFile(filepath.Join(path, NODES_FILE), func(r io.Reader) error {
+		return macro.ReadNodesCSV(r, net)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = readFile(filepath.Join(path, LINKS_FILE), func(r io.Reader) error {
+		return macro.ReadLinksCSV(r, net)
+	})
+	if err != nil {
+		return nil, err
+	}
+	lanesPath := filepath.Join(path, LANES_FILE)
+	if _, err := os.Stat(lanesPath); err == nil {
+		err = readFile(lanesPath, func(r io.Reader) error {
+			return macro.ReadLanesCSV(r, net)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return net, nil
+}
+
+// writeMacroNet writes macroscopic network to GeoJSON file or to directory with GMNS tables
+func writeMacroNet(path string, net *macro.Net) error {
+	if isGeoJSONPath(path) {
+		return writeGeoJSON(path, net.GeoFeatureCollection())
+	}
+	err := os.MkdirAll(path, 0o755)
+	if err != nil {
+		return errors.Wrap(err, "Can't create output directory")
+	}
+	err = writeFile(filepath.Join(path, NODES_FILE), func(w io.Writer) error {
+		return macro.WriteNodesCSV(w, net)
+	})
+	if err != nil {
+		return err
+	}
+	err = writeFile(filepath.Join(path, LINKS_FILE), func(w io.Writer) error {
+		return macro.WriteLinksCSV(w, net)
+	})
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(path, LANES_FILE), func(w io.Writer) error {
+		return macro.WriteLanesCSV(w, net)
+	})
+}
+
+// writeGeoJSON writes feature collection to the file
+func writeGeoJSON(path string, fc *geojson.FeatureCollection) error {
+	return writeFile(path, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		err := encoder.Encode(fc)
+		if err != nil {
+			return errors.Wrap(err, "Can't encode GeoJSON")
+		}
+		return nil
+	})
+}
+
+// writeReport writes generation report if its path is set
+func writeReport(path string, report *generators.GenerationReport) error {
+	if path == "" || report == nil {
+		return nil
+	}
+	return writeFile(path, func(w io.Writer) error {
+		return generators.WriteReport(w, report)
+	})
+}
+
+// isIOError checks whether the error has been caused by the file system rather than by the content of the file
+func isIOError(err error) bool {
+	var pathErr *fs.PathError
+	return errors.As(err, &pathErr)
+}
+
+// readFile opens the file and passes it to the reading function
+func readFile(path string, read func(r io.Reader) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "Can't open file")
+	}
+	defer file.Close()
+	err = read(file)
+	if err != nil {
+		return errors.Wrapf(err, "Can't read '%s'", path)
+	}
+	return nil
+}
+
+// writeFile creates the file and passes it to the writing function
+func writeFile(path string, write func(w io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "Can't create file")
+	}
+	err = write(file)
+	if err != nil {
+		file.Close()
+		return errors.Wrapf(err, "Can't write '%s'", path)
+	}
+	return errors.Wrapf(file.Close(), "Can't close '%s'", path)
+}
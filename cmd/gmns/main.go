@@ -0,0 +1,92 @@
+// Command gmns runs the generation pipeline of go-gmns: it reads macroscopic network, generates movements, mesoscopic and microscopic networks,
+// validates, converts and exports networks for debugging.
+//
+// Usage:
+//
+//	gmns <command> [flags]
+//
+// Commands:
+//
+//	generate  generate movements, mesoscopic and microscopic networks from macroscopic one
+//	validate  check consistency of macroscopic network
+//	stats     print summary of macroscopic network and generated ones
+//	convert   convert macroscopic network between CSV and GeoJSON
+//	geojson   export networks as GeoJSON for debugging
+//	diff      compare two versions of macroscopic network
+//
+// Macroscopic network is either a directory with GMNS tables node.csv, link.csv and optional lane.csv, or a GeoJSON file (.geojson, .json).
+//
+// Exit codes: 0 on success, 1 on runtime error (I/O, generation), 2 on invalid command line, 3 if validation has found problems.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Exit codes
+const (
+	EXIT_OK         = 0
+	EXIT_ERROR      = 1
+	EXIT_USAGE      = 2
+	EXIT_VALIDATION = 3
+)
+
+// command is a subcommand of the tool
+type command struct {
+	name        string
+	description string
+	run         func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands = []command{
+	{"generate", "generate movements, mesoscopic and microscopic networks from macroscopic one", runGenerate},
+	{"validate", "check consistency of macroscopic network", runValidate},
+	{"stats", "print summary of macroscopic network and generated ones", runStats},
+	{"convert", "convert macroscopic network between CSV and GeoJSON", runConvert},
+	{"geojson", "export networks as GeoJSON for debugging", runGeoJSON},
+	{"diff", "compare two versions of macroscopic network", runDiff},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the subcommand and returns exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return EXIT_USAGE
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		usage(stdout)
+		return EXIT_OK
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:], stdout, stderr)
+		}
+	}
+	fmt.Fprintf(stderr, "gmns: unknown command '%s'\n\n", args[0])
+	usage(stderr)
+	return EXIT_USAGE
+}
+
+// usage prints list of subcommands
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gmns <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'gmns <command> -h' for flags of the command")
+}
+
+// fail prints the error and returns runtime error exit code
+func fail(stderr io.Writer, err error) int {
+	fmt.Fprintf(stderr, "gmns: %v\n", err)
+	return EXIT_ERROR
+}
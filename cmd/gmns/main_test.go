@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeGridTables writes 3x3 grid of bidirectional links as GMNS tables with minimal set of columns
+func writeGridTables(t *testing.T, dir string) {
+	nodes := strings.Builder{}
+	nodes.WriteString("node_id,x_coord,y_coord\n")
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			fmt.Fprintf(&nodes, "%d,%f,%f\n", i*3+j, 37.6+float64(j)*0.002, 55.75+float64(i)*0.002)
+		}
+	}
+	links := strings.Builder{}
+	links.WriteString("link_id,from_node_id,to_node_id,lanes,free_speed,link_type,allowed_uses\n")
+	linkID := 0
+	addLink := func(source, target int) {
+		fmt.Fprintf(&links, "%d,%d,%d,%d,60,primary,\"auto,bike,walk\"\n", linkID, source, target, 1+linkID%2)
+		linkID++
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if j+1 < 3 {
+				addLink(i*3+j, i*3+j+1)
+				addLink(i*3+j+1, i*3+j)
+			}
+			if i+1 < 3 {
+				addLink(i*3+j, (i+1)*3+j)
+				addLink((i+1)*3+j, i*3+j)
+			}
+		}
+	}
+	assert.NoError(t, os.MkdirAll(dir, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, NODES_FILE), []byte(nodes.String()), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, LINKS_FILE), []byte(links.String()), 0o644))
+}
+
+func runArgs(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCommands(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input")
+	writeGridTables(t, input)
+
+	code, stdout, _ := runArgs("validate", "-in", input)
+	assert.Equal(t, EXIT_OK, code)
+	assert.Contains(t, stdout, "ok: 9 nodes, 24 links")
+
+	code, stdout, _ = runArgs("stats", "-in", input)
+	assert.Equal(t, EXIT_OK, code)
+	assert.Contains(t, stdout, "links:")
+	assert.NotContains(t, stdout, "Movements")
+	code, stdout, _ = runArgs("stats", "-in", input, "-generate", "-format", "markdown")
+	assert.Equal(t, EXIT_OK, code)
+	assert.Contains(t, stdout, "## Microscopic network")
+
+	output := filepath.Join(tmp, "output")
+	report := filepath.Join(tmp, "report.json")
+	code, _, stderr := runArgs("generate", "-in", input, "-out", output, "-cell-sizing", "adaptive", "-cut-lengths", "2,8,12", "-report", report)
+	assert.Equal(t, EXIT_OK, code, stderr)
+	for _, path := range []string{MOVEMENTS_FILE, filepath.Join(MESO_DIR, LINKS_FILE), filepath.Join(MICRO_DIR, NODES_FILE), "../report.json"} {
+		assert.FileExists(t, filepath.Join(output, path))
+	}
+
+	// CSV -> GeoJSON -> CSV keeps the network
+	geojsonPath := filepath.Join(tmp, "macro.geojson")
+	code, _, stderr = runArgs("convert", "-in", input, "-out", geojsonPath)
+	assert.Equal(t, EXIT_OK, code, stderr)
+	roundTrip := filepath.Join(tmp, "round_trip")
+	code, _, stderr = runArgs("convert", "-in", geojsonPath, "-out", roundTrip)
+	assert.Equal(t, EXIT_OK, code, stderr)
+	direct := filepath.Join(tmp, "direct")
+	code, _, stderr = runArgs("convert", "-in", input, "-out", direct)
+	assert.Equal(t, EXIT_OK, code, stderr)
+	for _, file := range []string{NODES_FILE, LINKS_FILE, LANES_FILE} {
+		expected, err := os.ReadFile(filepath.Join(direct, file))
+		assert.NoError(t, err)
+		actual, err := os.ReadFile(filepath.Join(roundTrip, file))
+		assert.NoError(t, err)
+		assert.Equal(t, string(expected), string(actual), file)
+	}
+
+	patch := filepath.Join(tmp, "patch.json")
+	code, stdout, stderr = runArgs("diff", "-old", input, "-new", geojsonPath, "-movements", "-patch", patch)
+	assert.Equal(t, EXIT_OK, code, stderr)
+	assert.Contains(t, stdout, "links: 0 added, 0 removed, 0 modified")
+	assert.FileExists(t, patch)
+
+	debug := filepath.Join(tmp, "debug")
+	code, _, stderr = runArgs("geojson", "-in", geojsonPath, "-out", debug, "-layers", "macro,meso")
+	assert.Equal(t, EXIT_OK, code, stderr)
+	assert.FileExists(t, filepath.Join(debug, "meso.geojson"))
+	assert.NoFileExists(t, filepath.Join(debug, "micro.geojson"))
+}
+
+func TestExitCodes(t *testing.T) {
+	tmp := t.TempDir()
+	input := filepath.Join(tmp, "input")
+	writeGridTables(t, input)
+
+	code, _, _ := runArgs()
+	assert.Equal(t, EXIT_USAGE, code)
+	code, _, _ = runArgs("unknown")
+	assert.Equal(t, EXIT_USAGE, code)
+	code, _, _ = runArgs("generate", "-in", input)
+	assert.Equal(t, EXIT_USAGE, code, "Output is required")
+	code, _, _ = runArgs("generate", "-in", input, "-out", tmp, "-cell-sizing", "huge")
+	assert.Equal(t, EXIT_USAGE, code)
+	code, _, _ = runArgs("stats", "-in", input, "-format", "xml")
+	assert.Equal(t, EXIT_USAGE, code)
+	code, _, _ = runArgs("stats", "-in", filepath.Join(tmp, "absent"))
+	assert.Equal(t, EXIT_ERROR, code)
+	code, _, stderr := runArgs("validate", "-in", filepath.Join(tmp, "absent"))
+	assert.Equal(t, EXIT_ERROR, code, "Missing input should be runtime error")
+	assert.Contains(t, stderr, "Can't open file")
+	code, _, _ = runArgs("validate", "-in", filepath.Join(tmp, "absent.geojson"))
+	assert.Equal(t, EXIT_ERROR, code)
+	broken := filepath.Join(tmp, "broken.geojson")
+	assert.NoError(t, os.WriteFile(broken, []byte("{"), 0o644))
+	code, _, _ = runArgs("validate", "-in", broken)
+	assert.Equal(t, EXIT_VALIDATION, code, "Network which can't be parsed should be reported as invalid")
+
+	// Link to absent node
+	links, err := os.ReadFile(filepath.Join(input, LINKS_FILE))
+	assert.NoError(t, err)
+	links = append(links, []byte("100,0,42,1,60,primary,auto\n")...)
+	assert.NoError(t, os.WriteFile(filepath.Join(input, LINKS_FILE), links, 0o644))
+	code, stdout, _ := runArgs("validate", "-in", input)
+	assert.Equal(t, EXIT_VALIDATION, code)
+	assert.Contains(t, stdout, "node not found")
+}
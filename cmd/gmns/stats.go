@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/LdDl/go-gmns/stats"
+)
+
+// Output formats of stats command
+const (
+	FORMAT_TEXT     = "text"
+	FORMAT_JSON     = "json"
+	FORMAT_MARKDOWN = "markdown"
+)
+
+// runStats prints summary of macroscopic network and, if requested, of generated movements, mesoscopic and microscopic networks
+func runStats(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("stats", stderr)
+	input := fs.String("in", "", "macroscopic network: directory with node.csv and link.csv or GeoJSON file")
+	format := fs.String("format", FORMAT_TEXT, "output format: text, json or markdown")
+	withGenerated := fs.Bool("generate", false, "generate movements, mesoscopic and microscopic networks and summarize them too")
+	genFlags := addGenFlags(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *input == "" {
+		fmt.Fprintln(stderr, "gmns stats: flag -in is required")
+		fs.Usage()
+		return EXIT_USAGE
+	}
+	var write func(w io.Writer, s *stats.Stats) error
+	switch *format {
+	case FORMAT_TEXT:
+		write = stats.WriteText
+	case FORMAT_JSON:
+		write = stats.WriteJSON
+	case FORMAT_MARKDOWN:
+		write = stats.WriteMarkdown
+	default:
+		fmt.Fprintf(stderr, "gmns stats: unknown format '%s'\n", *format)
+		return EXIT_USAGE
+	}
+	movementsOpts, mesoOpts, microOpts, err := genFlags.options(stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "gmns stats: %v\n", err)
+		return EXIT_USAGE
+	}
+	macroNet, err := readMacroNet(*input)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	nets := &networks{macroNet: macroNet}
+	if *withGenerated {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		nets, err = generate(ctx, macroNet, movementsOpts, mesoOpts, microOpts, true)
+		if err != nil {
+			return fail(stderr, err)
+		}
+		err = writeReport(genFlags.reportPath, movementsOpts.Report)
+		if err != nil {
+			return fail(stderr, err)
+		}
+	}
+	err = write(stdout, stats.Compute(nets.macroNet, nets.movements, nets.mesoNet, nets.microNet))
+	if err != nil {
+		return fail(stderr, err)
+	}
+	return EXIT_OK
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// runValidate checks consistency of macroscopic network and prints found problems. Exit code is EXIT_VALIDATION if there are problems
+// (including network which can't be parsed) and EXIT_ERROR if the network can't be read from the file system
+func runValidate(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("validate", stderr)
+	input := fs.String("in", "", "macroscopic network: directory with node.csv and link.csv or GeoJSON file")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *input == "" {
+		fmt.Fprintln(stderr, "gmns validate: flag -in is required")
+		fs.Usage()
+		return EXIT_USAGE
+	}
+	macroNet, err := readMacroNet(*input)
+	if err != nil {
+		if isIOError(err) {
+			return fail(stderr, err)
+		}
+		// Network which can't be parsed is invalid as well
+		fmt.Fprintf(stdout, "%v\n", err)
+		return EXIT_VALIDATION
+	}
+	problems := macroNet.Validate()
+	for _, problem := range problems {
+		fmt.Fprintln(stdout, problem)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(stdout, "%d problem(s) found\n", len(problems))
+		return EXIT_VALIDATION
+	}
+	fmt.Fprintf(stdout, "ok: %d nodes, %d links\n", len(macroNet.Nodes), len(macroNet.Links))
+	return EXIT_OK
+}
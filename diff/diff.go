@@ -0,0 +1,409 @@
+// Package diff compares two versions of macroscopic network (e.g. produced from different OSM extracts) and reports added, removed and modified
+// nodes, links and movements. Elements are matched by OSM identifiers and geometries rather than by internal identifiers which are not stable between runs.
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/LdDl/go-gmns/movement"
+	"github.com/LdDl/go-gmns/utils/geomath"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/wkt"
+)
+
+// ChangeKind is the kind of the element change
+type ChangeKind string
+
+const (
+	CHANGE_ADDED    = ChangeKind("added")
+	CHANGE_REMOVED  = ChangeKind("removed")
+	CHANGE_MODIFIED = ChangeKind("modified")
+)
+
+// ElementType is the type of the changed element
+type ElementType string
+
+const (
+	ELEMENT_NODE     = ElementType("node")
+	ELEMENT_LINK     = ElementType("link")
+	ELEMENT_MOVEMENT = ElementType("movement")
+)
+
+// Attribute which values are compared by geometry hashes
+const geometryAttribute = "geometry"
+
+// AttributeChange is the change of the element attribute. Old value is empty for added elements, new value is empty for removed ones
+type AttributeChange struct {
+	Attribute string `json:"attribute"`
+	Old       string `json:"old"`
+	New       string `json:"new"`
+}
+
+// ElementChange is the change of the node, link or movement
+type ElementChange struct {
+	Element ElementType `json:"element"`
+	Kind    ChangeKind  `json:"kind"`
+	// Key the element has been matched by: OSM identifiers or geometry hash
+	Key string `json:"key"`
+	// Identifier in the old network. -1 for added elements
+	OldID int64 `json:"old_id"`
+	// Identifier in the new network. -1 for removed elements
+	NewID int64 `json:"new_id"`
+	// Changed attributes for modified elements, every attribute for added and removed ones
+	Changes []AttributeChange `json:"changes"`
+
+	geom orb.Geometry
+}
+
+// Diff is the list of changes between two versions of the network. Changes are ordered by kinds (removed, added, modified) and keys
+type Diff struct {
+	Nodes     []ElementChange `json:"nodes"`
+	Links     []ElementChange `json:"links"`
+	Movements []ElementChange `json:"movements"`
+}
+
+// Empty checks if there are no changes
+func (d *Diff) Empty() bool {
+	return len(d.Nodes) == 0 && len(d.Links) == 0 && len(d.Movements) == 0
+}
+
+// Count returns number of changes of the given element type and kind
+func (d *Diff) Count(element ElementType, kind ChangeKind) int {
+	changes := d.Nodes
+	switch element {
+	case ELEMENT_LINK:
+		changes = d.Links
+	case ELEMENT_MOVEMENT:
+		changes = d.Movements
+	}
+	count := 0
+	for _, change := range changes {
+		if change.Kind == kind {
+			count++
+		}
+	}
+	return count
+}
+
+// Compare returns changes between the old and the new networks. Movements are compared only if both storages are not nil.
+// Nodes are matched by OSM node identifiers, links by OSM way identifier together with OSM identifiers of their end nodes,
+// movements by keys of their node, incoming and outcoming links. Elements left unmatched are matched by geometry hashes (geomath.GeometryHash) then
+func Compare(oldNet, newNet *macro.Net, oldMovements, newMovements movement.MovementsStorage) *Diff {
+	d := &Diff{
+		Nodes:     []ElementChange{},
+		Links:     []ElementChange{},
+		Movements: []ElementChange{},
+	}
+	oldNodes := nodesElements(oldNet)
+	newNodes := nodesElements(newNet)
+	d.Nodes = compareElements(ELEMENT_NODE, oldNodes, newNodes)
+	// Keys of matched elements are shared by both networks, so links and movements could refer to them
+	oldNodesKeys, newNodesKeys := elementsKeys[gmns.NodeID](oldNodes), elementsKeys[gmns.NodeID](newNodes)
+	oldLinks := linksElements(oldNet, oldNodesKeys)
+	newLinks := linksElements(newNet, newNodesKeys)
+	d.Links = compareElements(ELEMENT_LINK, oldLinks, newLinks)
+	if oldMovements != nil && newMovements != nil {
+		oldLinksKeys, newLinksKeys := elementsKeys[gmns.LinkID](oldLinks), elementsKeys[gmns.LinkID](newLinks)
+		d.Movements = compareElements(ELEMENT_MOVEMENT, movementsElements(oldMovements, oldNet, oldNodesKeys, oldLinksKeys), movementsElements(newMovements, newNet, newNodesKeys, newLinksKeys))
+	}
+	return d
+}
+
+// element is a network element prepared for comparison
+type element struct {
+	id int64
+	// Matching keys in order of priority. Empty key is ignored
+	keys       []string
+	attributes [][2]string
+	geom       orb.Geometry
+	// Key the element has been matched by. Empty for unmatched elements
+	matchedKey string
+}
+
+// key returns the key the element has been matched by or its first non-empty key if it has not been matched
+func (elem *element) key() string {
+	if elem.matchedKey != "" {
+		return elem.matchedKey
+	}
+	return firstKey(elem)
+}
+
+// elementsKeys returns keys of elements by identifiers
+func elementsKeys[T ~int64 | ~int](elements []*element) map[T]string {
+	keys := make(map[T]string, len(elements))
+	for _, elem := range elements {
+		keys[T(elem.id)] = elem.key()
+	}
+	return keys
+}
+
+// compareElements matches elements by keys stage by stage and returns sorted changes
+func compareElements(elementType ElementType, oldElements, newElements []*element) []ElementChange {
+	changes := []ElementChange{}
+	matchedOld := make(map[*element]bool, len(oldElements))
+	matchedNew := make(map[*element]bool, len(newElements))
+	stages := 0
+	for _, elem := range append(append([]*element{}, oldElements...), newElements...) {
+		stages = max(stages, len(elem.keys))
+	}
+	for stage := 0; stage < stages; stage++ {
+		newByKey := uniqueByKey(newElements, matchedNew, stage)
+		oldByKey := uniqueByKey(oldElements, matchedOld, stage)
+		for key, oldElem := range oldByKey {
+			newElem, ok := newByKey[key]
+			if !ok {
+				continue
+			}
+			matchedOld[oldElem] = true
+			matchedNew[newElem] = true
+			oldElem.matchedKey = key
+			newElem.matchedKey = key
+			attrsChanges := compareAttributes(oldElem.attributes, newElem.attributes)
+			if len(attrsChanges) == 0 {
+				continue
+			}
+			changes = append(changes, ElementChange{
+				Element: elementType,
+				Kind:    CHANGE_MODIFIED,
+				Key:     key,
+				OldID:   oldElem.id,
+				NewID:   newElem.id,
+				Changes: attrsChanges,
+				geom:    newElem.geom,
+			})
+		}
+	}
+	for _, oldElem := range oldElements {
+		if matchedOld[oldElem] {
+			continue
+		}
+		attrsChanges := make([]AttributeChange, len(oldElem.attributes))
+		for i, attr := range oldElem.attributes {
+			attrsChanges[i] = AttributeChange{Attribute: attr[0], Old: attr[1]}
+		}
+		changes = append(changes, ElementChange{Element: elementType, Kind: CHANGE_REMOVED, Key: firstKey(oldElem), OldID: oldElem.id, NewID: -1, Changes: attrsChanges, geom: oldElem.geom})
+	}
+	for _, newElem := range newElements {
+		if matchedNew[newElem] {
+			continue
+		}
+		attrsChanges := make([]AttributeChange, len(newElem.attributes))
+		for i, attr := range newElem.attributes {
+			attrsChanges[i] = AttributeChange{Attribute: attr[0], New: attr[1]}
+		}
+		changes = append(changes, ElementChange{Element: elementType, Kind: CHANGE_ADDED, Key: firstKey(newElem), OldID: -1, NewID: newElem.id, Changes: attrsChanges, geom: newElem.geom})
+	}
+	kindOrder := map[ChangeKind]int{CHANGE_REMOVED: 0, CHANGE_ADDED: 1, CHANGE_MODIFIED: 2}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return kindOrder[changes[i].Kind] < kindOrder[changes[j].Kind]
+		}
+		if changes[i].Key != changes[j].Key {
+			return changes[i].Key < changes[j].Key
+		}
+		if changes[i].OldID != changes[j].OldID {
+			return changes[i].OldID < changes[j].OldID
+		}
+		return changes[i].NewID < changes[j].NewID
+	})
+	return changes
+}
+
+// uniqueByKey returns unmatched elements by their key of the given stage. Elements sharing the key are ambiguous and left for the next stages
+func uniqueByKey(elements []*element, matched map[*element]bool, stage int) map[string]*element {
+	byKey := make(map[string]*element, len(elements))
+	ambiguous := map[string]bool{}
+	for _, elem := range elements {
+		if matched[elem] || stage >= len(elem.keys) || elem.keys[stage] == "" {
+			continue
+		}
+		key := elem.keys[stage]
+		if _, ok := byKey[key]; ok {
+			ambiguous[key] = true
+			continue
+		}
+		byKey[key] = elem
+	}
+	for key := range ambiguous {
+		delete(byKey, key)
+	}
+	return byKey
+}
+
+// firstKey returns the first non-empty key of the element or its identifier if there are no keys
+func firstKey(elem *element) string {
+	for _, key := range elem.keys {
+		if key != "" {
+			return key
+		}
+	}
+	return "id:" + strconv.FormatInt(elem.id, 10)
+}
+
+// compareAttributes returns changed attributes. Both lists are expected to have the same attributes in the same order
+func compareAttributes(oldAttributes, newAttributes [][2]string) []AttributeChange {
+	changes := []AttributeChange{}
+	for i := range oldAttributes {
+		oldValue, newValue := oldAttributes[i][1], newAttributes[i][1]
+		if oldValue == newValue {
+			continue
+		}
+		if oldAttributes[i][0] == geometryAttribute && wktHash(oldValue) == wktHash(newValue) {
+			continue
+		}
+		changes = append(changes, AttributeChange{Attribute: oldAttributes[i][0], Old: oldValue, New: newValue})
+	}
+	return changes
+}
+
+// wktHash returns geometry hash of WKT point or linestring. Small differences of coordinates (less than 1e-6) are ignored this way
+func wktHash(value string) string {
+	geom, err := wkt.Unmarshal(value)
+	if err != nil {
+		return value
+	}
+	switch g := geom.(type) {
+	case orb.Point:
+		return geomath.GeometryHash(orb.LineString{g})
+	case orb.LineString:
+		return geomath.GeometryHash(g)
+	default:
+		return value
+	}
+}
+
+// nodesElements returns nodes prepared for comparison
+func nodesElements(net *macro.Net) []*element {
+	nodesIDs := make([]gmns.NodeID, 0, len(net.Nodes))
+	for nodeID := range net.Nodes {
+		nodesIDs = append(nodesIDs, nodeID)
+	}
+	sort.Slice(nodesIDs, func(i, j int) bool {
+		return nodesIDs[i] < nodesIDs[j]
+	})
+	elements := make([]*element, 0, len(nodesIDs))
+	for _, nodeID := range nodesIDs {
+		node := net.Nodes[nodeID]
+		osmKey := ""
+		if node.OSMNode() > 0 {
+			osmKey = fmt.Sprintf("osm_node:%d", node.OSMNode())
+		}
+		geomKey := "geom:" + geomath.GeometryHash(orb.LineString{node.Geom()})
+		elements = append(elements, &element{
+			id:   int64(nodeID),
+			keys: []string{osmKey, geomKey},
+			attributes: [][2]string{
+				{"name", node.Name()},
+				{"osm_highway", node.OSMHighway()},
+				{"ctrl_type", node.ControlType().String()},
+				{"boundary_type", node.BoundaryType().String()},
+				{"activity_type", node.ActivityType().String()},
+				{geometryAttribute, wkt.MarshalString(node.Geom())},
+			},
+			geom: node.Geom(),
+		})
+	}
+	return elements
+}
+
+// linksElements returns links prepared for comparison
+func linksElements(net *macro.Net, nodesKeys map[gmns.NodeID]string) []*element {
+	linksIDs := make([]gmns.LinkID, 0, len(net.Links))
+	for linkID := range net.Links {
+		linksIDs = append(linksIDs, linkID)
+	}
+	sort.Slice(linksIDs, func(i, j int) bool {
+		return linksIDs[i] < linksIDs[j]
+	})
+	elements := make([]*element, 0, len(linksIDs))
+	for _, linkID := range linksIDs {
+		link := net.Links[linkID]
+		osmKey := ""
+		if link.OSMWay() > 0 {
+			osmKey = fmt.Sprintf("osm_way:%d:%d->%d", link.OSMWay(), link.SourceOSMNode(), link.TargetOSMNode())
+		}
+		// Nodes could be matched by OSM identifiers even if links have no ones
+		nodesKey := fmt.Sprintf("nodes:%s->%s", nodesKeys[link.SourceNode()], nodesKeys[link.TargetNode()])
+		geomKey := "geom:" + geomath.GeometryHash(link.Geom())
+		pockets := link.TurnPockets()
+		elements = append(elements, &element{
+			id:   int64(linkID),
+			keys: []string{osmKey, geomKey, nodesKey},
+			attributes: [][2]string{
+				{"name", link.Name()},
+				{"link_type", link.LinkType().String()},
+				{"link_class", link.LinkClass().String()},
+				{"is_link", link.LinkConnectionType().String()},
+				{"ctrl_type", link.ControlType().String()},
+				{"lanes", strconv.Itoa(link.LanesNum())},
+				{"free_speed", formatFloat(link.FreeSpeed())},
+				{"max_speed", formatFloat(link.MaxSpeed())},
+				{"capacity", strconv.Itoa(link.Capacity())},
+				{"allowed_uses", joinStrings(link.AllowedAgentTypes(), ",")},
+				{"turn_lanes", joinStrings(link.TurnLanes(), "|")},
+				{"change_lanes", joinStrings(link.LaneChanges(), "|")},
+				{"left_pocket", fmt.Sprintf("%d x %s", pockets.Left.Lanes, formatFloat(pockets.Left.Length))},
+				{"right_pocket", fmt.Sprintf("%d x %s", pockets.Right.Lanes, formatFloat(pockets.Right.Length))},
+				{"length", strconv.FormatFloat(link.LengthMeters(), 'f', 2, 64)},
+				{geometryAttribute, wkt.MarshalString(link.Geom())},
+			},
+			geom: link.Geom(),
+		})
+	}
+	return elements
+}
+
+// movementsElements returns movements prepared for comparison. Movements are matched by keys of their node and links only
+func movementsElements(movements movement.MovementsStorage, net *macro.Net, nodesKeys map[gmns.NodeID]string, linksKeys map[gmns.LinkID]string) []*element {
+	mvmtsIDs := make([]gmns.MovementID, 0, len(movements))
+	for mvmtID := range movements {
+		mvmtsIDs = append(mvmtsIDs, mvmtID)
+	}
+	sort.Slice(mvmtsIDs, func(i, j int) bool {
+		return mvmtsIDs[i] < mvmtsIDs[j]
+	})
+	elements := make([]*element, 0, len(mvmtsIDs))
+	for _, mvmtID := range mvmtsIDs {
+		mvmt := movements[mvmtID]
+		var geom orb.Geometry = mvmt.Geom()
+		if len(mvmt.Geom()) < 2 {
+			if node, ok := net.Nodes[mvmt.MacroNode()]; ok {
+				geom = node.Geom()
+			}
+		}
+		elements = append(elements, &element{
+			id:   int64(mvmtID),
+			keys: []string{fmt.Sprintf("movement:%s:%s=>%s", nodesKeys[mvmt.MacroNode()], linksKeys[mvmt.IncomeMacroLink()], linksKeys[mvmt.OutcomeMacroLink()])},
+			attributes: [][2]string{
+				{"type", mvmt.Type().String()},
+				{"mvmt_txt_id", mvmt.MvmtTextID().String()},
+				{"ctrl_type", mvmt.ControlType().String()},
+				{"lanes", strconv.Itoa(mvmt.LanesNum())},
+				{"ib_lanes", fmt.Sprintf("%d..%d", mvmt.IncomeLaneStart(), mvmt.IncomeLaneEnd())},
+				{"ob_lanes", fmt.Sprintf("%d..%d", mvmt.OutcomeLaneStart(), mvmt.OutcomeLaneEnd())},
+				{"allowed_uses", joinStrings(mvmt.AllowedAgentTypes(), ",")},
+			},
+			geom: geom,
+		})
+	}
+	return elements
+}
+
+// joinStrings joins string representations of values with the separator
+func joinStrings[T fmt.Stringer](values []T, sep string) string {
+	strs := make([]string, len(values))
+	for i, value := range values {
+		strs[i] = value.String()
+	}
+	return strings.Join(strs, sep)
+}
+
+// formatFloat returns the shortest representation of the float
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
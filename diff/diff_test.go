@@ -0,0 +1,95 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/LdDl/go-gmns/generators"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/stretchr/testify/assert"
+)
+
+// crossNet returns intersection of two bidirectional roads. Identifiers are shifted by idShift to emulate renumbering between runs
+func crossNet(t *testing.T, idShift int, centerCtrl string, links [][4]int) *macro.Net {
+	nodes := "node_id,osm_node_id,ctrl_type,x_coord,y_coord\n" +
+		fmt.Sprintf("%d,100,%s,37.6,55.75\n", idShift, centerCtrl) +
+		fmt.Sprintf("%d,101,,37.599,55.75\n", idShift+1) +
+		fmt.Sprintf("%d,102,,37.601,55.75\n", idShift+2) +
+		fmt.Sprintf("%d,103,,37.6,55.749\n", idShift+3) +
+		fmt.Sprintf("%d,104,,37.6,55.751\n", idShift+4)
+	linksCSV := strings.Builder{}
+	linksCSV.WriteString("link_id,osm_way_id,from_node_id,to_node_id,lanes,free_speed,link_type,allowed_uses\n")
+	for i, link := range links {
+		// OSM way identifier, source and target nodes offsets, lanes
+		fmt.Fprintf(&linksCSV, "%d,%d,%d,%d,%d,60,primary,auto\n", idShift+i, link[0], idShift+link[1], idShift+link[2], link[3])
+	}
+	net := macro.NewNet()
+	assert.NoError(t, macro.ReadNodesCSV(strings.NewReader(nodes), net))
+	assert.NoError(t, macro.ReadLinksCSV(strings.NewReader(linksCSV.String()), net))
+	return net
+}
+
+func TestCompare(t *testing.T) {
+	oldNet := crossNet(t, 0, "", [][4]int{
+		{10, 1, 0, 1}, {10, 0, 1, 1}, {10, 0, 2, 1}, {10, 2, 0, 1},
+		{20, 3, 0, 1}, {20, 0, 3, 1}, {20, 0, 4, 1}, {20, 4, 0, 1},
+	})
+	// Renumbered network: signal at the center, more lanes on the way 10 heading east, the way 20 heading north is removed and the new way is added
+	newNet := crossNet(t, 50, "signal", [][4]int{
+		{20, 0, 3, 1}, {20, 3, 0, 1}, {20, 4, 0, 1},
+		{10, 1, 0, 1}, {10, 0, 1, 1}, {10, 0, 2, 2}, {10, 2, 0, 1},
+		{30, 4, 2, 1},
+	})
+	oldMovements, err := generators.GenerateMovements(oldNet)
+	assert.NoError(t, err)
+	newMovements, err := generators.GenerateMovements(newNet)
+	assert.NoError(t, err)
+
+	d := Compare(oldNet, newNet, oldMovements, newMovements)
+	assert.Equal(t, []ElementChange{{
+		Element: ELEMENT_NODE,
+		Kind:    CHANGE_MODIFIED,
+		Key:     "osm_node:100",
+		OldID:   0,
+		NewID:   50,
+		Changes: []AttributeChange{{Attribute: "ctrl_type", Old: "common", New: "signal"}},
+	}}, stripGeoms(d.Nodes))
+	assert.Len(t, d.Links, 3)
+	assert.Equal(t, CHANGE_REMOVED, d.Links[0].Kind)
+	assert.Equal(t, "osm_way:20:100->104", d.Links[0].Key)
+	assert.Equal(t, CHANGE_ADDED, d.Links[1].Kind)
+	assert.Equal(t, "osm_way:30:104->102", d.Links[1].Key)
+	assert.Equal(t, CHANGE_MODIFIED, d.Links[2].Kind)
+	assert.Equal(t, []AttributeChange{{Attribute: "lanes", Old: "1", New: "2"}}, d.Links[2].Changes)
+	assert.Greater(t, d.Count(ELEMENT_MOVEMENT, CHANGE_REMOVED), 0, "Movements to the removed link are expected to be removed")
+	assert.Greater(t, d.Count(ELEMENT_MOVEMENT, CHANGE_ADDED), 0, "Movements from the new link are expected to be added")
+	assert.Zero(t, len(Compare(oldNet, oldNet, oldMovements, oldMovements).Movements), "Same movements are expected to be matched")
+
+	fc := d.GeoFeatureCollection()
+	assert.Len(t, fc.Features, len(d.Nodes)+len(d.Links)+len(d.Movements))
+	assert.Equal(t, "lanes: 1 -> 2", fc.Features[3].Properties["summary"])
+
+	var buf bytes.Buffer
+	assert.NoError(t, WritePatch(&buf, d))
+	patch, err := ReadPatch(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, stripGeoms(d.Links), patch.Links)
+}
+
+func TestCompareByGeometry(t *testing.T) {
+	links := [][4]int{{-1, 1, 0, 1}, {-1, 0, 2, 1}}
+	oldNet := crossNet(t, 0, "", links)
+	newNet := crossNet(t, 10, "", links)
+	assert.True(t, Compare(oldNet, newNet, nil, nil).Empty(), "Links without OSM identifiers are expected to be matched by geometries")
+}
+
+func stripGeoms(changes []ElementChange) []ElementChange {
+	result := make([]ElementChange, len(changes))
+	for i, change := range changes {
+		change.geom = nil
+		result[i] = change
+	}
+	return result
+}
@@ -0,0 +1,50 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/paulmach/orb/geojson"
+)
+
+// GeoFeature returns GeoJSON feature of the change: geometry of the new element (of the old one for removed elements) and properties describing the change.
+// Values of geometry attribute are omitted in the summary
+func (change *ElementChange) GeoFeature() *geojson.Feature {
+	f := geojson.NewFeature(change.geom)
+	f.Properties["element"] = string(change.Element)
+	f.Properties["kind"] = string(change.Kind)
+	f.Properties["key"] = change.Key
+	f.Properties["old_id"] = change.OldID
+	f.Properties["new_id"] = change.NewID
+	attributes := make([]string, len(change.Changes))
+	summary := make([]string, 0, len(change.Changes))
+	for i, attr := range change.Changes {
+		attributes[i] = attr.Attribute
+		if attr.Attribute == geometryAttribute {
+			summary = append(summary, attr.Attribute)
+			continue
+		}
+		switch change.Kind {
+		case CHANGE_ADDED:
+			summary = append(summary, fmt.Sprintf("%s: %s", attr.Attribute, attr.New))
+		case CHANGE_REMOVED:
+			summary = append(summary, fmt.Sprintf("%s: %s", attr.Attribute, attr.Old))
+		default:
+			summary = append(summary, fmt.Sprintf("%s: %s -> %s", attr.Attribute, attr.Old, attr.New))
+		}
+	}
+	f.Properties["attributes"] = strings.Join(attributes, ",")
+	f.Properties["summary"] = strings.Join(summary, "; ")
+	return f
+}
+
+// GeoFeatureCollection returns GeoJSON FeatureCollection of every change for map review. Changes read from a patch have no geometries
+func (d *Diff) GeoFeatureCollection() *geojson.FeatureCollection {
+	fc := geojson.NewFeatureCollection()
+	for _, changes := range [][]ElementChange{d.Nodes, d.Links, d.Movements} {
+		for i := range changes {
+			fc.Append(changes[i].GeoFeature())
+		}
+	}
+	return fc
+}
@@ -0,0 +1,30 @@
+package diff
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// WritePatch writes the diff as JSON patch: every change with its kind, matching key, identifiers in both networks and changed attributes.
+// Added and removed elements carry all their attributes, so the patch is self-contained
+func WritePatch(w io.Writer, d *Diff) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(d)
+	if err != nil {
+		return errors.Wrap(err, "Can't encode diff patch")
+	}
+	return nil
+}
+
+// ReadPatch reads JSON patch written by WritePatch
+func ReadPatch(r io.Reader) (*Diff, error) {
+	d := &Diff{}
+	err := json.NewDecoder(r).Decode(d)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't decode diff patch")
+	}
+	return d, nil
+}
@@ -0,0 +1,422 @@
+package editor
+
+import (
+	"github.com/LdDl/go-gmns/generators"
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/LdDl/go-gmns/movement"
+	"github.com/pkg/errors"
+)
+
+// command is applied operation which could be reverted
+type command struct {
+	op       Operation
+	snapshot *snapshot
+	split    *macro.LinkSplit
+	merged   map[gmns.LinkID]gmns.LinkID
+}
+
+// Transaction is group of operations which are committed, rolled back, undone and redone together
+type Transaction struct {
+	commands []*command
+}
+
+// Operations returns operations of the transaction in order of application
+func (tx *Transaction) Operations() []Operation {
+	ops := make([]Operation, len(tx.commands))
+	for i, cmd := range tx.commands {
+		ops[i] = cmd.op
+	}
+	return ops
+}
+
+// Editor applies reversible operations to the macroscopic network and corresponding movements.
+// Operations applied outside of explicit transaction are committed immediately as single-operation transactions
+type Editor struct {
+	net       *macro.Net
+	movements movement.MovementsStorage
+	options   generators.MovementsGenOptions
+	current   *Transaction
+	undoStack []*Transaction
+	redoStack []*Transaction
+}
+
+// NewEditor returns pointer to the new editor. Movements could be nil if there are no movements to keep in sync.
+// Driving side and identifiers allocator of movements options are used for movements created by operations
+func NewEditor(net *macro.Net, movements movement.MovementsStorage, opts ...generators.MovementsGenOptions) *Editor {
+	options := generators.DefaultMovementsGenOptions()
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	return &Editor{
+		net:       net,
+		movements: movements,
+		options:   options,
+		current:   nil,
+		undoStack: make([]*Transaction, 0),
+		redoStack: make([]*Transaction, 0),
+	}
+}
+
+// Begin starts the new transaction
+func (editor *Editor) Begin() error {
+	if editor.current != nil {
+		return ErrTransactionInProgress
+	}
+	editor.current = &Transaction{commands: make([]*command, 0)}
+	return nil
+}
+
+// Commit finishes the current transaction and puts it to the undo stack. Redo stack is cleared
+func (editor *Editor) Commit() error {
+	if editor.current == nil {
+		return ErrNoTransaction
+	}
+	if len(editor.current.commands) > 0 {
+		editor.undoStack = append(editor.undoStack, editor.current)
+		editor.redoStack = editor.redoStack[:0]
+	}
+	editor.current = nil
+	return nil
+}
+
+// Rollback reverts every operation of the current transaction and finishes it
+func (editor *Editor) Rollback() error {
+	if editor.current == nil {
+		return ErrNoTransaction
+	}
+	editor.revert(editor.current)
+	editor.current = nil
+	return nil
+}
+
+// Undo reverts the last committed transaction
+func (editor *Editor) Undo() error {
+	if editor.current != nil {
+		return ErrTransactionInProgress
+	}
+	if len(editor.undoStack) == 0 {
+		return ErrNothingToUndo
+	}
+	tx := editor.undoStack[len(editor.undoStack)-1]
+	editor.undoStack = editor.undoStack[:len(editor.undoStack)-1]
+	editor.revert(tx)
+	editor.redoStack = append(editor.redoStack, tx)
+	return nil
+}
+
+// Redo applies again the last undone transaction
+func (editor *Editor) Redo() error {
+	if editor.current != nil {
+		return ErrTransactionInProgress
+	}
+	if len(editor.redoStack) == 0 {
+		return ErrNothingToRedo
+	}
+	tx := editor.redoStack[len(editor.redoStack)-1]
+	redone := &Transaction{commands: make([]*command, 0, len(tx.commands))}
+	for _, cmd := range tx.commands {
+		redoneCmd, err := execute(editor.net, editor.movements, editor.options, cmd.op)
+		if err != nil {
+			editor.revert(redone)
+			return errors.Wrapf(err, "Can't redo operation '%s'", cmd.op.Type)
+		}
+		redone.commands = append(redone.commands, redoneCmd)
+	}
+	editor.redoStack = editor.redoStack[:len(editor.redoStack)-1]
+	editor.undoStack = append(editor.undoStack, redone)
+	return nil
+}
+
+// CanUndo checks whether there is committed transaction to undo
+func (editor *Editor) CanUndo() bool {
+	return editor.current == nil && len(editor.undoStack) > 0
+}
+
+// CanRedo checks whether there is undone transaction to redo
+func (editor *Editor) CanRedo() bool {
+	return editor.current == nil && len(editor.redoStack) > 0
+}
+
+// Apply applies the operation. If there is no transaction in progress then the operation is committed immediately.
+// Failed operation leaves the network untouched
+func (editor *Editor) Apply(op Operation) error {
+	_, err := editor.apply(op)
+	return err
+}
+
+// SplitLink splits the link (see macro.Net.SplitLink). Through movements between the parts are created at the inserted node
+func (editor *Editor) SplitLink(linkID gmns.LinkID, distance float64, splitOpposite bool) (*macro.LinkSplit, error) {
+	link, ok := editor.net.Links[linkID]
+	if !ok {
+		return nil, errors.Wrapf(macro.ErrLinkNotFound, "Link ID: %d", linkID)
+	}
+	cmd, err := editor.apply(Operation{
+		Type:          OPERATION_SPLIT_LINK,
+		Link:          NewLinkRef(link),
+		Distance:      distance,
+		SplitOpposite: splitOpposite,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cmd.split, nil
+}
+
+// MergeLinksAtNode merges links passing through the node (see macro.Net.MergeLinksAtNode)
+func (editor *Editor) MergeLinksAtNode(nodeID gmns.NodeID) (map[gmns.LinkID]gmns.LinkID, error) {
+	node, ok := editor.net.Nodes[nodeID]
+	if !ok {
+		return nil, errors.Wrapf(macro.ErrNodeNotFound, "Node ID: %d", nodeID)
+	}
+	cmd, err := editor.apply(Operation{
+		Type: OPERATION_MERGE_AT_NODE,
+		Node: NewNodeRef(node),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cmd.merged, nil
+}
+
+// SetLinkAttributes changes attributes of the link
+func (editor *Editor) SetLinkAttributes(linkID gmns.LinkID, attributes LinkAttributes) error {
+	link, ok := editor.net.Links[linkID]
+	if !ok {
+		return errors.Wrapf(macro.ErrLinkNotFound, "Link ID: %d", linkID)
+	}
+	_, err := editor.apply(Operation{
+		Type:           OPERATION_SET_LINK_ATTRIBUTES,
+		Link:           NewLinkRef(link),
+		LinkAttributes: &attributes,
+	})
+	return err
+}
+
+// SetNodeAttributes changes attributes of the node
+func (editor *Editor) SetNodeAttributes(nodeID gmns.NodeID, attributes NodeAttributes) error {
+	node, ok := editor.net.Nodes[nodeID]
+	if !ok {
+		return errors.Wrapf(macro.ErrNodeNotFound, "Node ID: %d", nodeID)
+	}
+	_, err := editor.apply(Operation{
+		Type:           OPERATION_SET_NODE_ATTRIBUTES,
+		Node:           NewNodeRef(node),
+		NodeAttributes: &attributes,
+	})
+	return err
+}
+
+// DeleteLink removes the link and movements going through it
+func (editor *Editor) DeleteLink(linkID gmns.LinkID) error {
+	link, ok := editor.net.Links[linkID]
+	if !ok {
+		return errors.Wrapf(macro.ErrLinkNotFound, "Link ID: %d", linkID)
+	}
+	_, err := editor.apply(Operation{
+		Type: OPERATION_DELETE_LINK,
+		Link: NewLinkRef(link),
+	})
+	return err
+}
+
+// DeleteNode removes the node, its incident links and dependent movements
+func (editor *Editor) DeleteNode(nodeID gmns.NodeID) error {
+	node, ok := editor.net.Nodes[nodeID]
+	if !ok {
+		return errors.Wrapf(macro.ErrNodeNotFound, "Node ID: %d", nodeID)
+	}
+	_, err := editor.apply(Operation{
+		Type: OPERATION_DELETE_NODE,
+		Node: NewNodeRef(node),
+	})
+	return err
+}
+
+// apply executes the operation within the current transaction or within the new committed one
+func (editor *Editor) apply(op Operation) (*command, error) {
+	cmd, err := execute(editor.net, editor.movements, editor.options, op)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't apply operation '%s'", op.Type)
+	}
+	if editor.current != nil {
+		editor.current.commands = append(editor.current.commands, cmd)
+		return cmd, nil
+	}
+	editor.undoStack = append(editor.undoStack, &Transaction{commands: []*command{cmd}})
+	editor.redoStack = editor.redoStack[:0]
+	return cmd, nil
+}
+
+// revert restores state of the network before the given transaction
+func (editor *Editor) revert(tx *Transaction) {
+	for i := len(tx.commands) - 1; i >= 0; i-- {
+		tx.commands[i].snapshot.restore(editor.net, editor.movements)
+	}
+}
+
+// execute applies the operation to the network and the movements. State of touched elements is saved beforehand,
+// so the network is restored if the operation fails
+func execute(net *macro.Net, movements movement.MovementsStorage, options generators.MovementsGenOptions, op Operation) (*command, error) {
+	cmd := &command{
+		op:       op,
+		snapshot: newSnapshot(net),
+	}
+	err := cmd.run(net, movements, options)
+	if err != nil {
+		cmd.snapshot.restore(net, movements)
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// run captures state of elements touched by the operation and applies it
+func (cmd *command) run(net *macro.Net, movements movement.MovementsStorage, options generators.MovementsGenOptions) error {
+	op := cmd.op
+	s := cmd.snapshot
+	switch op.Type {
+	case OPERATION_SPLIT_LINK:
+		if op.Link == nil {
+			return ErrInvalidOperation
+		}
+		linkID, err := op.Link.resolve(net)
+		if err != nil {
+			return err
+		}
+		link := net.Links[linkID]
+		s.captureNeighbourhood(net, link.SourceNode(), link.TargetNode())
+		s.captureMovements(movements)
+		split, err := net.SplitLink(linkID, op.Distance, op.SplitOpposite)
+		if err != nil {
+			return err
+		}
+		s.nodes[split.NodeID] = nil
+		s.links[split.Downstream] = nil
+		if split.OppositeDownstream >= 0 {
+			s.links[split.OppositeDownstream] = nil
+		}
+		// Movements at the former target nodes come from the downstream parts now
+		for _, mvmt := range movements {
+			if mvmt.IncomeMacroLink() == split.Upstream && mvmt.MacroNode() != split.NodeID {
+				movement.WithIncomeMacroLinkID(split.Downstream)(mvmt)
+			}
+			if split.OppositeUpstream >= 0 && mvmt.IncomeMacroLink() == split.OppositeUpstream && mvmt.MacroNode() != split.NodeID {
+				movement.WithIncomeMacroLinkID(split.OppositeDownstream)(mvmt)
+			}
+		}
+		if movements != nil {
+			ids := options.IDAllocator(movements)
+			pairs := [][2]gmns.LinkID{{split.Upstream, split.Downstream}}
+			if split.OppositeUpstream >= 0 {
+				pairs = append(pairs, [2]gmns.LinkID{split.OppositeUpstream, split.OppositeDownstream})
+			}
+			for _, pair := range pairs {
+				mvmt := throughMovement(ids.Next(), net.Nodes[split.NodeID], net.Links[pair[0]], net.Links[pair[1]], options.DrivingSide)
+				movements[mvmt.ID] = mvmt
+				s.movements[mvmt.ID] = nil
+			}
+		}
+		cmd.split = split
+	case OPERATION_MERGE_AT_NODE:
+		if op.Node == nil {
+			return ErrInvalidOperation
+		}
+		nodeID, err := op.Node.resolve(net)
+		if err != nil {
+			return err
+		}
+		s.captureNeighbourhood(net, nodeID)
+		s.captureMovements(movements)
+		merged, err := net.MergeLinksAtNode(nodeID)
+		if err != nil {
+			return err
+		}
+		for mvmtID, mvmt := range movements {
+			if mvmt.MacroNode() == nodeID {
+				delete(movements, mvmtID)
+				continue
+			}
+			if keptID, ok := merged[mvmt.IncomeMacroLink()]; ok {
+				movement.WithIncomeMacroLinkID(keptID)(mvmt)
+			}
+		}
+		cmd.merged = merged
+	case OPERATION_SET_LINK_ATTRIBUTES:
+		if op.Link == nil || op.LinkAttributes == nil {
+			return ErrInvalidOperation
+		}
+		linkID, err := op.Link.resolve(net)
+		if err != nil {
+			return err
+		}
+		s.captureLink(net, linkID)
+		op.LinkAttributes.apply(net.Links[linkID])
+	case OPERATION_SET_NODE_ATTRIBUTES:
+		if op.Node == nil || op.NodeAttributes == nil {
+			return ErrInvalidOperation
+		}
+		nodeID, err := op.Node.resolve(net)
+		if err != nil {
+			return err
+		}
+		s.captureNode(net, nodeID)
+		op.NodeAttributes.apply(net.Nodes[nodeID])
+	case OPERATION_DELETE_LINK:
+		if op.Link == nil {
+			return ErrInvalidOperation
+		}
+		linkID, err := op.Link.resolve(net)
+		if err != nil {
+			return err
+		}
+		link := net.Links[linkID]
+		s.captureNode(net, link.SourceNode())
+		s.captureNode(net, link.TargetNode())
+		s.captureLink(net, linkID)
+		s.captureMovements(movements)
+		return net.DeleteLink(linkID, movements)
+	case OPERATION_DELETE_NODE:
+		if op.Node == nil {
+			return ErrInvalidOperation
+		}
+		nodeID, err := op.Node.resolve(net)
+		if err != nil {
+			return err
+		}
+		s.captureNeighbourhood(net, nodeID)
+		s.captureMovements(movements)
+		return net.DeleteNode(nodeID, movements)
+	default:
+		return errors.Wrapf(ErrUnknownOperation, "Operation type: '%s'", op.Type)
+	}
+	return nil
+}
+
+// throughMovement returns movement between two parts of the split link at the inserted node. Lanes are connected one-to-one starting from the leftmost one
+func throughMovement(id gmns.MovementID, node *macro.Node, income, outcome *macro.Link, drivingSide types.DrivingSide) *movement.Movement {
+	incomeLanes := income.GetOutcomingLaneIndices()
+	lanesNum := outcome.GetIncomingLanes()
+	if len(incomeLanes) < lanesNum {
+		lanesNum = len(incomeLanes)
+	}
+	mvmtTextID, mvmtType := movement.FindMovementTypeForSide(income.GeomEuclidean(), outcome.GeomEuclidean(), drivingSide)
+	options := []func(*movement.Movement){
+		movement.WithOSMNodeID(node.OSMNode()),
+		movement.WithSourceOSMNodeID(income.SourceOSMNode()),
+		movement.WithTargetOSMNodeID(outcome.TargetOSMNode()),
+		movement.WithControlType(node.ControlType()),
+		movement.WithAllowedAgentTypes(income.AllowedAgentTypes()),
+		movement.WithLanesNum(lanesNum),
+		movement.WithGeom(movement.FindMovementGeom(income.Geom(), outcome.Geom())),
+	}
+	if lanesNum > 0 {
+		options = append(options,
+			movement.WithIncomeLane(incomeLanes[0], incomeLanes[lanesNum-1]),
+			movement.WithIncomeLaneSequence(0, lanesNum-1),
+			movement.WithOutcomeLane(1, lanesNum),
+			movement.WithOutcomeLaneSequence(0, lanesNum-1),
+		)
+	}
+	return movement.NewMovement(id, node.ID, income.ID, outcome.ID, mvmtTextID, mvmtType, options...)
+}
@@ -0,0 +1,245 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/LdDl/go-gmns/generators"
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/LdDl/go-gmns/movement"
+	"github.com/LdDl/go-gmns/utils/geomath"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/paulmach/osm"
+	"github.com/stretchr/testify/assert"
+)
+
+// testNet returns two-way road 1-2-3-4 made of two OSM ways (1-2-3 and 3-4). Identifiers are shifted by the offset
+// and links are added in the different order for the non-zero offset, so the same road could be "imported" with other identifiers
+func testNet(t *testing.T, offset int) (*macro.Net, movement.MovementsStorage) {
+	net := macro.NewNet()
+	points := []orb.Point{{37.6, 55.75}, {37.601, 55.75}, {37.602, 55.7505}, {37.603, 55.7505}}
+	for i, pt := range points {
+		assert.NoError(t, net.AddNode(macro.NewNodeFrom(gmns.NodeID(offset+i), macro.WithOSMNodeID(osm.NodeID(i+1)), macro.WithPointGeom(pt), macro.WithPointGeomEuclidean(geomath.PointToEuclidean(pt)))))
+	}
+	pairs := [][2]int{{0, 1}, {1, 0}, {1, 2}, {2, 1}, {2, 3}, {3, 2}}
+	if offset != 0 {
+		sort.Slice(pairs, func(i, j int) bool {
+			return pairs[i][0] > pairs[j][0] || (pairs[i][0] == pairs[j][0] && pairs[i][1] > pairs[j][1])
+		})
+	}
+	for i, pair := range pairs {
+		geom := orb.LineString{points[pair[0]], points[pair[1]]}
+		wayID := osm.WayID(100)
+		if pair[0]+pair[1] == 5 {
+			wayID = 101
+		}
+		link := macro.NewLinkFrom(gmns.LinkID(offset+i), gmns.NodeID(offset+pair[0]), gmns.NodeID(offset+pair[1]),
+			macro.WithOSMWayID(wayID),
+			macro.WithSourceOSMNodeID(osm.NodeID(pair[0]+1)),
+			macro.WithTargetOSMNodeID(osm.NodeID(pair[1]+1)),
+			macro.WithLineGeom(geom),
+			macro.WithLineGeomEuclidean(geomath.LineToEuclidean(geom)),
+			macro.WithLengthMeters(geo.LengthHaversine(geom)),
+			macro.WithLanesNum(2),
+			macro.WithAllowedAgentTypes([]types.AgentType{types.AGENT_AUTO}),
+			macro.WithBidirectionalSource(true),
+		)
+		macro.WithLanesInfo(macro.NewLanesInfo(link))(link)
+		assert.NoError(t, net.AddLink(link))
+	}
+	movements, err := generators.GenerateMovements(net)
+	assert.NoError(t, err)
+	return net, movements
+}
+
+// netState returns deep copies of network elements and movements
+func netState(net *macro.Net, movements movement.MovementsStorage) (map[gmns.NodeID]*macro.Node, map[gmns.LinkID]*macro.Link, movement.MovementsStorage) {
+	nodes := make(map[gmns.NodeID]*macro.Node, len(net.Nodes))
+	for id, node := range net.Nodes {
+		nodes[id] = node.Clone()
+	}
+	links := make(map[gmns.LinkID]*macro.Link, len(net.Links))
+	for id, link := range net.Links {
+		links[id] = link.Clone()
+	}
+	mvmts := make(movement.MovementsStorage, len(movements))
+	for id, mvmt := range movements {
+		mvmts[id] = mvmt.Clone()
+	}
+	return nodes, links, mvmts
+}
+
+// describeNet returns sorted descriptions of links and movements which do not depend on identifiers
+func describeNet(net *macro.Net, movements movement.MovementsStorage) []string {
+	ans := make([]string, 0, len(net.Links)+len(movements))
+	for _, link := range net.Links {
+		ans = append(ans, fmt.Sprintf("link %.7f %d %s", link.Geom(), link.LanesNum(), link.Name()))
+	}
+	for _, mvmt := range movements {
+		ans = append(ans, fmt.Sprintf("movement %.7f %.7f %d", net.Links[mvmt.IncomeMacroLink()].Geom(), net.Links[mvmt.OutcomeMacroLink()].Geom(), mvmt.LanesNum()))
+	}
+	sort.Strings(ans)
+	return ans
+}
+
+func TestSplitLink(t *testing.T) {
+	net, movements := testNet(t, 0)
+	editor := NewEditor(net, movements)
+	nodesBefore, linksBefore, movementsBefore := netState(net, movements)
+
+	split, err := editor.SplitLink(0, 30, true)
+	assert.NoError(t, err)
+	assert.Len(t, net.Nodes, 5)
+	assert.Len(t, net.Links, 8)
+	through := make(map[[2]gmns.LinkID]*movement.Movement)
+	for _, mvmt := range movements {
+		if mvmt.MacroNode() == split.NodeID {
+			through[[2]gmns.LinkID{mvmt.IncomeMacroLink(), mvmt.OutcomeMacroLink()}] = mvmt
+		}
+		_, ok := net.Links[mvmt.IncomeMacroLink()]
+		assert.True(t, ok, "Movement %d refers to missing income link", mvmt.ID)
+		assert.Equal(t, mvmt.MacroNode(), net.Links[mvmt.IncomeMacroLink()].TargetNode(), "Movement %d should start from link incoming to its node", mvmt.ID)
+	}
+	assert.Len(t, through, 2, "Through movements should be created for both directions")
+	forward := through[[2]gmns.LinkID{split.Upstream, split.Downstream}]
+	if assert.NotNil(t, forward) {
+		assert.Equal(t, 2, forward.LanesNum())
+		assert.Equal(t, movement.MOVEMENT_TYPE_THRU, forward.Type())
+		_, existed := movementsBefore[forward.ID]
+		assert.False(t, existed, "New movement should get new identifier")
+	}
+	assert.NotNil(t, through[[2]gmns.LinkID{split.OppositeUpstream, split.OppositeDownstream}])
+
+	assert.NoError(t, editor.Undo())
+	nodes, links, mvmts := netState(net, movements)
+	assert.Equal(t, nodesBefore, nodes)
+	assert.Equal(t, linksBefore, links)
+	assert.Equal(t, movementsBefore, mvmts, "Created movements should be removed on undo")
+
+	// Movements options give identifiers of created movements
+	opts := generators.DefaultMovementsGenOptions()
+	opts.DrivingSide = types.DRIVING_SIDE_LEFT
+	opts.IDs = movement.NewIDAllocator(1000)
+	editor = NewEditor(net, movements, opts)
+	split, err = editor.SplitLink(0, 30, true)
+	assert.NoError(t, err)
+	created := make([]gmns.MovementID, 0)
+	for id, mvmt := range movements {
+		if mvmt.MacroNode() == split.NodeID {
+			created = append(created, id)
+		}
+	}
+	assert.ElementsMatch(t, []gmns.MovementID{1000, 1001}, created, "Allocator of movements options should be used")
+	_, err = editor.SplitLink(split.Downstream, 10, false)
+	assert.NoError(t, err)
+	assert.Contains(t, movements, gmns.MovementID(1002), "Allocator should be shared by operations")
+}
+
+func TestMergeAndAttributes(t *testing.T) {
+	net, movements := testNet(t, 0)
+	editor := NewEditor(net, movements)
+
+	merged, err := editor.MergeLinksAtNode(1)
+	assert.NoError(t, err)
+	assert.Len(t, merged, 2)
+	_, ok := net.Nodes[1]
+	assert.False(t, ok, "Pass-through node should be removed")
+	for _, mvmt := range movements {
+		assert.NotEqual(t, gmns.NodeID(1), mvmt.MacroNode(), "Movements at the removed node should be removed")
+		_, ok := net.Links[mvmt.IncomeMacroLink()]
+		assert.True(t, ok, "Movement %d refers to removed link", mvmt.ID)
+	}
+	_, err = editor.MergeLinksAtNode(100)
+	assert.ErrorIs(t, err, macro.ErrNodeNotFound)
+
+	name, lanes := "Main street", 3
+	assert.NoError(t, editor.SetLinkAttributes(4, LinkAttributes{Name: &name, LanesNum: &lanes}))
+	assert.Equal(t, name, net.Links[4].Name())
+	assert.Equal(t, 3, net.Links[4].LanesNum())
+	assert.Equal(t, []int{3}, net.Links[4].LanesInfo().LanesList, "Lanes information should be rebuilt")
+	controlType := types.CONTROL_TYPE_IS_SIGNAL
+	assert.NoError(t, editor.SetNodeAttributes(2, NodeAttributes{ControlType: &controlType}))
+	assert.Equal(t, controlType, net.Nodes[2].ControlType())
+
+	assert.ErrorIs(t, editor.Apply(Operation{Type: OPERATION_SET_LINK_ATTRIBUTES, Link: &LinkRef{ID: 4, OSMWayID: -1}}), ErrInvalidOperation)
+	assert.ErrorIs(t, editor.Apply(Operation{Type: "unknown"}), ErrUnknownOperation)
+	assert.Len(t, editor.Log().Transactions, 3, "Failed operations should not be committed")
+}
+
+func TestTransactions(t *testing.T) {
+	net, movements := testNet(t, 0)
+	editor := NewEditor(net, movements)
+	nodesBefore, linksBefore, movementsBefore := netState(net, movements)
+
+	assert.ErrorIs(t, editor.Commit(), ErrNoTransaction)
+	assert.ErrorIs(t, editor.Rollback(), ErrNoTransaction)
+	assert.NoError(t, editor.Begin())
+	assert.ErrorIs(t, editor.Begin(), ErrTransactionInProgress)
+	_, err := editor.SplitLink(0, 30, true)
+	assert.NoError(t, err)
+	assert.NoError(t, editor.DeleteNode(3))
+	assert.ErrorIs(t, editor.Undo(), ErrTransactionInProgress)
+	assert.NoError(t, editor.Rollback())
+	nodes, links, mvmts := netState(net, movements)
+	assert.Equal(t, nodesBefore, nodes, "Rollback should revert every operation")
+	assert.Equal(t, linksBefore, links)
+	assert.Equal(t, movementsBefore, mvmts)
+	assert.False(t, editor.CanUndo())
+
+	assert.NoError(t, editor.Begin())
+	_, err = editor.SplitLink(0, 30, true)
+	assert.NoError(t, err)
+	_, err = editor.MergeLinksAtNode(2)
+	assert.NoError(t, err)
+	assert.NoError(t, editor.Commit())
+	nodesEdited, linksEdited, movementsEdited := netState(net, movements)
+	assert.Len(t, editor.Log().Transactions, 1)
+	assert.Len(t, editor.Log().Transactions[0], 2)
+
+	assert.NoError(t, editor.Undo())
+	assert.ErrorIs(t, editor.Undo(), ErrNothingToUndo)
+	nodes, links, mvmts = netState(net, movements)
+	assert.Equal(t, nodesBefore, nodes, "Undo should return the original network")
+	assert.Equal(t, linksBefore, links)
+	assert.Equal(t, movementsBefore, mvmts)
+
+	assert.NoError(t, editor.Redo())
+	assert.ErrorIs(t, editor.Redo(), ErrNothingToRedo)
+	nodes, links, mvmts = netState(net, movements)
+	assert.Equal(t, nodesEdited, nodes, "Redo should return the edited network")
+	assert.Equal(t, linksEdited, links)
+	assert.Equal(t, movementsEdited, mvmts)
+}
+
+func TestLogReplay(t *testing.T) {
+	net, movements := testNet(t, 0)
+	editor := NewEditor(net, movements)
+	_, err := editor.SplitLink(2, 30, true)
+	assert.NoError(t, err)
+	_, err = editor.MergeLinksAtNode(1)
+	assert.NoError(t, err)
+	lanes := 1
+	assert.NoError(t, editor.SetLinkAttributes(5, LinkAttributes{LanesNum: &lanes}))
+	assert.NoError(t, editor.DeleteLink(4))
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteLog(&buf, editor.Log()))
+	log, err := ReadLog(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, editor.Log(), log)
+
+	// Fresh import of the same road with other identifiers: references are resolved by OSM data
+	freshNet, freshMovements := testNet(t, 1000)
+	freshEditor := NewEditor(freshNet, freshMovements)
+	assert.NoError(t, freshEditor.Replay(log))
+	assert.Equal(t, describeNet(net, movements), describeNet(freshNet, freshMovements))
+	assert.Len(t, freshEditor.Log().Transactions, 4)
+
+	_, err = ReadLog(bytes.NewBufferString("{"))
+	assert.Error(t, err)
+}
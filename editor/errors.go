@@ -0,0 +1,13 @@
+package editor
+
+import "fmt"
+
+var (
+	ErrReferenceNotFound     = fmt.Errorf("referenced element not found")
+	ErrUnknownOperation      = fmt.Errorf("unknown operation type")
+	ErrInvalidOperation      = fmt.Errorf("operation misses required reference")
+	ErrTransactionInProgress = fmt.Errorf("transaction is in progress already")
+	ErrNoTransaction         = fmt.Errorf("there is no transaction in progress")
+	ErrNothingToUndo         = fmt.Errorf("nothing to undo")
+	ErrNothingToRedo         = fmt.Errorf("nothing to redo")
+)
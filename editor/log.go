@@ -0,0 +1,71 @@
+package editor
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Log is serializable list of committed transactions. It could be replayed on the fresh import of the same OSM extract
+type Log struct {
+	Transactions [][]Operation `json:"transactions"`
+}
+
+// Log returns committed transactions which have not been undone, in order of application
+func (editor *Editor) Log() *Log {
+	log := &Log{
+		Transactions: make([][]Operation, 0, len(editor.undoStack)),
+	}
+	for _, tx := range editor.undoStack {
+		log.Transactions = append(log.Transactions, tx.Operations())
+	}
+	return log
+}
+
+// Replay applies every transaction of the log. Each transaction is committed separately, so it could be undone afterwards.
+// Replay stops at the first failed transaction which is rolled back
+func (editor *Editor) Replay(log *Log) error {
+	for i, ops := range log.Transactions {
+		err := editor.Begin()
+		if err != nil {
+			return errors.Wrapf(err, "Can't begin transaction %d", i)
+		}
+		for _, op := range ops {
+			err = editor.Apply(op)
+			if err != nil {
+				rollbackErr := editor.Rollback()
+				if rollbackErr != nil {
+					return errors.Wrapf(rollbackErr, "Can't rollback transaction %d", i)
+				}
+				return errors.Wrapf(err, "Can't replay transaction %d", i)
+			}
+		}
+		err = editor.Commit()
+		if err != nil {
+			return errors.Wrapf(err, "Can't commit transaction %d", i)
+		}
+	}
+	return nil
+}
+
+// WriteLog writes the log as JSON
+func WriteLog(w io.Writer, log *Log) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(log)
+	if err != nil {
+		return errors.Wrap(err, "Can't encode operations log")
+	}
+	return nil
+}
+
+// ReadLog reads JSON log written by WriteLog
+func ReadLog(r io.Reader) (*Log, error) {
+	log := &Log{}
+	err := json.NewDecoder(r).Decode(log)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't decode operations log")
+	}
+	return log, nil
+}
@@ -0,0 +1,171 @@
+package editor
+
+import (
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/paulmach/osm"
+	"github.com/pkg/errors"
+)
+
+// OperationType is type of the network edit
+type OperationType string
+
+const (
+	OPERATION_SPLIT_LINK          = OperationType("split_link")
+	OPERATION_MERGE_AT_NODE       = OperationType("merge_at_node")
+	OPERATION_SET_LINK_ATTRIBUTES = OperationType("set_link_attributes")
+	OPERATION_SET_NODE_ATTRIBUTES = OperationType("set_node_attributes")
+	OPERATION_DELETE_LINK         = OperationType("delete_link")
+	OPERATION_DELETE_NODE         = OperationType("delete_node")
+)
+
+// LinkRef references macroscopic link both by internal identifier and by OSM data.
+// OSM data is used for lookup when internal identifiers differ between imports of the same OSM extract
+type LinkRef struct {
+	ID              gmns.LinkID `json:"id"`
+	OSMWayID        osm.WayID   `json:"osm_way_id"`
+	SourceOSMNodeID osm.NodeID  `json:"source_osm_node_id"`
+	TargetOSMNodeID osm.NodeID  `json:"target_osm_node_id"`
+}
+
+// NewLinkRef returns reference to the given link
+func NewLinkRef(link *macro.Link) *LinkRef {
+	return &LinkRef{
+		ID:              link.ID,
+		OSMWayID:        link.OSMWay(),
+		SourceOSMNodeID: link.SourceOSMNode(),
+		TargetOSMNodeID: link.TargetOSMNode(),
+	}
+}
+
+// matches checks whether the given link corresponds to OSM data of the reference. Links without OSM data match any reference
+func (ref *LinkRef) matches(link *macro.Link) bool {
+	if ref.OSMWayID < 0 {
+		return true
+	}
+	return link.OSMWay() == ref.OSMWayID && link.SourceOSMNode() == ref.SourceOSMNodeID && link.TargetOSMNode() == ref.TargetOSMNodeID
+}
+
+// resolve returns link identifier in the given network. Internal identifier is preferred if the link under it corresponds to OSM data
+func (ref *LinkRef) resolve(net *macro.Net) (gmns.LinkID, error) {
+	if link, ok := net.Links[ref.ID]; ok && ref.matches(link) {
+		return link.ID, nil
+	}
+	if ref.OSMWayID < 0 {
+		return -1, errors.Wrapf(ErrReferenceNotFound, "Link ID: %d", ref.ID)
+	}
+	found := gmns.LinkID(-1)
+	for linkID, link := range net.Links {
+		if ref.matches(link) && (found < 0 || linkID < found) {
+			found = linkID
+		}
+	}
+	if found < 0 {
+		return -1, errors.Wrapf(ErrReferenceNotFound, "Link ID: %d. OSM way ID: %d", ref.ID, ref.OSMWayID)
+	}
+	return found, nil
+}
+
+// NodeRef references macroscopic node both by internal identifier and by OSM node identifier
+type NodeRef struct {
+	ID        gmns.NodeID `json:"id"`
+	OSMNodeID osm.NodeID  `json:"osm_node_id"`
+}
+
+// NewNodeRef returns reference to the given node
+func NewNodeRef(node *macro.Node) *NodeRef {
+	return &NodeRef{
+		ID:        node.ID,
+		OSMNodeID: node.OSMNode(),
+	}
+}
+
+// resolve returns node identifier in the given network. Internal identifier is preferred if the node under it corresponds to OSM node
+func (ref *NodeRef) resolve(net *macro.Net) (gmns.NodeID, error) {
+	if node, ok := net.Nodes[ref.ID]; ok && (ref.OSMNodeID < 0 || node.OSMNode() == ref.OSMNodeID) {
+		return node.ID, nil
+	}
+	if ref.OSMNodeID < 0 {
+		return -1, errors.Wrapf(ErrReferenceNotFound, "Node ID: %d", ref.ID)
+	}
+	found := gmns.NodeID(-1)
+	for nodeID, node := range net.Nodes {
+		if node.OSMNode() == ref.OSMNodeID && (found < 0 || nodeID < found) {
+			found = nodeID
+		}
+	}
+	if found < 0 {
+		return -1, errors.Wrapf(ErrReferenceNotFound, "Node ID: %d. OSM node ID: %d", ref.ID, ref.OSMNodeID)
+	}
+	return found, nil
+}
+
+// LinkAttributes is set of link attributes to be changed. Nil fields are left untouched
+type LinkAttributes struct {
+	Name              *string            `json:"name,omitempty"`
+	LanesNum          *int               `json:"lanes_num,omitempty"`
+	FreeSpeed         *float64           `json:"free_speed,omitempty"`
+	MaxSpeed          *float64           `json:"max_speed,omitempty"`
+	Capacity          *int               `json:"capacity,omitempty"`
+	LinkType          *types.LinkType    `json:"link_type,omitempty"`
+	AllowedAgentTypes []types.AgentType  `json:"allowed_agent_types,omitempty"`
+	ControlType       *types.ControlType `json:"control_type,omitempty"`
+}
+
+// apply sets attributes to the given link. Lanes information is rebuilt if number of lanes has been changed (turn pockets are kept)
+func (attributes *LinkAttributes) apply(link *macro.Link) {
+	if attributes.Name != nil {
+		macro.WithLinkName(*attributes.Name)(link)
+	}
+	if attributes.FreeSpeed != nil {
+		macro.WithFreeSpeed(*attributes.FreeSpeed)(link)
+	}
+	if attributes.MaxSpeed != nil {
+		macro.WithMaxSpeed(*attributes.MaxSpeed)(link)
+	}
+	if attributes.Capacity != nil {
+		macro.WithCapacity(*attributes.Capacity)(link)
+	}
+	if attributes.LinkType != nil {
+		macro.WithLinkType(*attributes.LinkType)(link)
+	}
+	if attributes.AllowedAgentTypes != nil {
+		macro.WithAllowedAgentTypes(attributes.AllowedAgentTypes)(link)
+	}
+	if attributes.ControlType != nil {
+		macro.WithLinkControlType(*attributes.ControlType)(link)
+	}
+	if attributes.LanesNum != nil && *attributes.LanesNum != link.LanesNum() {
+		pockets := link.TurnPockets()
+		macro.WithLanesNum(*attributes.LanesNum)(link)
+		macro.WithLanesInfo(macro.NewLanesInfoWithPockets(link, pockets))(link)
+	}
+}
+
+// NodeAttributes is set of node attributes to be changed. Nil fields are left untouched
+type NodeAttributes struct {
+	Name        *string            `json:"name,omitempty"`
+	ControlType *types.ControlType `json:"control_type,omitempty"`
+}
+
+// apply sets attributes to the given node
+func (attributes *NodeAttributes) apply(node *macro.Node) {
+	if attributes.Name != nil {
+		macro.WithNodeName(*attributes.Name)(node)
+	}
+	if attributes.ControlType != nil {
+		macro.WithNodeControlType(*attributes.ControlType)(node)
+	}
+}
+
+// Operation is serializable description of the single network edit
+type Operation struct {
+	Type           OperationType   `json:"type"`
+	Link           *LinkRef        `json:"link,omitempty"`
+	Node           *NodeRef        `json:"node,omitempty"`
+	Distance       float64         `json:"distance,omitempty"`
+	SplitOpposite  bool            `json:"split_opposite,omitempty"`
+	LinkAttributes *LinkAttributes `json:"link_attributes,omitempty"`
+	NodeAttributes *NodeAttributes `json:"node_attributes,omitempty"`
+}
@@ -0,0 +1,111 @@
+package editor
+
+import (
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/LdDl/go-gmns/movement"
+)
+
+// snapshot keeps state of network elements touched by the single operation. Nil value means that element has not existed before the operation
+type snapshot struct {
+	nodes     map[gmns.NodeID]*macro.Node
+	links     map[gmns.LinkID]*macro.Link
+	movements map[gmns.MovementID]*movement.Movement
+	maxNodeID gmns.NodeID
+	maxLinkID gmns.LinkID
+}
+
+// newSnapshot creates snapshot with the current state of identifiers counters
+func newSnapshot(net *macro.Net) *snapshot {
+	return &snapshot{
+		nodes:     make(map[gmns.NodeID]*macro.Node),
+		links:     make(map[gmns.LinkID]*macro.Link),
+		movements: make(map[gmns.MovementID]*movement.Movement),
+		maxNodeID: net.MaxNodeID(),
+		maxLinkID: net.MaxLinkID(),
+	}
+}
+
+// captureNode saves state of the node unless it has been saved already
+func (s *snapshot) captureNode(net *macro.Net, nodeID gmns.NodeID) {
+	if _, ok := s.nodes[nodeID]; ok {
+		return
+	}
+	if node, ok := net.Nodes[nodeID]; ok {
+		s.nodes[nodeID] = node.Clone()
+		return
+	}
+	s.nodes[nodeID] = nil
+}
+
+// captureLink saves state of the link unless it has been saved already
+func (s *snapshot) captureLink(net *macro.Net, linkID gmns.LinkID) {
+	if _, ok := s.links[linkID]; ok {
+		return
+	}
+	if link, ok := net.Links[linkID]; ok {
+		s.links[linkID] = link.Clone()
+		return
+	}
+	s.links[linkID] = nil
+}
+
+// captureNeighbourhood saves state of the given nodes, every link incident to them and the opposite ends of those links
+func (s *snapshot) captureNeighbourhood(net *macro.Net, nodesIDs ...gmns.NodeID) {
+	for _, nodeID := range nodesIDs {
+		s.captureNode(net, nodeID)
+		node, ok := net.Nodes[nodeID]
+		if !ok {
+			continue
+		}
+		incidentLinks := append(append([]gmns.LinkID{}, node.IncomingLinks()...), node.OutcomingLinks()...)
+		for _, linkID := range incidentLinks {
+			s.captureLink(net, linkID)
+			if link, ok := net.Links[linkID]; ok {
+				s.captureNode(net, link.SourceNode())
+				s.captureNode(net, link.TargetNode())
+			}
+		}
+	}
+}
+
+// captureMovements saves state of movements at the captured nodes or going through the captured links
+func (s *snapshot) captureMovements(movements movement.MovementsStorage) {
+	for mvmtID, mvmt := range movements {
+		_, nodeCaptured := s.nodes[mvmt.MacroNode()]
+		_, incomeCaptured := s.links[mvmt.IncomeMacroLink()]
+		_, outcomeCaptured := s.links[mvmt.OutcomeMacroLink()]
+		if nodeCaptured || incomeCaptured || outcomeCaptured {
+			s.movements[mvmtID] = mvmt.Clone()
+		}
+	}
+}
+
+// restore puts saved state back to the network and the movements storage
+func (s *snapshot) restore(net *macro.Net, movements movement.MovementsStorage) {
+	for nodeID, node := range s.nodes {
+		if node == nil {
+			delete(net.Nodes, nodeID)
+			continue
+		}
+		net.Nodes[nodeID] = node.Clone()
+	}
+	for linkID, link := range s.links {
+		if link == nil {
+			delete(net.Links, linkID)
+			continue
+		}
+		net.Links[linkID] = link.Clone()
+	}
+	if movements != nil {
+		for mvmtID, mvmt := range s.movements {
+			if mvmt == nil {
+				delete(movements, mvmtID)
+				continue
+			}
+			movements[mvmtID] = mvmt.Clone()
+		}
+	}
+	net.SetMaxNodeID(s.maxNodeID)
+	net.SetMaxLinkID(s.maxLinkID)
+}
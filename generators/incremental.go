@@ -0,0 +1,497 @@
+package generators
+
+import (
+	"context"
+	"sort"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/LdDl/go-gmns/meso"
+	"github.com/LdDl/go-gmns/micro"
+	"github.com/LdDl/go-gmns/movement"
+	"github.com/pkg/errors"
+)
+
+// ChangeSet lists macroscopic links and nodes which have been added, changed or removed since the last generation.
+// Links with changed geometry or attributes (lanes, agent types and etc.) should be listed in Links, nodes with changed attributes (control type and etc.) should be listed in Nodes.
+// End nodes of removed links are found via existing movements and mesoscopic links, so if those are removed already the end nodes should be listed in Nodes too
+type ChangeSet struct {
+	Links []gmns.LinkID
+	Nodes []gmns.NodeID
+}
+
+// MesoPatch describes changes made by RegenerateMesoscopic. It is used by RegenerateMicroscopic to find microscopic elements to regenerate
+type MesoPatch struct {
+	// Regenerated macroscopic links (removed ones are listed too)
+	MacroLinks []gmns.LinkID
+	// Removed mesoscopic links: links of regenerated macroscopic links and connection links between them and the rest links
+	RemovedLinks []gmns.LinkID
+	// Added mesoscopic links
+	AddedLinks []gmns.LinkID
+	// Untouched mesoscopic links which have been reconnected to regenerated ones at nodes where movements are not needed
+	UpdatedLinks []gmns.LinkID
+	// Removed mesoscopic nodes
+	RemovedNodes []gmns.NodeID
+	// Added mesoscopic nodes
+	AddedNodes []gmns.NodeID
+}
+
+// RegenerateMovements regenerates movements in place at the macroscopic nodes affected by the changes: the changed nodes and end nodes of the changed links.
+// Movements at the rest nodes are kept with their identifiers
+func RegenerateMovements(macroNet *macro.Net, movements movement.MovementsStorage, changes ChangeSet, opts ...MovementsGenOptions) error {
+	options := DefaultMovementsGenOptions()
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	affectedNodes := make(map[gmns.NodeID]struct{})
+	for _, nodeID := range changes.Nodes {
+		affectedNodes[nodeID] = struct{}{}
+	}
+	changedLinks := make(map[gmns.LinkID]struct{}, len(changes.Links))
+	for _, linkID := range changes.Links {
+		changedLinks[linkID] = struct{}{}
+		if link, ok := macroNet.Links[linkID]; ok {
+			affectedNodes[link.SourceNode()] = struct{}{}
+			affectedNodes[link.TargetNode()] = struct{}{}
+		}
+	}
+	// Removed links are known by movements going through them only
+	for _, mvmt := range movements {
+		_, incomeChanged := changedLinks[mvmt.IncomeMacroLink()]
+		_, outcomeChanged := changedLinks[mvmt.OutcomeMacroLink()]
+		if incomeChanged || outcomeChanged {
+			affectedNodes[mvmt.MacroNode()] = struct{}{}
+		}
+	}
+	// Identifiers of removed movements are not reused
+	ids := options.IDAllocator(movements)
+	for mvmtID, mvmt := range movements {
+		if _, ok := affectedNodes[mvmt.MacroNode()]; ok {
+			delete(movements, mvmtID)
+		}
+	}
+	diag := options.diagnostics()
+	for _, nodeID := range sortedNodeIDsSet(affectedNodes) {
+		node, ok := macroNet.Nodes[nodeID]
+		if !ok {
+			continue
+		}
+		nodeMovements, err := findMovements(node, macroNet.Links, ids, options, diag)
+		if err != nil {
+			return errors.Wrapf(err, "Can't find movements for macro node with ID: '%d' (OSM ID: '%d')", node.ID, node.OSMNode())
+		}
+		for _, mvmt := range nodeMovements {
+			movements[mvmt.ID] = mvmt
+		}
+	}
+	return nil
+}
+
+// RegenerateMesoscopic patches the mesoscopic network in place after the changes of the macroscopic network. Movements must be regenerated already (see RegenerateMovements).
+// Mesoscopic links of every macroscopic link incident to affected nodes (the changed nodes and end nodes of the changed links) are regenerated
+// together with connection links at end nodes of those links. The rest mesoscopic nodes and links keep their identifiers, new elements get identifiers which are not used in the network
+func RegenerateMesoscopic(macroNet *macro.Net, mesoNet *meso.Net, movements movement.MovementsStorage, changes ChangeSet, opts ...MesoGenOptions) (*MesoPatch, error) {
+	options := DefaultMesoGenOptions()
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	workers := options.workersNum()
+	diag := newDiagnostics(options.Logger, options.Report)
+
+	oldMacroLinkMesoLinks := make(map[gmns.LinkID][]gmns.LinkID)
+	for _, mesoLinkID := range sortedMesoLinkIDs(mesoNet.Links) {
+		mesoLink := mesoNet.Links[mesoLinkID]
+		if mesoLink.MacroLink() >= 0 {
+			oldMacroLinkMesoLinks[mesoLink.MacroLink()] = append(oldMacroLinkMesoLinks[mesoLink.MacroLink()], mesoLinkID)
+		}
+	}
+
+	// Affected nodes: changed nodes and end nodes of changed links. End nodes of removed links are known by their mesoscopic nodes only
+	affectedNodes := make(map[gmns.NodeID]struct{})
+	for _, nodeID := range changes.Nodes {
+		affectedNodes[nodeID] = struct{}{}
+	}
+	for _, linkID := range changes.Links {
+		if link, ok := macroNet.Links[linkID]; ok {
+			affectedNodes[link.SourceNode()] = struct{}{}
+			affectedNodes[link.TargetNode()] = struct{}{}
+		}
+		for _, mesoLinkID := range oldMacroLinkMesoLinks[linkID] {
+			mesoLink := mesoNet.Links[mesoLinkID]
+			for _, mesoNodeID := range []gmns.NodeID{mesoLink.SourceNode(), mesoLink.TargetNode()} {
+				if mesoNode, ok := mesoNet.Nodes[mesoNodeID]; ok && mesoNode.MacroNode() >= 0 {
+					affectedNodes[mesoNode.MacroNode()] = struct{}{}
+				}
+			}
+		}
+	}
+
+	// Regenerated links: every link incident to affected nodes. Boundary nodes are end nodes of regenerated links
+	regeneratedLinks := make(map[gmns.LinkID]struct{})
+	for _, linkID := range changes.Links {
+		regeneratedLinks[linkID] = struct{}{}
+	}
+	for nodeID := range affectedNodes {
+		node, ok := macroNet.Nodes[nodeID]
+		if !ok {
+			continue
+		}
+		for _, linkID := range node.IncomingLinks() {
+			regeneratedLinks[linkID] = struct{}{}
+		}
+		for _, linkID := range node.OutcomingLinks() {
+			regeneratedLinks[linkID] = struct{}{}
+		}
+	}
+	boundaryNodes := make(map[gmns.NodeID]struct{})
+	for nodeID := range affectedNodes {
+		boundaryNodes[nodeID] = struct{}{}
+	}
+	for linkID := range regeneratedLinks {
+		if link, ok := macroNet.Links[linkID]; ok {
+			boundaryNodes[link.SourceNode()] = struct{}{}
+			boundaryNodes[link.TargetNode()] = struct{}{}
+		}
+	}
+	sortedRegeneratedLinks := sortedLinkIDsGeneric(regeneratedLinks)
+	patch := &MesoPatch{
+		MacroLinks: sortedRegeneratedLinks,
+	}
+
+	// Remove mesoscopic links of regenerated links and connection links attached to them
+	removedLinks := make(map[gmns.LinkID]struct{})
+	for _, macroLinkID := range sortedRegeneratedLinks {
+		for _, mesoLinkID := range oldMacroLinkMesoLinks[macroLinkID] {
+			removedLinks[mesoLinkID] = struct{}{}
+		}
+	}
+	for mesoLinkID, mesoLink := range mesoNet.Links {
+		if !mesoLink.IsConnection() {
+			continue
+		}
+		_, incomeRemoved := removedLinks[mesoLink.MovementMesoLinkIncome()]
+		_, outcomeRemoved := removedLinks[mesoLink.MovementMesoLinkOutcome()]
+		if incomeRemoved || outcomeRemoved {
+			removedLinks[mesoLinkID] = struct{}{}
+		}
+	}
+	patch.RemovedLinks = sortedLinkIDsGeneric(removedLinks)
+	candidateNodes := make(map[gmns.NodeID]struct{})
+	for _, mesoLinkID := range patch.RemovedLinks {
+		mesoLink := mesoNet.Links[mesoLinkID]
+		if !mesoLink.IsConnection() {
+			candidateNodes[mesoLink.SourceNode()] = struct{}{}
+			candidateNodes[mesoLink.TargetNode()] = struct{}{}
+		}
+		if sourceNode, ok := mesoNet.Nodes[mesoLink.SourceNode()]; ok {
+			sourceNode.OutcomingLinks().Delete(mesoLinkID)
+		}
+		if targetNode, ok := mesoNet.Nodes[mesoLink.TargetNode()]; ok {
+			targetNode.IncomingLinks().Delete(mesoLinkID)
+		}
+		delete(mesoNet.Links, mesoLinkID)
+	}
+	// Nodes shared with the rest links (they could be reconnected to regenerated links before) are kept
+	removedNodes := make(map[gmns.NodeID]struct{})
+	for nodeID := range candidateNodes {
+		node, ok := mesoNet.Nodes[nodeID]
+		if !ok || node.IncomingLinks().Len() > 0 || node.OutcomingLinks().Len() > 0 {
+			continue
+		}
+		delete(mesoNet.Nodes, nodeID)
+		removedNodes[nodeID] = struct{}{}
+	}
+	patch.RemovedNodes = sortedNodeIDsSet(removedNodes)
+	keptLinks := make(map[gmns.LinkID]struct{}, len(mesoNet.Links))
+	for mesoLinkID := range mesoNet.Links {
+		keptLinks[mesoLinkID] = struct{}{}
+	}
+
+	// Links incident to boundary nodes are observed to find out which movements are needed there, but only regenerated ones are cut and rebuilt
+	sortedBoundaryNodes := make([]gmns.NodeID, 0, len(boundaryNodes))
+	observedLinks := make(map[gmns.LinkID]struct{})
+	for _, nodeID := range sortedNodeIDsSet(boundaryNodes) {
+		node, ok := macroNet.Nodes[nodeID]
+		if !ok {
+			continue
+		}
+		sortedBoundaryNodes = append(sortedBoundaryNodes, nodeID)
+		for _, linkID := range node.IncomingLinks() {
+			observedLinks[linkID] = struct{}{}
+		}
+		for _, linkID := range node.OutcomingLinks() {
+			observedLinks[linkID] = struct{}{}
+		}
+	}
+	observedLinksIDs := sortedLinkIDsGeneric(observedLinks)
+	observedMacroLinks := make([]*macro.Link, 0, len(observedLinksIDs))
+	for _, linkID := range observedLinksIDs {
+		observedMacroLinks = append(observedMacroLinks, macroNet.Links[linkID])
+	}
+	needToObserve := observeMacroLinks(observedMacroLinks, options, workers)
+	existingRegeneratedLinks := make([]gmns.LinkID, 0, len(sortedRegeneratedLinks))
+	regeneratedProcessing := make(map[gmns.LinkID]*macroLinkProcessing, len(sortedRegeneratedLinks))
+	for _, linkID := range sortedRegeneratedLinks {
+		if macroLinkProcess, ok := needToObserve[linkID]; ok {
+			existingRegeneratedLinks = append(existingRegeneratedLinks, linkID)
+			regeneratedProcessing[linkID] = macroLinkProcess
+		}
+	}
+	err := prepareOffsets(macroNet, needToObserve, existingRegeneratedLinks, options, workers)
+	if err != nil {
+		return nil, err
+	}
+	macroNodesMovements, err := groupMovementsByNode(macroNet, movements)
+	if err != nil {
+		return nil, err
+	}
+	macroNodesNeedMovement, err := checkMovementsNecessity(macroNet, sortedBoundaryNodes, needToObserve, macroNodesMovements)
+	if err != nil {
+		return nil, err
+	}
+	performCuts(needToObserve, existingRegeneratedLinks, options, workers)
+
+	ids := newNetMesoIDs(mesoNet)
+	mesoNodes, mesoLinks, err := generateBaseNodesLinks(macroNet.Nodes, regeneratedProcessing, ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't generate base mesoscopic nodes and links")
+	}
+	for nodeID, node := range mesoNodes {
+		mesoNet.Nodes[nodeID] = node
+	}
+	for linkID, link := range mesoLinks {
+		mesoNet.Links[linkID] = link
+	}
+
+	// Connections between untouched links at boundary nodes are kept
+	connectedMovements := make(map[gmns.NodeID][]*movement.Movement, len(sortedBoundaryNodes))
+	for _, nodeID := range sortedBoundaryNodes {
+		_, affected := affectedNodes[nodeID]
+		for _, mvmt := range macroNodesMovements[nodeID] {
+			_, incomeRegenerated := regeneratedLinks[mvmt.IncomeMacroLink()]
+			_, outcomeRegenerated := regeneratedLinks[mvmt.OutcomeMacroLink()]
+			if affected || incomeRegenerated || outcomeRegenerated {
+				connectedMovements[nodeID] = append(connectedMovements[nodeID], mvmt)
+			}
+		}
+	}
+	// Remember ends of untouched links to find out which of them are reconnected
+	untouchedEnds := make(map[gmns.LinkID][2]gmns.NodeID)
+	for linkID := range needToObserve {
+		if _, ok := regeneratedLinks[linkID]; ok {
+			continue
+		}
+		for _, mesoLinkID := range oldMacroLinkMesoLinks[linkID] {
+			mesoLink := mesoNet.Links[mesoLinkID]
+			untouchedEnds[mesoLinkID] = [2]gmns.NodeID{mesoLink.SourceNode(), mesoLink.TargetNode()}
+		}
+	}
+	err = connectMesoscopicLinks(mesoNet.Links, mesoNet.Nodes, sortedBoundaryNodes, macroNet.Links, needToObserve, connectedMovements, macroNodesNeedMovement, ids, workers, diag)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't prepare connections between mesoscopic links")
+	}
+	for mesoLinkID, ends := range untouchedEnds {
+		mesoLink := mesoNet.Links[mesoLinkID]
+		if mesoLink.SourceNode() != ends[0] || mesoLink.TargetNode() != ends[1] {
+			patch.UpdatedLinks = append(patch.UpdatedLinks, mesoLinkID)
+		}
+	}
+	sort.Slice(patch.UpdatedLinks, func(i, j int) bool {
+		return patch.UpdatedLinks[i] < patch.UpdatedLinks[j]
+	})
+
+	addedNodes := make(map[gmns.NodeID]*meso.Node, len(mesoNodes))
+	for nodeID := range mesoNodes {
+		// Nodes could be removed while connecting links at nodes where movements are not needed
+		if node, ok := mesoNet.Nodes[nodeID]; ok {
+			addedNodes[nodeID] = node
+		}
+	}
+	patch.AddedNodes = sortedMesoNodeIDs(addedNodes)
+	for mesoLinkID := range mesoNet.Links {
+		if _, ok := keptLinks[mesoLinkID]; !ok {
+			patch.AddedLinks = append(patch.AddedLinks, mesoLinkID)
+		}
+	}
+	sort.Slice(patch.AddedLinks, func(i, j int) bool {
+		return patch.AddedLinks[i] < patch.AddedLinks[j]
+	})
+
+	err = updateBoundaryType(addedNodes, macroNet.Nodes)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't update boundary types for mesoscopic nodes")
+	}
+	err = updateLinksProperties(mesoNet.Nodes, mesoNet.Links, patch.AddedLinks, macroNet.Nodes, macroNet.Links, movements, diag)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't update additional information for mesoscopic links")
+	}
+	return patch, nil
+}
+
+// RegenerateMicroscopic patches the microscopic network in place after the mesoscopic one has been patched by RegenerateMesoscopic.
+// Cells of regenerated and reconnected macroscopic links are rebuilt. Since cells are merged at nodes where movements are not needed,
+// links incident to such nodes are rebuilt too. Connector cells attached to rebuilt links are rebuilt as well, the rest micro nodes and links keep their identifiers
+func RegenerateMicroscopic(macroNet *macro.Net, mesoNet *meso.Net, microNet *micro.Net, movements movement.MovementsStorage, patch *MesoPatch, opts ...MicroGenOptions) error {
+	options := DefaultMicroGenOptions()
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	diag := newDiagnostics(options.Logger, options.Report)
+	movementFlags := ComputeMovementFlags(macroNet, movements)
+	diag.passThroughNodes(movementFlags)
+
+	rebuiltLinks := make(map[gmns.LinkID]struct{})
+	queue := make([]gmns.LinkID, 0, len(patch.MacroLinks)+len(patch.UpdatedLinks))
+	queue = append(queue, patch.MacroLinks...)
+	for _, mesoLinkID := range patch.UpdatedLinks {
+		if mesoLink, ok := mesoNet.Links[mesoLinkID]; ok {
+			queue = append(queue, mesoLink.MacroLink())
+		}
+	}
+	for len(queue) > 0 {
+		linkID := queue[0]
+		queue = queue[1:]
+		if _, ok := rebuiltLinks[linkID]; ok {
+			continue
+		}
+		rebuiltLinks[linkID] = struct{}{}
+		link, ok := macroNet.Links[linkID]
+		if !ok {
+			continue
+		}
+		for _, nodeID := range []gmns.NodeID{link.SourceNode(), link.TargetNode()} {
+			node, ok := macroNet.Nodes[nodeID]
+			if !ok || movementFlags.NodesNeedMovement[nodeID] {
+				continue
+			}
+			queue = append(queue, node.IncomingLinks()...)
+			queue = append(queue, node.OutcomingLinks()...)
+		}
+	}
+
+	macroLinkMesoLinks := buildMacroToMesoMapping(mesoNet)
+	droppedMesoLinks := make(map[gmns.LinkID]struct{})
+	for _, mesoLinkID := range patch.RemovedLinks {
+		droppedMesoLinks[mesoLinkID] = struct{}{}
+	}
+	for linkID := range rebuiltLinks {
+		for _, mesoLinkID := range macroLinkMesoLinks[linkID] {
+			droppedMesoLinks[mesoLinkID] = struct{}{}
+		}
+	}
+	connections := make(map[gmns.LinkID]struct{})
+	for mesoLinkID, mesoLink := range mesoNet.Links {
+		if !mesoLink.IsConnection() {
+			continue
+		}
+		_, incomeDropped := droppedMesoLinks[mesoLink.MovementMesoLinkIncome()]
+		_, outcomeDropped := droppedMesoLinks[mesoLink.MovementMesoLinkOutcome()]
+		if incomeDropped || outcomeDropped {
+			connections[mesoLinkID] = struct{}{}
+		}
+	}
+	for mesoLinkID := range connections {
+		droppedMesoLinks[mesoLinkID] = struct{}{}
+	}
+	dropMicroElements(microNet, droppedMesoLinks)
+
+	sortedRebuiltLinks := make([]gmns.LinkID, 0, len(rebuiltLinks))
+	rebuiltNodes := make(map[gmns.NodeID]struct{})
+	for _, linkID := range sortedLinkIDsGeneric(rebuiltLinks) {
+		link, ok := macroNet.Links[linkID]
+		if !ok {
+			continue
+		}
+		sortedRebuiltLinks = append(sortedRebuiltLinks, linkID)
+		rebuiltNodes[link.SourceNode()] = struct{}{}
+		rebuiltNodes[link.TargetNode()] = struct{}{}
+	}
+	if options.workersNum() > 1 {
+		err := processMacroLinksParallel(macroNet, mesoNet, microNet, sortedRebuiltLinks, macroLinkMesoLinks, options, newProgressTracker(context.Background(), nil, nil), STAGE_MICRO_CELLS)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, macroLinkID := range sortedRebuiltLinks {
+			err := processMacroLink(macroNet, mesoNet, microNet, macroNet.Links[macroLinkID], macroLinkMesoLinks[macroLinkID], options)
+			if err != nil {
+				return errors.Wrapf(err, "failed to process macro link %d", macroLinkID)
+			}
+		}
+	}
+	err := connectMicroLinks(mesoNet, microNet, sortedLinkIDsGeneric(connections), options, diag)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect micro links")
+	}
+	err = fixGaps(macroNet, mesoNet, microNet, macroLinkMesoLinks, movementFlags, movements, sortedNodeIDsSet(rebuiltNodes), diag)
+	if err != nil {
+		return errors.Wrap(err, "failed to fix gaps")
+	}
+	if options.SeparateBikeWalk {
+		rebuiltMovements := make([]gmns.MovementID, 0)
+		for _, mvmtID := range sortedMovementIDs(movements) {
+			mvmt := movements[mvmtID]
+			_, incomeRebuilt := rebuiltLinks[mvmt.IncomeMacroLink()]
+			_, outcomeRebuilt := rebuiltLinks[mvmt.OutcomeMacroLink()]
+			if incomeRebuilt || outcomeRebuilt {
+				rebuiltMovements = append(rebuiltMovements, mvmtID)
+			}
+		}
+		connectBikeWalkLinks(macroNet, mesoNet, microNet, macroLinkMesoLinks, movementFlags, movements, rebuiltMovements, options)
+	}
+	return nil
+}
+
+// newNetMesoIDs returns allocator for patching of the existing network: it gives identifiers which are not used in the network.
+// Identifiers of nodes are searched starting from macroscopic node identifier multiplied by 100 as for generation from scratch
+func newNetMesoIDs(mesoNet *meso.Net) *mesoIDsAllocator {
+	reservedNodes := make(map[gmns.NodeID]struct{})
+	return &mesoIDsAllocator{
+		nodeID: func(macroNodeID gmns.NodeID) gmns.NodeID {
+			for id := macroNodeID * 100; ; id++ {
+				if _, ok := mesoNet.Nodes[id]; ok {
+					continue
+				}
+				if _, ok := reservedNodes[id]; ok {
+					continue
+				}
+				reservedNodes[id] = struct{}{}
+				return id
+			}
+		},
+		linkID: mesoNet.NewLinkID,
+	}
+}
+
+// dropMicroElements removes micro nodes and links belonging to the given meso links. References to removed links are removed from the rest nodes
+func dropMicroElements(microNet *micro.Net, mesoLinks map[gmns.LinkID]struct{}) {
+	for linkID, link := range microNet.Links {
+		if _, ok := mesoLinks[link.MesoLink()]; !ok {
+			continue
+		}
+		if sourceNode, ok := microNet.Nodes[link.SourceNode()]; ok {
+			sourceNode.OutcomingLinks().Delete(linkID)
+		}
+		if targetNode, ok := microNet.Nodes[link.TargetNode()]; ok {
+			targetNode.IncomingLinks().Delete(linkID)
+		}
+		microNet.DeleteLink(linkID)
+	}
+	for nodeID, node := range microNet.Nodes {
+		if _, ok := mesoLinks[node.MesoLink()]; ok {
+			microNet.DeleteNode(nodeID)
+		}
+	}
+}
+
+// sortedNodeIDsSet returns sorted slice of node IDs from a set
+func sortedNodeIDsSet(m map[gmns.NodeID]struct{}) []gmns.NodeID {
+	keys := make([]gmns.NodeID, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	return keys
+}
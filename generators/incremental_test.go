@@ -0,0 +1,150 @@
+package generators
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/LdDl/go-gmns/meso"
+	"github.com/LdDl/go-gmns/micro"
+	"github.com/LdDl/go-gmns/movement"
+	"github.com/stretchr/testify/assert"
+)
+
+// describeMesoNet returns text representation of the network which does not depend on identifiers of nodes, links and movements
+func describeMesoNet(net *meso.Net) string {
+	describeLink := func(linkID gmns.LinkID) string {
+		link, ok := net.Links[linkID]
+		if !ok {
+			return "missing"
+		}
+		return fmt.Sprintf("%d/%d/%d", link.MacroLink(), link.SegmentIdx(), link.MacroNode())
+	}
+	describeLinks := func(keys []interface{}) []string {
+		links := make([]string, 0, len(keys))
+		for _, key := range keys {
+			links = append(links, describeLink(key.(gmns.LinkID)))
+		}
+		sort.Strings(links)
+		return links
+	}
+	lines := make([]string, 0, len(net.Nodes)+len(net.Links))
+	for _, node := range net.Nodes {
+		lines = append(lines, fmt.Sprintf("N %v %d %d %v %v %v", node.Geom(), node.MacroNode(), node.MacroLink(), node.BoundaryType(), describeLinks(node.IncomingLinks().Keys()), describeLinks(node.OutcomingLinks().Keys())))
+	}
+	for _, link := range net.Links {
+		lines = append(lines, fmt.Sprintf("L %v %v %v %d %d %d %d %f %v %s %s %v %d %d %v %v", net.Nodes[link.SourceNode()].Geom(), net.Nodes[link.TargetNode()].Geom(), link.Geom(), link.MacroNode(), link.MacroLink(), link.SegmentIdx(), link.LanesNum(), link.LengthMeters(), link.MvmtTextID(), describeLink(link.MovementMesoLinkIncome()), describeLink(link.MovementMesoLinkOutcome()), link.IsConnection(), link.MovementIncomeLaneStartSeqID(), link.MovementOutcomeLaneStartSeqID(), link.ControlType(), link.LanesChange()))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// describeMicroNet returns text representation of the network which does not depend on identifiers of nodes, links and movements
+func describeMicroNet(net *micro.Net, mesoNet *meso.Net) string {
+	describeMesoLink := func(linkID gmns.LinkID) string {
+		link, ok := mesoNet.Links[linkID]
+		if !ok {
+			return "missing"
+		}
+		return fmt.Sprintf("%d/%d/%d/%v", link.MacroLink(), link.SegmentIdx(), link.MacroNode(), link.MvmtTextID())
+	}
+	lines := make([]string, 0, len(net.Nodes)+len(net.Links))
+	for _, node := range net.Nodes {
+		lines = append(lines, fmt.Sprintf("N %v %s %d %d %v %v %d %d", node.Geom(), describeMesoLink(node.MesoLink()), node.LaneID(), node.CellIndex(), node.IsUpstreamEnd(), node.IsDownstreamEnd(), node.IncomingLinks().Len(), node.OutcomingLinks().Len()))
+	}
+	for _, link := range net.Links {
+		lines = append(lines, fmt.Sprintf("L %v %v %v %s %d %v %d %f %v %v", net.Nodes[link.SourceNode()].Geom(), net.Nodes[link.TargetNode()].Geom(), link.Geom(), describeMesoLink(link.MesoLink()), link.MacroNode(), link.CellType(), link.LaneID(), link.CellLength(), link.IsFirstMovementCell(), link.AllowedAgentTypes()))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+func TestRegenerate(t *testing.T) {
+	findLink := func(net *macro.Net, source, target gmns.NodeID) gmns.LinkID {
+		for linkID, link := range net.Links {
+			if link.SourceNode() == source && link.TargetNode() == target {
+				return linkID
+			}
+		}
+		t.Fatalf("No link between %d and %d", source, target)
+		return -1
+	}
+	edits := map[string]func(net *macro.Net) ChangeSet{
+		"lanes": func(net *macro.Net) ChangeSet {
+			linkID := findLink(net, 12, 13)
+			link := net.Links[linkID]
+			macro.WithLanesNum(link.LanesNum() + 2)(link)
+			macro.WithLanesInfo(macro.NewLanesInfo(link))(link)
+			return ChangeSet{Links: []gmns.LinkID{linkID}}
+		},
+		"removal": func(net *macro.Net) ChangeSet {
+			linkID := findLink(net, 7, 12)
+			assert.NoError(t, net.DeleteLink(linkID))
+			return ChangeSet{Links: []gmns.LinkID{linkID}}
+		},
+	}
+	generate := func(macroNet *macro.Net, movements movement.MovementsStorage, separateBikeWalk bool) (*meso.Net, *micro.Net) {
+		mesoOpts := DefaultMesoGenOptions()
+		mesoOpts.Verbose = false
+		mesoNet, err := GenerateMesoscopic(macroNet, movements, mesoOpts)
+		assert.NoError(t, err)
+		microOpts := DefaultMicroGenOptions()
+		microOpts.SeparateBikeWalk = separateBikeWalk
+		microNet, err := GenerateMicroscopic(macroNet, mesoNet, movements, microOpts)
+		assert.NoError(t, err)
+		return mesoNet, microNet
+	}
+	for name, edit := range edits {
+		for _, separateBikeWalk := range []bool{false, true} {
+			// Full generation after the edit
+			expectedMacroNet := gridNet(5)
+			edit(expectedMacroNet)
+			expectedMovements, err := GenerateMovements(expectedMacroNet)
+			assert.NoError(t, err)
+			expectedMesoNet, expectedMicroNet := generate(expectedMacroNet, expectedMovements, separateBikeWalk)
+
+			// Incremental generation after the edit
+			macroNet := gridNet(5)
+			movements, err := GenerateMovements(macroNet)
+			assert.NoError(t, err)
+			mesoNet, microNet := generate(macroNet, movements, separateBikeWalk)
+			farLinkID := findLink(macroNet, 0, 1)
+			farMesoLinks := make(map[gmns.LinkID]*meso.Link)
+			farMicroLinks := make(map[gmns.LinkID]*micro.Link)
+			for _, link := range mesoNet.Links {
+				if link.MacroLink() == farLinkID {
+					farMesoLinks[link.ID] = link
+				}
+			}
+			for _, link := range microNet.Links {
+				if _, ok := farMesoLinks[link.MesoLink()]; ok {
+					farMicroLinks[link.ID] = link
+				}
+			}
+
+			changes := edit(macroNet)
+			assert.NoError(t, RegenerateMovements(macroNet, movements, changes))
+			mesoOpts := DefaultMesoGenOptions()
+			mesoOpts.Verbose = false
+			patch, err := RegenerateMesoscopic(macroNet, mesoNet, movements, changes, mesoOpts)
+			assert.NoError(t, err)
+			microOpts := DefaultMicroGenOptions()
+			microOpts.SeparateBikeWalk = separateBikeWalk
+			assert.NoError(t, RegenerateMicroscopic(macroNet, mesoNet, microNet, movements, patch, microOpts))
+
+			assert.Equal(t, len(expectedMovements), len(movements), "Edit: %s", name)
+			assert.Equal(t, describeMesoNet(expectedMesoNet), describeMesoNet(mesoNet), "Incremental generation should give the same mesoscopic network as full one. Edit: %s", name)
+			assert.Equal(t, describeMicroNet(expectedMicroNet, expectedMesoNet), describeMicroNet(microNet, mesoNet), "Incremental generation should give the same microscopic network as full one. Edit: %s. Separate bike/walk: %t", name, separateBikeWalk)
+			assert.NotEmpty(t, farMicroLinks)
+			for linkID, link := range farMesoLinks {
+				assert.Same(t, link, mesoNet.Links[linkID], "Untouched mesoscopic link should be kept. Edit: %s", name)
+			}
+			for linkID, link := range farMicroLinks {
+				assert.Same(t, link, microNet.Links[linkID], "Untouched microscopic link should be kept. Edit: %s", name)
+			}
+		}
+	}
+}
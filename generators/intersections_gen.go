@@ -0,0 +1,58 @@
+package generators
+
+import (
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/LdDl/go-gmns/movement"
+	"github.com/pkg/errors"
+)
+
+// IntersectionsGenOptions contains options for intersections consolidation followed by movements generation
+type IntersectionsGenOptions struct {
+	// Consolidation options passed to macro.Net.ConsolidateIntersections
+	Consolidation macro.ConsolidationOptions
+	// Movements options passed to GenerateMovements
+	Movements MovementsGenOptions
+}
+
+// DefaultIntersectionsGenOptions returns default options for intersections consolidation and movements generation
+func DefaultIntersectionsGenOptions() IntersectionsGenOptions {
+	return IntersectionsGenOptions{
+		Consolidation: macro.DefaultConsolidationOptions(),
+		Movements:     DefaultMovementsGenOptions(),
+	}
+}
+
+// ConsolidateIntersections consolidates complex intersections of the given macroscopic network (see macro.Net.ConsolidateIntersections)
+// and generates movements for the resulting network.
+// U-turn movements at collapsed intersections are dropped: reverse directions are already ignored by movements generation,
+// so after collapse those could appear only between separate carriageways of the same road.
+func ConsolidateIntersections(macroNet *macro.Net, opts ...IntersectionsGenOptions) ([]*macro.Intersection, movement.MovementsStorage, error) {
+	options := DefaultIntersectionsGenOptions()
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	intersections, err := macroNet.ConsolidateIntersections(options.Consolidation)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Can't consolidate intersections")
+	}
+	movements, err := GenerateMovements(macroNet, options.Movements)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Can't generate movements for consolidated network")
+	}
+	collapsedNodes := make(map[gmns.NodeID]struct{})
+	for _, intersection := range intersections {
+		if intersection.CollapsedNode >= 0 {
+			collapsedNodes[intersection.CollapsedNode] = struct{}{}
+		}
+	}
+	for mvmtID, mvmt := range movements {
+		if _, ok := collapsedNodes[mvmt.MacroNode()]; !ok {
+			continue
+		}
+		if mvmt.Type() == movement.MOVEMENT_TYPE_U_TURN {
+			delete(movements, mvmtID)
+		}
+	}
+	return intersections, movements, nil
+}
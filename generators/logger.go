@@ -0,0 +1,87 @@
+package generators
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/rs/zerolog"
+)
+
+// Logger receives diagnostics of generators. Arguments after the message are alternating keys and values as for log/slog: "link_id", 10, "node_id", 5.
+// Implementations must be safe for concurrent use
+type Logger interface {
+	// Info receives progress messages. They are sent only if Verbose option is set
+	Info(msg string, keyvals ...any)
+	// Warn receives messages about skipped or patched elements of networks
+	Warn(msg string, keyvals ...any)
+}
+
+// nopLogger discards every message
+type nopLogger struct{}
+
+func (nopLogger) Info(msg string, keyvals ...any) {}
+func (nopLogger) Warn(msg string, keyvals ...any) {}
+
+// NopLogger returns logger which discards every message. It is used when no logger is provided in generator options
+func NopLogger() Logger {
+	return nopLogger{}
+}
+
+// zerologLogger passes messages to zerolog.Logger
+type zerologLogger struct {
+	logger zerolog.Logger
+}
+
+// NewZerologLogger returns logger which passes messages to the given zerolog logger. Keys which are not strings are formatted via fmt.Sprint
+func NewZerologLogger(logger zerolog.Logger) Logger {
+	return zerologLogger{logger: logger}
+}
+
+func (l zerologLogger) Info(msg string, keyvals ...any) {
+	l.logger.Info().Fields(zerologFields(keyvals)).Msg(msg)
+}
+
+func (l zerologLogger) Warn(msg string, keyvals ...any) {
+	l.logger.Warn().Fields(zerologFields(keyvals)).Msg(msg)
+}
+
+// zerologFields converts alternating keys and values into the map of fields. Value of the odd trailing key is empty
+func zerologFields(keyvals []any) map[string]any {
+	fields := make(map[string]any, (len(keyvals)+1)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		if i+1 < len(keyvals) {
+			fields[key] = keyvals[i+1]
+		} else {
+			fields[key] = nil
+		}
+	}
+	return fields
+}
+
+// slogLogger passes messages to slog.Logger
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns logger which passes messages to the given slog logger
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return slogLogger{logger: logger}
+}
+
+func (l slogLogger) Info(msg string, keyvals ...any) {
+	l.logger.Log(context.Background(), slog.LevelInfo, msg, keyvals...)
+}
+
+func (l slogLogger) Warn(msg string, keyvals ...any) {
+	l.logger.Log(context.Background(), slog.LevelWarn, msg, keyvals...)
+}
+
+// loggerOrNop returns the given logger or no-op one if it is nil
+func loggerOrNop(logger Logger) Logger {
+	if logger == nil {
+		return nopLogger{}
+	}
+	return logger
+}
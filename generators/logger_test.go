@@ -0,0 +1,44 @@
+package generators
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerAdapters(t *testing.T) {
+	var slogBuf bytes.Buffer
+	var zerologBuf bytes.Buffer
+	loggers := map[string]Logger{
+		"slog":    NewSlogLogger(slog.New(slog.NewJSONHandler(&slogBuf, nil))),
+		"zerolog": NewZerologLogger(zerolog.New(&zerologBuf)),
+	}
+	buffers := map[string]*bytes.Buffer{
+		"slog":    &slogBuf,
+		"zerolog": &zerologBuf,
+	}
+	for name, logger := range loggers {
+		macroNet := gridNet(3)
+		movements, err := GenerateMovements(macroNet)
+		assert.NoError(t, err)
+		mesoOpts := DefaultMesoGenOptions()
+		mesoOpts.Verbose = true
+		mesoOpts.Logger = logger
+		_, err = GenerateMesoscopic(macroNet, movements, mesoOpts)
+		assert.NoError(t, err)
+
+		output := buffers[name].String()
+		assert.Contains(t, output, `"scope":"gen_meso"`, "Logger: %s", name)
+		assert.Contains(t, output, `"elapsed":`, "Logger: %s", name)
+		assert.Equal(t, 10, strings.Count(output, `"level":"INFO"`)+strings.Count(output, `"level":"info"`), "Every stage should be reported. Logger: %s", name)
+	}
+	logger := NewZerologLogger(zerolog.New(&zerologBuf))
+	zerologBuf.Reset()
+	logger.Warn("odd", "link_id", 1, "node_id")
+	assert.Contains(t, zerologBuf.String(), `"link_id":1`)
+	assert.Contains(t, zerologBuf.String(), `"node_id":null`)
+}
@@ -1,6 +1,7 @@
 package generators
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"sort"
@@ -15,28 +16,131 @@ import (
 	"github.com/paulmach/orb"
 	"github.com/paulmach/orb/geo"
 	"github.com/pkg/errors"
-	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 )
 
-func init() {
-	zerolog.TimeFieldFormat = time.RFC3339
-}
-
 const (
-	SHORTCUT_LENGTH  = 0.1
+	// Default length of the cut for links which do not need movements at the node
+	SHORTCUT_LENGTH = 0.1
+	// Default minimum length of the link remaining after cuts
 	MIN_CUT_LENGTH   = 2.0
 	TOTAL_CUT_LENGTH = 2 * SHORTCUT_LENGTH * MIN_CUT_LENGTH
 )
 
 var (
+	// Deprecated: use MesoGenOptions.CutLengths instead. It only seeds cut lengths of DefaultMesoGenOptions and of options with empty CutLengths
 	CUT_LENGTHS          = [100]float64{2.0, 8.0, 12.0, 14.0, 16.0, 18.0, 20, 22, 24, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25, 25}
 	ErrNotImplementedYet = fmt.Errorf("not implemented yet")
 	ErrBadParentInfo     = fmt.Errorf("bad parent information")
 	ErrBadInterface      = fmt.Errorf("bad interface")
-	VERBOSE              = true
+	// Deprecated: use MesoGenOptions.Verbose instead. It only seeds Verbose of DefaultMesoGenOptions
+	VERBOSE = true
 )
 
+// MesoGenOptions contains options for mesoscopic network generation
+type MesoGenOptions struct {
+	// Cut lengths [meters] at the ends of links where movements are needed. Index is number of lanes, the last value is used for greater numbers.
+	// CUT_LENGTHS values are used if it is empty
+	CutLengths []float64
+	// Lane width [meters] used for offset of bidirectional links' geometries. Non-positive value means macro.LANE_WIDTH
+	LaneWidth float64
+	// Side of the centerline where geometries of bidirectional links are placed
+	DrivingSide types.DrivingSide
+	// Cut length [meters] at the ends of links where movements are not needed. Non-positive value means SHORTCUT_LENGTH
+	ShortcutLength float64
+	// Minimum length [meters] of the link remaining after cuts. Non-positive value means MIN_CUT_LENGTH
+	MinCutLength float64
+	// Number of goroutines for processing links and nodes. Non-positive value means runtime.GOMAXPROCS(0). Output does not depend on this value
+	Workers int
+	// Progress receives progress of generation stages. Could be nil
+	Progress ProgressFunc
+	// Logger receives diagnostics. No-op logger is used if it is nil
+	Logger Logger
+	// Report collects statistics and anomalies of generation. Could be nil
+	Report *GenerationReport
+	// Verbose enables progress messages sent to Logger
+	Verbose bool
+	// StableIDs derives identifiers from parent macroscopic links and segment indices (movements for connection links) instead of processing order.
+	// It is not used by RegenerateMesoscopic
+	StableIDs bool
+}
+
+// DefaultMesoGenOptions returns default options for meso generation
+func DefaultMesoGenOptions() MesoGenOptions {
+	cutLengths := make([]float64, len(CUT_LENGTHS))
+	copy(cutLengths, CUT_LENGTHS[:])
+	return MesoGenOptions{
+		CutLengths:     cutLengths,
+		LaneWidth:      macro.LANE_WIDTH,
+		DrivingSide:    types.DRIVING_SIDE_RIGHT,
+		ShortcutLength: SHORTCUT_LENGTH,
+		MinCutLength:   MIN_CUT_LENGTH,
+		Workers:        1,
+		Verbose:        VERBOSE,
+	}
+}
+
+// cutLength returns cut length for the given number of lanes
+func (opts MesoGenOptions) cutLength(lanesNum int) float64 {
+	if len(opts.CutLengths) == 0 {
+		return CUT_LENGTHS[min(max(lanesNum, 0), len(CUT_LENGTHS)-1)]
+	}
+	return opts.CutLengths[min(max(lanesNum, 0), len(opts.CutLengths)-1)]
+}
+
+// laneWidth returns lane width [meters] for offsets of bidirectional links
+func (opts MesoGenOptions) laneWidth() float64 {
+	if opts.LaneWidth <= 0 {
+		return macro.LANE_WIDTH
+	}
+	return opts.LaneWidth
+}
+
+// shortcutLength returns cut length [meters] for the ends of links where movements are not needed
+func (opts MesoGenOptions) shortcutLength() float64 {
+	if opts.ShortcutLength <= 0 {
+		return SHORTCUT_LENGTH
+	}
+	return opts.ShortcutLength
+}
+
+// minCutLength returns minimum length [meters] of the link remaining after cuts
+func (opts MesoGenOptions) minCutLength() float64 {
+	if opts.MinCutLength <= 0 {
+		return MIN_CUT_LENGTH
+	}
+	return opts.MinCutLength
+}
+
+// offsetDirection returns direction of offset for bidirectional links: geometries are moved to the right of the centerline for right-hand traffic
+func (opts MesoGenOptions) offsetDirection() float64 {
+	if opts.DrivingSide == types.DRIVING_SIDE_LEFT {
+		return 1.0
+	}
+	return -1.0
+}
+
+// logger returns logger for diagnostics
+func (opts MesoGenOptions) logger() Logger {
+	return loggerOrNop(opts.Logger)
+}
+
+// workersNum returns number of goroutines for processing links and nodes
+func (opts MesoGenOptions) workersNum() int {
+	return workersNum(opts.Workers)
+}
+
+// reversedLinkIdx returns index of the first link after the i-th one which geometry is the reversed geometry of the i-th link.
+// candidates are indices (in ascending order) of links having hash of the reversed geometry. Returns -1 if there is no such link
+func reversedLinkIdx(macroLinks []*macro.Link, i int, candidates []int) int {
+	pointsNum := len(macroLinks[i].GeomEuclidean())
+	for k := sort.SearchInts(candidates, i+1); k < len(candidates); k++ {
+		if len(macroLinks[candidates[k]].GeomEuclidean()) == pointsNum {
+			return candidates[k]
+		}
+	}
+	return -1
+}
+
 type macroLinkProcessing struct {
 	needsOffset         bool
 	offsetDirection     float64 // -1.0 or 1.0; determines which side of centerline to offset
@@ -65,21 +169,160 @@ type macroLinkProcessing struct {
 	targetMacroNodeID gmns.NodeID
 }
 
-func GenerateMesoscopic(macroNet *macro.Net, movements movement.MovementsStorage) (*meso.Net, error) {
-	if VERBOSE {
-		log.Info().Str("scope", "gen_meso").Msg("Preparing mesoscopic network")
+// GenerateMesoscopic generates mesoscopic network from macroscopic network and movements
+func GenerateMesoscopic(macroNet *macro.Net, movements movement.MovementsStorage, opts ...MesoGenOptions) (*meso.Net, error) {
+	return GenerateMesoscopicContext(context.Background(), macroNet, movements, opts...)
+}
+
+// GenerateMesoscopicContext is the same as GenerateMesoscopic, but it stops generation with the context's error if the context is done before the next stage
+func GenerateMesoscopicContext(ctx context.Context, macroNet *macro.Net, movements movement.MovementsStorage, opts ...MesoGenOptions) (*meso.Net, error) {
+	options := DefaultMesoGenOptions()
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	logger := options.logger()
+	diag := newDiagnostics(logger, options.Report)
+	tracker := newProgressTracker(ctx, options.Progress, diag)
+	if options.Verbose {
+		logger.Info("Preparing mesoscopic network", "scope", "gen_meso")
 	}
 
+	workers := options.workersNum()
+	if err := tracker.begin(STAGE_MESO_OFFSETS); err != nil {
+		return nil, err
+	}
 	st := time.Now()
-	if VERBOSE {
-		log.Info().Str("scope", "gen_meso").Msg("Preparing geometries offsets")
+	if options.Verbose {
+		logger.Info("Preparing geometries offsets", "scope", "gen_meso")
 	}
 
 	macroLinks := macroLinksToSlice(macroNet.Links)
+	needToObserve := observeMacroLinks(macroLinks, options, workers)
+	sortedNeedToObserve := sortedLinkIDs(needToObserve)
+	err := prepareOffsets(macroNet, needToObserve, sortedNeedToObserve, options, workers)
+	if err != nil {
+		return nil, err
+	}
+	tracker.end(STAGE_MESO_OFFSETS, len(sortedNeedToObserve))
+	if options.Verbose {
+		logger.Info("Done preparing geometries offsets. Aggregate movements for nodes", "scope", "gen_meso", "elapsed", time.Since(st).Seconds())
+	}
+	if err := tracker.begin(STAGE_MESO_MOVEMENTS); err != nil {
+		return nil, err
+	}
+	st = time.Now()
+	macroNodesMovements, err := groupMovementsByNode(macroNet, movements)
+	if err != nil {
+		return nil, err
+	}
+	tracker.end(STAGE_MESO_MOVEMENTS, len(movements))
+	if options.Verbose {
+		logger.Info("Done aggregating movements. Process movements (check necessity)", "scope", "gen_meso", "elapsed", time.Since(st).Seconds())
+	}
+	if err := tracker.begin(STAGE_MESO_NECESSITY); err != nil {
+		return nil, err
+	}
+	st = time.Now()
+	sortedMacroNodeIDsMain := sortedMacroNodeIDs(macroNet.Nodes)
+	macroNodesNeedMovement, err := checkMovementsNecessity(macroNet, sortedMacroNodeIDsMain, needToObserve, macroNodesMovements)
+	if err != nil {
+		return nil, err
+	}
+	tracker.end(STAGE_MESO_NECESSITY, len(sortedMacroNodeIDsMain))
+
+	if options.Verbose {
+		logger.Info("Done checking necessity of movements. Process movements (calculate cuts' lengths and perform cuts)", "scope", "gen_meso", "elapsed", time.Since(st).Seconds())
+	}
+	if err := tracker.begin(STAGE_MESO_CUTS); err != nil {
+		return nil, err
+	}
+	st = time.Now()
+	performCuts(needToObserve, sortedNeedToObserve, options, workers)
+	tracker.end(STAGE_MESO_CUTS, len(sortedNeedToObserve))
+	if options.Verbose {
+		logger.Info("Done cuts. Build mesoscopic links", "scope", "gen_meso", "elapsed", time.Since(st).Seconds())
+	}
+	if err := tracker.begin(STAGE_MESO_BASE_LINKS); err != nil {
+		return nil, err
+	}
+	st = time.Now()
+
+	ids := newSequentialMesoIDs()
+	mesoNodes, mesoLinks, err := generateBaseNodesLinks(macroNet.Nodes, needToObserve, ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't generate base mesoscopic nodes and links")
+	}
+	tracker.end(STAGE_MESO_BASE_LINKS, len(sortedNeedToObserve))
+	if options.Verbose {
+		logger.Info("Done building mesoscopic links. Connect mesoscopic links", "scope", "gen_meso", "elapsed", time.Since(st).Seconds())
+	}
+	if err := tracker.begin(STAGE_MESO_CONNECTIONS); err != nil {
+		return nil, err
+	}
+	st = time.Now()
+
+	err = connectMesoscopicLinks(mesoLinks, mesoNodes, sortedMacroNodeIDsMain, macroNet.Links, needToObserve, macroNodesMovements, macroNodesNeedMovement, ids, workers, diag)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't prepare connections between mesoscopic links")
+	}
+	tracker.end(STAGE_MESO_CONNECTIONS, len(sortedMacroNodeIDsMain))
+	if options.Verbose {
+		logger.Info("Done connecting links. Updating boundary type for mesoscopic nodes", "scope", "gen_meso", "elapsed", time.Since(st).Seconds())
+	}
+	if err := tracker.begin(STAGE_MESO_BOUNDARY_TYPES); err != nil {
+		return nil, err
+	}
+	st = time.Now()
+
+	err = updateBoundaryType(mesoNodes, macroNet.Nodes)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't update boundary types for mesoscopic nodes")
+	}
+	tracker.end(STAGE_MESO_BOUNDARY_TYPES, len(mesoNodes))
+	if options.Verbose {
+		logger.Info("Done updating boundary type. Updating additional information for mesoscopic links", "scope", "gen_meso", "elapsed", time.Since(st).Seconds())
+	}
+	if err := tracker.begin(STAGE_MESO_LINKS_PROPERTIES); err != nil {
+		return nil, err
+	}
+	st = time.Now()
+
+	err = updateLinksProperties(mesoNodes, mesoLinks, sortedMesoLinkIDs(mesoLinks), macroNet.Nodes, macroNet.Links, movements, diag)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't update additional information for mesoscopic links")
+	}
+	tracker.end(STAGE_MESO_LINKS_PROPERTIES, len(mesoLinks))
+	if options.Verbose {
+		logger.Info("Done updating links additional information. Preparing mesoscopic network done!", "scope", "gen_meso", "elapsed", time.Since(st).Seconds())
+	}
+
+	mesoNet := meso.Net{
+		Nodes: mesoNodes,
+		Links: mesoLinks,
+	}
+	if options.StableIDs {
+		if err := applyStableMesoIDs(macroNet, &mesoNet, movements); err != nil {
+			return nil, err
+		}
+	}
+	return &mesoNet, nil
+}
+
+// observeMacroLinks prepares processing data for the given macroscopic links (sorted by identifiers) and detects bidirectional ones: links having reversed twin are offset from the centerline
+func observeMacroLinks(macroLinks []*macro.Link, options MesoGenOptions, workers int) map[gmns.LinkID]*macroLinkProcessing {
 	needToObserve := make(map[gmns.LinkID]*macroLinkProcessing, len(macroLinks))
-	hashesEuclidean := make(map[gmns.LinkID]string, len(macroLinks))
-	for i := range macroLinks {
-		hashesEuclidean[macroLinks[i].ID] = geomath.GeometryHash(macroLinks[i].GeomEuclidean())
+	hashesEuclidean := make([]string, len(macroLinks))
+	reversedHashesEuclidean := make([]string, len(macroLinks))
+	parallelFor(len(macroLinks), workers, func(i int) {
+		hashesEuclidean[i] = geomath.GeometryHash(macroLinks[i].GeomEuclidean())
+		reversedGeom := macroLinks[i].GeomEuclidean().Clone()
+		reversedGeom.Reverse()
+		reversedHashesEuclidean[i] = geomath.GeometryHash(reversedGeom)
+	})
+	// Indices of links (in ascending order) for every geometry hash
+	hashesIndices := make(map[string][]int, len(macroLinks))
+	for i := range hashesEuclidean {
+		hashesIndices[hashesEuclidean[i]] = append(hashesIndices[hashesEuclidean[i]], i)
 	}
 
 	// Detect bidirectional links (single-threaded to avoid race conditions)
@@ -89,81 +332,61 @@ func GenerateMesoscopic(macroNet *macro.Net, movements movement.MovementsStorage
 		if _, ok := needToObserve[macroLinkID]; ok {
 			continue
 		}
-
-		reversedGeom := macroLink.GeomEuclidean().Clone()
-		reversedGeom.Reverse()
-		reversedGeomHash := geomath.GeometryHash(reversedGeom)
-		reversedLinkExists := false
-
-		// Compare only with subsequent links
-		for j := i + 1; j < len(macroLinks); j++ {
-			macroLinkCompare := macroLinks[j]
-			macroLinkCompareID := macroLinkCompare.ID
-			macroLinkGeomEuclidean := macroLinkCompare.GeomEuclidean()
-			if len(reversedGeom) != len(macroLinkGeomEuclidean) {
-				continue
-			}
-			hashedGeomEuclidean, ok := hashesEuclidean[macroLinkCompareID]
-			if !ok {
-				panic("Could not happen. Hashes are prepared for every macroscopic link")
-			}
-			if reversedGeomHash == hashedGeomEuclidean {
-				reversedLinkExists = true
-				// Both bidirectional links use the same negative offset (-1.0)
-				// Since their geometries are reversed, they end up on opposite sides of centerline
-				needToObserve[macroLinkID] = &macroLinkProcessing{id: macroLinkID, lanesInfo: macroLink.LanesInfo(), needsOffset: true, offsetDirection: -1.0, sourceMacroNodeID: macroLink.SourceNode(), targetMacroNodeID: macroLink.TargetNode()}
-				needToObserve[macroLinkCompareID] = &macroLinkProcessing{id: macroLinkCompareID, lanesInfo: macroLinkCompare.LanesInfo(), needsOffset: true, offsetDirection: -1.0, sourceMacroNodeID: macroLinkCompare.SourceNode(), targetMacroNodeID: macroLinkCompare.TargetNode()}
-				break
-			}
-		}
-		if !reversedLinkExists {
-			needToObserve[macroLinkID] = &macroLinkProcessing{id: macroLinkID, lanesInfo: macroLink.LanesInfo(), offsetDirection: -1.0, sourceMacroNodeID: macroLink.SourceNode(), targetMacroNodeID: macroLink.TargetNode()}
+		pairIdx := reversedLinkIdx(macroLinks, i, hashesIndices[reversedHashesEuclidean[i]])
+		if pairIdx < 0 {
+			needToObserve[macroLinkID] = &macroLinkProcessing{id: macroLinkID, lanesInfo: macroLink.LanesInfo().Clone(), offsetDirection: options.offsetDirection(), sourceMacroNodeID: macroLink.SourceNode(), targetMacroNodeID: macroLink.TargetNode()}
+			continue
 		}
+		macroLinkCompare := macroLinks[pairIdx]
+		macroLinkCompareID := macroLinkCompare.ID
+		// Both bidirectional links use the same offset direction (negative for right-hand traffic)
+		// Since their geometries are reversed, they end up on opposite sides of centerline
+		needToObserve[macroLinkID] = &macroLinkProcessing{id: macroLinkID, lanesInfo: macroLink.LanesInfo().Clone(), needsOffset: true, offsetDirection: options.offsetDirection(), sourceMacroNodeID: macroLink.SourceNode(), targetMacroNodeID: macroLink.TargetNode()}
+		needToObserve[macroLinkCompareID] = &macroLinkProcessing{id: macroLinkCompareID, lanesInfo: macroLinkCompare.LanesInfo().Clone(), needsOffset: true, offsetDirection: options.offsetDirection(), sourceMacroNodeID: macroLinkCompare.SourceNode(), targetMacroNodeID: macroLinkCompare.TargetNode()}
 	}
 
-	sortedNeedToObserve := sortedLinkIDs(needToObserve)
-	for _, macroLinkID := range sortedNeedToObserve {
+	return needToObserve
+}
+
+// prepareOffsets calculates offset geometries for the given macroscopic links and scales lanes change points to the offset lengths
+func prepareOffsets(macroNet *macro.Net, needToObserve map[gmns.LinkID]*macroLinkProcessing, linkIDs []gmns.LinkID, options MesoGenOptions, workers int) error {
+	errs := make([]error, len(linkIDs))
+	parallelFor(len(linkIDs), workers, func(idx int) {
+		macroLinkID := linkIDs[idx]
 		macroLinkProcess := needToObserve[macroLinkID]
 		macroLink, ok := macroNet.Links[macroLinkID]
 		if !ok {
-			return nil, errors.Wrapf(macro.ErrLinkNotFound, "Offset Link ID: %d", macroLinkID)
+			errs[idx] = errors.Wrapf(macro.ErrLinkNotFound, "Offset Link ID: %d", macroLinkID)
+			return
 		}
 		if !macroLinkProcess.needsOffset {
 			macroLinkProcess.offsetGeomEuclidean = macroLink.GeomEuclidean().Clone()
 			macroLinkProcess.offsetGeom = macroLink.Geom().Clone()
-			continue
+		} else {
+			offsetDistance := 2 * (float64(macroLink.MaxLanes())/2 + 0.5) * options.laneWidth()
+			macroLinkProcess.offsetGeomEuclidean = geomath.OffsetCurve(macroLink.GeomEuclidean(), macroLinkProcess.offsetDirection*offsetDistance)
+			macroLinkProcess.offsetGeom = geomath.LineToSpherical(macroLinkProcess.offsetGeomEuclidean)
 		}
-		offsetDistance := 2 * (float64(macroLink.MaxLanes())/2 + 0.5) * macro.LANE_WIDTH
-		macroLinkProcess.offsetGeomEuclidean = geomath.OffsetCurve(macroLink.GeomEuclidean(), macroLinkProcess.offsetDirection*offsetDistance)
-		macroLinkProcess.offsetGeom = geomath.LineToSpherical(macroLinkProcess.offsetGeomEuclidean)
-	}
-	// Update breakpoints since geometry has changed
-	for _, macroLinkID := range sortedNeedToObserve {
-		macroLinkProcess := needToObserve[macroLinkID]
+		// Update breakpoints since geometry has changed
 		// Re-calcuate length for offset geometry and round to 2 decimal places
 		macroLinkProcess.lengthMetersOffset = math.Round(geo.LengthHaversine(macroLinkProcess.offsetGeom)*100.0) / 100.0
-		macroLink, ok := macroNet.Links[macroLinkID]
-		if !ok {
-			return nil, errors.Wrapf(macro.ErrLinkNotFound, "Offset Link ID: %d", macroLinkID)
-		}
 		for i, item := range macroLinkProcess.lanesInfo.LanesChangePoints {
 			macroLinkProcess.lanesInfo.LanesChangePoints[i] = (item / macroLink.LengthMeters()) * macroLinkProcess.lengthMetersOffset
 		}
+	})
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
 	}
-	if VERBOSE {
-		log.Info().Str("scope", "gen_meso").Float64("elapsed", time.Since(st).Seconds()).Msg("Done preparing geometries offsets. Aggregate movements for nodes")
-	}
-	st = time.Now()
+	return nil
+}
+
+// groupMovementsByNode returns movements (sorted by identifiers) for every macroscopic node
+func groupMovementsByNode(macroNet *macro.Net, movements movement.MovementsStorage) (map[gmns.NodeID][]*movement.Movement, error) {
 	macroNodesMovements := make(map[gmns.NodeID][]*movement.Movement, len(macroNet.Nodes))
 	// Sort movement IDs for deterministic iteration
-	sortedMovementIDs := make([]gmns.MovementID, 0, len(movements))
-	for mvmtID := range movements {
-		sortedMovementIDs = append(sortedMovementIDs, mvmtID)
-	}
-	sort.Slice(sortedMovementIDs, func(i, j int) bool {
-		return sortedMovementIDs[i] < sortedMovementIDs[j]
-	})
-	for _, mvmtID := range sortedMovementIDs {
+	for _, mvmtID := range sortedMovementIDs(movements) {
 		mvmt := movements[mvmtID]
 		macroNodeID := mvmt.MacroNode()
 		if _, ok := macroNet.Nodes[macroNodeID]; !ok {
@@ -174,18 +397,19 @@ func GenerateMesoscopic(macroNet *macro.Net, movements movement.MovementsStorage
 		}
 		macroNodesMovements[macroNodeID] = append(macroNodesMovements[macroNodeID], mvmt)
 	}
-	if VERBOSE {
-		log.Info().Str("scope", "gen_meso").Float64("elapsed", time.Since(st).Seconds()).Msg("Done aggregating movements. Process movements (check necessity)")
-	}
-	st = time.Now()
+	return macroNodesMovements, nil
+}
+
+// checkMovementsNecessity finds the given macroscopic nodes which do not need movements (i.e. links are just continued there) and marks ends of incident links for shortcuts.
+// Every link incident to the given nodes must be observed
+func checkMovementsNecessity(macroNet *macro.Net, nodeIDs []gmns.NodeID, needToObserve map[gmns.LinkID]*macroLinkProcessing, macroNodesMovements map[gmns.NodeID][]*movement.Movement) (map[gmns.NodeID]bool, error) {
 	macroNodesNeedMovement := make(map[gmns.NodeID]bool)
 	// Assume that every node need movement (i.e. all nodes are intersections by default). We will filter this set later
-	sortedMacroNodeIDsMain := sortedMacroNodeIDs(macroNet.Nodes)
-	for _, nodeID := range sortedMacroNodeIDsMain {
+	for _, nodeID := range nodeIDs {
 		macroNodesNeedMovement[nodeID] = true
 	}
 
-	for _, nodeID := range sortedMacroNodeIDsMain {
+	for _, nodeID := range nodeIDs {
 		macroNode := macroNet.Nodes[nodeID]
 		if macroNode.ControlType() == types.CONTROL_TYPE_IS_SIGNAL {
 			continue
@@ -305,60 +529,16 @@ func GenerateMesoscopic(macroNet *macro.Net, movements movement.MovementsStorage
 		}
 	}
 
-	if VERBOSE {
-		log.Info().Str("scope", "gen_meso").Float64("elapsed", time.Since(st).Seconds()).Msg("Done checking necessity of movements. Process movements (calculate cuts' lengths and perform cuts)")
-	}
-	st = time.Now()
-	for _, macroLinkID := range sortedNeedToObserve {
-		macroLinkProcess := needToObserve[macroLinkID]
-		macroLinkProcess.updateCutLength()
-		macroLinkProcess.performCut()
-	}
-	if VERBOSE {
-		log.Info().Str("scope", "gen_meso").Float64("elapsed", time.Since(st).Seconds()).Msg("Done cuts. Build mesoscopic links")
-	}
-	st = time.Now()
-
-	mesoNodes, mesoLinks, err := generateBaseNodesLinks(macroNet.Nodes, needToObserve)
-	if err != nil {
-		return nil, errors.Wrap(err, "Can't generate base mesoscopic nodes and links")
-	}
-	if VERBOSE {
-		log.Info().Str("scope", "gen_meso").Float64("elapsed", time.Since(st).Seconds()).Msg("Done building mesoscopic links. Connect mesoscopic links")
-	}
-	st = time.Now()
-
-	err = connectMesoscopicLinks(mesoLinks, mesoNodes, macroNet.Nodes, macroNet.Links, needToObserve, macroNodesMovements, macroNodesNeedMovement)
-	if err != nil {
-		return nil, errors.Wrap(err, "Can't prepare connections between mesoscopic links")
-	}
-	if VERBOSE {
-		log.Info().Str("scope", "gen_meso").Float64("elapsed", time.Since(st).Seconds()).Msg("Done connecting links. Updating boundary type for mesoscopic nodes")
-	}
-	st = time.Now()
-
-	err = updateBoundaryType(mesoNodes, macroNet.Nodes)
-	if err != nil {
-		return nil, errors.Wrap(err, "Can't update boundary types for mesoscopic nodes")
-	}
-	if VERBOSE {
-		log.Info().Str("scope", "gen_meso").Float64("elapsed", time.Since(st).Seconds()).Msg("Done updating boundary type. Updating additional information for mesoscopic links")
-	}
-	st = time.Now()
-
-	err = updateLinksProperties(mesoNodes, mesoLinks, macroNet.Nodes, macroNet.Links, movements)
-	if err != nil {
-		return nil, errors.Wrap(err, "Can't update additional information for mesoscopic links")
-	}
-	if VERBOSE {
-		log.Info().Str("scope", "gen_meso").Float64("elapsed", time.Since(st).Seconds()).Msg("Done updating links additional information. Preparing mesoscopic network done!")
-	}
+	return macroNodesNeedMovement, nil
+}
 
-	mesoNet := meso.Net{
-		Nodes: mesoNodes,
-		Links: mesoLinks,
-	}
-	return &mesoNet, nil
+// performCuts calculates cuts' lengths for the given macroscopic links and performs cuts
+func performCuts(needToObserve map[gmns.LinkID]*macroLinkProcessing, linkIDs []gmns.LinkID, options MesoGenOptions, workers int) {
+	parallelFor(len(linkIDs), workers, func(idx int) {
+		macroLinkProcess := needToObserve[linkIDs[idx]]
+		macroLinkProcess.updateCutLength(options)
+		macroLinkProcess.performCut()
+	})
 }
 
 func macroLinksToSlice(links map[gmns.LinkID]*macro.Link) []*macro.Link {
@@ -421,6 +601,18 @@ func sortedMesoLinkIDs(m map[gmns.LinkID]*meso.Link) []gmns.LinkID {
 	return keys
 }
 
+// sortedMovementIDs returns sorted slice of movement IDs from a storage
+func sortedMovementIDs(m movement.MovementsStorage) []gmns.MovementID {
+	keys := make([]gmns.MovementID, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
 // sortedLinkIDsGeneric returns sorted slice of link IDs from a map with struct{} values
 func sortedLinkIDsGeneric(m map[gmns.LinkID]struct{}) []gmns.LinkID {
 	keys := make([]gmns.LinkID, 0, len(m))
@@ -433,78 +625,81 @@ func sortedLinkIDsGeneric(m map[gmns.LinkID]struct{}) []gmns.LinkID {
 	return keys
 }
 
-func (macroLinkProcess *macroLinkProcessing) updateCutLength() {
+func (macroLinkProcess *macroLinkProcessing) updateCutLength(opts MesoGenOptions) {
+	shortcutLength := opts.shortcutLength()
+	minCutLength := opts.minCutLength()
+	totalCutLength := 2 * shortcutLength * minCutLength
 	laneChangePoints := macroLinkProcess.lanesInfo.LanesChangePoints
 	// Dodge potential change of number of lanes on two ends of the macroscopic link
 	// @todo: Bound check for LanesChangePoints
-	upstreamMaxCut := math.Max(SHORTCUT_LENGTH, laneChangePoints[1]-laneChangePoints[0]-3)
+	upstreamMaxCut := math.Max(shortcutLength, laneChangePoints[1]-laneChangePoints[0]-3)
 	// Defife a variable downstreamMaxCut which is the maximum length of a cut that can be made downstream of the link,
 	// calculated as the maximum of the shortcutLen and the difference between the last two elements in the link.lanesChangePoints minus 3.
 	// @todo: Bound check for LanesChangePoints
-	downstreamMaxCut := math.Max(SHORTCUT_LENGTH, laneChangePoints[len(laneChangePoints)-1]-laneChangePoints[len(laneChangePoints)-2]-3)
+	downstreamMaxCut := math.Max(shortcutLength, laneChangePoints[len(laneChangePoints)-1]-laneChangePoints[len(laneChangePoints)-2]-3)
 	if macroLinkProcess.upstreamShortCut && macroLinkProcess.downstreamShortCut {
-		if macroLinkProcess.lengthMetersOffset > TOTAL_CUT_LENGTH {
-			macroLinkProcess.upstreamCutLen = SHORTCUT_LENGTH
-			macroLinkProcess.downstreamCutLen = SHORTCUT_LENGTH
+		if macroLinkProcess.lengthMetersOffset > totalCutLength {
+			macroLinkProcess.upstreamCutLen = shortcutLength
+			macroLinkProcess.downstreamCutLen = shortcutLength
 		} else {
-			macroLinkProcess.upstreamCutLen = (macroLinkProcess.lengthMetersOffset / TOTAL_CUT_LENGTH) * SHORTCUT_LENGTH
+			macroLinkProcess.upstreamCutLen = (macroLinkProcess.lengthMetersOffset / totalCutLength) * shortcutLength
 			macroLinkProcess.downstreamCutLen = macroLinkProcess.upstreamCutLen
 		}
 	} else if macroLinkProcess.upstreamShortCut {
 		cutIdx := 0
 		cutPlaceFound := false
 		for i := macroLinkProcess.lanesInfo.LanesList[len(macroLinkProcess.lanesInfo.LanesList)-1]; i >= 0; i-- {
-			if macroLinkProcess.lengthMetersOffset > math.Min(downstreamMaxCut, CUT_LENGTHS[i])+SHORTCUT_LENGTH+MIN_CUT_LENGTH {
+			if macroLinkProcess.lengthMetersOffset > math.Min(downstreamMaxCut, opts.cutLength(i))+shortcutLength+minCutLength {
 				cutIdx = i
 				cutPlaceFound = true
 				break
 			}
 		}
 		if cutPlaceFound {
-			macroLinkProcess.upstreamCutLen = SHORTCUT_LENGTH
-			macroLinkProcess.downstreamCutLen = math.Min(downstreamMaxCut, CUT_LENGTHS[cutIdx])
+			macroLinkProcess.upstreamCutLen = shortcutLength
+			macroLinkProcess.downstreamCutLen = math.Min(downstreamMaxCut, opts.cutLength(cutIdx))
 		} else {
-			downStreamCut := math.Min(downstreamMaxCut, CUT_LENGTHS[0])
-			totalLen := downStreamCut + SHORTCUT_LENGTH + MIN_CUT_LENGTH
-			macroLinkProcess.upstreamCutLen = (macroLinkProcess.lengthMetersOffset / totalLen) * SHORTCUT_LENGTH
+			downStreamCut := math.Min(downstreamMaxCut, opts.cutLength(0))
+			totalLen := downStreamCut + shortcutLength + minCutLength
+			macroLinkProcess.upstreamCutLen = (macroLinkProcess.lengthMetersOffset / totalLen) * shortcutLength
 			macroLinkProcess.downstreamCutLen = (macroLinkProcess.lengthMetersOffset / totalLen) * downStreamCut
 		}
 	} else if macroLinkProcess.downstreamShortCut {
 		cutIdx := 0
 		cutPlaceFound := false
 		for i := macroLinkProcess.lanesInfo.LanesList[len(macroLinkProcess.lanesInfo.LanesList)-1]; i >= 0; i-- {
-			if macroLinkProcess.lengthMetersOffset > math.Min(upstreamMaxCut, CUT_LENGTHS[i])+SHORTCUT_LENGTH+MIN_CUT_LENGTH {
+			if macroLinkProcess.lengthMetersOffset > math.Min(upstreamMaxCut, opts.cutLength(i))+shortcutLength+minCutLength {
 				cutIdx = i
 				cutPlaceFound = true
 				break
 			}
 		}
 		if cutPlaceFound {
-			macroLinkProcess.upstreamCutLen = math.Min(upstreamMaxCut, CUT_LENGTHS[cutIdx])
-			macroLinkProcess.downstreamCutLen = SHORTCUT_LENGTH
+			macroLinkProcess.upstreamCutLen = math.Min(upstreamMaxCut, opts.cutLength(cutIdx))
+			macroLinkProcess.downstreamCutLen = shortcutLength
 		} else {
-			upStreamCut := math.Min(upstreamMaxCut, CUT_LENGTHS[0])
-			totalLen := upStreamCut + SHORTCUT_LENGTH + MIN_CUT_LENGTH
-			macroLinkProcess.upstreamCutLen = (macroLinkProcess.lengthMetersOffset / totalLen) * CUT_LENGTHS[0]
-			macroLinkProcess.downstreamCutLen = (macroLinkProcess.lengthMetersOffset / totalLen) * SHORTCUT_LENGTH
+			upStreamCut := math.Min(upstreamMaxCut, opts.cutLength(0))
+			totalLen := upStreamCut + shortcutLength + minCutLength
+			macroLinkProcess.upstreamCutLen = (macroLinkProcess.lengthMetersOffset / totalLen) * opts.cutLength(0)
+			macroLinkProcess.downstreamCutLen = (macroLinkProcess.lengthMetersOffset / totalLen) * shortcutLength
 		}
 	} else {
 		cutIdx := 0
 		cutPlaceFound := false
 		for i := macroLinkProcess.lanesInfo.LanesList[len(macroLinkProcess.lanesInfo.LanesList)-1]; i >= 0; i-- {
-			if macroLinkProcess.lengthMetersOffset > math.Min(upstreamMaxCut, CUT_LENGTHS[i])+math.Min(downstreamMaxCut, CUT_LENGTHS[i])+MIN_CUT_LENGTH {
+			if macroLinkProcess.lengthMetersOffset > math.Min(upstreamMaxCut, opts.cutLength(i))+math.Min(downstreamMaxCut, opts.cutLength(i))+minCutLength {
 				cutIdx = i
 				cutPlaceFound = true
 				break
 			}
 		}
 		if cutPlaceFound {
-			macroLinkProcess.upstreamCutLen = math.Min(upstreamMaxCut, CUT_LENGTHS[cutIdx])
-			macroLinkProcess.downstreamCutLen = math.Min(downstreamMaxCut, CUT_LENGTHS[cutIdx])
+			macroLinkProcess.upstreamCutLen = math.Min(upstreamMaxCut, opts.cutLength(cutIdx))
+			macroLinkProcess.downstreamCutLen = math.Min(downstreamMaxCut, opts.cutLength(cutIdx))
 		} else {
-			upStreamCut := math.Min(upstreamMaxCut, CUT_LENGTHS[0])
-			downStreamCut := math.Min(downstreamMaxCut, CUT_LENGTHS[0])
-			totalLen := downStreamCut + upStreamCut + MIN_CUT_LENGTH
+			upStreamCut := math.Min(upstreamMaxCut, opts.cutLength(0))
+			downStreamCut := math.Min(downstreamMaxCut, opts.cutLength(0))
+			totalLen := downStreamCut + upStreamCut + minCutLength
 			macroLinkProcess.upstreamCutLen = (macroLinkProcess.lengthMetersOffset / totalLen) * upStreamCut
 			macroLinkProcess.downstreamCutLen = (macroLinkProcess.lengthMetersOffset / totalLen) * downStreamCut
 		}
@@ -557,9 +752,32 @@ func (macroLinkProcess *macroLinkProcessing) performCut() {
 	}
 }
 
-func generateBaseNodesLinks(macroNodes map[gmns.NodeID]*macro.Node, macroLinksProcessed map[gmns.LinkID]*macroLinkProcessing) (map[gmns.NodeID]*meso.Node, map[gmns.LinkID]*meso.Link, error) {
-	lastMesoLinkID := gmns.LinkID(0)
+// mesoIDsAllocator provides identifiers for new mesoscopic nodes and links
+type mesoIDsAllocator struct {
+	nodeID func(macroNodeID gmns.NodeID) gmns.NodeID
+	linkID func() gmns.LinkID
+}
+
+// newSequentialMesoIDs returns allocator for generation from scratch: identifier of the node is macroscopic node identifier multiplied by 100
+// plus number of mesoscopic nodes already generated for the macroscopic node, links are numbered sequentially from zero
+func newSequentialMesoIDs() *mesoIDsAllocator {
 	expandedMesoNodes := make(map[gmns.NodeID]int)
+	lastMesoLinkID := gmns.LinkID(0)
+	return &mesoIDsAllocator{
+		nodeID: func(macroNodeID gmns.NodeID) gmns.NodeID {
+			expNodesNum := expandedMesoNodes[macroNodeID]
+			expandedMesoNodes[macroNodeID] += 1
+			return macroNodeID*100 + gmns.NodeID(expNodesNum)
+		},
+		linkID: func() gmns.LinkID {
+			id := lastMesoLinkID
+			lastMesoLinkID += 1
+			return id
+		},
+	}
+}
+
+func generateBaseNodesLinks(macroNodes map[gmns.NodeID]*macro.Node, macroLinksProcessed map[gmns.LinkID]*macroLinkProcessing, ids *mesoIDsAllocator) (map[gmns.NodeID]*meso.Node, map[gmns.LinkID]*meso.Link, error) {
 	collectedMesoNodes := make(map[gmns.NodeID]*meso.Node)
 	collectedMesoLinks := make(map[gmns.LinkID]*meso.Link)
 	// Sort keys for deterministic iteration
@@ -577,13 +795,8 @@ func generateBaseNodesLinks(macroNodes map[gmns.NodeID]*macro.Node, macroLinksPr
 			// @todo: handle centroids
 			return nil, nil, errors.Wrap(ErrNotImplementedYet, "Prepare upstream mesoscopic node from centroid")
 		} else {
-			expNodesNum, ok := expandedMesoNodes[macroLinkProcess.sourceMacroNodeID]
-			if !ok {
-				expandedMesoNodes[macroLinkProcess.sourceMacroNodeID] = 0
-			}
-			expandedMesoNodes[macroLinkProcess.sourceMacroNodeID] += 1
 			upstreamMesoNode = meso.NewNodeFrom(
-				macroLinkProcess.sourceMacroNodeID*100+gmns.NodeID(expNodesNum),
+				ids.nodeID(macroLinkProcess.sourceMacroNodeID),
 				meso.WithPointGeom(macroLinkProcess.offsetGeomCut[0][0]), // No explicit copy or clone method since Point is not slice, but array
 				meso.WithPointEuclideanGeom(macroLinkProcess.offsetGeomEuclideanCut[0][0]),
 				meso.WithPointMacroNodeID(macroLinkProcess.sourceMacroNodeID),
@@ -608,11 +821,6 @@ func generateBaseNodesLinks(macroNodes map[gmns.NodeID]*macro.Node, macroLinksPr
 			if targetMacroNode.IsCentroid() && segmentIdx == segmentsToCut-1 {
 				return nil, nil, errors.Wrap(ErrNotImplementedYet, "Prepare downstream mesoscopic node from centroid")
 			} else {
-				expNodesNum, ok := expandedMesoNodes[macroLinkProcess.targetMacroNodeID]
-				if !ok {
-					expandedMesoNodes[macroLinkProcess.targetMacroNodeID] = 0
-				}
-				expandedMesoNodes[macroLinkProcess.targetMacroNodeID] += 1
 				macroNodeID := gmns.NodeID(-1)
 				macroLinkID := macroLinkProcess.id
 				zoneID := gmns.NodeID(-1)
@@ -624,7 +832,7 @@ func generateBaseNodesLinks(macroNodes map[gmns.NodeID]*macro.Node, macroLinksPr
 					activityLinkType = targetMacroNode.ActivityLinkType()
 				}
 				downstreamMesoNode = meso.NewNodeFrom(
-					macroLinkProcess.targetMacroNodeID*100+gmns.NodeID(expNodesNum),
+					ids.nodeID(macroLinkProcess.targetMacroNodeID),
 					meso.WithPointGeom(macroLinkProcess.offsetGeomCut[segmentIdx][len(macroLinkProcess.offsetGeomCut[segmentIdx])-1]), // No explicit copy or clone method since Point is not slice, but array
 					meso.WithPointEuclideanGeom(macroLinkProcess.offsetGeomEuclideanCut[segmentIdx][len(macroLinkProcess.offsetGeomEuclideanCut[segmentIdx])-1]),
 					meso.WithPointMacroNodeID(macroNodeID),
@@ -637,7 +845,7 @@ func generateBaseNodesLinks(macroNodes map[gmns.NodeID]*macro.Node, macroLinksPr
 			}
 
 			mesoLink := meso.NewLinkFrom(
-				lastMesoLinkID,
+				ids.linkID(),
 				upstreamMesoNodeID,
 				downstreamMesoNode.ID,
 				meso.WithLanesNum(macroLinkProcess.lanesInfoCut.LanesList[segmentIdx]),
@@ -650,12 +858,11 @@ func generateBaseNodesLinks(macroNodes map[gmns.NodeID]*macro.Node, macroLinksPr
 				meso.WithLineMacroNodeID(-1),
 				meso.WithLengthMeters(geo.LengthHaversine(macroLinkProcess.offsetGeomCut[segmentIdx])),
 			)
-			meso.WithOutcomingLinks(lastMesoLinkID)(collectedMesoNodes[upstreamMesoNodeID])
-			meso.WithIncomingLinks(lastMesoLinkID)(collectedMesoNodes[downstreamMesoNode.ID])
+			meso.WithOutcomingLinks(mesoLink.ID)(collectedMesoNodes[upstreamMesoNodeID])
+			meso.WithIncomingLinks(mesoLink.ID)(collectedMesoNodes[downstreamMesoNode.ID])
 
 			// Prepare mesoscopic link
 			collectedMesoLinks[mesoLink.ID] = mesoLink
-			lastMesoLinkID += 1
 			upstreamMesoNodeID = downstreamMesoNode.ID // This must be done since current upstream node is downstream node for next segment
 		}
 	}
@@ -665,22 +872,15 @@ func generateBaseNodesLinks(macroNodes map[gmns.NodeID]*macro.Node, macroLinksPr
 func connectMesoscopicLinks(
 	mesoLinks map[gmns.LinkID]*meso.Link,
 	mesoNodes map[gmns.NodeID]*meso.Node,
-	macroNodes map[gmns.NodeID]*macro.Node,
+	sortedMacroNodes []gmns.NodeID,
 	macroLinks map[gmns.LinkID]*macro.Link,
 	macroLinksProcessed map[gmns.LinkID]*macroLinkProcessing,
 	macroNodesMovements map[gmns.NodeID][]*movement.Movement,
 	macroNodesNeedMovement map[gmns.NodeID]bool,
+	ids *mesoIDsAllocator,
+	workers int,
+	diag *diagnostics,
 ) error {
-	lastMesoLinkID := gmns.LinkID(0)
-
-	// Find max ID (for further links creating)
-	for _, mesoLink := range mesoLinks {
-		if mesoLink.ID > lastMesoLinkID {
-			lastMesoLinkID = mesoLink.ID
-		}
-	}
-	lastMesoLinkID++
-
 	// Collect mesoscopic links for parent macroscopic links
 	macroLinkMesoLinks := make(map[gmns.LinkID][]*meso.Link)
 	for i := range mesoLinks {
@@ -699,106 +899,157 @@ func connectMesoscopicLinks(
 		})
 	}
 
+	// Connection links for the nodes which need movements are prepared in parallel.
+	// Identifiers are assigned afterwards in order of macroscopic nodes, so they do not depend on number of workers
+	connectionLinks := make([][]*meso.Link, len(sortedMacroNodes))
+	errs := make([]error, len(sortedMacroNodes))
+	parallelFor(len(sortedMacroNodes), workers, func(idx int) {
+		macroNodeID := sortedMacroNodes[idx]
+		if !macroNodesNeedMovement[macroNodeID] {
+			return
+		}
+		connectionLinks[idx], errs[idx] = prepareConnectionLinks(macroNodeID, macroNodesMovements[macroNodeID], macroLinks, macroLinksProcessed, macroLinkMesoLinks)
+	})
+
 	// Start main loop for finding connections between mesoscopic links
-	sortedMacroNodes := sortedMacroNodeIDs(macroNodes)
-	for _, macroNodeID := range sortedMacroNodes {
-		// macroNode := macroNodes[macroNodeID]
+	for idx, macroNodeID := range sortedMacroNodes {
+		if errs[idx] != nil {
+			return errs[idx]
+		}
+		if macroNodesNeedMovement[macroNodeID] {
+			for _, mesoLink := range connectionLinks[idx] {
+				mesoLink.ID = ids.linkID()
+				meso.WithOutcomingLinks(mesoLink.ID)(mesoNodes[mesoLink.SourceNode()])
+				meso.WithIncomingLinks(mesoLink.ID)(mesoNodes[mesoLink.TargetNode()])
+				// Prepare mesoscopic link
+				mesoLinks[mesoLink.ID] = mesoLink
+			}
+			continue
+		}
 		macroNodeMvmts, ok := macroNodesMovements[macroNodeID]
 		if !ok {
 			continue
 		}
 		for j := range macroNodeMvmts {
 			mvmt := macroNodeMvmts[j]
-			incomeMacroLinkID := mvmt.IncomeMacroLink()
-			outcomeMacroLinkID := mvmt.OutcomeMacroLink()
-			incomingMacroLink, ok := macroLinks[incomeMacroLinkID]
-			if !ok {
-				return errors.Wrapf(macro.ErrLinkNotFound, "Can't find macro link for further connection: %d", incomeMacroLinkID)
-			}
-			outcomingMacroLink, ok := macroLinks[outcomeMacroLinkID]
-			if !ok {
-				return errors.Wrapf(macro.ErrLinkNotFound, "Can't find macro link for further connection: %d", outcomeMacroLinkID)
-			}
-
-			incomingMacroLinkProcessed, ok := macroLinksProcessed[incomeMacroLinkID]
-			if !ok {
-				return errors.Wrapf(macro.ErrLinkNotFound, "Can't find processed macro link for further connection: %d", incomeMacroLinkID)
-			}
-			outcomingMacroLinkProcessed, ok := macroLinksProcessed[outcomeMacroLinkID]
-			if !ok {
-				return errors.Wrapf(macro.ErrLinkNotFound, "Can't find processed macro link for further connection: %d", outcomeMacroLinkID)
-			}
-
-			incomingMesolinks := macroLinkMesoLinks[incomingMacroLink.ID]
-			if len(incomingMesolinks) == 0 {
-				panic("No mesoscopic links for incoming macro link")
+			incomingMesoLink, outcomingMesoLink, incomingMacroLinkProcessed, outcomingMacroLinkProcessed, err := movementMesoLinks(mvmt, macroLinks, macroLinksProcessed, macroLinkMesoLinks)
+			if err != nil {
+				return err
 			}
-			outcomingMesolinks := macroLinkMesoLinks[outcomingMacroLink.ID]
-			if len(outcomingMesolinks) == 0 {
-				panic("No mesoscopic links for outcoming macro link")
-			}
-
-			incomingMesoLink := incomingMesolinks[len(incomingMesolinks)-1]
 			incomingMesoLinkGeom := incomingMesoLink.Geom()
 			incomingMesoLinkGeomEuclidean := incomingMesoLink.GeomEuclidean()
-			outcomingMesoLink := outcomingMesolinks[0]
 			outcomingMesoLinkGeom := outcomingMesoLink.Geom()
 			outcomingMesoLinkGeomEuclidean := outcomingMesoLink.GeomEuclidean()
+			if incomingMacroLinkProcessed.downstreamIsTarget && !outcomingMacroLinkProcessed.upstreamIsTarget {
+				// remove incoming micro nodes and links of outcomingMesoLink, then connect to incomingMesoLink
+				incomingMesoLinkTargetNodeID := incomingMesoLink.TargetNode()
+				outcomingMesoLinkSourceNodeID := outcomingMesoLink.SourceNode()
 
-			geom := orb.LineString{incomingMesoLinkGeom[len(incomingMesoLinkGeom)-1], outcomingMesoLinkGeom[0]}
-			geomEuclidean := orb.LineString{incomingMesoLinkGeomEuclidean[len(incomingMesoLinkGeomEuclidean)-1], outcomingMesoLinkGeomEuclidean[0]}
-			if macroNodesNeedMovement[macroNodeID] {
-				sourceMesoNodeID := incomingMesoLink.TargetNode()
-				targetMesoNodeID := outcomingMesoLink.SourceNode()
-				mesoLink := meso.NewLinkFrom(
-					lastMesoLinkID,
-					sourceMesoNodeID,
-					targetMesoNodeID,
-					meso.WithLanesNum(mvmt.LanesNum()),
-					meso.WithLineGeom(geom),
-					meso.WithLineGeomEuclidean(geomEuclidean),
-					meso.WithLineMacroLinkID(-1),
-					meso.WithIsConnection(true),
-					meso.WithMovementID(mvmt.ID),
-					meso.WithLineMacroNodeID(macroNodeID),
-					meso.WithLengthMeters(geo.LengthHaversine(geom)),
-					meso.WithMovementCompositeType(mvmt.MvmtTextID()),
-					meso.WithMovementMesoLinkIncome(incomingMesoLink.ID),
-					meso.WithMovementMesoLinkOutcome(outcomingMesoLink.ID),
-					meso.WithMovementIncomeLaneStartSeqID(mvmt.StartIncomeLaneSeqID()),
-					meso.WithMovementOutcomeLaneStartSeqID(mvmt.StartOutcomeLaneSeqID()),
-				)
-				meso.WithOutcomingLinks(lastMesoLinkID)(mesoNodes[sourceMesoNodeID])
-				meso.WithIncomingLinks(lastMesoLinkID)(mesoNodes[targetMesoNodeID])
-				// Prepare mesoscopic link
-				mesoLinks[mesoLink.ID] = mesoLink
-				lastMesoLinkID += 1
-			} else {
-				if incomingMacroLinkProcessed.downstreamIsTarget && !outcomingMacroLinkProcessed.upstreamIsTarget {
-					// remove incoming micro nodes and links of outcomingMesoLink, then connect to incomingMesoLink
-					incomingMesoLinkTargetNodeID := incomingMesoLink.TargetNode()
-					outcomingMesoLinkSourceNodeID := outcomingMesoLink.SourceNode()
-
-					meso.WithSourceNodeID(incomingMesoLinkTargetNodeID)(outcomingMesoLink)
-					meso.WithLineGeom(append(orb.LineString{incomingMesoLinkGeom[len(incomingMesoLinkGeom)-1]}, outcomingMesoLinkGeom[1:]...))(outcomingMesoLink)
-					meso.WithLineGeomEuclidean(append(orb.LineString{incomingMesoLinkGeomEuclidean[len(incomingMesoLinkGeomEuclidean)-1]}, outcomingMesoLinkGeomEuclidean[1:]...))(outcomingMesoLink)
-					delete(mesoNodes, outcomingMesoLinkSourceNodeID)
-				} else if !incomingMacroLinkProcessed.downstreamIsTarget && outcomingMacroLinkProcessed.upstreamIsTarget {
-					// remove outgoing micro nodes and links of incomingMesoLink, then connect to outcomingMesoLink
-					incomingMesoLinkTargetNodeID := incomingMesoLink.TargetNode()
-					outcomingMesoLinkSourceNodeID := outcomingMesoLink.SourceNode()
-
-					meso.WithTargetNodeID(outcomingMesoLinkSourceNodeID)(incomingMesoLink)
-					meso.WithLineGeom(append(incomingMesoLinkGeom[:len(incomingMesoLinkGeom)-1], outcomingMesoLinkGeom[0]))(incomingMesoLink)
-					meso.WithLineGeomEuclidean(append(incomingMesoLinkGeomEuclidean[:len(incomingMesoLinkGeomEuclidean)-1], outcomingMesoLinkGeomEuclidean[0]))(incomingMesoLink)
-					delete(mesoNodes, incomingMesoLinkTargetNodeID)
-				}
+				meso.WithSourceNodeID(incomingMesoLinkTargetNodeID)(outcomingMesoLink)
+				meso.WithLineGeom(append(orb.LineString{incomingMesoLinkGeom[len(incomingMesoLinkGeom)-1]}, outcomingMesoLinkGeom[1:]...))(outcomingMesoLink)
+				meso.WithLineGeomEuclidean(append(orb.LineString{incomingMesoLinkGeomEuclidean[len(incomingMesoLinkGeomEuclidean)-1]}, outcomingMesoLinkGeomEuclidean[1:]...))(outcomingMesoLink)
+				delete(mesoNodes, outcomingMesoLinkSourceNodeID)
+				diag.shortcutCreated()
+			} else if !incomingMacroLinkProcessed.downstreamIsTarget && outcomingMacroLinkProcessed.upstreamIsTarget {
+				// remove outgoing micro nodes and links of incomingMesoLink, then connect to outcomingMesoLink
+				incomingMesoLinkTargetNodeID := incomingMesoLink.TargetNode()
+				outcomingMesoLinkSourceNodeID := outcomingMesoLink.SourceNode()
+
+				meso.WithTargetNodeID(outcomingMesoLinkSourceNodeID)(incomingMesoLink)
+				meso.WithLineGeom(append(incomingMesoLinkGeom[:len(incomingMesoLinkGeom)-1], outcomingMesoLinkGeom[0]))(incomingMesoLink)
+				meso.WithLineGeomEuclidean(append(incomingMesoLinkGeomEuclidean[:len(incomingMesoLinkGeomEuclidean)-1], outcomingMesoLinkGeomEuclidean[0]))(incomingMesoLink)
+				delete(mesoNodes, incomingMesoLinkTargetNodeID)
+				diag.shortcutCreated()
 			}
 		}
 	}
 	return nil
 }
 
+// prepareConnectionLinks returns connection links for every movement of the macroscopic node which needs movements.
+// Identifiers of returned links are not assigned yet. It only reads shared data, so it is safe to call it for different nodes concurrently
+func prepareConnectionLinks(
+	macroNodeID gmns.NodeID,
+	macroNodeMvmts []*movement.Movement,
+	macroLinks map[gmns.LinkID]*macro.Link,
+	macroLinksProcessed map[gmns.LinkID]*macroLinkProcessing,
+	macroLinkMesoLinks map[gmns.LinkID][]*meso.Link,
+) ([]*meso.Link, error) {
+	connections := make([]*meso.Link, 0, len(macroNodeMvmts))
+	for j := range macroNodeMvmts {
+		mvmt := macroNodeMvmts[j]
+		incomingMesoLink, outcomingMesoLink, _, _, err := movementMesoLinks(mvmt, macroLinks, macroLinksProcessed, macroLinkMesoLinks)
+		if err != nil {
+			return nil, err
+		}
+		incomingMesoLinkGeom := incomingMesoLink.Geom()
+		incomingMesoLinkGeomEuclidean := incomingMesoLink.GeomEuclidean()
+		outcomingMesoLinkGeom := outcomingMesoLink.Geom()
+		outcomingMesoLinkGeomEuclidean := outcomingMesoLink.GeomEuclidean()
+
+		geom := orb.LineString{incomingMesoLinkGeom[len(incomingMesoLinkGeom)-1], outcomingMesoLinkGeom[0]}
+		geomEuclidean := orb.LineString{incomingMesoLinkGeomEuclidean[len(incomingMesoLinkGeomEuclidean)-1], outcomingMesoLinkGeomEuclidean[0]}
+		mesoLink := meso.NewLinkFrom(
+			-1,
+			incomingMesoLink.TargetNode(),
+			outcomingMesoLink.SourceNode(),
+			meso.WithLanesNum(mvmt.LanesNum()),
+			meso.WithLineGeom(geom),
+			meso.WithLineGeomEuclidean(geomEuclidean),
+			meso.WithLineMacroLinkID(-1),
+			meso.WithIsConnection(true),
+			meso.WithMovementID(mvmt.ID),
+			meso.WithLineMacroNodeID(macroNodeID),
+			meso.WithLengthMeters(geo.LengthHaversine(geom)),
+			meso.WithMovementCompositeType(mvmt.MvmtTextID()),
+			meso.WithMovementMesoLinkIncome(incomingMesoLink.ID),
+			meso.WithMovementMesoLinkOutcome(outcomingMesoLink.ID),
+			meso.WithMovementIncomeLaneStartSeqID(mvmt.StartIncomeLaneSeqID()),
+			meso.WithMovementOutcomeLaneStartSeqID(mvmt.StartOutcomeLaneSeqID()),
+		)
+		connections = append(connections, mesoLink)
+	}
+	return connections, nil
+}
+
+// movementMesoLinks returns the last mesoscopic link of the incoming macroscopic link and the first mesoscopic link of the outcoming one for the given movement
+func movementMesoLinks(
+	mvmt *movement.Movement,
+	macroLinks map[gmns.LinkID]*macro.Link,
+	macroLinksProcessed map[gmns.LinkID]*macroLinkProcessing,
+	macroLinkMesoLinks map[gmns.LinkID][]*meso.Link,
+) (*meso.Link, *meso.Link, *macroLinkProcessing, *macroLinkProcessing, error) {
+	incomeMacroLinkID := mvmt.IncomeMacroLink()
+	outcomeMacroLinkID := mvmt.OutcomeMacroLink()
+	incomingMacroLink, ok := macroLinks[incomeMacroLinkID]
+	if !ok {
+		return nil, nil, nil, nil, errors.Wrapf(macro.ErrLinkNotFound, "Can't find macro link for further connection: %d", incomeMacroLinkID)
+	}
+	outcomingMacroLink, ok := macroLinks[outcomeMacroLinkID]
+	if !ok {
+		return nil, nil, nil, nil, errors.Wrapf(macro.ErrLinkNotFound, "Can't find macro link for further connection: %d", outcomeMacroLinkID)
+	}
+
+	incomingMacroLinkProcessed, ok := macroLinksProcessed[incomeMacroLinkID]
+	if !ok {
+		return nil, nil, nil, nil, errors.Wrapf(macro.ErrLinkNotFound, "Can't find processed macro link for further connection: %d", incomeMacroLinkID)
+	}
+	outcomingMacroLinkProcessed, ok := macroLinksProcessed[outcomeMacroLinkID]
+	if !ok {
+		return nil, nil, nil, nil, errors.Wrapf(macro.ErrLinkNotFound, "Can't find processed macro link for further connection: %d", outcomeMacroLinkID)
+	}
+
+	incomingMesolinks := macroLinkMesoLinks[incomingMacroLink.ID]
+	if len(incomingMesolinks) == 0 {
+		panic("No mesoscopic links for incoming macro link")
+	}
+	outcomingMesolinks := macroLinkMesoLinks[outcomingMacroLink.ID]
+	if len(outcomingMesolinks) == 0 {
+		panic("No mesoscopic links for outcoming macro link")
+	}
+	return incomingMesolinks[len(incomingMesolinks)-1], outcomingMesolinks[0], incomingMacroLinkProcessed, outcomingMacroLinkProcessed, nil
+}
+
 func updateBoundaryType(mesoNodes map[gmns.NodeID]*meso.Node, macroNodes map[gmns.NodeID]*macro.Node) error {
 	for i := range mesoNodes {
 		mesoNode := mesoNodes[i]
@@ -831,13 +1082,15 @@ func updateBoundaryType(mesoNodes map[gmns.NodeID]*meso.Node, macroNodes map[gmn
 func updateLinksProperties(
 	mesoNodes map[gmns.NodeID]*meso.Node,
 	mesoLinks map[gmns.LinkID]*meso.Link,
+	mesoLinkIDs []gmns.LinkID,
 	macroNodes map[gmns.NodeID]*macro.Node,
 	macroLinks map[gmns.LinkID]*macro.Link,
 	movements movement.MovementsStorage,
+	diag *diagnostics,
 ) error {
 	movementMesoLinks := make(map[gmns.LinkID]struct{})
-	for i := range mesoLinks {
-		mesoLink := mesoLinks[i]
+	for _, mesoLinkID := range mesoLinkIDs {
+		mesoLink := mesoLinks[mesoLinkID]
 		macroNodeID := mesoLink.MacroNode()
 		macroLinkID := mesoLink.MacroLink()
 
@@ -855,6 +1108,8 @@ func updateLinksProperties(
 			meso.WithFreeSpeed(macroLink.FreeSpeed())(mesoLink)
 			meso.WithCapacity(macroLink.Capacity())(mesoLink)
 			meso.WithAllowedAgentTypes(macroLink.AllowedAgentTypes())(mesoLink)
+			meso.WithLanes(macroLink.SegmentLanes(mesoLink.SegmentIdx()))(mesoLink)
+			meso.WithLaneChanges(macroLink.SegmentLaneChanges(mesoLink.SegmentIdx()))(mesoLink)
 			// Reset contrl type property to default
 			meso.WithControlType(types.CONTROL_TYPE_NOT_SIGNAL)(mesoLink)
 			continue
@@ -881,7 +1136,10 @@ func updateLinksProperties(
 	}
 
 	// Inherit macroscopic link properties for movement links
-	for mesoLinkID := range movementMesoLinks {
+	for _, mesoLinkID := range mesoLinkIDs {
+		if _, ok := movementMesoLinks[mesoLinkID]; !ok {
+			continue
+		}
 		mesoLink, ok := mesoLinks[mesoLinkID]
 		if !ok {
 			return errors.Wrapf(meso.ErrLinkNotFound, "Can't find mesoscopic link %d while processing movement links", mesoLinkID)
@@ -893,7 +1151,7 @@ func updateLinksProperties(
 		}
 		incomingMesoLinks := sourceMesoNode.IncomingLinks()
 		if incomingMesoLinks.Len() == 0 {
-			// @todo: should make warning?
+			diag.skip("gen_meso", ELEMENT_MESO_LINK, int64(mesoLinkID), int64(sourceMesoNodeID), SKIP_NO_UPSTREAM_LINK, "Movement mesoscopic link has no upstream link: properties are not inherited")
 			continue
 		}
 		upstreamMesoLinkIDRef := incomingMesoLinks.Front()
@@ -906,7 +1164,6 @@ func updateLinksProperties(
 		case gmns.LinkID:
 			upstreamMesoLinkID = id
 		default:
-			fmt.Println(id)
 			return errors.Wrapf(ErrBadInterface, "Can't get correct type for upstream for source mesoscopic node %d of mesoscopic link %d", sourceMesoNode.ID, mesoLinkID)
 		}
 		upstreamMesoLink, ok := mesoLinks[upstreamMesoLinkID]
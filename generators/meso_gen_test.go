@@ -0,0 +1,101 @@
+package generators
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/LdDl/go-gmns/meso"
+	"github.com/stretchr/testify/assert"
+)
+
+// dumpMesoNet returns text representation of every node and link of the network sorted by identifiers
+func dumpMesoNet(net *meso.Net) string {
+	lines := make([]string, 0, len(net.Nodes)+len(net.Links))
+	for _, node := range net.Nodes {
+		lines = append(lines, fmt.Sprintf("N %d %v %d %d %v %v %v", node.ID, node.Geom(), node.MacroNode(), node.MacroLink(), node.BoundaryType(), node.IncomingLinks().Keys(), node.OutcomingLinks().Keys()))
+	}
+	for _, link := range net.Links {
+		lines = append(lines, fmt.Sprintf("L %d %d %d %v %d %d %d %d %f %d %d %d %v", link.ID, link.SourceNode(), link.TargetNode(), link.Geom(), link.MacroNode(), link.MacroLink(), link.SegmentIdx(), link.LanesNum(), link.LengthMeters(), link.Movement(), link.MovementMesoLinkIncome(), link.MovementMesoLinkOutcome(), link.IsConnection()))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+func TestGenerateMesoscopicParallel(t *testing.T) {
+	macroNet := gridNet(5)
+	movements, err := GenerateMovements(macroNet)
+	assert.NoError(t, err)
+	generate := func(workers int) *meso.Net {
+		opts := DefaultMesoGenOptions()
+		opts.Verbose = false
+		opts.Workers = workers
+		mesoNet, err := GenerateMesoscopic(macroNet, movements, opts)
+		assert.NoError(t, err)
+		return mesoNet
+	}
+	sequential := generate(1)
+	parallel := generate(4)
+	assert.Equal(t, dumpMesoNet(sequential), dumpMesoNet(parallel), "Parallel generation should give the same network as sequential one")
+}
+
+func TestMesoGenOptionsDefaults(t *testing.T) {
+	movements, err := GenerateMovements(gridNet(4))
+	assert.NoError(t, err)
+	generate := func(opts MesoGenOptions) string {
+		mesoNet, err := GenerateMesoscopic(gridNet(4), movements, opts)
+		assert.NoError(t, err)
+		return dumpMesoNet(mesoNet)
+	}
+	defaults := DefaultMesoGenOptions()
+	defaults.Verbose = false
+	expected := generate(defaults)
+	assert.Equal(t, expected, generate(MesoGenOptions{}), "Zero-value options should give the same network as default ones")
+
+	// Deprecated globals seed default options
+	cutLengths := CUT_LENGTHS
+	defer func() {
+		CUT_LENGTHS = cutLengths
+		VERBOSE = true
+	}()
+	CUT_LENGTHS[3] = 1
+	VERBOSE = false
+	seeded := DefaultMesoGenOptions()
+	assert.Equal(t, 1.0, seeded.CutLengths[3])
+	assert.False(t, seeded.Verbose)
+	assert.NotEqual(t, expected, generate(seeded), "Cut lengths from CUT_LENGTHS should be used")
+	assert.Equal(t, generate(seeded), generate(MesoGenOptions{}), "Empty cut lengths should fall back to CUT_LENGTHS")
+
+	opts := MesoGenOptions{LaneWidth: 7, ShortcutLength: 1, MinCutLength: 5, CutLengths: []float64{1}}
+	assert.NotEqual(t, expected, generate(opts), "Explicit options should be used")
+}
+
+func BenchmarkGenerateMesoscopic(b *testing.B) {
+	// Grids of 50*50 and 158*158 nodes give ~10k and ~100k macroscopic links
+	for _, n := range []int{50, 158} {
+		for _, workers := range []int{1, max(runtime.GOMAXPROCS(0), 2)} {
+			b.Run(fmt.Sprintf("links=%d/workers=%d", 4*n*(n-1), workers), func(b *testing.B) {
+				if testing.Short() && n > 50 {
+					b.Skip("skipping ~100k links benchmark in short mode")
+				}
+				macroNet := gridNet(n)
+				movements, err := GenerateMovements(macroNet)
+				if err != nil {
+					b.Fatal(err)
+				}
+				opts := DefaultMesoGenOptions()
+				opts.Verbose = false
+				opts.Workers = workers
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					_, err := GenerateMesoscopic(macroNet, movements, opts)
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package generators
+
+import (
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/LdDl/go-gmns/meso"
+	"github.com/LdDl/go-gmns/micro"
+	"github.com/LdDl/go-gmns/movement"
+	"github.com/paulmach/orb/geo"
+)
+
+// connectBikeWalkLinks connects separated bike (lane -1) and walk (lane -2) lanes through macro nodes.
+// Bike lanes follow every movement except U-turns. Walk lanes are connected by sidewalk cells around the outer corner of the intersection
+// and by crosswalk cells for the rest movements (control type of the node is kept by the crosswalk cells, so unsignalized crossings are distinguished). Bike/walk lanes are merged at pass-through nodes. Only the given movements (sorted for deterministic output) are processed
+func connectBikeWalkLinks(macroNet *macro.Net, mesoNet *meso.Net, microNet *micro.Net, macroLinkMesoLinks map[gmns.LinkID][]gmns.LinkID, flags *MovementFlags, movements movement.MovementsStorage, mvmtIDs []gmns.MovementID, opts MicroGenOptions) {
+	globalMapping := buildMesoMicroMapping(microNet)
+
+	// Movement meso links (sorted for deterministic iteration)
+	mvmtMesoLinks := make(map[gmns.MovementID]*meso.Link)
+	for _, mesoLinkID := range sortedMesoLinkIDs(mesoNet.Links) {
+		mesoLink := mesoNet.Links[mesoLinkID]
+		if mesoLink.Movement() < 0 {
+			continue
+		}
+		if _, ok := mvmtMesoLinks[mesoLink.Movement()]; !ok {
+			mvmtMesoLinks[mesoLink.Movement()] = mesoLink
+		}
+	}
+
+	for _, mvmtID := range mvmtIDs {
+		mvmt, ok := movements[mvmtID]
+		if !ok {
+			continue
+		}
+		macroNode, ok := macroNet.Nodes[mvmt.MacroNode()]
+		if !ok {
+			continue
+		}
+		incomingMesoLinkIDs := macroLinkMesoLinks[mvmt.IncomeMacroLink()]
+		outcomingMesoLinkIDs := macroLinkMesoLinks[mvmt.OutcomeMacroLink()]
+		if len(incomingMesoLinkIDs) == 0 || len(outcomingMesoLinkIDs) == 0 {
+			continue
+		}
+		incomingMicroLanes := globalMapping[incomingMesoLinkIDs[len(incomingMesoLinkIDs)-1]]
+		outcomingMicroLanes := globalMapping[outcomingMesoLinkIDs[0]]
+
+		for _, laneID := range []int{-1, -2} {
+			agentType := types.AGENT_BIKE
+			if laneID == -2 {
+				agentType = types.AGENT_WALK
+			}
+			incomeLaneNodes := incomingMicroLanes[laneID]
+			outcomeLaneNodes := outcomingMicroLanes[laneID]
+			if len(incomeLaneNodes) == 0 || len(outcomeLaneNodes) == 0 {
+				continue
+			}
+			if !agentTypeAllowed(mvmt.AllowedAgentTypes(), agentType) {
+				continue
+			}
+			startNode, ok := microNet.Nodes[incomeLaneNodes[len(incomeLaneNodes)-1]]
+			if !ok {
+				continue
+			}
+			endNode, ok := microNet.Nodes[outcomeLaneNodes[0]]
+			if !ok {
+				continue
+			}
+
+			if !flags.NodesNeedMovement[macroNode.ID] {
+				if mvmt.Type() != movement.MOVEMENT_TYPE_U_TURN {
+					mergeMicroNodes(microNet, startNode, endNode)
+				}
+				continue
+			}
+
+			mesoLink, ok := mvmtMesoLinks[mvmt.ID]
+			if !ok {
+				continue
+			}
+			cellType, ok := bikeWalkConnectorCellType(laneID, mvmt.Type(), opts.DrivingSide)
+			if !ok {
+				continue
+			}
+			createConnectorCells(microNet, mesoLink, startNode, endNode, laneID, cellType, []types.AgentType{agentType}, opts.connectorCellLength(mesoLink))
+		}
+	}
+}
+
+// bikeWalkConnectorCellType returns cell type for the connector of bike (-1) or walk (-2) lane for the given movement. Returns false if lanes should not be connected
+func bikeWalkConnectorCellType(laneID int, mvmtType movement.MovementType, drivingSide types.DrivingSide) (types.CellType, bool) {
+	if mvmtType == movement.MOVEMENT_TYPE_U_TURN && laneID == -1 {
+		return types.CELL_UNDEFINED, false
+	}
+	if laneID == -1 {
+		return types.CELL_FORWARD, true
+	}
+	// Walk lanes are placed at the outer side of the road, so turn to the outer side does not cross any road
+	outerTurn := movement.MOVEMENT_TYPE_RIGHT
+	if drivingSide == types.DRIVING_SIDE_LEFT {
+		outerTurn = movement.MOVEMENT_TYPE_LEFT
+	}
+	if mvmtType == outerTurn {
+		return types.CELL_SIDEWALK, true
+	}
+	return types.CELL_CROSSWALK, true
+}
+
+// mergeMicroNodes redirects outcoming links of the target node to the source one and deletes the target node.
+// Geometry of redirected links starts at the source node. Does nothing if the target node has been connected already
+func mergeMicroNodes(microNet *micro.Net, source, target *micro.Node) {
+	if source.ID == target.ID || target.IncomingLinks().Len() > 0 {
+		return
+	}
+	for el := target.OutcomingLinks().Front(); el != nil; el = el.Next() {
+		microLinkID := el.Key.(gmns.LinkID)
+		microLink, ok := microNet.Links[microLinkID]
+		if !ok {
+			continue
+		}
+		geom := microLink.Geom().Clone()
+		geom[0] = source.Geom()
+		geomEuclidean := microLink.GeomEuclidean().Clone()
+		geomEuclidean[0] = source.GeomEuclidean()
+		micro.WithLineGeom(geom)(microLink)
+		micro.WithLineGeomEuclidean(geomEuclidean)(microLink)
+		micro.WithLengthMeters(geo.LengthHaversine(geom))(microLink)
+		microLink.SetSourceNode(source.ID)
+		source.AddOutcomingLink(microLinkID)
+	}
+	delete(microNet.Nodes, target.ID)
+}
+
+// agentTypeAllowed checks whether the agent type is in the list. Empty list allows any agent type
+func agentTypeAllowed(agentTypes []types.AgentType, agentType types.AgentType) bool {
+	if len(agentTypes) == 0 {
+		return true
+	}
+	for _, allowed := range agentTypes {
+		if allowed == agentType {
+			return true
+		}
+	}
+	return false
+}
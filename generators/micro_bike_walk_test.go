@@ -0,0 +1,145 @@
+package generators
+
+import (
+	"testing"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/LdDl/go-gmns/micro"
+	"github.com/LdDl/go-gmns/movement"
+	"github.com/LdDl/go-gmns/utils/geomath"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+// bentChainNet returns one-way road 0-1-2 bent at the pass-through node 1. Every agent type is allowed
+func bentChainNet() *macro.Net {
+	net := macro.NewNet()
+	points := []orb.Point{{37.6, 55.75}, {37.602, 55.75}, {37.603, 55.7515}}
+	for i, pt := range points {
+		net.Nodes[gmns.NodeID(i)] = macro.NewNodeFrom(gmns.NodeID(i), macro.WithPointGeom(pt), macro.WithPointGeomEuclidean(geomath.PointToEuclidean(pt)))
+	}
+	for i := 0; i+1 < len(points); i++ {
+		source, target := gmns.NodeID(i), gmns.NodeID(i+1)
+		geom := orb.LineString{points[i], points[i+1]}
+		link := macro.NewLinkFrom(gmns.LinkID(i), source, target,
+			macro.WithLineGeom(geom),
+			macro.WithLineGeomEuclidean(geomath.LineToEuclidean(geom)),
+			macro.WithLengthMeters(geo.LengthHaversine(geom)),
+			macro.WithLanesNum(1),
+			macro.WithFreeSpeed(40),
+			macro.WithCapacity(1000),
+			macro.WithLinkType(types.LINK_SECONDARY),
+			macro.WithAllowedAgentTypes([]types.AgentType{types.AGENT_AUTO, types.AGENT_BIKE, types.AGENT_WALK}),
+		)
+		macro.WithLanesInfo(macro.NewLanesInfo(link))(link)
+		net.Links[link.ID] = link
+		macro.WithOutcomingLinks(link.ID)(net.Nodes[source])
+		macro.WithIncomingLinks(link.ID)(net.Nodes[target])
+	}
+	return net
+}
+
+// generateBikeWalk generates microscopic network with separated bike/walk lanes
+func generateBikeWalk(t *testing.T, macroNet *macro.Net) *micro.Net {
+	movements, err := GenerateMovements(macroNet)
+	assert.NoError(t, err)
+	mesoOpts := DefaultMesoGenOptions()
+	mesoOpts.Verbose = false
+	mesoNet, err := GenerateMesoscopic(macroNet, movements, mesoOpts)
+	assert.NoError(t, err)
+	microOpts := DefaultMicroGenOptions()
+	microOpts.SeparateBikeWalk = true
+	microNet, err := GenerateMicroscopic(macroNet, mesoNet, movements, microOpts)
+	assert.NoError(t, err)
+	return microNet
+}
+
+// assertLinksGeometry checks that every link exists between existing nodes, its geometry connects those nodes and its length matches the geometry
+func assertLinksGeometry(t *testing.T, microNet *micro.Net) {
+	for _, link := range microNet.Links {
+		source, ok := microNet.Nodes[link.SourceNode()]
+		if !assert.True(t, ok, "Link %d refers to missing source node %d", link.ID, link.SourceNode()) {
+			continue
+		}
+		target, ok := microNet.Nodes[link.TargetNode()]
+		if !assert.True(t, ok, "Link %d refers to missing target node %d", link.ID, link.TargetNode()) {
+			continue
+		}
+		geom := link.Geom()
+		assert.InDelta(t, 0, geo.DistanceHaversine(source.Geom(), geom[0]), 1e-3, "Geometry of link %d should start at its source node", link.ID)
+		assert.InDelta(t, 0, geo.DistanceHaversine(target.Geom(), geom[len(geom)-1]), 1e-3, "Geometry of link %d should end at its target node", link.ID)
+		assert.InDelta(t, geo.LengthHaversine(geom), link.LengthMeters(), 1e-6, "Length of link %d should match its geometry", link.ID)
+	}
+}
+
+func TestGenerateMicroscopicBikeWalkPassThrough(t *testing.T) {
+	microNet := generateBikeWalk(t, bentChainNet())
+	assertLinksGeometry(t, microNet)
+	for _, laneID := range []int{-1, -2} {
+		// Separated lane is the single chain of cells from the first macro node to the last one
+		starts, ends := 0, 0
+		for _, node := range microNet.Nodes {
+			if node.LaneID() != laneID {
+				continue
+			}
+			if node.IncomingLinks().Len() == 0 {
+				starts++
+			}
+			if node.OutcomingLinks().Len() == 0 {
+				ends++
+			}
+		}
+		assert.Equal(t, 1, starts, "Lane %d should be merged at the pass-through node", laneID)
+		assert.Equal(t, 1, ends, "Lane %d should be merged at the pass-through node", laneID)
+	}
+}
+
+func TestGenerateMicroscopicBikeWalkIntersections(t *testing.T) {
+	macroNet := gridNet(3)
+	microNet := generateBikeWalk(t, macroNet)
+	assertLinksGeometry(t, microNet)
+	cellsNum := make(map[int]map[types.CellType]int)
+	for _, link := range microNet.Links {
+		if link.MacroNode() < 0 || link.LaneID() >= 0 {
+			continue
+		}
+		if cellsNum[link.LaneID()] == nil {
+			cellsNum[link.LaneID()] = make(map[types.CellType]int)
+		}
+		cellsNum[link.LaneID()][link.CellType()]++
+		if link.CellType() == types.CELL_CROSSWALK {
+			assert.Equal(t, macroNet.Nodes[link.MacroNode()].ControlType(), link.ControlType(), "Crosswalk cell should keep control type of the node")
+		}
+		assert.Equal(t, []types.AgentType{map[int]types.AgentType{-1: types.AGENT_BIKE, -2: types.AGENT_WALK}[link.LaneID()]}, link.AllowedAgentTypes())
+	}
+	assert.Greater(t, cellsNum[-1][types.CELL_FORWARD], 0, "Bike lanes should be connected through intersections")
+	assert.Len(t, cellsNum[-1], 1, "Bike connectors should be forward cells only")
+	assert.Greater(t, cellsNum[-2][types.CELL_SIDEWALK], 0, "Walk lanes should be connected around the outer corners")
+	assert.Greater(t, cellsNum[-2][types.CELL_CROSSWALK], 0, "Walk lanes should be connected across the roads at unsignalized nodes")
+}
+
+func TestBikeWalkConnectorCellType(t *testing.T) {
+	cases := []struct {
+		laneID      int
+		mvmtType    movement.MovementType
+		drivingSide types.DrivingSide
+		cellType    types.CellType
+		ok          bool
+	}{
+		{-1, movement.MOVEMENT_TYPE_THRU, types.DRIVING_SIDE_RIGHT, types.CELL_FORWARD, true},
+		{-1, movement.MOVEMENT_TYPE_U_TURN, types.DRIVING_SIDE_RIGHT, types.CELL_UNDEFINED, false},
+		{-2, movement.MOVEMENT_TYPE_RIGHT, types.DRIVING_SIDE_RIGHT, types.CELL_SIDEWALK, true},
+		{-2, movement.MOVEMENT_TYPE_LEFT, types.DRIVING_SIDE_RIGHT, types.CELL_CROSSWALK, true},
+		{-2, movement.MOVEMENT_TYPE_LEFT, types.DRIVING_SIDE_LEFT, types.CELL_SIDEWALK, true},
+		{-2, movement.MOVEMENT_TYPE_THRU, types.DRIVING_SIDE_LEFT, types.CELL_CROSSWALK, true},
+		{-2, movement.MOVEMENT_TYPE_U_TURN, types.DRIVING_SIDE_RIGHT, types.CELL_CROSSWALK, true},
+	}
+	for _, c := range cases {
+		cellType, ok := bikeWalkConnectorCellType(c.laneID, c.mvmtType, c.drivingSide)
+		assert.Equal(t, c.ok, ok, "Lane: %d. Movement: %s", c.laneID, c.mvmtType)
+		assert.Equal(t, c.cellType, cellType, "Lane: %d. Movement: %s", c.laneID, c.mvmtType)
+	}
+}
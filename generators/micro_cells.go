@@ -0,0 +1,138 @@
+package generators
+
+import (
+	"math"
+	"strings"
+
+	"github.com/LdDl/go-gmns/meso"
+)
+
+// CellSizingPolicy is just type alias for the way meso links are divided into micro cells
+type CellSizingPolicy uint16
+
+const (
+	// CELL_SIZING_FIXED divides meso link into equal cells of MicroGenOptions.CellLength
+	CELL_SIZING_FIXED = CellSizingPolicy(iota)
+	// CELL_SIZING_SPEED uses cells of length travelled at free flow speed during MicroGenOptions.CellTimeStep (CTM stability condition)
+	CELL_SIZING_SPEED
+	// CELL_SIZING_ADAPTIVE uses cells of MicroGenOptions.IntersectionCellLength within MicroGenOptions.IntersectionZoneLength from macro nodes and cells of MicroGenOptions.CellLength elsewhere
+	CELL_SIZING_ADAPTIVE
+)
+
+var cellSizingPolicyStr = []string{"fixed", "speed", "adaptive"}
+
+func (iotaIdx CellSizingPolicy) String() string {
+	return cellSizingPolicyStr[iotaIdx]
+}
+
+// NewCellSizingPolicyFrom returns cell sizing policy for the given string. Unknown values are treated as CELL_SIZING_FIXED
+func NewCellSizingPolicyFrom(str string) CellSizingPolicy {
+	str = strings.ToLower(strings.TrimSpace(str))
+	for i, value := range cellSizingPolicyStr {
+		if str == value {
+			return CellSizingPolicy(i)
+		}
+	}
+	return CELL_SIZING_FIXED
+}
+
+const (
+	defaultCellTimeStep           = 1.0
+	defaultIntersectionCellLength = 2.5
+	defaultIntersectionZoneLength = 30.0
+)
+
+// cellsDistances returns distances [meters] of cells boundaries along the meso link: from 0 to the length of the link.
+// Flags upstreamNode and downstreamNode tell whether the corresponding end of the meso link touches macro node (used by adaptive policy only)
+func (opts MicroGenOptions) cellsDistances(mesoLink *meso.Link, upstreamNode, downstreamNode bool) []float64 {
+	length := mesoLink.LengthMeters()
+	switch opts.CellSizing {
+	case CELL_SIZING_SPEED:
+		return uniformCellsDistances(0, length, opts.speedCellLength(mesoLink.FreeSpeed()))
+	case CELL_SIZING_ADAPTIVE:
+		zoneLength := opts.IntersectionZoneLength
+		if upstreamNode && downstreamNode {
+			zoneLength = math.Min(zoneLength, length/2)
+		} else {
+			zoneLength = math.Min(zoneLength, length)
+		}
+		upstreamZone, downstreamZone := 0.0, 0.0
+		if upstreamNode {
+			upstreamZone = zoneLength
+		}
+		if downstreamNode {
+			downstreamZone = zoneLength
+		}
+		ans := []float64{0}
+		pieces := [][3]float64{
+			{0, upstreamZone, opts.intersectionCellLength()},
+			{upstreamZone, length - downstreamZone, opts.fixedCellLength()},
+			{length - downstreamZone, length, opts.intersectionCellLength()},
+		}
+		for _, piece := range pieces {
+			if piece[1]-piece[0] < 1e-6 {
+				continue
+			}
+			ans = append(ans, uniformCellsDistances(piece[0], piece[1], piece[2])[1:]...)
+		}
+		if len(ans) == 1 {
+			ans = append(ans, length)
+		}
+		return ans
+	default:
+		return uniformCellsDistances(0, length, opts.fixedCellLength())
+	}
+}
+
+// connectorCellLength returns cell length [meters] for connectors through macro nodes
+func (opts MicroGenOptions) connectorCellLength(mesoLink *meso.Link) float64 {
+	switch opts.CellSizing {
+	case CELL_SIZING_SPEED:
+		return opts.speedCellLength(mesoLink.FreeSpeed())
+	case CELL_SIZING_ADAPTIVE:
+		return opts.intersectionCellLength()
+	default:
+		return opts.fixedCellLength()
+	}
+}
+
+// fixedCellLength returns cell length [meters] outside of intersection zones. Falls back to the default one if MicroGenOptions.CellLength is not positive
+func (opts MicroGenOptions) fixedCellLength() float64 {
+	if opts.CellLength <= 0 {
+		return defaultCellLength
+	}
+	return opts.CellLength
+}
+
+// intersectionCellLength returns cell length [meters] within intersection zones. Falls back to the fixed cell length if MicroGenOptions.IntersectionCellLength is not positive
+func (opts MicroGenOptions) intersectionCellLength() float64 {
+	if opts.IntersectionCellLength <= 0 {
+		return opts.fixedCellLength()
+	}
+	return opts.IntersectionCellLength
+}
+
+// speedCellLength returns distance [meters] travelled at the given free flow speed [km/h] during single time step.
+// Falls back to the fixed cell length if either speed or time step is not set
+func (opts MicroGenOptions) speedCellLength(freeSpeed float64) float64 {
+	if freeSpeed <= 0 || opts.CellTimeStep <= 0 {
+		return opts.fixedCellLength()
+	}
+	return freeSpeed / 3.6 * opts.CellTimeStep
+}
+
+// uniformCellsDistances divides [start; end] interval into equal cells of approximately given length and returns boundaries of those cells.
+// Non-positive cell length gives the single cell
+func uniformCellsDistances(start, end, cellLength float64) []float64 {
+	length := end - start
+	cellsNum := 1
+	if cellLength > 0 {
+		cellsNum = int(math.Max(1.0, math.Round(length/cellLength)))
+	}
+	ans := make([]float64, cellsNum+1)
+	for cellIdx := 0; cellIdx <= cellsNum; cellIdx++ {
+		fraction := float64(cellIdx) / float64(cellsNum)
+		ans[cellIdx] = start + length*fraction
+	}
+	return ans
+}
@@ -0,0 +1,98 @@
+package generators
+
+import (
+	"testing"
+
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/LdDl/go-gmns/meso"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCellsDistances(t *testing.T) {
+	mesoLink := meso.NewLinkFrom(0, 0, 1, meso.WithLengthMeters(100), meso.WithFreeSpeed(36))
+	cellsLengths := func(distances []float64) []float64 {
+		ans := make([]float64, len(distances)-1)
+		for i := range ans {
+			ans[i] = distances[i+1] - distances[i]
+		}
+		return ans
+	}
+
+	opts := DefaultMicroGenOptions()
+	opts.CellLength = 9
+	distances := opts.cellsDistances(mesoLink, true, true)
+	assert.Len(t, distances, 12)
+	assert.Equal(t, 0.0, distances[0])
+	assert.InDelta(t, 100, distances[len(distances)-1], 1e-9)
+	for _, cellLength := range cellsLengths(distances) {
+		assert.InDelta(t, 100.0/11, cellLength, 1e-9, "Fixed policy should give equal cells")
+	}
+
+	opts.CellSizing = CELL_SIZING_SPEED
+	opts.CellTimeStep = 2
+	distances = opts.cellsDistances(mesoLink, true, true)
+	assert.Len(t, distances, 6, "10 m/s during 2 seconds should give 20 meters cells")
+	assert.Equal(t, 9.0, opts.connectorCellLength(meso.NewLinkFrom(1, 1, 2)), "Speed policy should fall back to the fixed length without speed")
+
+	opts.CellSizing = CELL_SIZING_ADAPTIVE
+	opts.CellLength = 10
+	opts.IntersectionCellLength = 2.5
+	opts.IntersectionZoneLength = 20
+	lengths := cellsLengths(opts.cellsDistances(mesoLink, true, false))
+	assert.Len(t, lengths, 8+8)
+	assert.InDelta(t, 2.5, lengths[0], 1e-9, "Short cells should be used near the upstream node")
+	assert.InDelta(t, 10, lengths[len(lengths)-1], 1e-9, "Regular cells should be used far from nodes")
+	lengths = cellsLengths(opts.cellsDistances(mesoLink, true, true))
+	assert.Len(t, lengths, 8+6+8)
+	assert.InDelta(t, 2.5, lengths[len(lengths)-1], 1e-9, "Short cells should be used near the downstream node")
+	assert.Len(t, opts.cellsDistances(mesoLink, false, false), 11)
+
+	// Intersection cell length is not set: fixed length is used everywhere
+	opts = MicroGenOptions{CellLength: 4.5, LaneWidth: 3.5, CellSizing: CELL_SIZING_ADAPTIVE, IntersectionZoneLength: 30}
+	for _, cellLength := range cellsLengths(opts.cellsDistances(mesoLink, true, true)) {
+		assert.Greater(t, cellLength, 4.0)
+		assert.Less(t, cellLength, 5.0)
+	}
+	assert.Equal(t, 4.5, opts.connectorCellLength(mesoLink))
+	opts.CellLength = 0
+	assert.Equal(t, defaultCellLength, opts.connectorCellLength(mesoLink), "Default length should be used if cell length is not set")
+	assert.Equal(t, []float64{10, 20}, uniformCellsDistances(10, 20, -1), "Non-positive cell length should give the single cell")
+}
+
+func TestGenerateMicroscopicCellLength(t *testing.T) {
+	macroNet, mesoNet, movements := prepareGrid(t, 3)
+	for _, opts := range []MicroGenOptions{
+		{CellLength: 7, LaneWidth: 3.5, CellSizing: CELL_SIZING_FIXED},
+		{CellLength: 7, LaneWidth: 3.5, CellSizing: CELL_SIZING_SPEED, CellTimeStep: 0.5},
+		{CellLength: 7, LaneWidth: 3.5, CellSizing: CELL_SIZING_ADAPTIVE, IntersectionCellLength: 3, IntersectionZoneLength: 30},
+		{CellLength: 7, LaneWidth: 3.5, CellSizing: CELL_SIZING_ADAPTIVE, IntersectionZoneLength: 30},
+	} {
+		microNet, err := GenerateMicroscopic(macroNet, mesoNet, movements, opts)
+		assert.NoError(t, err, "Policy: %s", opts.CellSizing)
+		shortest, longest := 1e9, 0.0
+		for _, link := range microNet.Links {
+			if link.CellType() != types.CELL_FORWARD || mesoNet.Links[link.MesoLink()].IsConnection() {
+				continue
+			}
+			assert.InDelta(t, link.LengthMeters(), link.CellLength(), 0.05, "Cell length should match length of the cell. Policy: %s", opts.CellSizing)
+			if link.CellLength() < shortest {
+				shortest = link.CellLength()
+			}
+			if link.CellLength() > longest {
+				longest = link.CellLength()
+			}
+		}
+		switch {
+		case opts.CellSizing == CELL_SIZING_ADAPTIVE && opts.IntersectionCellLength > 0:
+			assert.InDelta(t, 3, shortest, 0.5, "Policy: %s", opts.CellSizing)
+			assert.InDelta(t, 7, longest, 1, "Policy: %s", opts.CellSizing)
+		case opts.CellSizing == CELL_SIZING_SPEED:
+			// Grid links have free speed of 60 km/h
+			assert.InDelta(t, 60/3.6*0.5, shortest, 1, "Policy: %s", opts.CellSizing)
+			assert.InDelta(t, 60/3.6*0.5, longest, 1, "Policy: %s", opts.CellSizing)
+		default:
+			assert.InDelta(t, 7, shortest, 1, "Policy: %s", opts.CellSizing)
+			assert.InDelta(t, 7, longest, 1, "Policy: %s", opts.CellSizing)
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package generators
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"sort"
+	"time"
 
 	"github.com/LdDl/go-gmns/gmns"
 	"github.com/LdDl/go-gmns/gmns/types"
@@ -31,34 +33,95 @@ type MicroGenOptions struct {
 	BikeLaneWidth    float64
 	WalkLaneWidth    float64
 	SeparateBikeWalk bool
-	Verbose          bool
+	// CellSizing defines how meso links are divided into cells
+	CellSizing CellSizingPolicy
+	// CellTimeStep is simulation time step [seconds] for CELL_SIZING_SPEED policy
+	CellTimeStep float64
+	// IntersectionCellLength is cell length [meters] near macro nodes for CELL_SIZING_ADAPTIVE policy
+	IntersectionCellLength float64
+	// IntersectionZoneLength is distance [meters] from macro nodes where shorter cells are used for CELL_SIZING_ADAPTIVE policy
+	IntersectionZoneLength float64
+	// NoLaneChangeZone is distance [meters] before the downstream macro node where lane changes are prohibited. Non-positive value disables the zone
+	NoLaneChangeZone float64
+	// Workers is number of goroutines processing macro links. Value 1 means sequential processing, non-positive value means GOMAXPROCS
+	Workers int
+	// DrivingSide mirrors lanes numbering and bike/walk lanes placement for left-hand traffic
+	DrivingSide types.DrivingSide
+	// Progress receives progress of generation stages. Could be nil
+	Progress ProgressFunc
+	// Logger receives diagnostics. No-op logger is used if it is nil
+	Logger Logger
+	// Report collects statistics and anomalies of generation. Could be nil
+	Report *GenerationReport
+	// Verbose enables progress messages sent to Logger
+	Verbose bool
+	// StableIDs derives identifiers from parent mesoscopic links, lanes and cell indices instead of processing order. It is not used by RegenerateMicroscopic
+	StableIDs bool
 }
 
 // DefaultMicroGenOptions returns default options for micro generation
 func DefaultMicroGenOptions() MicroGenOptions {
 	return MicroGenOptions{
-		CellLength:       defaultCellLength,
-		LaneWidth:        defaultLaneWidth,
-		BikeLaneWidth:    defaultBikeLaneWidth,
-		WalkLaneWidth:    defaultWalkLaneWidth,
-		SeparateBikeWalk: false,
-		Verbose:          false,
+		CellLength:             defaultCellLength,
+		LaneWidth:              defaultLaneWidth,
+		BikeLaneWidth:          defaultBikeLaneWidth,
+		WalkLaneWidth:          defaultWalkLaneWidth,
+		SeparateBikeWalk:       false,
+		CellSizing:             CELL_SIZING_FIXED,
+		CellTimeStep:           defaultCellTimeStep,
+		IntersectionCellLength: defaultIntersectionCellLength,
+		IntersectionZoneLength: defaultIntersectionZoneLength,
+		NoLaneChangeZone:       0,
+		Workers:                1,
+		DrivingSide:            types.DRIVING_SIDE_RIGHT,
+		Verbose:                false,
 	}
 }
 
+// logger returns logger for diagnostics
+func (opts MicroGenOptions) logger() Logger {
+	return loggerOrNop(opts.Logger)
+}
+
+// offsetSign returns multiplier for lanes offsets: lane 1 is placed at the left side of meso link for right-hand traffic and at the right side for left-hand traffic
+func (opts MicroGenOptions) offsetSign() float64 {
+	if opts.DrivingSide == types.DRIVING_SIDE_LEFT {
+		return -1.0
+	}
+	return 1.0
+}
+
+// laneChangeDirections returns directions (relative to the driver) of lane changes to the higher lane number and to the lower one
+func (opts MicroGenOptions) laneChangeDirections() (higher types.LaneChange, lower types.LaneChange) {
+	if opts.DrivingSide == types.DRIVING_SIDE_LEFT {
+		return types.LANE_CHANGE_LEFT, types.LANE_CHANGE_RIGHT
+	}
+	return types.LANE_CHANGE_RIGHT, types.LANE_CHANGE_LEFT
+}
+
 // mesoMicroMapping tracks micro node IDs for each meso link's lanes
 // Structure: mesoLinkID -> laneID -> []nodeID (sorted by cellIndex)
 type mesoMicroMapping map[gmns.LinkID]map[int][]gmns.NodeID
 
 // GenerateMicroscopic generates microscopic network from macro and meso networks
 func GenerateMicroscopic(macroNet *macro.Net, mesoNet *meso.Net, movements movement.MovementsStorage, opts ...MicroGenOptions) (*micro.Net, error) {
+	return GenerateMicroscopicContext(context.Background(), macroNet, mesoNet, movements, opts...)
+}
+
+// GenerateMicroscopicContext is the same as GenerateMicroscopic, but it stops generation with the context's error if the context is done before the next stage
+// (or before the next macro link for sequential processing)
+func GenerateMicroscopicContext(ctx context.Context, macroNet *macro.Net, mesoNet *meso.Net, movements movement.MovementsStorage, opts ...MicroGenOptions) (*micro.Net, error) {
 	options := DefaultMicroGenOptions()
 	if len(opts) > 0 {
 		options = opts[0]
 	}
 
+	logger := options.logger()
+	diag := newDiagnostics(logger, options.Report)
+	tracker := newProgressTracker(ctx, options.Progress, diag)
+	st := time.Now()
 	if options.Verbose {
-		fmt.Print("Generating microscopic network...")
+		logger.Info("Generating microscopic network", "scope", "gen_micro")
 	}
 
 	microNet := micro.NewNet()
@@ -74,30 +137,69 @@ func GenerateMicroscopic(macroNet *macro.Net, mesoNet *meso.Net, movements movem
 	sort.Slice(sortedMacroLinkIDs, func(i, j int) bool {
 		return sortedMacroLinkIDs[i] < sortedMacroLinkIDs[j]
 	})
-	for _, macroLinkID := range sortedMacroLinkIDs {
-		macroLink := macroNet.Links[macroLinkID]
-		mesoLinkIDs := macroLinkMesoLinks[macroLink.ID]
-		err := processMacroLink(macroNet, mesoNet, microNet, macroLink, mesoLinkIDs, options)
+	if err := tracker.begin(STAGE_MICRO_CELLS); err != nil {
+		return nil, err
+	}
+	if options.workersNum() > 1 {
+		err := processMacroLinksParallel(macroNet, mesoNet, microNet, sortedMacroLinkIDs, macroLinkMesoLinks, options, tracker, STAGE_MICRO_CELLS)
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to process macro link %d", macroLink.ID)
+			return nil, err
+		}
+	} else {
+		for i, macroLinkID := range sortedMacroLinkIDs {
+			if err := tracker.check(STAGE_MICRO_CELLS); err != nil {
+				return nil, err
+			}
+			macroLink := macroNet.Links[macroLinkID]
+			mesoLinkIDs := macroLinkMesoLinks[macroLink.ID]
+			err := processMacroLink(macroNet, mesoNet, microNet, macroLink, mesoLinkIDs, options)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to process macro link %d", macroLink.ID)
+			}
+			tracker.reportStep(STAGE_MICRO_CELLS, i+1, len(sortedMacroLinkIDs))
 		}
 	}
+	tracker.end(STAGE_MICRO_CELLS, len(sortedMacroLinkIDs))
 
 	// Connect micro links through movements
-	err := connectMicroLinks(mesoNet, microNet, options)
+	if err := tracker.begin(STAGE_MICRO_CONNECTIONS); err != nil {
+		return nil, err
+	}
+	err := connectMicroLinks(mesoNet, microNet, sortedMesoLinkIDs(mesoNet.Links), options, diag)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to connect micro links")
 	}
+	tracker.end(STAGE_MICRO_CONNECTIONS, len(mesoNet.Links))
 
 	// Compute movement flags and fix gaps
+	if err := tracker.begin(STAGE_MICRO_GAPS); err != nil {
+		return nil, err
+	}
 	movementFlags := ComputeMovementFlags(macroNet, movements)
-	err = fixGaps(macroNet, mesoNet, microNet, macroLinkMesoLinks, movementFlags, movements)
+	diag.passThroughNodes(movementFlags)
+	err = fixGaps(macroNet, mesoNet, microNet, macroLinkMesoLinks, movementFlags, movements, sortedMacroNodeIDs(macroNet.Nodes), diag)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to fix gaps")
 	}
+	tracker.end(STAGE_MICRO_GAPS, len(macroNet.Nodes))
+
+	// Connect bike/walk lanes through intersections
+	if options.SeparateBikeWalk {
+		if err := tracker.begin(STAGE_MICRO_BIKE_WALK); err != nil {
+			return nil, err
+		}
+		connectBikeWalkLinks(macroNet, mesoNet, microNet, macroLinkMesoLinks, movementFlags, movements, sortedMovementIDs(movements), options)
+		tracker.end(STAGE_MICRO_BIKE_WALK, len(movements))
+	}
+
+	if options.StableIDs {
+		if err := applyStableMicroIDs(macroNet, mesoNet, microNet, movements); err != nil {
+			return nil, err
+		}
+	}
 
 	if options.Verbose {
-		fmt.Printf(" Done. Nodes: %d, Links: %d\n", len(microNet.Nodes), len(microNet.Links))
+		logger.Info("Done generating microscopic network", "scope", "gen_micro", "elapsed", time.Since(st).Seconds(), "nodes", len(microNet.Nodes), "links", len(microNet.Links))
 	}
 
 	return microNet, nil
@@ -218,15 +320,18 @@ func processMacroLink(macroNet *macro.Net, mesoNet *meso.Net, microNet *micro.Ne
 
 	// Local mapping to track micro nodes during generation
 	localMapping := make(mesoMicroMapping)
+	// Cells boundaries along each meso link
+	cellsDistances := make(map[gmns.LinkID][]float64, len(mesoLinkIDs))
 
 	// Create micro nodes for each meso link
-	for _, mesoLinkID := range mesoLinkIDs {
+	for i, mesoLinkID := range mesoLinkIDs {
 		mesoLink, ok := mesoNet.Links[mesoLinkID]
 		if !ok {
 			return fmt.Errorf("meso link %d not found", mesoLinkID)
 		}
 
-		err := createMicroNodesForMesoLink(microNet, mesoLink, originalLanesNum, hasBike, hasWalk, opts, localMapping)
+		cellsDistances[mesoLinkID] = opts.cellsDistances(mesoLink, i == 0, i == len(mesoLinkIDs)-1)
+		err := createMicroNodesForMesoLink(microNet, mesoLink, originalLanesNum, hasBike, hasWalk, opts, cellsDistances[mesoLinkID], localMapping)
 		if err != nil {
 			return errors.Wrapf(err, "failed to create micro nodes for meso link %d", mesoLinkID)
 		}
@@ -246,13 +351,20 @@ func processMacroLink(macroNet *macro.Net, mesoNet *meso.Net, microNet *micro.Ne
 		return err
 	}
 
+	// Distance from the end of each meso link to the downstream macro node
+	distancesToNode := make([]float64, len(mesoLinkIDs))
+	for i := len(mesoLinkIDs) - 2; i >= 0; i-- {
+		nextDistances := cellsDistances[mesoLinkIDs[i+1]]
+		distancesToNode[i] = distancesToNode[i+1] + nextDistances[len(nextDistances)-1]
+	}
+
 	// Create micro links (cells)
-	for _, mesoLinkID := range mesoLinkIDs {
+	for i, mesoLinkID := range mesoLinkIDs {
 		mesoLink, ok := mesoNet.Links[mesoLinkID]
 		if !ok {
 			continue
 		}
-		err := createMicroLinksForMesoLink(microNet, mesoLink, mainAgents, hasBike, hasWalk, localMapping)
+		err := createMicroLinksForMesoLink(microNet, mesoLink, mainAgents, hasBike, hasWalk, cellsDistances[mesoLinkID], distancesToNode[i], opts, localMapping)
 		if err != nil {
 			return errors.Wrapf(err, "failed to create micro links for meso link %d", mesoLinkID)
 		}
@@ -261,25 +373,21 @@ func processMacroLink(macroNet *macro.Net, mesoNet *meso.Net, microNet *micro.Ne
 	return nil
 }
 
-// createMicroNodesForMesoLink creates micro nodes for a single meso link
-func createMicroNodesForMesoLink(microNet *micro.Net, mesoLink *meso.Link, originalLanesNum float64, hasBike, hasWalk bool, opts MicroGenOptions, localMapping mesoMicroMapping) error {
-	lanesChange := mesoLink.LanesChange()
-	laneChangesLeft := float64(lanesChange[0])
-	lanesNumOffset := -1 * (originalLanesNum/2 - 0.5 + laneChangesLeft)
-
+// createMicroNodesForMesoLink creates micro nodes for a single meso link at the given cells boundaries
+func createMicroNodesForMesoLink(microNet *micro.Net, mesoLink *meso.Link, originalLanesNum float64, hasBike, hasWalk bool, opts MicroGenOptions, cellsDistances []float64, localMapping mesoMicroMapping) error {
 	// Calculate number of cells
-	cellsNum := int(math.Max(1.0, math.Round(mesoLink.LengthMeters()/opts.CellLength)))
+	cellsNum := len(cellsDistances) - 1
 
 	// Generate lane geometries with offset
 	laneGeometries := make([]orb.LineString, mesoLink.LanesNum())
 	var bikeGeometry, walkGeometry orb.LineString
-	lastOffset := 0.0
+	laneOffsets, lastOffset := mesoLanesOffsets(mesoLink, originalLanesNum, opts.LaneWidth)
+	offsetSign := opts.offsetSign()
 
 	for i := 0; i < mesoLink.LanesNum(); i++ {
-		laneOffset := (lanesNumOffset + float64(i)) * opts.LaneWidth
-		lastOffset = laneOffset
+		laneOffset := laneOffsets[i]
 		if math.Abs(laneOffset) > 1e-2 {
-			laneGeometries[i] = geomath.OffsetCurve(mesoLink.GeomEuclidean(), -laneOffset)
+			laneGeometries[i] = geomath.OffsetCurve(mesoLink.GeomEuclidean(), -offsetSign*laneOffset)
 			laneGeometries[i] = geomath.LineToSpherical(laneGeometries[i])
 		} else {
 			laneGeometries[i] = mesoLink.Geom().Clone()
@@ -290,7 +398,7 @@ func createMicroNodesForMesoLink(microNet *micro.Net, mesoLink *meso.Link, origi
 	if hasBike {
 		bikeOffset := lastOffset + opts.BikeLaneWidth
 		if math.Abs(bikeOffset) > 1e-2 {
-			bikeGeometry = geomath.OffsetCurve(mesoLink.GeomEuclidean(), -bikeOffset)
+			bikeGeometry = geomath.OffsetCurve(mesoLink.GeomEuclidean(), -offsetSign*bikeOffset)
 			bikeGeometry = geomath.LineToSpherical(bikeGeometry)
 		} else {
 			bikeGeometry = mesoLink.Geom().Clone()
@@ -302,7 +410,7 @@ func createMicroNodesForMesoLink(microNet *micro.Net, mesoLink *meso.Link, origi
 			walkOffset += opts.BikeLaneWidth
 		}
 		if math.Abs(walkOffset) > 1e-2 {
-			walkGeometry = geomath.OffsetCurve(mesoLink.GeomEuclidean(), -walkOffset)
+			walkGeometry = geomath.OffsetCurve(mesoLink.GeomEuclidean(), -offsetSign*walkOffset)
 			walkGeometry = geomath.LineToSpherical(walkGeometry)
 		} else {
 			walkGeometry = mesoLink.Geom().Clone()
@@ -320,8 +428,7 @@ func createMicroNodesForMesoLink(microNet *micro.Net, mesoLink *meso.Link, origi
 		laneNodeIDs := make([]gmns.NodeID, 0, cellsNum+1)
 
 		for cellIdx := 0; cellIdx <= cellsNum; cellIdx++ {
-			fraction := float64(cellIdx) / float64(cellsNum)
-			distance := mesoLink.LengthMeters() * fraction
+			distance := cellsDistances[cellIdx]
 			point, _ := geo.PointAtDistanceAlongLine(laneGeometries[laneIdx], distance)
 			pointEuc := geomath.PointToEuclidean(point)
 
@@ -346,8 +453,7 @@ func createMicroNodesForMesoLink(microNet *micro.Net, mesoLink *meso.Link, origi
 		bikeNodeIDs := make([]gmns.NodeID, 0, cellsNum+1)
 
 		for cellIdx := 0; cellIdx <= cellsNum; cellIdx++ {
-			fraction := float64(cellIdx) / float64(cellsNum)
-			distance := mesoLink.LengthMeters() * fraction
+			distance := cellsDistances[cellIdx]
 			point, _ := geo.PointAtDistanceAlongLine(bikeGeometry, distance)
 			pointEuc := geomath.PointToEuclidean(point)
 
@@ -372,8 +478,7 @@ func createMicroNodesForMesoLink(microNet *micro.Net, mesoLink *meso.Link, origi
 		walkNodeIDs := make([]gmns.NodeID, 0, cellsNum+1)
 
 		for cellIdx := 0; cellIdx <= cellsNum; cellIdx++ {
-			fraction := float64(cellIdx) / float64(cellsNum)
-			distance := mesoLink.LengthMeters() * fraction
+			distance := cellsDistances[cellIdx]
 			point, _ := geo.PointAtDistanceAlongLine(walkGeometry, distance)
 			pointEuc := geomath.PointToEuclidean(point)
 
@@ -395,6 +500,56 @@ func createMicroNodesForMesoLink(microNet *micro.Net, mesoLink *meso.Link, origi
 	return nil
 }
 
+// mesoLanesOffsets returns offsets of lanes centers from the meso link geometry (negative values are towards the inner side) and the offset
+// which bike/walk lanes are placed from. Per-lane widths are used if the meso link has lanes attributes, otherwise every lane has the default width
+func mesoLanesOffsets(mesoLink *meso.Link, originalLanesNum float64, defaultWidth float64) ([]float64, float64) {
+	offsets := make([]float64, mesoLink.LanesNum())
+	if len(offsets) == 0 {
+		return offsets, 0.0
+	}
+	lanes := mesoLink.Lanes()
+	if len(lanes) != len(offsets) {
+		laneChangesLeft := float64(mesoLink.LanesChange()[0])
+		lanesNumOffset := -1 * (originalLanesNum/2 - 0.5 + laneChangesLeft)
+		for i := range offsets {
+			offsets[i] = (lanesNumOffset + float64(i)) * defaultWidth
+		}
+		return offsets, offsets[len(offsets)-1]
+	}
+	widths := make(map[int]float64, len(lanes))
+	for _, lane := range lanes {
+		widths[lane.LaneNum] = lane.WidthOrDefault(defaultWidth)
+	}
+	width := func(laneNum int) float64 {
+		if laneWidth, ok := widths[laneNum]; ok {
+			return laneWidth
+		}
+		return defaultWidth
+	}
+	// Lanes without pockets are centered at the meso link geometry
+	baseWidth := 0.0
+	for laneNum := 1; laneNum <= int(originalLanesNum); laneNum++ {
+		baseWidth += width(laneNum)
+	}
+	for i, lane := range lanes {
+		// Inner edge of the lane relative to the inner edge of the lane 1
+		edge := 0.0
+		if lane.LaneNum >= 1 {
+			for laneNum := 1; laneNum < lane.LaneNum; laneNum++ {
+				edge += width(laneNum)
+			}
+		} else {
+			for laneNum := lane.LaneNum; laneNum < 0; laneNum++ {
+				edge -= width(laneNum)
+			}
+		}
+		offsets[i] = -baseWidth/2 + edge + width(lane.LaneNum)/2
+	}
+	// Keep the same distance between the outer edge of the last lane and bike/walk lanes as for the default width
+	lastOffset := offsets[len(offsets)-1] + (width(lanes[len(lanes)-1].LaneNum)-defaultWidth)/2
+	return offsets, lastOffset
+}
+
 // markEndNodes marks upstream and downstream end nodes
 func markEndNodes(macroNet *macro.Net, microNet *micro.Net, macroLink *macro.Link, mesoLinkIDs []gmns.LinkID, hasBike, hasWalk bool, localMapping mesoMicroMapping) error {
 	// First meso link - upstream end
@@ -516,12 +671,26 @@ func mergeAdjacentMesoLinks(mesoNet *meso.Net, microNet *micro.Net, mesoLinkIDs
 	return nil
 }
 
-// createMicroLinksForMesoLink creates micro links (cells) for a meso link
-func createMicroLinksForMesoLink(microNet *micro.Net, mesoLink *meso.Link, mainAgents []types.AgentType, hasBike, hasWalk bool, localMapping mesoMicroMapping) error {
+// createMicroLinksForMesoLink creates micro links (cells) for a meso link.
+// Lane change cells are created only if they are permitted by the meso link lanes and end outside of the no-change zone (distanceToNode is distance from the end of the meso link to the downstream macro node)
+func createMicroLinksForMesoLink(microNet *micro.Net, mesoLink *meso.Link, mainAgents []types.AgentType, hasBike, hasWalk bool, cellsDistances []float64, distanceToNode float64, opts MicroGenOptions, localMapping mesoMicroMapping) error {
 	lanes, ok := localMapping[mesoLink.ID]
 	if !ok {
 		return nil
 	}
+	cellLength := func(cellIdx int) float64 {
+		if cellIdx+1 >= len(cellsDistances) {
+			return -1
+		}
+		return cellsDistances[cellIdx+1] - cellsDistances[cellIdx]
+	}
+	laneChangeOutsideZone := func(cellIdx int) bool {
+		if opts.NoLaneChangeZone <= 0 || cellIdx+1 >= len(cellsDistances) {
+			return true
+		}
+		return cellsDistances[len(cellsDistances)-1]-cellsDistances[cellIdx+1]+distanceToNode >= opts.NoLaneChangeZone
+	}
+	higherDirection, lowerDirection := opts.laneChangeDirections()
 
 	// Get sorted lane IDs (positive lanes only for main traffic)
 	var regularLaneIDs []int
@@ -534,35 +703,46 @@ func createMicroLinksForMesoLink(microNet *micro.Net, mesoLink *meso.Link, mainA
 
 	for _, laneID := range regularLaneIDs {
 		laneNodes := lanes[laneID]
+		laneAgents, laneOptions := mesoLaneProperties(mesoLink, laneID, mainAgents)
 
 		// Forward links
 		for cellIdx := 0; cellIdx < len(laneNodes)-1; cellIdx++ {
 			err := createMicroLink(microNet, mesoLink, laneNodes[cellIdx], laneNodes[cellIdx+1],
-				laneID, types.CELL_FORWARD, mainAgents)
+				laneID, types.CELL_FORWARD, cellLength(cellIdx), laneAgents, laneOptions...)
 			if err != nil {
 				return err
 			}
 		}
 
-		// Lane change left (to higher lane number)
+		// Lane change to higher lane number
 		nextLaneID := laneID + 1
-		if nextLaneNodes, ok := lanes[nextLaneID]; ok {
+		nextLaneAgents, _ := mesoLaneProperties(mesoLink, nextLaneID, mainAgents)
+		changeAgents, agentsShared := commonAgentTypes(laneAgents, nextLaneAgents)
+		if nextLaneNodes, ok := lanes[nextLaneID]; ok && agentsShared && mesoLink.LaneChange(laneID).Has(higherDirection) {
 			for cellIdx := 0; cellIdx < len(laneNodes)-1 && cellIdx < len(nextLaneNodes)-1; cellIdx++ {
+				if !laneChangeOutsideZone(cellIdx) {
+					continue
+				}
 				err := createMicroLink(microNet, mesoLink, laneNodes[cellIdx], nextLaneNodes[cellIdx+1],
-					laneID, types.CELL_LANE_CHANGE, mainAgents)
+					laneID, types.CELL_LANE_CHANGE, cellLength(cellIdx), changeAgents, laneOptions...)
 				if err != nil {
 					return err
 				}
 			}
 		}
 
-		// Lane change right (to lower lane number)
+		// Lane change to lower lane number
 		prevLaneID := laneID - 1
-		if prevLaneID > 0 {
+		prevLaneAgents, _ := mesoLaneProperties(mesoLink, prevLaneID, mainAgents)
+		changeAgents, agentsShared = commonAgentTypes(laneAgents, prevLaneAgents)
+		if prevLaneID > 0 && agentsShared && mesoLink.LaneChange(laneID).Has(lowerDirection) {
 			if prevLaneNodes, ok := lanes[prevLaneID]; ok {
 				for cellIdx := 0; cellIdx < len(laneNodes)-1 && cellIdx < len(prevLaneNodes)-1; cellIdx++ {
+					if !laneChangeOutsideZone(cellIdx) {
+						continue
+					}
 					err := createMicroLink(microNet, mesoLink, laneNodes[cellIdx], prevLaneNodes[cellIdx+1],
-						laneID, types.CELL_LANE_CHANGE, mainAgents)
+						laneID, types.CELL_LANE_CHANGE, cellLength(cellIdx), changeAgents, laneOptions...)
 					if err != nil {
 						return err
 					}
@@ -576,7 +756,7 @@ func createMicroLinksForMesoLink(microNet *micro.Net, mesoLink *meso.Link, mainA
 		if bikeNodes, ok := lanes[-1]; ok {
 			for cellIdx := 0; cellIdx < len(bikeNodes)-1; cellIdx++ {
 				err := createMicroLink(microNet, mesoLink, bikeNodes[cellIdx], bikeNodes[cellIdx+1],
-					-1, types.CELL_FORWARD, []types.AgentType{types.AGENT_BIKE})
+					-1, types.CELL_FORWARD, cellLength(cellIdx), []types.AgentType{types.AGENT_BIKE})
 				if err != nil {
 					return err
 				}
@@ -589,7 +769,7 @@ func createMicroLinksForMesoLink(microNet *micro.Net, mesoLink *meso.Link, mainA
 		if walkNodes, ok := lanes[-2]; ok {
 			for cellIdx := 0; cellIdx < len(walkNodes)-1; cellIdx++ {
 				err := createMicroLink(microNet, mesoLink, walkNodes[cellIdx], walkNodes[cellIdx+1],
-					-2, types.CELL_FORWARD, []types.AgentType{types.AGENT_WALK})
+					-2, types.CELL_SIDEWALK, cellLength(cellIdx), []types.AgentType{types.AGENT_WALK})
 				if err != nil {
 					return err
 				}
@@ -600,9 +780,44 @@ func createMicroLinksForMesoLink(microNet *micro.Net, mesoLink *meso.Link, mainA
 	return nil
 }
 
+// mesoLaneProperties returns allowed agent types and additional micro link options for the given lane (1-indexed) of the meso link.
+// Lanes without attributes inherit the given agent types
+func mesoLaneProperties(mesoLink *meso.Link, laneID int, mainAgents []types.AgentType) ([]types.AgentType, []func(*micro.Link)) {
+	lanes := mesoLink.Lanes()
+	if laneID < 1 || laneID > len(lanes) {
+		return mainAgents, nil
+	}
+	lane := lanes[laneID-1]
+	agents := mainAgents
+	if len(lane.AllowedAgentTypes) > 0 {
+		agents = lane.AllowedAgentTypes
+	}
+	return agents, []func(*micro.Link){
+		micro.WithLaneWidth(lane.Width),
+		micro.WithLaneDesignation(lane.Designation),
+	}
+}
+
+// commonAgentTypes returns agent types allowed on both lanes (empty list allows any agent type). Returns false if lanes have no agent types in common
+func commonAgentTypes(source, target []types.AgentType) ([]types.AgentType, bool) {
+	if len(target) == 0 {
+		return source, true
+	}
+	if len(source) == 0 {
+		return target, true
+	}
+	common := make([]types.AgentType, 0, len(source))
+	for _, agentType := range source {
+		if agentTypeAllowed(target, agentType) {
+			common = append(common, agentType)
+		}
+	}
+	return common, len(common) > 0
+}
+
 // createMicroLink creates a single micro link
 func createMicroLink(microNet *micro.Net, mesoLink *meso.Link, sourceNodeID, targetNodeID gmns.NodeID,
-	laneID int, cellType types.CellType, allowedAgents []types.AgentType) error {
+	laneID int, cellType types.CellType, cellLength float64, allowedAgents []types.AgentType, options ...func(*micro.Link)) error {
 
 	sourceNode, ok := microNet.Nodes[sourceNodeID]
 	if !ok {
@@ -627,6 +842,7 @@ func createMicroLink(microNet *micro.Net, mesoLink *meso.Link, sourceNodeID, tar
 		micro.WithMacroNodeID(mesoLink.MacroNode()),
 		micro.WithCellType(cellType),
 		micro.WithLaneID(laneID),
+		micro.WithCellLength(cellLength),
 		micro.WithMesoLinkType(mesoLink.LinkType()),
 		micro.WithControlType(mesoLink.ControlType()),
 		micro.WithFreeSpeed(mesoLink.FreeSpeed()),
@@ -634,6 +850,9 @@ func createMicroLink(microNet *micro.Net, mesoLink *meso.Link, sourceNodeID, tar
 		micro.WithAllowedAgentTypes(allowedAgents),
 		micro.WithMovementCompositeType(movement.MOVEMENT_UNDEFINED),
 	)
+	for _, option := range options {
+		option(link)
+	}
 
 	microNet.AddLink(link)
 	sourceNode.AddOutcomingLink(linkID)
@@ -642,22 +861,16 @@ func createMicroLink(microNet *micro.Net, mesoLink *meso.Link, sourceNodeID, tar
 	return nil
 }
 
-// connectMicroLinks connects micro links through movements
-func connectMicroLinks(mesoNet *meso.Net, microNet *micro.Net, opts MicroGenOptions) error {
+// connectMicroLinks connects micro links through movements of the given meso links (sorted for deterministic output)
+func connectMicroLinks(mesoNet *meso.Net, microNet *micro.Net, mesoLinkIDs []gmns.LinkID, opts MicroGenOptions, diag *diagnostics) error {
 	// Build global mapping from all micro nodes
 	globalMapping := buildMesoMicroMapping(microNet)
 
-	// Sort meso link IDs for deterministic iteration
-	sortedMesoLinkIDs := make([]gmns.LinkID, 0, len(mesoNet.Links))
-	for linkID := range mesoNet.Links {
-		sortedMesoLinkIDs = append(sortedMesoLinkIDs, linkID)
-	}
-	sort.Slice(sortedMesoLinkIDs, func(i, j int) bool {
-		return sortedMesoLinkIDs[i] < sortedMesoLinkIDs[j]
-	})
-
-	for _, mesoLinkID := range sortedMesoLinkIDs {
-		mesoLink := mesoNet.Links[mesoLinkID]
+	for _, mesoLinkID := range mesoLinkIDs {
+		mesoLink, ok := mesoNet.Links[mesoLinkID]
+		if !ok {
+			continue
+		}
 		// Process only movement meso links
 		if mesoLink.Movement() < 0 {
 			continue
@@ -693,6 +906,7 @@ func connectMicroLinks(mesoNet *meso.Net, microNet *micro.Net, opts MicroGenOpti
 			outcomeLaneNodes, outOk := outcomeLanes[outcomeLaneID]
 
 			if !incOk || !outOk || len(incomeLaneNodes) == 0 || len(outcomeLaneNodes) == 0 {
+				diag.skip("gen_micro", ELEMENT_MESO_LINK, int64(mesoLinkID), int64(mesoLink.MacroNode()), SKIP_NO_LANE_CELLS, "Movement lane has no cells to connect: connector cells are skipped")
 				continue
 			}
 
@@ -708,97 +922,105 @@ func connectMicroLinks(mesoNet *meso.Net, microNet *micro.Net, opts MicroGenOpti
 				continue
 			}
 
-			// Create connector geometry
-			laneGeom := orb.LineString{startNode.Geom(), endNode.Geom()}
-			laneLength := geo.LengthHaversine(laneGeom)
-			cellsNum := int(math.Max(1.0, math.Round(laneLength/opts.CellLength)))
-
-			// Create intermediate nodes and links
-			lastNodeID := startNodeID
-			isFirstMovement := true
-
-			for cellIdx := 1; cellIdx < cellsNum; cellIdx++ {
-				fraction := float64(cellIdx) / float64(cellsNum)
-				distance := laneLength * fraction
-				point, _ := geo.PointAtDistanceAlongLine(laneGeom, distance)
-				pointEuc := geomath.PointToEuclidean(point)
-
-				nodeID := microNet.MaxNodeID()
-				node := micro.NewNodeFrom(nodeID,
-					micro.WithPointGeom(point),
-					micro.WithPointGeomEuclidean(pointEuc),
-					micro.WithNodeMesoLinkID(mesoLink.ID),
-					micro.WithNodeLaneID(laneOffset+1),
-					micro.WithCellIndex(cellIdx),
-				)
-				microNet.AddNode(node)
-
-				// Create link from last node to this node
-				lastNode, _ := microNet.Nodes[lastNodeID]
-				linkGeom := orb.LineString{lastNode.Geom(), node.Geom()}
-				linkGeomEuc := orb.LineString{lastNode.GeomEuclidean(), node.GeomEuclidean()}
-				linkLength := geo.LengthHaversine(linkGeom)
-
-				linkID := microNet.MaxLinkID()
-				link := micro.NewLinkFrom(linkID, lastNodeID, nodeID,
-					micro.WithLineGeom(linkGeom),
-					micro.WithLineGeomEuclidean(linkGeomEuc),
-					micro.WithLengthMeters(linkLength),
-					micro.WithMesoLinkID(mesoLink.ID),
-					micro.WithMacroLinkID(mesoLink.MacroLink()),
-					micro.WithMacroNodeID(mesoLink.MacroNode()),
-					micro.WithCellType(types.CELL_FORWARD),
-					micro.WithLaneID(laneOffset+1),
-					micro.WithMesoLinkType(mesoLink.LinkType()),
-					micro.WithControlType(mesoLink.ControlType()),
-					micro.WithFreeSpeed(mesoLink.FreeSpeed()),
-					micro.WithCapacity(mesoLink.Capacity()),
-					micro.WithAllowedAgentTypes(mesoLink.AllowedAgentTypes()),
-					micro.WithIsFirstMovementCell(isFirstMovement),
-					micro.WithMovementCompositeType(mesoLink.MvmtTextID()),
-				)
-				microNet.AddLink(link)
-				lastNode.AddOutcomingLink(linkID)
-				node.AddIncomingLink(linkID)
-
-				isFirstMovement = false
-				lastNodeID = nodeID
-			}
-
-			// Create final link to end node
-			lastNode, _ := microNet.Nodes[lastNodeID]
-			linkGeom := orb.LineString{lastNode.Geom(), endNode.Geom()}
-			linkGeomEuc := orb.LineString{lastNode.GeomEuclidean(), endNode.GeomEuclidean()}
-			linkLength := geo.LengthHaversine(linkGeom)
-
-			linkID := microNet.MaxLinkID()
-			link := micro.NewLinkFrom(linkID, lastNodeID, endNodeID,
-				micro.WithLineGeom(linkGeom),
-				micro.WithLineGeomEuclidean(linkGeomEuc),
-				micro.WithLengthMeters(linkLength),
-				micro.WithMesoLinkID(mesoLink.ID),
-				micro.WithMacroLinkID(mesoLink.MacroLink()),
-				micro.WithMacroNodeID(mesoLink.MacroNode()),
-				micro.WithCellType(types.CELL_FORWARD),
-				micro.WithLaneID(laneOffset+1),
-				micro.WithMesoLinkType(mesoLink.LinkType()),
-				micro.WithControlType(mesoLink.ControlType()),
-				micro.WithFreeSpeed(mesoLink.FreeSpeed()),
-				micro.WithCapacity(mesoLink.Capacity()),
-				micro.WithAllowedAgentTypes(mesoLink.AllowedAgentTypes()),
-				micro.WithIsFirstMovementCell(isFirstMovement),
-				micro.WithMovementCompositeType(mesoLink.MvmtTextID()),
-			)
-			microNet.AddLink(link)
-			lastNode.AddOutcomingLink(linkID)
-			endNode.AddIncomingLink(linkID)
+			createConnectorCells(microNet, mesoLink, startNode, endNode, laneOffset+1, types.CELL_FORWARD, mesoLink.AllowedAgentTypes(), opts.connectorCellLength(mesoLink))
 		}
 	}
 
 	return nil
 }
 
-// prepareBikeWalkAgents separates bike/walk agents if needed
+// createConnectorCells creates chain of micro links (and intermediate micro nodes) between two micro nodes through the movement meso link
+func createConnectorCells(microNet *micro.Net, mesoLink *meso.Link, startNode, endNode *micro.Node, laneID int, cellType types.CellType, allowedAgents []types.AgentType, cellLength float64) {
+	// Create connector geometry
+	laneGeom := orb.LineString{startNode.Geom(), endNode.Geom()}
+	laneLength := geo.LengthHaversine(laneGeom)
+	cellsNum := int(math.Max(1.0, math.Round(laneLength/cellLength)))
+	actualCellLength := laneLength / float64(cellsNum)
+
+	// Create intermediate nodes and links
+	lastNodeID := startNode.ID
+	isFirstMovement := true
+
+	for cellIdx := 1; cellIdx < cellsNum; cellIdx++ {
+		fraction := float64(cellIdx) / float64(cellsNum)
+		distance := laneLength * fraction
+		point, _ := geo.PointAtDistanceAlongLine(laneGeom, distance)
+		pointEuc := geomath.PointToEuclidean(point)
+
+		nodeID := microNet.MaxNodeID()
+		node := micro.NewNodeFrom(nodeID,
+			micro.WithPointGeom(point),
+			micro.WithPointGeomEuclidean(pointEuc),
+			micro.WithNodeMesoLinkID(mesoLink.ID),
+			micro.WithNodeLaneID(laneID),
+			micro.WithCellIndex(cellIdx),
+		)
+		microNet.AddNode(node)
+
+		// Create link from last node to this node
+		lastNode, _ := microNet.Nodes[lastNodeID]
+		linkGeom := orb.LineString{lastNode.Geom(), node.Geom()}
+		linkGeomEuc := orb.LineString{lastNode.GeomEuclidean(), node.GeomEuclidean()}
+		linkLength := geo.LengthHaversine(linkGeom)
+
+		linkID := microNet.MaxLinkID()
+		link := micro.NewLinkFrom(linkID, lastNodeID, nodeID,
+			micro.WithLineGeom(linkGeom),
+			micro.WithLineGeomEuclidean(linkGeomEuc),
+			micro.WithLengthMeters(linkLength),
+			micro.WithMesoLinkID(mesoLink.ID),
+			micro.WithMacroLinkID(mesoLink.MacroLink()),
+			micro.WithMacroNodeID(mesoLink.MacroNode()),
+			micro.WithCellType(cellType),
+			micro.WithLaneID(laneID),
+			micro.WithCellLength(actualCellLength),
+			micro.WithMesoLinkType(mesoLink.LinkType()),
+			micro.WithControlType(mesoLink.ControlType()),
+			micro.WithFreeSpeed(mesoLink.FreeSpeed()),
+			micro.WithCapacity(mesoLink.Capacity()),
+			micro.WithAllowedAgentTypes(allowedAgents),
+			micro.WithIsFirstMovementCell(isFirstMovement),
+			micro.WithMovementCompositeType(mesoLink.MvmtTextID()),
+		)
+		microNet.AddLink(link)
+		lastNode.AddOutcomingLink(linkID)
+		node.AddIncomingLink(linkID)
+
+		isFirstMovement = false
+		lastNodeID = nodeID
+	}
+
+	// Create final link to end node
+	lastNode, _ := microNet.Nodes[lastNodeID]
+	linkGeom := orb.LineString{lastNode.Geom(), endNode.Geom()}
+	linkGeomEuc := orb.LineString{lastNode.GeomEuclidean(), endNode.GeomEuclidean()}
+	linkLength := geo.LengthHaversine(linkGeom)
+
+	linkID := microNet.MaxLinkID()
+	link := micro.NewLinkFrom(linkID, lastNodeID, endNode.ID,
+		micro.WithLineGeom(linkGeom),
+		micro.WithLineGeomEuclidean(linkGeomEuc),
+		micro.WithLengthMeters(linkLength),
+		micro.WithMesoLinkID(mesoLink.ID),
+		micro.WithMacroLinkID(mesoLink.MacroLink()),
+		micro.WithMacroNodeID(mesoLink.MacroNode()),
+		micro.WithCellType(cellType),
+		micro.WithLaneID(laneID),
+		micro.WithCellLength(actualCellLength),
+		micro.WithMesoLinkType(mesoLink.LinkType()),
+		micro.WithControlType(mesoLink.ControlType()),
+		micro.WithFreeSpeed(mesoLink.FreeSpeed()),
+		micro.WithCapacity(mesoLink.Capacity()),
+		micro.WithAllowedAgentTypes(allowedAgents),
+		micro.WithIsFirstMovementCell(isFirstMovement),
+		micro.WithMovementCompositeType(mesoLink.MvmtTextID()),
+	)
+	microNet.AddLink(link)
+	lastNode.AddOutcomingLink(linkID)
+	endNode.AddIncomingLink(linkID)
+}
+
+// prepareBikeWalkAgents separates bike/walk agents if needed. Motorized agents (auto, bus, truck and etc.) stay on the main lanes
 func prepareBikeWalkAgents(agentTypes []types.AgentType, separate bool) (main []types.AgentType, bike bool, walk bool) {
 	if len(agentTypes) == 0 || !separate {
 		main = make([]types.AgentType, len(agentTypes))
@@ -806,27 +1028,24 @@ func prepareBikeWalkAgents(agentTypes []types.AgentType, separate bool) (main []
 		return main, false, false
 	}
 
-	hasAuto := false
+	motorized := []types.AgentType{}
 	hasBike := false
 	hasWalk := false
 
 	for _, agent := range agentTypes {
 		switch agent {
-		case types.AGENT_AUTO:
-			hasAuto = true
 		case types.AGENT_BIKE:
 			hasBike = true
 		case types.AGENT_WALK:
 			hasWalk = true
+		default:
+			motorized = append(motorized, agent)
 		}
 	}
+	hasMotorized := len(motorized) > 0
 
-	if hasAuto && hasBike && hasWalk {
-		return []types.AgentType{types.AGENT_AUTO}, true, true
-	} else if hasAuto && hasBike {
-		return []types.AgentType{types.AGENT_AUTO}, true, false
-	} else if hasAuto && hasWalk {
-		return []types.AgentType{types.AGENT_AUTO}, false, true
+	if hasMotorized && (hasBike || hasWalk) {
+		return motorized, hasBike, hasWalk
 	} else if hasBike && hasWalk {
 		return []types.AgentType{types.AGENT_BIKE}, false, true
 	}
@@ -838,7 +1057,7 @@ func prepareBikeWalkAgents(agentTypes []types.AgentType, separate bool) (main []
 // When movementIsNeeded == false for a macro node, there are duplicate micro nodes at the
 // boundary between incoming and outcoming meso links. This function removes the duplicates
 // based on the downstreamIsTarget and upstreamIsTarget flags on macro links.
-func fixGaps(macroNet *macro.Net, mesoNet *meso.Net, microNet *micro.Net, macroLinkMesoLinks map[gmns.LinkID][]gmns.LinkID, flags *MovementFlags, movements movement.MovementsStorage) error {
+func fixGaps(macroNet *macro.Net, mesoNet *meso.Net, microNet *micro.Net, macroLinkMesoLinks map[gmns.LinkID][]gmns.LinkID, flags *MovementFlags, movements movement.MovementsStorage, macroNodeIDs []gmns.NodeID, diag *diagnostics) error {
 	// Build global mapping of meso link to micro nodes per lane
 	globalMapping := buildMesoMicroMapping(microNet)
 
@@ -856,17 +1075,12 @@ func fixGaps(macroNet *macro.Net, mesoNet *meso.Net, microNet *micro.Net, macroL
 		movementsByNode[mvmt.MacroNode()] = append(movementsByNode[mvmt.MacroNode()], mvmt)
 	}
 
-	// Process each macro node (sorted for deterministic iteration)
-	sortedMacroNodeIDs := make([]gmns.NodeID, 0, len(macroNet.Nodes))
-	for nodeID := range macroNet.Nodes {
-		sortedMacroNodeIDs = append(sortedMacroNodeIDs, nodeID)
-	}
-	sort.Slice(sortedMacroNodeIDs, func(i, j int) bool {
-		return sortedMacroNodeIDs[i] < sortedMacroNodeIDs[j]
-	})
-
-	for _, macroNodeID := range sortedMacroNodeIDs {
-		macroNode := macroNet.Nodes[macroNodeID]
+	// Process each given macro node (sorted for deterministic iteration)
+	for _, macroNodeID := range macroNodeIDs {
+		macroNode, ok := macroNet.Nodes[macroNodeID]
+		if !ok {
+			continue
+		}
 		// Skip nodes where movements are needed (intersections)
 		if flags.NodesNeedMovement[macroNode.ID] {
 			continue
@@ -904,6 +1118,7 @@ func fixGaps(macroNet *macro.Net, mesoNet *meso.Net, microNet *micro.Net, macroL
 
 			// Skip if lane counts don't match
 			if len(incomeLanes) != len(outcomeLanes) {
+				diag.skip("gen_micro", ELEMENT_MOVEMENT, int64(mvmt.ID), int64(macroNode.ID), SKIP_LANES_MISMATCH, "Movement lanes numbers do not match: gap is not fixed")
 				continue
 			}
 			// Skip if any lane is 0 (invalid)
@@ -968,13 +1183,10 @@ func fixGaps(macroNet *macro.Net, mesoNet *meso.Net, microNet *micro.Net, macroL
 			}
 
 			// Skip if lane indices are invalid
-			if incomeLaneStartIdx < 0 || outcomeLaneStartIdx < 0 {
-				continue
-			}
-			if incomeLaneStartIdx+len(incomeLanes)-1 > incomingMesoLink.LanesNum()-1 {
-				continue
-			}
-			if outcomeLaneStartIdx+len(outcomeLanes)-1 > outcomingMesoLink.LanesNum()-1 {
+			if incomeLaneStartIdx < 0 || outcomeLaneStartIdx < 0 ||
+				incomeLaneStartIdx+len(incomeLanes)-1 > incomingMesoLink.LanesNum()-1 ||
+				outcomeLaneStartIdx+len(outcomeLanes)-1 > outcomingMesoLink.LanesNum()-1 {
+				diag.skip("gen_micro", ELEMENT_MOVEMENT, int64(mvmt.ID), int64(macroNode.ID), SKIP_LANES_OUT_OF_RANGE, "Movement lanes are out of mesoscopic links lanes: gap is not fixed")
 				continue
 			}
 
@@ -1017,6 +1229,7 @@ func fixGaps(macroNet *macro.Net, mesoNet *meso.Net, microNet *micro.Net, macroL
 
 					// Delete the duplicate node
 					delete(microNet.Nodes, outcomeFirstNodeID)
+					diag.gapFixed()
 				}
 			} else if !flags.DownstreamIsTarget[incomingMacroLinkID] && flags.UpstreamIsTarget[outcomingMacroLinkID] {
 				// Delete incoming micro nodes (last nodes of incoming lanes)
@@ -1054,6 +1267,7 @@ func fixGaps(macroNet *macro.Net, mesoNet *meso.Net, microNet *micro.Net, macroL
 
 					// Delete the duplicate node
 					delete(microNet.Nodes, incomeLastNodeID)
+					diag.gapFixed()
 				}
 			}
 		}
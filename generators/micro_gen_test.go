@@ -0,0 +1,271 @@
+package generators
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/LdDl/go-gmns/meso"
+	"github.com/LdDl/go-gmns/micro"
+	"github.com/LdDl/go-gmns/movement"
+	"github.com/LdDl/go-gmns/utils/geomath"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+// gridNet returns n*n grid of bidirectional links with varying number of lanes
+func gridNet(n int) *macro.Net {
+	net := macro.NewNet()
+	step := 0.002
+	nodeID := func(i, j int) gmns.NodeID {
+		return gmns.NodeID(i*n + j)
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			pt := orb.Point{37.6 + float64(j)*step, 55.75 + float64(i)*step}
+			net.Nodes[nodeID(i, j)] = macro.NewNodeFrom(nodeID(i, j), macro.WithPointGeom(pt), macro.WithPointGeomEuclidean(geomath.PointToEuclidean(pt)))
+		}
+	}
+	linkID := gmns.LinkID(0)
+	addLink := func(source, target gmns.NodeID) {
+		geom := orb.LineString{net.Nodes[source].Geom(), net.Nodes[target].Geom()}
+		link := macro.NewLinkFrom(linkID, source, target,
+			macro.WithLineGeom(geom),
+			macro.WithLineGeomEuclidean(geomath.LineToEuclidean(geom)),
+			macro.WithLengthMeters(geo.LengthHaversine(geom)),
+			macro.WithLanesNum(1+int(linkID/2)%3),
+			macro.WithFreeSpeed(60),
+			macro.WithCapacity(1000),
+			macro.WithLinkType(types.LINK_PRIMARY),
+			macro.WithAllowedAgentTypes([]types.AgentType{types.AGENT_AUTO, types.AGENT_BIKE, types.AGENT_WALK}),
+			macro.WithBidirectionalSource(true),
+		)
+		macro.WithLanesInfo(macro.NewLanesInfo(link))(link)
+		net.Links[linkID] = link
+		macro.WithOutcomingLinks(linkID)(net.Nodes[source])
+		macro.WithIncomingLinks(linkID)(net.Nodes[target])
+		linkID++
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if j+1 < n {
+				addLink(nodeID(i, j), nodeID(i, j+1))
+				addLink(nodeID(i, j+1), nodeID(i, j))
+			}
+			if i+1 < n {
+				addLink(nodeID(i, j), nodeID(i+1, j))
+				addLink(nodeID(i+1, j), nodeID(i, j))
+			}
+		}
+	}
+	return net
+}
+
+func prepareGrid(t testing.TB, n int) (*macro.Net, *meso.Net, movement.MovementsStorage) {
+	macroNet := gridNet(n)
+	movements, err := GenerateMovements(macroNet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mesoOpts := DefaultMesoGenOptions()
+	mesoOpts.Verbose = false
+	mesoNet, err := GenerateMesoscopic(macroNet, movements, mesoOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return macroNet, mesoNet, movements
+}
+
+// dumpMicroNet returns text representation of every node and link of the network sorted by identifiers
+func dumpMicroNet(net *micro.Net) string {
+	lines := make([]string, 0, len(net.Nodes)+len(net.Links))
+	for _, node := range net.Nodes {
+		lines = append(lines, fmt.Sprintf("N %d %v %d %d %d %v %v %v %v", node.ID, node.Geom(), node.MesoLink(), node.LaneID(), node.CellIndex(), node.IsUpstreamEnd(), node.IsDownstreamEnd(), node.IncomingLinks().Keys(), node.OutcomingLinks().Keys()))
+	}
+	for _, link := range net.Links {
+		lines = append(lines, fmt.Sprintf("L %d %d %d %v %d %d %v %d %f %v %v", link.ID, link.SourceNode(), link.TargetNode(), link.Geom(), link.MesoLink(), link.MacroNode(), link.CellType(), link.LaneID(), link.CellLength(), link.IsFirstMovementCell(), link.AllowedAgentTypes()))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+func TestGenerateMicroscopicParallel(t *testing.T) {
+	macroNet, mesoNet, movements := prepareGrid(t, 4)
+	for _, separateBikeWalk := range []bool{false, true} {
+		opts := DefaultMicroGenOptions()
+		opts.SeparateBikeWalk = separateBikeWalk
+		sequential, err := GenerateMicroscopic(macroNet, mesoNet, movements, opts)
+		assert.NoError(t, err)
+
+		opts.Workers = 4
+		parallel, err := GenerateMicroscopic(macroNet, mesoNet, movements, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, sequential.MaxNodeID(), parallel.MaxNodeID())
+		assert.Equal(t, sequential.MaxLinkID(), parallel.MaxLinkID())
+		assert.Equal(t, dumpMicroNet(sequential), dumpMicroNet(parallel), "Parallel generation should give the same network as sequential one. Separate bike/walk: %t", separateBikeWalk)
+	}
+}
+
+// laneChangeNet returns single 3-lane link 0->1 of about 200 meters with the given lane change permissions
+func laneChangeNet(laneChanges []types.LaneChange) *macro.Net {
+	net := chainNet(2)
+	pt := orb.Point{37.603, 55.75}
+	macro.WithPointGeom(pt)(net.Nodes[1])
+	macro.WithPointGeomEuclidean(geomath.PointToEuclidean(pt))(net.Nodes[1])
+	link := net.Links[0]
+	geom := orb.LineString{net.Nodes[0].Geom(), pt}
+	macro.WithLineGeom(geom)(link)
+	macro.WithLineGeomEuclidean(geomath.LineToEuclidean(geom))(link)
+	macro.WithLengthMeters(geo.LengthHaversine(geom))(link)
+	macro.WithLanesNum(3)(link)
+	macro.WithLanesInfo(macro.NewLanesInfo(link))(link)
+	macro.WithLaneChanges(laneChanges)(link)
+	return net
+}
+
+func TestGenerateMicroscopicLaneChanges(t *testing.T) {
+	generate := func(macroNet *macro.Net, opts MicroGenOptions) *micro.Net {
+		movements, err := GenerateMovements(macroNet)
+		assert.NoError(t, err)
+		mesoOpts := DefaultMesoGenOptions()
+		mesoOpts.Verbose = false
+		mesoNet, err := GenerateMesoscopic(macroNet, movements, mesoOpts)
+		assert.NoError(t, err)
+		microNet, err := GenerateMicroscopic(macroNet, mesoNet, movements, opts)
+		assert.NoError(t, err)
+		return microNet
+	}
+	// Number of lane change cells by source and target lanes
+	laneChanges := func(microNet *micro.Net) map[[2]int]int {
+		ans := make(map[[2]int]int)
+		for _, link := range microNet.Links {
+			if link.CellType() == types.CELL_LANE_CHANGE {
+				ans[[2]int{link.LaneID(), microNet.Nodes[link.TargetNode()].LaneID()}]++
+			}
+		}
+		return ans
+	}
+
+	// Distance from the closest lane change cell to the downstream end of the lanes
+	closestLaneChange := func(microNet *micro.Net) float64 {
+		ends := make(map[int]orb.Point)
+		for _, node := range microNet.Nodes {
+			if node.IsDownstreamEnd() {
+				ends[node.LaneID()] = node.Geom()
+			}
+		}
+		ans := math.Inf(1)
+		for _, link := range microNet.Links {
+			if link.CellType() == types.CELL_LANE_CHANGE {
+				target := microNet.Nodes[link.TargetNode()]
+				ans = math.Min(ans, geo.DistanceHaversine(target.Geom(), ends[target.LaneID()]))
+			}
+		}
+		return ans
+	}
+
+	macroNet := laneChangeNet(nil)
+	opts := DefaultMicroGenOptions()
+	assert.Less(t, closestLaneChange(generate(macroNet, opts)), 1e-6, "Lane changes should be allowed up to the end of the link without the zone")
+	opts.NoLaneChangeZone = 50
+	microNet := generate(macroNet, opts)
+	closest := closestLaneChange(microNet)
+	assert.GreaterOrEqual(t, closest, 50-1e-6, "Lane changes should not be created inside the zone")
+	assert.Less(t, closest, 50+opts.CellLength, "Lane changes should be created right before the zone")
+	assert.Equal(t, map[[2]int]int{{1, 2}: 1, {2, 1}: 1, {2, 3}: 1, {3, 2}: 1}, booleanCounts(laneChanges(microNet)))
+
+	// Lane 1 is allowed to change to the right only, lane 2 to the left only, lane 3 is not allowed to change
+	macroNet = laneChangeNet([]types.LaneChange{types.LANE_CHANGE_RIGHT, types.LANE_CHANGE_LEFT, types.LANE_CHANGE_NONE})
+	opts = DefaultMicroGenOptions()
+	assert.Equal(t, map[[2]int]int{{1, 2}: 1, {2, 1}: 1}, booleanCounts(laneChanges(generate(macroNet, opts))), "Right-hand traffic: lane 1 is the leftmost one")
+	opts.DrivingSide = types.DRIVING_SIDE_LEFT
+	assert.Equal(t, map[[2]int]int{{2, 3}: 1}, booleanCounts(laneChanges(generate(macroNet, opts))), "Left-hand traffic: lane 1 is the rightmost one")
+}
+
+func TestGenerateMicroscopicReservedLaneChanges(t *testing.T) {
+	macroNet := laneChangeNet(nil)
+	link := macroNet.Links[0]
+	macro.WithAllowedAgentTypes([]types.AgentType{types.AGENT_AUTO, types.AGENT_BUS})(link)
+	busLane := gmns.NewLane(link.ID, 3)
+	busLane.AllowedAgentTypes = []types.AgentType{types.AGENT_BUS}
+	busLane.Designation = types.LANE_DESIGNATION_BUS
+	macro.WithLanes([]gmns.Lane{busLane})(link)
+	movements, err := GenerateMovements(macroNet)
+	assert.NoError(t, err)
+	mesoOpts := DefaultMesoGenOptions()
+	mesoOpts.Verbose = false
+	mesoNet, err := GenerateMesoscopic(macroNet, movements, mesoOpts)
+	assert.NoError(t, err)
+	microNet, err := GenerateMicroscopic(macroNet, mesoNet, movements, DefaultMicroGenOptions())
+	assert.NoError(t, err)
+
+	changes := make(map[[2]int]int)
+	for _, microLink := range microNet.Links {
+		targetLane := microNet.Nodes[microLink.TargetNode()].LaneID()
+		if targetLane == 3 || microLink.LaneID() == 3 {
+			assert.NotContains(t, microLink.AllowedAgentTypes(), types.AGENT_AUTO, "Autos should not enter the bus lane. Cell type: %s. Lanes: %d -> %d", microLink.CellType(), microLink.LaneID(), targetLane)
+		}
+		if microLink.CellType() == types.CELL_LANE_CHANGE {
+			changes[[2]int{microLink.LaneID(), targetLane}]++
+		}
+	}
+	assert.Greater(t, changes[[2]int{2, 3}], 0, "Buses should be allowed to change into the bus lane")
+	assert.Greater(t, changes[[2]int{3, 2}], 0, "Buses should be allowed to leave the bus lane")
+
+	// Lanes without common agent types are not connected by lane changes
+	taxiLane := gmns.NewLane(link.ID, 2)
+	taxiLane.AllowedAgentTypes = []types.AgentType{types.AGENT_TAXI}
+	macro.WithLanes([]gmns.Lane{taxiLane, busLane})(link)
+	mesoNet, err = GenerateMesoscopic(macroNet, movements, mesoOpts)
+	assert.NoError(t, err)
+	microNet, err = GenerateMicroscopic(macroNet, mesoNet, movements, DefaultMicroGenOptions())
+	assert.NoError(t, err)
+	for _, microLink := range microNet.Links {
+		if microLink.CellType() == types.CELL_LANE_CHANGE {
+			lanes := [2]int{microLink.LaneID(), microNet.Nodes[microLink.TargetNode()].LaneID()}
+			assert.NotEqual(t, [2]int{2, 3}, lanes)
+			assert.NotEqual(t, [2]int{3, 2}, lanes)
+		}
+	}
+}
+
+// booleanCounts replaces every positive count with 1
+func booleanCounts(counts map[[2]int]int) map[[2]int]int {
+	ans := make(map[[2]int]int, len(counts))
+	for key, count := range counts {
+		if count > 0 {
+			ans[key] = 1
+		}
+	}
+	return ans
+}
+
+func BenchmarkGenerateMicroscopic(b *testing.B) {
+	// Grids of 50*50 and 158*158 nodes give ~10k and ~100k macroscopic links
+	for _, n := range []int{50, 158} {
+		for _, workers := range []int{1, max(runtime.GOMAXPROCS(0), 2)} {
+			b.Run(fmt.Sprintf("links=%d/workers=%d", 4*n*(n-1), workers), func(b *testing.B) {
+				if testing.Short() && n > 50 {
+					b.Skip("skipping ~100k links benchmark in short mode")
+				}
+				macroNet, mesoNet, movements := prepareGrid(b, n)
+				opts := DefaultMicroGenOptions()
+				opts.Workers = workers
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					_, err := GenerateMicroscopic(macroNet, mesoNet, movements, opts)
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package generators
+
+import (
+	"sync/atomic"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/LdDl/go-gmns/meso"
+	"github.com/LdDl/go-gmns/micro"
+	"github.com/pkg/errors"
+)
+
+// workersNum returns number of goroutines for processing macro links
+func (opts MicroGenOptions) workersNum() int {
+	return workersNum(opts.Workers)
+}
+
+// processMacroLinksParallel processes macro links in worker goroutines. Every macro link is processed into its own local network
+// with identifiers starting from zero, then local networks are appended to the resulting one in the given order of macro links.
+// So identifiers are the same as for the sequential processing. Workers report progress of the given stage and skip remaining macro links once the context is done
+func processMacroLinksParallel(macroNet *macro.Net, mesoNet *meso.Net, microNet *micro.Net, sortedMacroLinkIDs []gmns.LinkID, macroLinkMesoLinks map[gmns.LinkID][]gmns.LinkID, opts MicroGenOptions, tracker *progressTracker, stage string) error {
+	localNets := make([]*micro.Net, len(sortedMacroLinkIDs))
+	errs := make([]error, len(sortedMacroLinkIDs))
+	var processed atomic.Int64
+	parallelFor(len(sortedMacroLinkIDs), opts.workersNum(), func(idx int) {
+		if tracker.ctx.Err() != nil {
+			return
+		}
+		macroLink := macroNet.Links[sortedMacroLinkIDs[idx]]
+		localNet := micro.NewNet()
+		errs[idx] = processMacroLink(macroNet, mesoNet, localNet, macroLink, macroLinkMesoLinks[macroLink.ID], opts)
+		localNets[idx] = localNet
+		tracker.reportStep(stage, int(processed.Add(1)), len(sortedMacroLinkIDs))
+	})
+	// Skipped macro links leave the result incomplete
+	if err := tracker.check(stage); err != nil {
+		return err
+	}
+
+	// Merge phase: deterministic identifiers assignment
+	for idx, macroLinkID := range sortedMacroLinkIDs {
+		if errs[idx] != nil {
+			return errors.Wrapf(errs[idx], "failed to process macro link %d", macroLinkID)
+		}
+		microNet.Append(localNets[idx])
+	}
+	return nil
+}
@@ -1,17 +1,67 @@
 package generators
 
 import (
+	"context"
 	"sort"
 
 	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/gmns/types"
 	"github.com/LdDl/go-gmns/macro"
 	"github.com/LdDl/go-gmns/movement"
 	"github.com/pkg/errors"
 )
 
+// MovementsGenOptions contains options for movements generation
+type MovementsGenOptions struct {
+	// DrivingSide affects lanes connections ordering and movements types (e.g. side of U-turns)
+	DrivingSide types.DrivingSide
+	// Progress receives number of processed macroscopic nodes. Could be nil
+	Progress ProgressFunc
+	// Logger receives diagnostics. No-op logger is used if it is nil
+	Logger Logger
+	// Report collects statistics and anomalies of generation. Could be nil
+	Report *GenerationReport
+	// IDs gives identifiers for new movements. Allocator starting next to the maximum identifier of the storage being filled is used if it is nil
+	// (i.e. from zero for generation from scratch)
+	IDs *movement.IDAllocator
+	// StableIDs derives identifiers of movements from OSM identifiers (or geometries) of their nodes and links instead of processing order,
+	// so movements keep identifiers across generations if their parts are unchanged. It is not used by RegenerateMovements
+	StableIDs bool
+}
+
+// DefaultMovementsGenOptions returns default options for movements generation
+func DefaultMovementsGenOptions() MovementsGenOptions {
+	return MovementsGenOptions{
+		DrivingSide: types.DRIVING_SIDE_RIGHT,
+	}
+}
+
+// diagnostics returns diagnostics for the logger and the report of the options
+func (opts MovementsGenOptions) diagnostics() *diagnostics {
+	return newDiagnostics(opts.Logger, opts.Report)
+}
+
+// IDAllocator returns allocator of the options or the new one giving identifiers next to the maximum identifier of the storage
+func (opts MovementsGenOptions) IDAllocator(movements movement.MovementsStorage) *movement.IDAllocator {
+	if opts.IDs != nil {
+		return opts.IDs
+	}
+	return movement.NewIDAllocator(movements.NextID())
+}
+
 // GenerateMovements generates movements for the given macroscopic network
-func GenerateMovements(macroNet *macro.Net) (movement.MovementsStorage, error) {
+func GenerateMovements(macroNet *macro.Net, opts ...MovementsGenOptions) (movement.MovementsStorage, error) {
+	return GenerateMovementsContext(context.Background(), macroNet, opts...)
+}
+
+// GenerateMovementsContext is the same as GenerateMovements, but it stops generation with the context's error if the context is done
+func GenerateMovementsContext(ctx context.Context, macroNet *macro.Net, opts ...MovementsGenOptions) (movement.MovementsStorage, error) {
+	options := DefaultMovementsGenOptions()
+	if len(opts) > 0 {
+		options = opts[0]
+	}
 	ans := movement.NewMovementsStorage()
+	ids := options.IDAllocator(ans)
 	// Sort node IDs for deterministic iteration
 	sortedNodeIDs := make([]gmns.NodeID, 0, len(macroNet.Nodes))
 	for id := range macroNet.Nodes {
@@ -20,9 +70,17 @@ func GenerateMovements(macroNet *macro.Net) (movement.MovementsStorage, error) {
 	sort.Slice(sortedNodeIDs, func(i, j int) bool {
 		return sortedNodeIDs[i] < sortedNodeIDs[j]
 	})
-	for _, nodeID := range sortedNodeIDs {
+	diag := options.diagnostics()
+	tracker := newProgressTracker(ctx, options.Progress, diag)
+	if err := tracker.begin(STAGE_MOVEMENTS); err != nil {
+		return nil, err
+	}
+	for i, nodeID := range sortedNodeIDs {
+		if err := tracker.check(STAGE_MOVEMENTS); err != nil {
+			return nil, err
+		}
 		node := macroNet.Nodes[nodeID]
-		movements, err := findMovements(node, macroNet.Links)
+		movements, err := findMovements(node, macroNet.Links, ids, options, diag)
 		if err != nil {
 			return nil, errors.Wrapf(err, "Can't find movements for macro node with ID: '%d' (OSM ID: '%d')", node.ID, node.OSMNode())
 		}
@@ -30,12 +88,19 @@ func GenerateMovements(macroNet *macro.Net) (movement.MovementsStorage, error) {
 			mvmt := movements[j]
 			ans[mvmt.ID] = mvmt
 		}
+		tracker.reportStep(STAGE_MOVEMENTS, i+1, len(sortedNodeIDs))
+	}
+	tracker.end(STAGE_MOVEMENTS, len(sortedNodeIDs))
+	if options.StableIDs {
+		if err := applyStableMovementsIDs(macroNet, ans); err != nil {
+			return nil, err
+		}
 	}
 	return ans, nil
 }
 
 // findMovements generates array of movements for the given macroscopic node [this function is not exported yet]
-func findMovements(macroNode *macro.Node, links map[gmns.LinkID]*macro.Link) ([]*movement.Movement, error) {
+func findMovements(macroNode *macro.Node, links map[gmns.LinkID]*macro.Link, ids *movement.IDAllocator, options MovementsGenOptions, diag *diagnostics) ([]*movement.Movement, error) {
 	movements := []*movement.Movement{}
 
 	macroIncomingLinks := macroNode.IncomingLinks()
@@ -69,7 +134,7 @@ func findMovements(macroNode *macro.Node, links map[gmns.LinkID]*macro.Link) ([]
 			return movements, nil
 		}
 
-		connections := macro.GenerateSpansConnections(outcomingLink, incomingLinksList)
+		connections := macro.GenerateSpansConnectionsForSide(outcomingLink, incomingLinksList, options.DrivingSide)
 		incomingLaneIndices := outcomingLink.GetOutcomingLaneIndices()
 		for i := range incomingLinksList {
 			incomingLink := incomingLinksList[i]
@@ -80,10 +145,10 @@ func findMovements(macroNode *macro.Node, links map[gmns.LinkID]*macro.Link) ([]
 			lanesNum := incomeLaneIndexEnd - incomeLaneIndexStart + 1
 
 			outcomingLaneIndices := incomingLink.GetOutcomingLaneIndices()
-			mvmtTextID, mvmtType := movement.FindMovementType(incomingLink.GeomEuclidean(), outcomingLink.GeomEuclidean())
+			mvmtTextID, mvmtType := movement.FindMovementTypeForSide(incomingLink.GeomEuclidean(), outcomingLink.GeomEuclidean(), options.DrivingSide)
 			mvmtGeom := movement.FindMovementGeom(incomingLink.Geom(), outcomingLink.Geom())
 			mvmt := movement.NewMovement(
-				movement.GenMovementID(),
+				ids.Next(),
 				macroNode.ID, incomingLink.ID, outcomingLinkID, mvmtTextID, mvmtType,
 				movement.WithOSMNodeID(macroNode.OSMNode()),
 				movement.WithSourceOSMNodeID(incomingLink.SourceOSMNode()),
@@ -120,10 +185,10 @@ func findMovements(macroNode *macro.Node, links map[gmns.LinkID]*macro.Link) ([]
 				}
 			}
 			if len(outcomingLinksList) == 0 {
-				// @todo: can just return?
+				diag.skip("gen_movements", ELEMENT_MACRO_LINK, int64(incomingLink.ID), int64(macroNode.ID), SKIP_NO_OUTCOMING_LINKS, "Intersection incoming link has no outcoming links except the reversed one: movements of the rest incoming links are skipped")
 				return movements, nil
 			}
-			connections := macro.GenerateIntersectionsConnections(incomingLink, outcomingLinksList)
+			connections := macro.GenerateIntersectionsConnectionsForSide(incomingLink, outcomingLinksList, options.DrivingSide)
 			outcomingLaneIndices := incomingLink.GetOutcomingLaneIndices()
 
 			for i := range outcomingLinksList {
@@ -135,10 +200,10 @@ func findMovements(macroNode *macro.Node, links map[gmns.LinkID]*macro.Link) ([]
 				lanesNum := incomeLaneIndexEnd - incomeLaneIndexStart + 1
 
 				incomingLaneIndices := outcomingLink.GetOutcomingLaneIndices()
-				mvmtTextID, mvmtType := movement.FindMovementType(incomingLink.GeomEuclidean(), outcomingLink.GeomEuclidean())
+				mvmtTextID, mvmtType := movement.FindMovementTypeForSide(incomingLink.GeomEuclidean(), outcomingLink.GeomEuclidean(), options.DrivingSide)
 				mvmtGeom := movement.FindMovementGeom(incomingLink.Geom(), outcomingLink.Geom())
 				mvmt := movement.NewMovement(
-					movement.GenMovementID(),
+					ids.Next(),
 					macroNode.ID, incomingLinkID, outcomingLink.ID, mvmtTextID, mvmtType,
 					movement.WithOSMNodeID(macroNode.OSMNode()),
 					movement.WithSourceOSMNodeID(incomingLink.SourceOSMNode()),
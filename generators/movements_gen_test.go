@@ -0,0 +1,59 @@
+package generators
+
+import (
+	"testing"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/LdDl/go-gmns/movement"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateMovementsIDs(t *testing.T) {
+	first, err := GenerateMovements(gridNet(3))
+	assert.NoError(t, err)
+	second, err := GenerateMovements(gridNet(3))
+	assert.NoError(t, err)
+	assert.Equal(t, gmns.MovementID(len(first)), first.NextID(), "Identifiers should start from zero")
+	for id, mvmt := range first {
+		assert.Equal(t, mvmt.MacroNode(), second[id].MacroNode(), "Independent generations should give the same identifiers")
+		assert.Equal(t, mvmt.IncomeMacroLink(), second[id].IncomeMacroLink())
+		assert.Equal(t, mvmt.OutcomeMacroLink(), second[id].OutcomeMacroLink())
+	}
+
+	// Seeded allocator appends movements after the imported ones
+	opts := DefaultMovementsGenOptions()
+	opts.IDs = movement.NewIDAllocator(first.NextID())
+	appended, err := GenerateMovements(gridNet(3), opts)
+	assert.NoError(t, err)
+	for id := range appended {
+		_, ok := first[id]
+		assert.False(t, ok, "Identifier %d is used already", id)
+	}
+	assert.Equal(t, gmns.MovementID(len(first)+len(appended)), appended.NextID())
+}
+
+func TestConsolidateIntersectionsMovementsOptions(t *testing.T) {
+	movementsOptions := func() MovementsGenOptions {
+		opts := DefaultMovementsGenOptions()
+		opts.DrivingSide = types.DRIVING_SIDE_LEFT
+		opts.IDs = movement.NewIDAllocator(100)
+		return opts
+	}
+	expected, err := GenerateMovements(gridNet(3), movementsOptions())
+	assert.NoError(t, err)
+
+	opts := DefaultIntersectionsGenOptions()
+	opts.Movements = movementsOptions()
+	_, movements, err := ConsolidateIntersections(gridNet(3), opts)
+	assert.NoError(t, err)
+	assert.Equal(t, len(expected), len(movements))
+	for id, mvmt := range expected {
+		if !assert.Contains(t, movements, id, "Seeded identifiers should be used") {
+			continue
+		}
+		assert.Equal(t, mvmt.Type(), movements[id].Type(), "Driving side should be forwarded")
+		assert.Equal(t, mvmt.IncomeMacroLink(), movements[id].IncomeMacroLink())
+		assert.Equal(t, mvmt.OutcomeMacroLink(), movements[id].OutcomeMacroLink())
+	}
+}
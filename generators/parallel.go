@@ -0,0 +1,42 @@
+package generators
+
+import (
+	"runtime"
+	"sync"
+)
+
+// workersNum returns number of goroutines for the given workers option: non-positive value means GOMAXPROCS
+func workersNum(workers int) int {
+	if workers <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return workers
+}
+
+// parallelFor calls fn for every index in [0; n) using the given number of goroutines. Calls are made sequentially in the current goroutine if there is single worker.
+// fn must write its results to the index-specific places only
+func parallelFor(n int, workers int, fn func(idx int)) {
+	workers = min(workers, n)
+	if workers <= 1 {
+		for idx := 0; idx < n; idx++ {
+			fn(idx)
+		}
+		return
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				fn(idx)
+			}
+		}()
+	}
+	for idx := 0; idx < n; idx++ {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,109 @@
+package generators
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Names of generation stages passed to ProgressFunc
+const (
+	STAGE_MOVEMENTS = "movements"
+
+	STAGE_MESO_OFFSETS          = "meso_offsets"
+	STAGE_MESO_MOVEMENTS        = "meso_movements"
+	STAGE_MESO_NECESSITY        = "meso_necessity"
+	STAGE_MESO_CUTS             = "meso_cuts"
+	STAGE_MESO_BASE_LINKS       = "meso_base_links"
+	STAGE_MESO_CONNECTIONS      = "meso_connections"
+	STAGE_MESO_BOUNDARY_TYPES   = "meso_boundary_types"
+	STAGE_MESO_LINKS_PROPERTIES = "meso_links_properties"
+
+	STAGE_MICRO_CELLS       = "micro_cells"
+	STAGE_MICRO_CONNECTIONS = "micro_connections"
+	STAGE_MICRO_GAPS        = "micro_gaps"
+	STAGE_MICRO_BIKE_WALK   = "micro_bike_walk"
+)
+
+// ProgressFunc receives progress of the generation: stage name, number of processed and total elements of the stage
+// and time elapsed since the generation start. It is called from the goroutine which runs the generation,
+// except for stages processed by several workers: then it is called from worker goroutines, but never concurrently and with non-decreasing number of processed elements
+type ProgressFunc func(stage string, processed int, total int, elapsed time.Duration)
+
+// progressTracker reports progress of generation stages, records their timings and checks cancellation of the context
+type progressTracker struct {
+	ctx        context.Context
+	progress   ProgressFunc
+	diag       *diagnostics
+	start      time.Time
+	stageStart time.Time
+
+	// mu serializes calls of the progress function made by workers
+	mu sync.Mutex
+	// Last reported stage and number of its processed elements
+	lastStage     string
+	lastProcessed int
+}
+
+// newProgressTracker returns tracker for the generation started right now. Progress function could be nil
+func newProgressTracker(ctx context.Context, progress ProgressFunc, diag *diagnostics) *progressTracker {
+	now := time.Now()
+	return &progressTracker{
+		ctx:        ctx,
+		progress:   progress,
+		diag:       diag,
+		start:      now,
+		stageStart: now,
+	}
+}
+
+// begin checks cancellation of the context and starts the stage
+func (tracker *progressTracker) begin(stage string) error {
+	if err := tracker.check(stage); err != nil {
+		return err
+	}
+	tracker.stageStart = time.Now()
+	return nil
+}
+
+// end reports completion of the stage and records its timing
+func (tracker *progressTracker) end(stage string, total int) {
+	tracker.diag.stage(stage, total, time.Since(tracker.stageStart))
+	tracker.report(stage, total, total)
+}
+
+// check returns error if the context is canceled or its deadline is exceeded. The error wraps the context's one
+func (tracker *progressTracker) check(stage string) error {
+	if err := tracker.ctx.Err(); err != nil {
+		return errors.Wrapf(err, "Generation has been stopped at stage '%s'", stage)
+	}
+	return nil
+}
+
+// report passes progress of the stage to the progress function if it is set
+func (tracker *progressTracker) report(stage string, processed int, total int) {
+	if tracker.progress == nil {
+		return
+	}
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	// Workers could finish elements in a different order than they have been counted
+	if stage == tracker.lastStage && processed < tracker.lastProcessed {
+		return
+	}
+	tracker.lastStage, tracker.lastProcessed = stage, processed
+	tracker.progress(stage, processed, total, time.Since(tracker.start))
+}
+
+// reportStep passes progress of the stage to the progress function once per percent of total elements. Completion of the stage is reported by end
+func (tracker *progressTracker) reportStep(stage string, processed int, total int) {
+	if tracker.progress == nil || processed >= total {
+		return
+	}
+	if step := total / 100; step > 1 && processed%step != 0 {
+		return
+	}
+	tracker.report(stage, processed, total)
+}
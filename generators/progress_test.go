@@ -0,0 +1,71 @@
+package generators
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateContext(t *testing.T) {
+	macroNet, mesoNet, movements := prepareGrid(t, 4)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := GenerateMovementsContext(canceled, macroNet)
+	assert.True(t, errors.Is(err, context.Canceled), "Movements generation should be stopped by canceled context: %v", err)
+	_, err = GenerateMesoscopicContext(canceled, gridNet(4), movements)
+	assert.True(t, errors.Is(err, context.Canceled), "Meso generation should be stopped by canceled context: %v", err)
+	_, err = GenerateMicroscopicContext(canceled, macroNet, mesoNet, movements)
+	assert.True(t, errors.Is(err, context.Canceled), "Micro generation should be stopped by canceled context: %v", err)
+
+	stages := []string{}
+	lastProcessed := map[string]int{}
+	progress := func(stage string, processed int, total int, elapsed time.Duration) {
+		if len(stages) == 0 || stages[len(stages)-1] != stage {
+			stages = append(stages, stage)
+		}
+		assert.LessOrEqual(t, processed, total)
+		assert.GreaterOrEqual(t, processed, lastProcessed[stage])
+		lastProcessed[stage] = processed
+	}
+	microOpts := DefaultMicroGenOptions()
+	microOpts.SeparateBikeWalk = true
+	microOpts.Progress = progress
+	_, err = GenerateMicroscopicContext(context.Background(), macroNet, mesoNet, movements, microOpts)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{STAGE_MICRO_CELLS, STAGE_MICRO_CONNECTIONS, STAGE_MICRO_GAPS, STAGE_MICRO_BIKE_WALK}, stages)
+	assert.Equal(t, len(macroNet.Links), lastProcessed[STAGE_MICRO_CELLS])
+
+	stages = stages[:0]
+	mesoOpts := DefaultMesoGenOptions()
+	mesoOpts.Verbose = false
+	mesoOpts.Progress = progress
+	_, err = GenerateMesoscopicContext(context.Background(), gridNet(4), movements, mesoOpts)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{STAGE_MESO_OFFSETS, STAGE_MESO_MOVEMENTS, STAGE_MESO_NECESSITY, STAGE_MESO_CUTS, STAGE_MESO_BASE_LINKS, STAGE_MESO_CONNECTIONS, STAGE_MESO_BOUNDARY_TYPES, STAGE_MESO_LINKS_PROPERTIES}, stages)
+}
+
+func TestGenerateMicroscopicParallelContext(t *testing.T) {
+	macroNet, mesoNet, movements := prepareGrid(t, 6)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reported := 0
+	opts := DefaultMicroGenOptions()
+	opts.Workers = 4
+	opts.Progress = func(stage string, processed int, total int, elapsed time.Duration) {
+		if stage != STAGE_MICRO_CELLS || processed >= total {
+			return
+		}
+		reported++
+		// Remaining macro links should be skipped
+		cancel()
+	}
+	_, err := GenerateMicroscopicContext(ctx, macroNet, mesoNet, movements, opts)
+	assert.True(t, errors.Is(err, context.Canceled), "Micro generation should be stopped by canceled context: %v", err)
+	assert.Contains(t, err.Error(), STAGE_MICRO_CELLS, "Generation should be stopped at the stage of workers")
+	assert.Greater(t, reported, 0, "Workers should report progress of processed macro links")
+	assert.Less(t, reported, len(macroNet.Links))
+}
@@ -0,0 +1,178 @@
+package generators
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/pkg/errors"
+)
+
+// SkipReason is the reason why an element has been skipped (or left as is) during generation
+type SkipReason string
+
+const (
+	// Intersection incoming link has no outcoming links except the reversed one, so movements of the rest incoming links of the node are not generated
+	SKIP_NO_OUTCOMING_LINKS = SkipReason("no_outcoming_links")
+	// Movement mesoscopic link has no upstream link to inherit link type, free speed, capacity and agent types from
+	SKIP_NO_UPSTREAM_LINK = SkipReason("no_upstream_link")
+	// Lane of the movement has no cells on the incoming or outcoming mesoscopic link, so connector cells are not created
+	SKIP_NO_LANE_CELLS = SkipReason("no_lane_cells")
+	// Numbers of incoming and outcoming lanes of the movement differ, so the gap at pass-through node is not fixed
+	SKIP_LANES_MISMATCH = SkipReason("lanes_mismatch")
+	// Lanes of the movement are out of lanes of mesoscopic links, so the gap at pass-through node is not fixed
+	SKIP_LANES_OUT_OF_RANGE = SkipReason("lanes_out_of_range")
+)
+
+// ElementType is the type of a skipped element
+type ElementType string
+
+const (
+	ELEMENT_MACRO_LINK = ElementType("macro_link")
+	ELEMENT_MESO_LINK  = ElementType("meso_link")
+	ELEMENT_MOVEMENT   = ElementType("movement")
+)
+
+// StageTiming is time spent on the generation stage
+type StageTiming struct {
+	Stage string `json:"stage"`
+	// Number of elements processed on the stage
+	Processed      int     `json:"processed"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+}
+
+// SkippedElement is an element which has been skipped (or left as is) during generation
+type SkippedElement struct {
+	// Generator scope: gen_movements, gen_meso or gen_micro
+	Scope   string      `json:"scope"`
+	Element ElementType `json:"element"`
+	ID      int64       `json:"id"`
+	Reason  SkipReason  `json:"reason"`
+	// Related element: macroscopic node for movements and macroscopic links, mesoscopic node for mesoscopic links. -1 if there is no one
+	NodeID int64 `json:"node_id"`
+}
+
+// GenerationReport collects statistics and anomalies of generation. Pass the same report to options of several generators to collect the whole pipeline.
+// Generators do not return the report: it is filled in place through the options pointer, so the generators keep their signatures
+// and the report keeps stages completed before an error. It is safe to fill the report from several goroutines
+type GenerationReport struct {
+	mu sync.Mutex
+
+	Stages  []StageTiming    `json:"stages"`
+	Skipped []SkippedElement `json:"skipped"`
+	// Number of mesoscopic links reconnected directly to each other at nodes where movements are not needed
+	ShortcutsCreated int `json:"shortcuts_created"`
+	// Number of microscopic nodes merged at nodes where movements are not needed
+	GapsFixed int `json:"gaps_fixed"`
+	// Macroscopic nodes where movements are not needed according to ComputeMovementFlags
+	PassThroughNodes []gmns.NodeID `json:"pass_through_nodes"`
+}
+
+// NewGenerationReport returns empty report
+func NewGenerationReport() *GenerationReport {
+	return &GenerationReport{
+		Stages:           []StageTiming{},
+		Skipped:          []SkippedElement{},
+		PassThroughNodes: []gmns.NodeID{},
+	}
+}
+
+// WriteReport writes the report as JSON
+func WriteReport(w io.Writer, report *GenerationReport) error {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(report)
+	if err != nil {
+		return errors.Wrap(err, "Can't encode generation report")
+	}
+	return nil
+}
+
+// diagnostics passes warnings to the logger and records them into the report if it is set
+type diagnostics struct {
+	logger Logger
+	report *GenerationReport
+}
+
+// newDiagnostics returns diagnostics for the given logger and report. Both could be nil
+func newDiagnostics(logger Logger, report *GenerationReport) *diagnostics {
+	return &diagnostics{
+		logger: loggerOrNop(logger),
+		report: report,
+	}
+}
+
+// skip reports skipped element
+func (diag *diagnostics) skip(scope string, element ElementType, id int64, nodeID int64, reason SkipReason, msg string) {
+	diag.logger.Warn(msg, "scope", scope, "element", string(element), "id", id, "node_id", nodeID, "reason", string(reason))
+	if diag.report == nil {
+		return
+	}
+	diag.report.mu.Lock()
+	defer diag.report.mu.Unlock()
+	diag.report.Skipped = append(diag.report.Skipped, SkippedElement{
+		Scope:   scope,
+		Element: element,
+		ID:      id,
+		Reason:  reason,
+		NodeID:  nodeID,
+	})
+}
+
+// shortcutCreated counts mesoscopic links reconnected to each other
+func (diag *diagnostics) shortcutCreated() {
+	if diag.report == nil {
+		return
+	}
+	diag.report.mu.Lock()
+	defer diag.report.mu.Unlock()
+	diag.report.ShortcutsCreated++
+}
+
+// gapFixed counts merged microscopic nodes
+func (diag *diagnostics) gapFixed() {
+	if diag.report == nil {
+		return
+	}
+	diag.report.mu.Lock()
+	defer diag.report.mu.Unlock()
+	diag.report.GapsFixed++
+}
+
+// passThroughNodes records nodes where movements are not needed. Nodes already recorded (e.g. by previous incremental regeneration) are replaced
+func (diag *diagnostics) passThroughNodes(flags *MovementFlags) {
+	if diag.report == nil {
+		return
+	}
+	nodes := make([]gmns.NodeID, 0)
+	for nodeID, needMovement := range flags.NodesNeedMovement {
+		if !needMovement {
+			nodes = append(nodes, nodeID)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i] < nodes[j]
+	})
+	diag.report.mu.Lock()
+	defer diag.report.mu.Unlock()
+	diag.report.PassThroughNodes = nodes
+}
+
+// stage records time spent on the generation stage
+func (diag *diagnostics) stage(stage string, processed int, elapsed time.Duration) {
+	if diag.report == nil {
+		return
+	}
+	diag.report.mu.Lock()
+	defer diag.report.mu.Unlock()
+	diag.report.Stages = append(diag.report.Stages, StageTiming{
+		Stage:          stage,
+		Processed:      processed,
+		ElapsedSeconds: elapsed.Seconds(),
+	})
+}
@@ -0,0 +1,85 @@
+package generators
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/LdDl/go-gmns/utils/geomath"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+// chainNet returns chain of n nodes connected by one-way links going straight to the east
+func chainNet(n int) *macro.Net {
+	net := macro.NewNet()
+	for i := 0; i < n; i++ {
+		pt := orb.Point{37.6 + float64(i)*0.002, 55.75}
+		net.Nodes[gmns.NodeID(i)] = macro.NewNodeFrom(gmns.NodeID(i), macro.WithPointGeom(pt), macro.WithPointGeomEuclidean(geomath.PointToEuclidean(pt)))
+	}
+	for i := 0; i+1 < n; i++ {
+		source, target := gmns.NodeID(i), gmns.NodeID(i+1)
+		geom := orb.LineString{net.Nodes[source].Geom(), net.Nodes[target].Geom()}
+		link := macro.NewLinkFrom(gmns.LinkID(i), source, target,
+			macro.WithLineGeom(geom),
+			macro.WithLineGeomEuclidean(geomath.LineToEuclidean(geom)),
+			macro.WithLengthMeters(geo.LengthHaversine(geom)),
+			macro.WithLanesNum(2),
+			macro.WithFreeSpeed(60),
+			macro.WithCapacity(1000),
+			macro.WithLinkType(types.LINK_PRIMARY),
+			macro.WithAllowedAgentTypes([]types.AgentType{types.AGENT_AUTO}),
+		)
+		macro.WithLanesInfo(macro.NewLanesInfo(link))(link)
+		net.Links[link.ID] = link
+		macro.WithOutcomingLinks(link.ID)(net.Nodes[source])
+		macro.WithIncomingLinks(link.ID)(net.Nodes[target])
+	}
+	return net
+}
+
+func TestGenerationReport(t *testing.T) {
+	report := NewGenerationReport()
+	macroNet := chainNet(3)
+	movementsOpts := DefaultMovementsGenOptions()
+	movementsOpts.Report = report
+	movements, err := GenerateMovements(macroNet, movementsOpts)
+	assert.NoError(t, err)
+	mesoOpts := DefaultMesoGenOptions()
+	mesoOpts.Verbose = false
+	mesoOpts.Report = report
+	mesoNet, err := GenerateMesoscopic(macroNet, movements, mesoOpts)
+	assert.NoError(t, err)
+	microOpts := DefaultMicroGenOptions()
+	microOpts.Report = report
+	_, err = GenerateMicroscopic(macroNet, mesoNet, movements, microOpts)
+	assert.NoError(t, err)
+
+	stages := make([]string, 0, len(report.Stages))
+	for _, stage := range report.Stages {
+		stages = append(stages, stage.Stage)
+		assert.GreaterOrEqual(t, stage.ElapsedSeconds, 0.0)
+	}
+	assert.Equal(t, []string{
+		STAGE_MOVEMENTS,
+		STAGE_MESO_OFFSETS, STAGE_MESO_MOVEMENTS, STAGE_MESO_NECESSITY, STAGE_MESO_CUTS, STAGE_MESO_BASE_LINKS, STAGE_MESO_CONNECTIONS, STAGE_MESO_BOUNDARY_TYPES, STAGE_MESO_LINKS_PROPERTIES,
+		STAGE_MICRO_CELLS, STAGE_MICRO_CONNECTIONS, STAGE_MICRO_GAPS,
+	}, stages)
+	assert.Equal(t, len(macroNet.Nodes), report.Stages[0].Processed)
+	assert.Equal(t, []gmns.NodeID{1}, report.PassThroughNodes)
+	assert.Greater(t, report.ShortcutsCreated, 0)
+	assert.Greater(t, report.GapsFixed, 0)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteReport(&buf, report))
+	decoded := NewGenerationReport()
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), decoded))
+	assert.Equal(t, report.Stages, decoded.Stages)
+	assert.Equal(t, report.PassThroughNodes, decoded.PassThroughNodes)
+	assert.Equal(t, report.ShortcutsCreated, decoded.ShortcutsCreated)
+	assert.Equal(t, report.GapsFixed, decoded.GapsFixed)
+}
@@ -0,0 +1,195 @@
+package generators
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/LdDl/go-gmns/meso"
+	"github.com/LdDl/go-gmns/micro"
+	"github.com/LdDl/go-gmns/movement"
+	"github.com/LdDl/go-gmns/utils/geomath"
+	"github.com/pkg/errors"
+)
+
+// Stable identifiers are kept below 2^53 so they are represented exactly by float64 (JSON, GeoJSON)
+const stableIDsSpace = int64(1) << 53
+
+// stableIDs returns stable identifier for every element with the given content key: hash of the key in [1, 2^53).
+// Collisions are resolved by linear probing in order of keys (and of current identifiers for equal keys),
+// so the element keeps its identifier until another element with colliding hash and lesser key appears
+func stableIDs(keys map[int64]string) map[int64]int64 {
+	ids := make([]int64, 0, len(keys))
+	for id := range keys {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if keys[ids[i]] != keys[ids[j]] {
+			return keys[ids[i]] < keys[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	ans := make(map[int64]int64, len(ids))
+	used := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		h := fnv.New64a()
+		h.Write([]byte(keys[id]))
+		stableID := int64(h.Sum64()%uint64(stableIDsSpace-1)) + 1
+		for {
+			if _, ok := used[stableID]; !ok {
+				break
+			}
+			stableID = stableID%(stableIDsSpace-1) + 1
+		}
+		used[stableID] = struct{}{}
+		ans[id] = stableID
+	}
+	return ans
+}
+
+// macroStableKeys contains content keys of macroscopic nodes and links
+type macroStableKeys struct {
+	nodes map[gmns.NodeID]string
+	links map[gmns.LinkID]string
+}
+
+// newMacroStableKeys returns content keys of macroscopic elements. OSM identifiers are used when they are known (OSM way and OSM nodes of the ends for links, so directions are distinguished),
+// otherwise geometry is used
+func newMacroStableKeys(macroNet *macro.Net) *macroStableKeys {
+	keys := &macroStableKeys{
+		nodes: make(map[gmns.NodeID]string, len(macroNet.Nodes)),
+		links: make(map[gmns.LinkID]string, len(macroNet.Links)),
+	}
+	for id, node := range macroNet.Nodes {
+		if node.OSMNode() > 0 {
+			keys.nodes[id] = fmt.Sprintf("osm_node:%d", node.OSMNode())
+			continue
+		}
+		pt := node.Geom()
+		keys.nodes[id] = fmt.Sprintf("node_geom:%f,%f", pt[0], pt[1])
+	}
+	for id, link := range macroNet.Links {
+		if link.OSMWay() > 0 && link.SourceOSMNode() > 0 && link.TargetOSMNode() > 0 {
+			keys.links[id] = fmt.Sprintf("osm_way:%d:%d>%d", link.OSMWay(), link.SourceOSMNode(), link.TargetOSMNode())
+			continue
+		}
+		keys.links[id] = "link_geom:" + geomath.GeometryHash(link.Geom())
+	}
+	return keys
+}
+
+// movement returns content key of the movement: keys of its macroscopic node, income and outcome links
+func (keys *macroStableKeys) movement(mvmt *movement.Movement) string {
+	return fmt.Sprintf("movement:%s|%s|%s", keys.nodes[mvmt.MacroNode()], keys.links[mvmt.IncomeMacroLink()], keys.links[mvmt.OutcomeMacroLink()])
+}
+
+// mesoStableKeys returns content keys of mesoscopic nodes and links. Key of the link generated from macroscopic link is the macroscopic link key and index of the segment,
+// key of the connection link is its movement key. Key of the node is the least key of its incident non-connection links with the side of the link
+func mesoStableKeys(macroNet *macro.Net, mesoNet *meso.Net, movements movement.MovementsStorage) (map[gmns.NodeID]string, map[gmns.LinkID]string) {
+	macroKeys := newMacroStableKeys(macroNet)
+	linksKeys := make(map[gmns.LinkID]string, len(mesoNet.Links))
+	connections := make([]gmns.LinkID, 0)
+	for id, link := range mesoNet.Links {
+		if !link.IsConnection() {
+			linksKeys[id] = fmt.Sprintf("segment:%s|%d", macroKeys.links[link.MacroLink()], link.SegmentIdx())
+			continue
+		}
+		connections = append(connections, id)
+	}
+	for _, id := range connections {
+		link := mesoNet.Links[id]
+		if mvmt, ok := movements[link.Movement()]; ok {
+			linksKeys[id] = "connection:" + macroKeys.movement(mvmt)
+			continue
+		}
+		linksKeys[id] = fmt.Sprintf("connection:%s|%s", linksKeys[link.MovementMesoLinkIncome()], linksKeys[link.MovementMesoLinkOutcome()])
+	}
+	nodesKeys := make(map[gmns.NodeID]string, len(mesoNet.Nodes))
+	for id, link := range mesoNet.Links {
+		if link.IsConnection() {
+			continue
+		}
+		setLeastKey(nodesKeys, link.SourceNode(), linksKeys[id]+"|source")
+		setLeastKey(nodesKeys, link.TargetNode(), linksKeys[id]+"|target")
+	}
+	for id, node := range mesoNet.Nodes {
+		if _, ok := nodesKeys[id]; !ok {
+			pt := node.Geom()
+			nodesKeys[id] = fmt.Sprintf("node_geom:%f,%f", pt[0], pt[1])
+		}
+	}
+	return nodesKeys, linksKeys
+}
+
+// setLeastKey sets key of the node if the node has no key yet or the given key is less than the current one
+func setLeastKey(keys map[gmns.NodeID]string, nodeID gmns.NodeID, key string) {
+	if current, ok := keys[nodeID]; !ok || key < current {
+		keys[nodeID] = key
+	}
+}
+
+// microStableKeys returns content keys of microscopic nodes and links. Key of the node is key of its mesoscopic link, lane and index of the cell,
+// key of the link is keys of its end nodes and type of the cell
+func microStableKeys(mesoLinksKeys map[gmns.LinkID]string, microNet *micro.Net) (map[gmns.NodeID]string, map[gmns.LinkID]string) {
+	nodesKeys := make(map[gmns.NodeID]string, len(microNet.Nodes))
+	for id, node := range microNet.Nodes {
+		nodesKeys[id] = fmt.Sprintf("cell:%s|%d|%d", mesoLinksKeys[node.MesoLink()], node.LaneID(), node.CellIndex())
+	}
+	linksKeys := make(map[gmns.LinkID]string, len(microNet.Links))
+	for id, link := range microNet.Links {
+		linksKeys[id] = fmt.Sprintf("cell_link:%s>%s|%s", nodesKeys[link.SourceNode()], nodesKeys[link.TargetNode()], link.CellType())
+	}
+	return nodesKeys, linksKeys
+}
+
+// applyStableMovementsIDs renumbers movements with identifiers derived from their content keys
+func applyStableMovementsIDs(macroNet *macro.Net, movements movement.MovementsStorage) error {
+	macroKeys := newMacroStableKeys(macroNet)
+	keys := make(map[int64]string, len(movements))
+	for id, mvmt := range movements {
+		keys[int64(id)] = macroKeys.movement(mvmt)
+	}
+	ids := make(map[gmns.MovementID]gmns.MovementID, len(keys))
+	for id, stableID := range stableIDs(keys) {
+		ids[gmns.MovementID(id)] = gmns.MovementID(stableID)
+	}
+	return errors.Wrap(movements.Renumber(ids), "Can't renumber movements")
+}
+
+// applyStableMesoIDs renumbers mesoscopic nodes and links with identifiers derived from their content keys
+func applyStableMesoIDs(macroNet *macro.Net, mesoNet *meso.Net, movements movement.MovementsStorage) error {
+	nodesKeys, linksKeys := mesoStableKeys(macroNet, mesoNet, movements)
+	nodesIDs, linksIDs := stableNodesLinksIDs(nodesKeys, linksKeys)
+	return errors.Wrap(mesoNet.Renumber(nodesIDs, linksIDs), "Can't renumber mesoscopic network")
+}
+
+// applyStableMicroIDs renumbers microscopic nodes and links with identifiers derived from their content keys
+func applyStableMicroIDs(macroNet *macro.Net, mesoNet *meso.Net, microNet *micro.Net, movements movement.MovementsStorage) error {
+	_, mesoLinksKeys := mesoStableKeys(macroNet, mesoNet, movements)
+	nodesKeys, linksKeys := microStableKeys(mesoLinksKeys, microNet)
+	nodesIDs, linksIDs := stableNodesLinksIDs(nodesKeys, linksKeys)
+	return errors.Wrap(microNet.Renumber(nodesIDs, linksIDs), "Can't renumber microscopic network")
+}
+
+// stableNodesLinksIDs returns mappings from current identifiers of nodes and links to the stable ones
+func stableNodesLinksIDs(nodesKeys map[gmns.NodeID]string, linksKeys map[gmns.LinkID]string) (map[gmns.NodeID]gmns.NodeID, map[gmns.LinkID]gmns.LinkID) {
+	keys := make(map[int64]string, len(nodesKeys))
+	for id, key := range nodesKeys {
+		keys[int64(id)] = key
+	}
+	nodesIDs := make(map[gmns.NodeID]gmns.NodeID, len(nodesKeys))
+	for id, stableID := range stableIDs(keys) {
+		nodesIDs[gmns.NodeID(id)] = gmns.NodeID(stableID)
+	}
+	keys = make(map[int64]string, len(linksKeys))
+	for id, key := range linksKeys {
+		keys[int64(id)] = key
+	}
+	linksIDs := make(map[gmns.LinkID]gmns.LinkID, len(linksKeys))
+	for id, stableID := range stableIDs(keys) {
+		linksIDs[gmns.LinkID(id)] = gmns.LinkID(stableID)
+	}
+	return nodesIDs, linksIDs
+}
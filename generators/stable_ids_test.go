@@ -0,0 +1,106 @@
+package generators
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/LdDl/go-gmns/meso"
+	"github.com/LdDl/go-gmns/micro"
+	"github.com/LdDl/go-gmns/movement"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStableIDs(t *testing.T) {
+	generate := func(macroNet *macro.Net) (movement.MovementsStorage, *meso.Net, *micro.Net) {
+		movementsOpts := DefaultMovementsGenOptions()
+		movementsOpts.StableIDs = true
+		movements, err := GenerateMovements(macroNet, movementsOpts)
+		assert.NoError(t, err)
+		mesoOpts := DefaultMesoGenOptions()
+		mesoOpts.Verbose = false
+		mesoOpts.StableIDs = true
+		mesoNet, err := GenerateMesoscopic(macroNet, movements, mesoOpts)
+		assert.NoError(t, err)
+		microOpts := DefaultMicroGenOptions()
+		microOpts.SeparateBikeWalk = true
+		microOpts.StableIDs = true
+		microNet, err := GenerateMicroscopic(macroNet, mesoNet, movements, microOpts)
+		assert.NoError(t, err)
+		return movements, mesoNet, microNet
+	}
+	// Identifiers of elements by their descriptions. Elements with ambiguous descriptions are marked with -1
+	describe := func(macroNet *macro.Net, movements movement.MovementsStorage, mesoNet *meso.Net, microNet *micro.Net) map[string]int {
+		ans := make(map[string]int)
+		add := func(description string, id int) {
+			if _, ok := ans[description]; ok {
+				ans[description] = -1
+				return
+			}
+			ans[description] = id
+		}
+		for _, mvmt := range movements {
+			add(fmt.Sprintf("movement %v %v", macroNet.Links[mvmt.IncomeMacroLink()].Geom(), macroNet.Links[mvmt.OutcomeMacroLink()].Geom()), int(mvmt.ID))
+		}
+		for _, node := range mesoNet.Nodes {
+			add(fmt.Sprintf("meso node %v", node.Geom()), int(node.ID))
+		}
+		for _, link := range mesoNet.Links {
+			add(fmt.Sprintf("meso link %v %d %v", link.Geom(), link.LanesNum(), link.IsConnection()), int(link.ID))
+		}
+		for _, node := range microNet.Nodes {
+			add(fmt.Sprintf("micro node %v %d %d", node.Geom(), node.LaneID(), node.CellIndex()), int(node.ID))
+		}
+		for _, link := range microNet.Links {
+			add(fmt.Sprintf("micro link %v %v %d", link.Geom(), link.CellType(), link.LaneID()), int(link.ID))
+		}
+		return ans
+	}
+
+	macroNet := gridNet(5)
+	movements, mesoNet, microNet := generate(macroNet)
+	expected := describe(macroNet, movements, mesoNet, microNet)
+	for id := range mesoNet.Links {
+		assert.Greater(t, id, gmns.LinkID(0))
+	}
+
+	// The same network with the link removed: elements are processed in different order and get different sequential identifiers
+	editedNet := gridNet(5)
+	for linkID, link := range editedNet.Links {
+		if link.SourceNode() == 0 && link.TargetNode() == 1 {
+			assert.NoError(t, editedNet.DeleteLink(linkID))
+			break
+		}
+	}
+	editedMovements, editedMesoNet, editedMicroNet := generate(editedNet)
+	actual := describe(editedNet, editedMovements, editedMesoNet, editedMicroNet)
+
+	unchanged := 0
+	for description, id := range actual {
+		expectedID, ok := expected[description]
+		if !ok || id < 0 || expectedID < 0 {
+			continue
+		}
+		assert.Equal(t, expectedID, id, "Element should keep identifier: %s", description)
+		unchanged++
+	}
+	assert.Greater(t, unchanged, len(actual)/2)
+}
+
+func TestStableIDsCollisions(t *testing.T) {
+	ids := stableIDs(map[int64]string{1: "b", 2: "a", 3: "a", 4: "c"})
+	assert.Len(t, ids, 4)
+	used := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		assert.Greater(t, id, int64(0))
+		assert.Less(t, id, stableIDsSpace)
+		used[id] = struct{}{}
+	}
+	assert.Len(t, used, 4)
+	// Equal keys are resolved by probing in order of current identifiers
+	assert.Equal(t, ids[2]%(stableIDsSpace-1)+1, ids[3])
+	// Identifiers depend on keys only
+	other := stableIDs(map[int64]string{10: "c", 7: "a", 8: "a", 9: "b"})
+	assert.Equal(t, []int64{ids[1], ids[2], ids[3], ids[4]}, []int64{other[9], other[7], other[8], other[10]})
+}
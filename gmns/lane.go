@@ -0,0 +1,46 @@
+package gmns
+
+import "github.com/LdDl/go-gmns/gmns/types"
+
+// LaneID is just type alias for the lane identifier
+type LaneID int
+
+// Lane is attributes of the single lane of the link (GMNS lane table).
+// Lanes are numbered the same way as in macro.Link.GetOutcomingLaneIndices: 1, 2, ... from the inner side, negative numbers for the inner pockets and numbers above lanes number for the outer pockets
+type Lane struct {
+	ID     LaneID
+	LinkID LinkID
+	// Lane number relative to the centerline
+	LaneNum int
+	// Width in meters. Non-positive value means that default width should be used
+	Width float64
+	// Allowed agent types. Empty set means that agent types are inherited from the link
+	AllowedAgentTypes []types.AgentType
+	Designation       types.LaneDesignation
+}
+
+// NewLane returns lane attributes with default values for the given lane of the link
+func NewLane(linkID LinkID, laneNum int) Lane {
+	return Lane{
+		ID:                LaneID(-1),
+		LinkID:            linkID,
+		LaneNum:           laneNum,
+		Width:             -1,
+		AllowedAgentTypes: []types.AgentType{},
+		Designation:       types.LANE_DESIGNATION_GENERAL,
+	}
+}
+
+// Clone returns deep copy of the lane attributes
+func (lane Lane) Clone() Lane {
+	lane.AllowedAgentTypes = append([]types.AgentType{}, lane.AllowedAgentTypes...)
+	return lane
+}
+
+// WidthOrDefault returns width of the lane or the given default one if width is not set
+func (lane Lane) WidthOrDefault(defaultWidth float64) float64 {
+	if lane.Width <= 0 {
+		return defaultWidth
+	}
+	return lane.Width
+}
@@ -12,9 +12,17 @@ const (
 	ACCESS_SERVICE
 	ACCESS_BICYCLE
 	ACCESS_FOOT
+	ACCESS_PSV
+	ACCESS_BUS
+	ACCESS_HGV
+	ACCESS_HOV
+	ACCESS_TAXI
+	ACCESS_EMERGENCY
+	ACCESS_MAXWEIGHT
+	ACCESS_MAXHEIGHT
 )
 
-var accessTypeStr = []string{"undefined", "highway", "motor_vehicle", "motorcar", "access", "service", "bicycle", "foot"}
+var accessTypeStr = []string{"undefined", "highway", "motor_vehicle", "motorcar", "access", "service", "bicycle", "foot", "psv", "bus", "hgv", "hov", "taxi", "emergency", "maxweight", "maxheight"}
 
 func (iotaIdx AccessType) String() string {
 	return accessTypeStr[iotaIdx]
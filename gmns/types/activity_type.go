@@ -1,5 +1,7 @@
 package types
 
+import "strings"
+
 // ActivityType is just type alias for the activity type
 type ActivityType uint16
 
@@ -14,3 +16,14 @@ var activityTypeStr = []string{"none", "poi", "link"}
 func (iotaIdx ActivityType) String() string {
 	return activityTypeStr[iotaIdx]
 }
+
+// NewActivityTypeFrom returns activity type for the given string. Outputs ACTIVITY_NONE for unknown values
+func NewActivityTypeFrom(str string) ActivityType {
+	str = strings.ToLower(strings.TrimSpace(str))
+	for i, value := range activityTypeStr {
+		if str == value {
+			return ActivityType(i)
+		}
+	}
+	return ACTIVITY_NONE
+}
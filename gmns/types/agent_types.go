@@ -1,5 +1,10 @@
 package types
 
+import (
+	"strconv"
+	"strings"
+)
+
 // AgentType is just type alias for the agent type
 type AgentType uint16
 
@@ -8,21 +13,64 @@ const (
 	AGENT_AUTO
 	AGENT_BIKE
 	AGENT_WALK
+	AGENT_BUS
+	AGENT_TRUCK
+	AGENT_HOV
+	AGENT_TAXI
+	AGENT_EMERGENCY
 )
 
-var agentTypeStr = []string{"undefined", "auto", "bike", "walk"}
+var agentTypeStr = []string{"undefined", "auto", "bike", "walk", "bus", "truck", "hov", "taxi", "emergency"}
+
+const (
+	// Trucks are not allowed on links with "maxweight" less than this value [tonnes]
+	TRUCK_WEIGHT_DEFAULT = 7.5
+	// Trucks are not allowed on links with "maxheight" less than this value [meters]
+	TRUCK_HEIGHT_DEFAULT = 4.0
+)
 
 func (iotaIdx AgentType) String() string {
 	return agentTypeStr[iotaIdx]
 }
 
+// NewAgentTypeFrom returns agent type for the given string. Outputs AGENT_UNDEFINED for unknown values
+func NewAgentTypeFrom(str string) AgentType {
+	str = strings.ToLower(strings.TrimSpace(str))
+	for i, agentType := range agentTypeStr {
+		if str == agentType {
+			return AgentType(i)
+		}
+	}
+	return AGENT_UNDEFINED
+}
+
 var (
 	agentTypesAll = map[AgentType]struct{}{
-		AGENT_AUTO: {},
-		AGENT_BIKE: {},
-		AGENT_WALK: {},
+		AGENT_AUTO:      {},
+		AGENT_BIKE:      {},
+		AGENT_WALK:      {},
+		AGENT_BUS:       {},
+		AGENT_TRUCK:     {},
+		AGENT_HOV:       {},
+		AGENT_TAXI:      {},
+		AGENT_EMERGENCY: {},
+	}
+	sortedAgentTypes         = []AgentType{AGENT_AUTO, AGENT_BIKE, AGENT_WALK}
+	sortedAgentTypesExtended = []AgentType{AGENT_AUTO, AGENT_BIKE, AGENT_WALK, AGENT_BUS, AGENT_TRUCK, AGENT_HOV, AGENT_TAXI, AGENT_EMERGENCY}
+
+	// Highway types which are not allowed for any motorized agent
+	motorizedHighwayExcludeValues = map[string]struct{}{
+		"cycleway":      {},
+		"footway":       {},
+		"pedestrian":    {},
+		"steps":         {},
+		"track":         {},
+		"corridor":      {},
+		"elevator":      {},
+		"escalator":     {},
+		"service":       {},
+		"living_street": {},
 	}
-	sortedAgentTypes = []AgentType{AGENT_AUTO, AGENT_BIKE, AGENT_WALK}
 
 	AGENT_TYPES_DEFAULT = []AgentType{AGENT_AUTO}
 
@@ -45,6 +93,47 @@ var (
 				"yes": struct{}{},
 			},
 		},
+		AGENT_BUS: {
+			ACCESS_BUS: {
+				"yes":        struct{}{},
+				"designated": struct{}{},
+			},
+			ACCESS_PSV: {
+				"yes":        struct{}{},
+				"designated": struct{}{},
+			},
+		},
+		AGENT_TRUCK: {
+			ACCESS_HGV: {
+				"yes":         struct{}{},
+				"designated":  struct{}{},
+				"delivery":    struct{}{},
+				"destination": struct{}{},
+			},
+		},
+		AGENT_HOV: {
+			ACCESS_HOV: {
+				"yes":        struct{}{},
+				"designated": struct{}{},
+				"lane":       struct{}{},
+			},
+		},
+		AGENT_TAXI: {
+			ACCESS_TAXI: {
+				"yes":        struct{}{},
+				"designated": struct{}{},
+			},
+			ACCESS_PSV: {
+				"yes":        struct{}{},
+				"designated": struct{}{},
+			},
+		},
+		AGENT_EMERGENCY: {
+			ACCESS_EMERGENCY: {
+				"yes":        struct{}{},
+				"designated": struct{}{},
+			},
+		},
 	}
 
 	agentsAccessExcludeValues = map[AgentType]map[AccessType]map[string]struct{}{
@@ -116,6 +205,90 @@ var (
 				"private": struct{}{},
 			},
 		},
+		AGENT_BUS: {
+			ACCESS_HIGHWAY: motorizedHighwayExcludeValues,
+			ACCESS_BUS: {
+				"no": struct{}{},
+			},
+			ACCESS_PSV: {
+				"no": struct{}{},
+			},
+			ACCESS_MOTOR_VEHICLE: {
+				"no": struct{}{},
+			},
+			ACCESS_OSM_ACCESS: {
+				"private": struct{}{},
+			},
+		},
+		AGENT_TRUCK: {
+			ACCESS_HIGHWAY: motorizedHighwayExcludeValues,
+			ACCESS_HGV: {
+				"no": struct{}{},
+			},
+			ACCESS_MOTOR_VEHICLE: {
+				"no": struct{}{},
+			},
+			ACCESS_OSM_ACCESS: {
+				"private": struct{}{},
+			},
+			ACCESS_SERVICE: {
+				"parking":          struct{}{},
+				"parking_aisle":    struct{}{},
+				"driveway":         struct{}{},
+				"private":          struct{}{},
+				"emergency_access": struct{}{},
+			},
+		},
+		AGENT_HOV: {
+			ACCESS_HIGHWAY: motorizedHighwayExcludeValues,
+			ACCESS_HOV: {
+				"no": struct{}{},
+			},
+			ACCESS_MOTOR_VEHICLE: {
+				"no": struct{}{},
+			},
+			ACCESS_MOTORCAR: {
+				"no": struct{}{},
+			},
+			ACCESS_OSM_ACCESS: {
+				"private": struct{}{},
+			},
+			ACCESS_SERVICE: {
+				"parking":          struct{}{},
+				"parking_aisle":    struct{}{},
+				"driveway":         struct{}{},
+				"private":          struct{}{},
+				"emergency_access": struct{}{},
+			},
+		},
+		AGENT_TAXI: {
+			ACCESS_HIGHWAY: motorizedHighwayExcludeValues,
+			ACCESS_TAXI: {
+				"no": struct{}{},
+			},
+			ACCESS_PSV: {
+				"no": struct{}{},
+			},
+			ACCESS_MOTOR_VEHICLE: {
+				"no": struct{}{},
+			},
+			ACCESS_OSM_ACCESS: {
+				"private": struct{}{},
+			},
+			ACCESS_SERVICE: {
+				"parking":          struct{}{},
+				"parking_aisle":    struct{}{},
+				"driveway":         struct{}{},
+				"private":          struct{}{},
+				"emergency_access": struct{}{},
+			},
+		},
+		AGENT_EMERGENCY: {
+			ACCESS_HIGHWAY: motorizedHighwayExcludeValues,
+			ACCESS_EMERGENCY: {
+				"no": struct{}{},
+			},
+		},
 	}
 )
 
@@ -142,17 +315,55 @@ func AgentsIntersection(left []AgentType, right []AgentType) map[AgentType]struc
 	return intersection
 }
 
+// AccessTags is set of OSM tags values which affect allowed agent types. Empty value means that tag is absent
+type AccessTags struct {
+	Highway      string
+	Access       string
+	Service      string
+	MotorVehicle string
+	Motorcar     string
+	Bicycle      string
+	Foot         string
+	PSV          string
+	Bus          string
+	HGV          string
+	HOV          string
+	Taxi         string
+	Emergency    string
+	MaxWeight    string
+	MaxHeight    string
+}
+
+// NewAllowableAgentTypeFrom returns allowed basic agent types (auto, bike, walk) for the given OSM tags values. See NewAllowableAgentTypeFromTags for the extended set of agent types
 func NewAllowableAgentTypeFrom(motorVehicle, motorcar, bicycle, foot, highway, access, service string) (allowedAgents []AgentType) {
-	for _, agentType := range sortedAgentTypes {
+	tags := AccessTags{
+		Highway:      highway,
+		Access:       access,
+		Service:      service,
+		MotorVehicle: motorVehicle,
+		Motorcar:     motorcar,
+		Bicycle:      bicycle,
+		Foot:         foot,
+	}
+	return allowableAgentTypes(tags, sortedAgentTypes)
+}
+
+// NewAllowableAgentTypeFromTags returns allowed agent types (including bus, truck, HOV, taxi and emergency) for the given OSM tags values
+func NewAllowableAgentTypeFromTags(tags AccessTags) []AgentType {
+	return allowableAgentTypes(tags, sortedAgentTypesExtended)
+}
+
+func allowableAgentTypes(tags AccessTags, candidates []AgentType) (allowedAgents []AgentType) {
+	for _, agentType := range candidates {
 		if _, ok := agentTypesAll[agentType]; !ok {
 			continue
 		}
-		included := findIncludedAgent(motorVehicle, motorcar, bicycle, foot, agentType)
+		included := findIncludedAgent(tags, agentType)
 		if included {
 			allowedAgents = append(allowedAgents, agentType)
 			continue
 		}
-		excluded := findExcludedAgent(motorVehicle, motorcar, bicycle, foot, highway, access, service, agentType)
+		excluded := findExcludedAgent(tags, agentType)
 		if excluded {
 			allowedAgents = append(allowedAgents, agentType)
 			continue
@@ -161,7 +372,7 @@ func NewAllowableAgentTypeFrom(motorVehicle, motorcar, bicycle, foot, highway, a
 	return allowedAgents
 }
 
-func findIncludedAgent(motorVehicle, motorcar, bicycle, foot string, agentType AgentType) bool {
+func findIncludedAgent(tags AccessTags, agentType AgentType) bool {
 	accessType, ok := agentsAccessIncludeValues[agentType]
 	if !ok {
 		return false
@@ -169,21 +380,52 @@ func findIncludedAgent(motorVehicle, motorcar, bicycle, foot string, agentType A
 	switch agentType {
 	case AGENT_AUTO:
 		// Check `motor_vehicle`
-		if _, ok := accessType[ACCESS_MOTOR_VEHICLE][motorVehicle]; ok {
+		if _, ok := accessType[ACCESS_MOTOR_VEHICLE][tags.MotorVehicle]; ok {
 			return true
 		}
 		// Check `motorcar`
-		if _, ok := accessType[ACCESS_MOTORCAR][motorcar]; ok {
+		if _, ok := accessType[ACCESS_MOTORCAR][tags.Motorcar]; ok {
 			return true
 		}
 	case AGENT_BIKE:
 		// Check `bicycle`
-		if _, ok := accessType[ACCESS_BICYCLE][bicycle]; ok {
+		if _, ok := accessType[ACCESS_BICYCLE][tags.Bicycle]; ok {
 			return true
 		}
 	case AGENT_WALK:
 		// Check `foot`
-		if _, ok := accessType[ACCESS_FOOT][foot]; ok {
+		if _, ok := accessType[ACCESS_FOOT][tags.Foot]; ok {
+			return true
+		}
+	case AGENT_BUS:
+		// Check `bus`, then `psv` if there is no specific tag
+		if _, ok := accessType[ACCESS_BUS][tags.Bus]; ok {
+			return true
+		}
+		if _, ok := accessType[ACCESS_PSV][tags.PSV]; ok && tags.Bus == "" {
+			return true
+		}
+	case AGENT_TRUCK:
+		// Check `hgv`
+		if _, ok := accessType[ACCESS_HGV][tags.HGV]; ok {
+			return true
+		}
+	case AGENT_HOV:
+		// Check `hov`
+		if _, ok := accessType[ACCESS_HOV][tags.HOV]; ok {
+			return true
+		}
+	case AGENT_TAXI:
+		// Check `taxi`, then `psv` if there is no specific tag
+		if _, ok := accessType[ACCESS_TAXI][tags.Taxi]; ok {
+			return true
+		}
+		if _, ok := accessType[ACCESS_PSV][tags.PSV]; ok && tags.Taxi == "" {
+			return true
+		}
+	case AGENT_EMERGENCY:
+		// Check `emergency`
+		if _, ok := accessType[ACCESS_EMERGENCY][tags.Emergency]; ok {
 			return true
 		}
 	default:
@@ -192,7 +434,7 @@ func findIncludedAgent(motorVehicle, motorcar, bicycle, foot string, agentType A
 	return false
 }
 
-func findExcludedAgent(motorVehicle, motorcar, bicycle, foot, highway, access, service string, agentType AgentType) bool {
+func findExcludedAgent(tags AccessTags, agentType AgentType) bool {
 	accessType, ok := agentsAccessExcludeValues[agentType]
 	if !ok {
 		return true
@@ -200,57 +442,114 @@ func findExcludedAgent(motorVehicle, motorcar, bicycle, foot, highway, access, s
 	switch agentType {
 	case AGENT_AUTO:
 		// Check `highway`
-		if _, ok := accessType[ACCESS_HIGHWAY][highway]; ok {
+		if _, ok := accessType[ACCESS_HIGHWAY][tags.Highway]; ok {
 			return false
 		}
 		// Check `motor_vehicle`
-		if _, ok := accessType[ACCESS_MOTOR_VEHICLE][motorVehicle]; ok {
+		if _, ok := accessType[ACCESS_MOTOR_VEHICLE][tags.MotorVehicle]; ok {
 			return false
 		}
 		// Check `motorcar`
-		if _, ok := accessType[ACCESS_MOTORCAR][motorcar]; ok {
+		if _, ok := accessType[ACCESS_MOTORCAR][tags.Motorcar]; ok {
 			return false
 		}
 		// Check `access`
-		if _, ok := accessType[ACCESS_OSM_ACCESS][access]; ok {
+		if _, ok := accessType[ACCESS_OSM_ACCESS][tags.Access]; ok {
 			return false
 		}
 		// Check `service`
-		if _, ok := accessType[ACCESS_SERVICE][service]; ok {
+		if _, ok := accessType[ACCESS_SERVICE][tags.Service]; ok {
 			return false
 		}
 	case AGENT_BIKE:
 		// Check `highway`
-		if _, ok := accessType[ACCESS_HIGHWAY][highway]; ok {
+		if _, ok := accessType[ACCESS_HIGHWAY][tags.Highway]; ok {
 			return false
 		}
 		// Check `bicycle`
-		if _, ok := accessType[ACCESS_BICYCLE][bicycle]; ok {
+		if _, ok := accessType[ACCESS_BICYCLE][tags.Bicycle]; ok {
 			return false
 		}
 		// Check `service`
-		if _, ok := accessType[ACCESS_SERVICE][service]; ok {
+		if _, ok := accessType[ACCESS_SERVICE][tags.Service]; ok {
 			return false
 		}
 		// Check `access`
-		if _, ok := accessType[ACCESS_OSM_ACCESS][access]; ok {
+		if _, ok := accessType[ACCESS_OSM_ACCESS][tags.Access]; ok {
 			return false
 		}
 	case AGENT_WALK:
 		// Check `highway`
-		if _, ok := accessType[ACCESS_HIGHWAY][highway]; ok {
+		if _, ok := accessType[ACCESS_HIGHWAY][tags.Highway]; ok {
 			return false
 		}
 		// Check `foot`
-		if _, ok := accessType[ACCESS_FOOT][foot]; ok {
+		if _, ok := accessType[ACCESS_FOOT][tags.Foot]; ok {
 			return false
 		}
 		// Check `service`
-		if _, ok := accessType[ACCESS_SERVICE][service]; ok {
+		if _, ok := accessType[ACCESS_SERVICE][tags.Service]; ok {
 			return false
 		}
 		// Check `access`
-		if _, ok := accessType[ACCESS_OSM_ACCESS][access]; ok {
+		if _, ok := accessType[ACCESS_OSM_ACCESS][tags.Access]; ok {
+			return false
+		}
+	case AGENT_BUS, AGENT_TRUCK, AGENT_HOV, AGENT_TAXI:
+		// Check `highway`, `motor_vehicle`, `motorcar`, `access` and `service`
+		if _, ok := accessType[ACCESS_HIGHWAY][tags.Highway]; ok {
+			return false
+		}
+		if _, ok := accessType[ACCESS_MOTOR_VEHICLE][tags.MotorVehicle]; ok {
+			return false
+		}
+		if _, ok := accessType[ACCESS_MOTORCAR][tags.Motorcar]; ok {
+			return false
+		}
+		if _, ok := accessType[ACCESS_OSM_ACCESS][tags.Access]; ok {
+			return false
+		}
+		if _, ok := accessType[ACCESS_SERVICE][tags.Service]; ok {
+			return false
+		}
+		// Check agent specific tags
+		switch agentType {
+		case AGENT_BUS:
+			if _, ok := accessType[ACCESS_BUS][tags.Bus]; ok {
+				return false
+			}
+			if _, ok := accessType[ACCESS_PSV][tags.PSV]; ok && tags.Bus == "" {
+				return false
+			}
+		case AGENT_TRUCK:
+			if _, ok := accessType[ACCESS_HGV][tags.HGV]; ok {
+				return false
+			}
+			// Check `maxweight` and `maxheight`
+			if maxWeight, ok := parseMaxWeight(tags.MaxWeight); ok && maxWeight < TRUCK_WEIGHT_DEFAULT {
+				return false
+			}
+			if maxHeight, ok := parseMaxHeight(tags.MaxHeight); ok && maxHeight < TRUCK_HEIGHT_DEFAULT {
+				return false
+			}
+		case AGENT_HOV:
+			if _, ok := accessType[ACCESS_HOV][tags.HOV]; ok {
+				return false
+			}
+		case AGENT_TAXI:
+			if _, ok := accessType[ACCESS_TAXI][tags.Taxi]; ok {
+				return false
+			}
+			if _, ok := accessType[ACCESS_PSV][tags.PSV]; ok && tags.Taxi == "" {
+				return false
+			}
+		}
+	case AGENT_EMERGENCY:
+		// Emergency vehicles ignore general access restrictions. Check `highway` and `emergency` only
+		if _, ok := accessType[ACCESS_HIGHWAY][tags.Highway]; ok {
+			return false
+		}
+		if _, ok := accessType[ACCESS_EMERGENCY][tags.Emergency]; ok {
 			return false
 		}
 	default:
@@ -259,3 +558,50 @@ func findExcludedAgent(motorVehicle, motorcar, bicycle, foot, highway, access, s
 
 	return true
 }
+
+// parseMaxWeight parses value of OSM "maxweight" tag into tonnes. Supports plain numbers, "t" and "lbs" units
+func parseMaxWeight(value string) (float64, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(value, "lbs"):
+		value = strings.TrimSuffix(value, "lbs")
+		multiplier = 0.00045359237
+	case strings.HasSuffix(value, "st"):
+		// Short tons
+		value = strings.TrimSuffix(value, "st")
+		multiplier = 0.90718474
+	case strings.HasSuffix(value, "t"):
+		value = strings.TrimSuffix(value, "t")
+	}
+	weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || weight <= 0 {
+		return 0, false
+	}
+	return weight * multiplier, true
+}
+
+// parseMaxHeight parses value of OSM "maxheight" tag into meters. Supports plain numbers, "m" unit and feet/inches notation (e.g. 12'6")
+func parseMaxHeight(value string) (float64, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if feetIdx := strings.Index(value, "'"); feetIdx >= 0 {
+		feet, err := strconv.ParseFloat(strings.TrimSpace(value[:feetIdx]), 64)
+		if err != nil {
+			return 0, false
+		}
+		inches := 0.0
+		inchesStr := strings.TrimSpace(strings.TrimSuffix(value[feetIdx+1:], "\""))
+		if inchesStr != "" {
+			inches, err = strconv.ParseFloat(inchesStr, 64)
+			if err != nil {
+				return 0, false
+			}
+		}
+		return feet*0.3048 + inches*0.0254, feet > 0 || inches > 0
+	}
+	height, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, "m")), 64)
+	if err != nil || height <= 0 {
+		return 0, false
+	}
+	return height, true
+}
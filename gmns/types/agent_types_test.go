@@ -0,0 +1,37 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowableAgentTypeFromTags(t *testing.T) {
+	agents := NewAllowableAgentTypeFromTags(AccessTags{Highway: "primary"})
+	assert.Equal(t, []AgentType{AGENT_AUTO, AGENT_BIKE, AGENT_WALK, AGENT_BUS, AGENT_TRUCK, AGENT_HOV, AGENT_TAXI, AGENT_EMERGENCY}, agents)
+
+	agents = NewAllowableAgentTypeFromTags(AccessTags{Highway: "residential", MaxWeight: "3.5 t", MaxHeight: "12'6\""})
+	assert.NotContains(t, agents, AGENT_TRUCK)
+
+	agents = NewAllowableAgentTypeFromTags(AccessTags{Highway: "service", Service: "bus", Access: "no", PSV: "designated", Foot: "no", Bicycle: "no"})
+	assert.Equal(t, []AgentType{AGENT_BUS, AGENT_TAXI}, agents)
+
+	agents = NewAllowableAgentTypeFromTags(AccessTags{Highway: "primary", Access: "private", Bus: "no", PSV: "yes"})
+	assert.Equal(t, []AgentType{AGENT_TAXI, AGENT_EMERGENCY}, agents)
+
+	agents = NewAllowableAgentTypeFromTags(AccessTags{Highway: "primary", HGV: "no"})
+	assert.Equal(t, []AgentType{AGENT_AUTO, AGENT_BIKE, AGENT_WALK, AGENT_BUS, AGENT_HOV, AGENT_TAXI, AGENT_EMERGENCY}, agents)
+
+	agents = NewAllowableAgentTypeFromTags(AccessTags{Highway: "primary", Access: "private", HOV: "designated"})
+	assert.Equal(t, []AgentType{AGENT_HOV, AGENT_EMERGENCY}, agents)
+
+	// Plain number is in tonnes
+	agents = NewAllowableAgentTypeFromTags(AccessTags{Highway: "primary", MaxWeight: "5"})
+	assert.NotContains(t, agents, AGENT_TRUCK)
+	agents = NewAllowableAgentTypeFromTags(AccessTags{Highway: "primary", MaxWeight: "10"})
+	assert.Contains(t, agents, AGENT_TRUCK)
+
+	// Basic agent types only
+	agents = NewAllowableAgentTypeFrom("", "", "", "", "primary", "", "")
+	assert.Equal(t, []AgentType{AGENT_AUTO, AGENT_BIKE, AGENT_WALK}, agents)
+}
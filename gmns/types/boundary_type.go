@@ -1,5 +1,7 @@
 package types
 
+import "strings"
+
 // BoundaryType is just type alias for the boundary type
 type BoundaryType uint16
 
@@ -15,3 +17,14 @@ var boundaryTypeStr = []string{"none", "income_only", "outcome_only", "income_ou
 func (iotaIdx BoundaryType) String() string {
 	return boundaryTypeStr[iotaIdx]
 }
+
+// NewBoundaryTypeFrom returns boundary type for the given string. Outputs BOUNDARY_NONE for unknown values
+func NewBoundaryTypeFrom(str string) BoundaryType {
+	str = strings.ToLower(strings.TrimSpace(str))
+	for i, value := range boundaryTypeStr {
+		if str == value {
+			return BoundaryType(i)
+		}
+	}
+	return BOUNDARY_NONE
+}
@@ -10,9 +10,13 @@ const (
 	CELL_FORWARD
 	// CELL_LANE_CHANGE is a lane changing cell (movement between lanes)
 	CELL_LANE_CHANGE
+	// CELL_SIDEWALK is a pedestrian cell along the road or around the corner of the intersection
+	CELL_SIDEWALK
+	// CELL_CROSSWALK is a pedestrian cell crossing the road at the intersection
+	CELL_CROSSWALK
 )
 
-var cellTypeStr = [...]string{"undefined", "forward", "lane_change"}
+var cellTypeStr = [...]string{"undefined", "forward", "lane_change", "sidewalk", "crosswalk"}
 
 // String returns the string representation of CellType
 func (iotaIdx CellType) String() string {
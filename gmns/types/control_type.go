@@ -1,5 +1,7 @@
 package types
 
+import "strings"
+
 // ControlType is just type alias for the control type
 type ControlType uint16
 
@@ -13,3 +15,14 @@ var controlTypeStr = []string{"common", "signal"}
 func (iotaIdx ControlType) String() string {
 	return controlTypeStr[iotaIdx]
 }
+
+// NewControlTypeFrom returns control type for the given string. Unknown values are treated as common (not signalized) control
+func NewControlTypeFrom(str string) ControlType {
+	str = strings.ToLower(strings.TrimSpace(str))
+	for i, value := range controlTypeStr {
+		if str == value {
+			return ControlType(i)
+		}
+	}
+	return CONTROL_TYPE_NOT_SIGNAL
+}
@@ -0,0 +1,28 @@
+package types
+
+import "strings"
+
+// DrivingSide is just type alias for the side of the road which vehicles keep to
+type DrivingSide uint16
+
+const (
+	DRIVING_SIDE_RIGHT = DrivingSide(iota)
+	DRIVING_SIDE_LEFT
+)
+
+var drivingSideStr = []string{"right", "left"}
+
+func (iotaIdx DrivingSide) String() string {
+	return drivingSideStr[iotaIdx]
+}
+
+// NewDrivingSideFrom returns driving side for the given string. Unknown values are treated as right-hand traffic
+func NewDrivingSideFrom(str string) DrivingSide {
+	str = strings.ToLower(strings.TrimSpace(str))
+	for i, value := range drivingSideStr {
+		if str == value {
+			return DrivingSide(i)
+		}
+	}
+	return DRIVING_SIDE_RIGHT
+}
@@ -0,0 +1,39 @@
+package types
+
+import "strings"
+
+// LaneChange is just type alias for the lane change permission. It is a bit mask of allowed directions (relative to the driver)
+type LaneChange uint8
+
+const (
+	LANE_CHANGE_NONE  = LaneChange(0)
+	LANE_CHANGE_LEFT  = LaneChange(1 << 0)
+	LANE_CHANGE_RIGHT = LaneChange(1 << 1)
+	LANE_CHANGE_BOTH  = LANE_CHANGE_LEFT | LANE_CHANGE_RIGHT
+)
+
+var laneChangeStr = []string{"no", "only_left", "only_right", "yes"}
+
+// String returns OSM notation of the lane change permission (as for "change:lanes" tag)
+func (iotaIdx LaneChange) String() string {
+	return laneChangeStr[iotaIdx&LANE_CHANGE_BOTH]
+}
+
+// Has checks whether changing lane in the given direction is allowed
+func (iotaIdx LaneChange) Has(direction LaneChange) bool {
+	return iotaIdx&direction == direction
+}
+
+// NewLaneChangeFrom returns lane change permission for the single lane value of OSM "change:lanes" tag. Unknown and empty values allow both directions
+func NewLaneChangeFrom(str string) LaneChange {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "no":
+		return LANE_CHANGE_NONE
+	case "not_left", "only_right":
+		return LANE_CHANGE_RIGHT
+	case "not_right", "only_left":
+		return LANE_CHANGE_LEFT
+	default:
+		return LANE_CHANGE_BOTH
+	}
+}
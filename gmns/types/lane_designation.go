@@ -0,0 +1,30 @@
+package types
+
+import "strings"
+
+// LaneDesignation is just type alias for the special purpose of the lane
+type LaneDesignation uint16
+
+const (
+	LANE_DESIGNATION_GENERAL = LaneDesignation(iota)
+	LANE_DESIGNATION_BUS
+	LANE_DESIGNATION_HOV
+	LANE_DESIGNATION_BIKE
+)
+
+var laneDesignationStr = []string{"general", "bus", "hov", "bike"}
+
+func (iotaIdx LaneDesignation) String() string {
+	return laneDesignationStr[iotaIdx]
+}
+
+// NewLaneDesignationFrom returns lane designation for the given string. Unknown values are treated as general purpose lanes
+func NewLaneDesignationFrom(str string) LaneDesignation {
+	str = strings.ToLower(strings.TrimSpace(str))
+	for i, designation := range laneDesignationStr {
+		if str == designation {
+			return LaneDesignation(i)
+		}
+	}
+	return LANE_DESIGNATION_GENERAL
+}
@@ -1,5 +1,7 @@
 package types
 
+import "strings"
+
 // LinkClass is just type alias for the link class
 type LinkClass uint16
 
@@ -15,3 +17,14 @@ var linkClassStr = []string{"undefined", "highway", "railway", "aeroway"}
 func (iotaIdx LinkClass) String() string {
 	return linkClassStr[iotaIdx]
 }
+
+// NewLinkClassFrom returns link class for the given string. Outputs LINK_CLASS_UNDEFINED for unknown values
+func NewLinkClassFrom(str string) LinkClass {
+	str = strings.ToLower(strings.TrimSpace(str))
+	for i, value := range linkClassStr {
+		if str == value {
+			return LinkClass(i)
+		}
+	}
+	return LINK_CLASS_UNDEFINED
+}
@@ -1,5 +1,7 @@
 package types
 
+import "strings"
+
 // LinkConnectionType is just type alias for the link connection type
 type LinkConnectionType uint16
 
@@ -15,3 +17,14 @@ var linkConnectionTypeStr = []string{"no", "yes"}
 func (iotaIdx LinkConnectionType) String() string {
 	return linkConnectionTypeStr[iotaIdx]
 }
+
+// NewLinkConnectionTypeFrom returns link connection type for the given string. Outputs NOT_A_LINK for unknown values
+func NewLinkConnectionTypeFrom(str string) LinkConnectionType {
+	str = strings.ToLower(strings.TrimSpace(str))
+	for i, value := range linkConnectionTypeStr {
+		if str == value {
+			return LinkConnectionType(i)
+		}
+	}
+	return NOT_A_LINK
+}
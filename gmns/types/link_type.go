@@ -1,5 +1,7 @@
 package types
 
+import "strings"
+
 // LinkType is just type alias for the link type
 type LinkType uint16
 
@@ -157,3 +159,14 @@ func FindPriorLinkType(linkTypes []LinkType) LinkType {
 	}
 	return maxPriorityLink
 }
+
+// NewLinkTypeFrom returns link type for the given string. Outputs LINK_UNDEFINED for unknown values
+func NewLinkTypeFrom(str string) LinkType {
+	str = strings.ToLower(strings.TrimSpace(str))
+	for i, value := range linkTypeStr {
+		if str == value {
+			return LinkType(i)
+		}
+	}
+	return LINK_UNDEFINED
+}
@@ -0,0 +1,63 @@
+package types
+
+import "strings"
+
+// TurnDirection is set of turn designations for the single lane (bitmask). Zero value means that lane has no marking
+type TurnDirection uint16
+
+const (
+	TURN_NONE    = TurnDirection(0)
+	TURN_THROUGH = TurnDirection(1 << (iota - 1))
+	TURN_LEFT
+	TURN_SLIGHT_LEFT
+	TURN_SHARP_LEFT
+	TURN_RIGHT
+	TURN_SLIGHT_RIGHT
+	TURN_SHARP_RIGHT
+	TURN_REVERSE
+	TURN_MERGE_TO_LEFT
+	TURN_MERGE_TO_RIGHT
+)
+
+const (
+	// Any turn to the left
+	TURN_ANY_LEFT = TURN_LEFT | TURN_SLIGHT_LEFT | TURN_SHARP_LEFT
+	// Any turn to the right
+	TURN_ANY_RIGHT = TURN_RIGHT | TURN_SLIGHT_RIGHT | TURN_SHARP_RIGHT
+)
+
+var turnDirectionStr = []string{"through", "left", "slight_left", "sharp_left", "right", "slight_right", "sharp_right", "reverse", "merge_to_left", "merge_to_right"}
+
+// String returns designations in OSM notation separated by ';'. Outputs "none" for lane without marking
+func (turn TurnDirection) String() string {
+	if turn == TURN_NONE {
+		return "none"
+	}
+	designations := make([]string, 0, 1)
+	for i, designation := range turnDirectionStr {
+		if turn&(1<<i) != 0 {
+			designations = append(designations, designation)
+		}
+	}
+	return strings.Join(designations, ";")
+}
+
+// Has checks whether lane has any of the given designations
+func (turn TurnDirection) Has(other TurnDirection) bool {
+	return turn&other != 0
+}
+
+// NewTurnDirectionFrom parses lane designations in OSM notation (e.g. "through;right"). Unknown values are ignored
+func NewTurnDirectionFrom(designations string) TurnDirection {
+	turn := TURN_NONE
+	for _, designation := range strings.Split(designations, ";") {
+		designation = strings.ToLower(strings.TrimSpace(designation))
+		for i, str := range turnDirectionStr {
+			if designation == str {
+				turn |= 1 << i
+				break
+			}
+		}
+	}
+	return turn
+}
@@ -4,6 +4,8 @@ import (
 	"sort"
 
 	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/LdDl/go-gmns/movement"
 	"github.com/LdDl/go-gmns/utils"
 	"github.com/LdDl/go-gmns/utils/geomath"
 )
@@ -18,9 +20,14 @@ type ConnectionPair struct {
 	Second int
 }
 
-// GenerateSpansConnections generates connections between links for the span between roads
+// GenerateSpansConnections generates connections between links for the span between roads (right-hand traffic is assumed)
 func GenerateSpansConnections(outcomingLink *Link, incomingLinksList []*Link) [][]ConnectionPair {
-	// Sort outcoming links by angle in descending order (left to right)
+	return GenerateSpansConnectionsForSide(outcomingLink, incomingLinksList, types.DRIVING_SIDE_RIGHT)
+}
+
+// GenerateSpansConnectionsForSide generates connections between links for the span between roads for the given driving side
+func GenerateSpansConnectionsForSide(outcomingLink *Link, incomingLinksList []*Link, drivingSide types.DrivingSide) [][]ConnectionPair {
+	// Sort incoming links from the inner side to the curb side (left to right for right-hand traffic)
 	angles := make([]float64, len(incomingLinksList))
 	for i, inLink := range incomingLinksList {
 		inGeomEuclidean := inLink.GeomEuclidean()
@@ -44,9 +51,7 @@ func GenerateSpansConnections(outcomingLink *Link, incomingLinksList []*Link) []
 	for i := range indices {
 		indices[i] = i
 	}
-	sort.Slice(indices, func(i, j int) bool {
-		return angles[indices[i]] > angles[indices[j]]
-	})
+	sortIndicesByAngle(indices, angles, drivingSide)
 	incomingLinksSorted := make([]*Link, len(incomingLinksList))
 	for i := range incomingLinksSorted {
 		incomingLinksSorted[i] = incomingLinksList[indices[i]]
@@ -78,9 +83,23 @@ func GenerateSpansConnections(outcomingLink *Link, incomingLinksList []*Link) []
 	return connections
 }
 
-// GenerateIntersectionsConnections generates connections between links for the junctions of the roads
+// GenerateIntersectionsConnections generates connections between links for the junctions of the roads (right-hand traffic is assumed)
 func GenerateIntersectionsConnections(incomingLink *Link, outcomingLinks []*Link) [][]ConnectionPair {
-	// Sort outcoming links by angle in descending order (left to right)
+	return GenerateIntersectionsConnectionsForSide(incomingLink, outcomingLinks, types.DRIVING_SIDE_RIGHT)
+}
+
+// GenerateIntersectionsConnectionsForSide generates connections between links for the junctions of the roads for the given driving side.
+// Lane with index 0 is the inner one: the leftmost lane for right-hand traffic and the rightmost lane for left-hand traffic
+// Turn designations of the incoming link lanes (see WithTurnLanes) are honored when they are present, otherwise lanes are assigned by angular order of outcoming links
+func GenerateIntersectionsConnectionsForSide(incomingLink *Link, outcomingLinks []*Link, drivingSide types.DrivingSide) [][]ConnectionPair {
+	connections := intersectionsConnectionsByAngles(incomingLink, outcomingLinks, drivingSide)
+	applyTurnLanes(incomingLink, outcomingLinks, connections, drivingSide)
+	return connections
+}
+
+// intersectionsConnectionsByAngles assigns lanes of the incoming link to outcoming links by their angular order
+func intersectionsConnectionsByAngles(incomingLink *Link, outcomingLinks []*Link, drivingSide types.DrivingSide) [][]ConnectionPair {
+	// Sort outcoming links from the inner side to the curb side (left to right for right-hand traffic)
 	angles := make([]float64, len(outcomingLinks))
 	for i, outLink := range outcomingLinks {
 		inGeomEuclidean := incomingLink.GeomEuclidean()
@@ -104,9 +123,7 @@ func GenerateIntersectionsConnections(incomingLink *Link, outcomingLinks []*Link
 	for i := range indices {
 		indices[i] = i
 	}
-	sort.Slice(indices, func(i, j int) bool {
-		return angles[indices[i]] > angles[indices[j]]
-	})
+	sortIndicesByAngle(indices, angles, drivingSide)
 	outcomingLinksSorted := make([]*Link, len(outcomingLinks))
 	for i := range outcomingLinksSorted {
 		outcomingLinksSorted[i] = outcomingLinks[indices[i]]
@@ -235,3 +252,93 @@ func GenerateIntersectionsConnections(incomingLink *Link, outcomingLinks []*Link
 
 	return connections
 }
+
+// sortIndicesByAngle sorts indices by angle in descending order for right-hand traffic and in ascending order for left-hand traffic
+func sortIndicesByAngle(indices []int, angles []float64, drivingSide types.DrivingSide) {
+	if drivingSide == types.DRIVING_SIDE_LEFT {
+		sort.Slice(indices, func(i, j int) bool {
+			return angles[indices[i]] < angles[indices[j]]
+		})
+		return
+	}
+	sort.Slice(indices, func(i, j int) bool {
+		return angles[indices[i]] > angles[indices[j]]
+	})
+}
+
+// applyTurnLanes overrides connections for outcoming links which movements are matched by turn designations of the incoming link lanes.
+// Unmarked lanes match movements which have no explicitly designated lanes. Connections for unmatched outcoming links are kept as is.
+func applyTurnLanes(incomingLink *Link, outcomingLinks []*Link, connections [][]ConnectionPair, drivingSide types.DrivingSide) {
+	turnLanes := incomingLink.TurnLanes()
+	if !hasTurnDesignations(turnLanes) || len(turnLanes) != incomingLink.GetOutcomingLanes() {
+		return
+	}
+	designated := types.TURN_NONE
+	for _, turn := range turnLanes {
+		designated |= turn
+	}
+	inGeomEuclidean := incomingLink.GeomEuclidean()
+	if len(inGeomEuclidean) == 0 {
+		// If no euclidean geom has been provided, try to get one
+		inGeomEuclidean = geomath.LineToEuclidean(incomingLink.Geom())
+	}
+	for i, outLink := range outcomingLinks {
+		outGeomEuclidean := outLink.GeomEuclidean()
+		if len(outGeomEuclidean) == 0 {
+			// If no euclidean geom has been provided, try to get one
+			outGeomEuclidean = geomath.LineToEuclidean(outLink.Geom())
+		}
+		_, mvmtType := movement.FindMovementTypeForSide(inGeomEuclidean, outGeomEuclidean, drivingSide)
+		required := turnDirectionsForMovement(mvmtType)
+		// Take the first continuous group of matching lanes
+		first, last := -1, -1
+		for laneIdx, turn := range turnLanes {
+			matched := turn.Has(required) || (turn == types.TURN_NONE && !designated.Has(required))
+			if matched && first < 0 {
+				first = laneIdx
+			}
+			if !matched && first >= 0 {
+				break
+			}
+			if matched {
+				last = laneIdx
+			}
+		}
+		if first < 0 {
+			continue
+		}
+		outLanes := outLink.GetIncomingLanes()
+		lanesNum := min(last-first+1, outLanes)
+		innerTurn := mvmtType == movement.MOVEMENT_TYPE_U_TURN ||
+			(drivingSide == types.DRIVING_SIDE_RIGHT && mvmtType == movement.MOVEMENT_TYPE_LEFT) ||
+			(drivingSide == types.DRIVING_SIDE_LEFT && mvmtType == movement.MOVEMENT_TYPE_RIGHT)
+		if innerTurn {
+			// Inner lanes go to the inner lanes of the outcoming link
+			connections[i] = []ConnectionPair{
+				{first, first + lanesNum - 1},
+				{0, lanesNum - 1},
+			}
+			continue
+		}
+		connections[i] = []ConnectionPair{
+			{last - lanesNum + 1, last},
+			{outLanes - lanesNum, outLanes - 1},
+		}
+	}
+}
+
+// turnDirectionsForMovement returns lane designations which allow the given movement type
+func turnDirectionsForMovement(mvmtType movement.MovementType) types.TurnDirection {
+	switch mvmtType {
+	case movement.MOVEMENT_TYPE_THRU:
+		return types.TURN_THROUGH | types.TURN_MERGE_TO_LEFT | types.TURN_MERGE_TO_RIGHT
+	case movement.MOVEMENT_TYPE_LEFT:
+		return types.TURN_ANY_LEFT
+	case movement.MOVEMENT_TYPE_RIGHT:
+		return types.TURN_ANY_RIGHT
+	case movement.MOVEMENT_TYPE_U_TURN:
+		return types.TURN_REVERSE
+	default:
+		return types.TURN_NONE
+	}
+}
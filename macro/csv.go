@@ -0,0 +1,500 @@
+package macro
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/LdDl/go-gmns/utils/geomath"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/wkt"
+	"github.com/paulmach/orb/geo"
+	"github.com/paulmach/osm"
+	"github.com/pkg/errors"
+)
+
+var nodesCSVHeader = []string{"node_id", "name", "osm_node_id", "osm_highway", "zone_id", "ctrl_type", "activity_type", "activity_link_type", "boundary_type", "intersection_id", "poi_id", "x_coord", "y_coord"}
+
+var linksCSVHeader = []string{"link_id", "name", "osm_way_id", "from_node_id", "to_node_id", "from_osm_node_id", "to_osm_node_id", "length", "lanes", "free_speed", "max_speed", "capacity", "link_class", "is_link", "link_type", "ctrl_type", "allowed_uses", "was_bidirectional", "turn_lanes", "change_lanes", "left_pocket_lanes", "left_pocket_length", "right_pocket_lanes", "right_pocket_length", "geometry"}
+
+// WriteNodesCSV writes nodes of the network as GMNS node table (node.csv) sorted by identifiers
+func WriteNodesCSV(w io.Writer, net *Net) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(nodesCSVHeader)
+	if err != nil {
+		return errors.Wrap(err, "Can't write nodes header")
+	}
+	nodesIDs := make([]gmns.NodeID, 0, len(net.Nodes))
+	for nodeID := range net.Nodes {
+		nodesIDs = append(nodesIDs, nodeID)
+	}
+	sort.Slice(nodesIDs, func(i, j int) bool {
+		return nodesIDs[i] < nodesIDs[j]
+	})
+	for _, nodeID := range nodesIDs {
+		node := net.Nodes[nodeID]
+		err = writer.Write([]string{
+			strconv.Itoa(int(node.ID)),
+			node.Name(),
+			strconv.FormatInt(int64(node.OSMNode()), 10),
+			node.OSMHighway(),
+			strconv.Itoa(int(node.Zone())),
+			node.ControlType().String(),
+			node.ActivityType().String(),
+			node.ActivityLinkType().String(),
+			node.BoundaryType().String(),
+			strconv.Itoa(node.Intersection()),
+			strconv.Itoa(int(node.POI())),
+			formatFloat(node.Geom().X()),
+			formatFloat(node.Geom().Y()),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Can't write node %d", node.ID)
+		}
+	}
+	writer.Flush()
+	return errors.Wrap(writer.Error(), "Can't flush nodes")
+}
+
+// WriteLinksCSV writes links of the network as GMNS link table (link.csv) sorted by identifiers. Geometry is written as WKT
+func WriteLinksCSV(w io.Writer, net *Net) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(linksCSVHeader)
+	if err != nil {
+		return errors.Wrap(err, "Can't write links header")
+	}
+	linksIDs := make([]gmns.LinkID, 0, len(net.Links))
+	for linkID := range net.Links {
+		linksIDs = append(linksIDs, linkID)
+	}
+	sort.Slice(linksIDs, func(i, j int) bool {
+		return linksIDs[i] < linksIDs[j]
+	})
+	for _, linkID := range linksIDs {
+		link := net.Links[linkID]
+		allowedUses := make([]string, len(link.AllowedAgentTypes()))
+		for i, agentType := range link.AllowedAgentTypes() {
+			allowedUses[i] = agentType.String()
+		}
+		turnLanes := make([]string, len(link.TurnLanes()))
+		for i, turn := range link.TurnLanes() {
+			turnLanes[i] = turn.String()
+		}
+		laneChanges := make([]string, len(link.LaneChanges()))
+		for i, laneChange := range link.LaneChanges() {
+			laneChanges[i] = laneChange.String()
+		}
+		pockets := link.TurnPockets()
+		err = writer.Write([]string{
+			strconv.Itoa(int(link.ID)),
+			link.Name(),
+			strconv.FormatInt(int64(link.OSMWay()), 10),
+			strconv.Itoa(int(link.SourceNode())),
+			strconv.Itoa(int(link.TargetNode())),
+			strconv.FormatInt(int64(link.SourceOSMNode()), 10),
+			strconv.FormatInt(int64(link.TargetOSMNode()), 10),
+			formatFloat(link.LengthMeters()),
+			strconv.Itoa(link.LanesNum()),
+			formatFloat(link.FreeSpeed()),
+			formatFloat(link.MaxSpeed()),
+			strconv.Itoa(link.Capacity()),
+			link.LinkClass().String(),
+			link.LinkConnectionType().String(),
+			link.LinkType().String(),
+			link.ControlType().String(),
+			strings.Join(allowedUses, ","),
+			strconv.FormatBool(link.WasBidirectional()),
+			strings.Join(turnLanes, "|"),
+			strings.Join(laneChanges, "|"),
+			strconv.Itoa(pockets.Left.Lanes),
+			formatFloat(pockets.Left.Length),
+			strconv.Itoa(pockets.Right.Lanes),
+			formatFloat(pockets.Right.Length),
+			wkt.MarshalString(link.Geom()),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Can't write link %d", link.ID)
+		}
+	}
+	writer.Flush()
+	return errors.Wrap(writer.Error(), "Can't flush links")
+}
+
+// ReadNodesCSV reads GMNS node table (node.csv) and adds nodes to the network.
+// Columns "node_id", "x_coord" and "y_coord" are required, the rest ones are optional
+func ReadNodesCSV(r io.Reader, net *Net) error {
+	table, err := newCSVTable(r, "nodes", []string{"node_id", "x_coord", "y_coord"})
+	if err != nil {
+		return err
+	}
+	for {
+		attrs, err := table.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		node, err := attrs.node()
+		if err != nil {
+			return err
+		}
+		err = net.addReadNode(node, attrs)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadLinksCSV reads GMNS link table (link.csv) and adds links to the network. Source and target nodes must exist already: links are appended to their lists.
+// Columns "link_id", "from_node_id" and "to_node_id" are required, the rest ones are optional. Straight geometry between nodes is used if "geometry" (WKT) is empty,
+// length is calculated from geometry if "length" is empty. Lanes information is built from number of lanes and turn pockets
+func ReadLinksCSV(r io.Reader, net *Net) error {
+	table, err := newCSVTable(r, "links", []string{"link_id", "from_node_id", "to_node_id"})
+	if err != nil {
+		return err
+	}
+	for {
+		attrs, err := table.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		link, err := attrs.link(net)
+		if err != nil {
+			return err
+		}
+		err = net.addReadLink(link, attrs)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// node builds the node from attributes. Coordinates are taken from geometry if it is given or from "x_coord" and "y_coord" otherwise
+func (attrs *attributes) node() (*Node, error) {
+	nodeID, err := attrs.int("node_id")
+	if err != nil {
+		return nil, err
+	}
+	pt, ok := attrs.geom.(orb.Point)
+	if !ok {
+		pt[0], err = attrs.float("x_coord")
+		if err != nil {
+			return nil, err
+		}
+		pt[1], err = attrs.float("y_coord")
+		if err != nil {
+			return nil, err
+		}
+	}
+	osmNodeID, err := attrs.intOr("osm_node_id", -1)
+	if err != nil {
+		return nil, err
+	}
+	zoneID, err := attrs.intOr("zone_id", -1)
+	if err != nil {
+		return nil, err
+	}
+	intersectionID, err := attrs.intOr("intersection_id", -1)
+	if err != nil {
+		return nil, err
+	}
+	poiID, err := attrs.intOr("poi_id", -1)
+	if err != nil {
+		return nil, err
+	}
+	node := NewNodeFrom(
+		gmns.NodeID(nodeID),
+		WithNodeName(attrs.value("name")),
+		WithOSMNodeID(osm.NodeID(osmNodeID)),
+		WithOSMHighwayTag(attrs.value("osm_highway")),
+		WithZoneID(gmns.NodeID(zoneID)),
+		WithNodeControlType(types.NewControlTypeFrom(attrs.value("ctrl_type"))),
+		WithActivityType(types.NewActivityTypeFrom(attrs.value("activity_type"))),
+		WithActivityLinkType(types.NewLinkTypeFrom(attrs.value("activity_link_type"))),
+		WithBoundaryType(types.NewBoundaryTypeFrom(attrs.value("boundary_type"))),
+		WithIntersectionID(intersectionID),
+		WithPOI(gmns.PoiID(poiID)),
+		WithPointGeom(pt),
+		WithPointGeomEuclidean(geomath.PointToEuclidean(pt)),
+	)
+	return node, nil
+}
+
+// addReadNode adds the node read from attributes to the network
+func (net *Net) addReadNode(node *Node, attrs *attributes) error {
+	if _, ok := net.Nodes[node.ID]; ok {
+		return errors.Wrapf(ErrNodeExists, "Node ID: %d. Source: %s %d", node.ID, attrs.source, attrs.num)
+	}
+	net.Nodes[node.ID] = node
+	return nil
+}
+
+// addReadLink adds the link read from attributes to the network and appends it to lists of its source and target nodes
+func (net *Net) addReadLink(link *Link, attrs *attributes) error {
+	if _, ok := net.Links[link.ID]; ok {
+		return errors.Wrapf(ErrLinkExists, "Link ID: %d. Source: %s %d", link.ID, attrs.source, attrs.num)
+	}
+	net.Links[link.ID] = link
+	WithOutcomingLinks(link.ID)(net.Nodes[link.SourceNode()])
+	WithIncomingLinks(link.ID)(net.Nodes[link.TargetNode()])
+	return nil
+}
+
+// link builds the link from attributes. Source and target nodes must exist in the network
+func (attrs *attributes) link(net *Net) (*Link, error) {
+	linkID, err := attrs.int("link_id")
+	if err != nil {
+		return nil, err
+	}
+	sourceNodeID, err := attrs.int("from_node_id")
+	if err != nil {
+		return nil, err
+	}
+	targetNodeID, err := attrs.int("to_node_id")
+	if err != nil {
+		return nil, err
+	}
+	sourceNode, ok := net.Nodes[gmns.NodeID(sourceNodeID)]
+	if !ok {
+		return nil, errors.Wrapf(ErrNodeNotFound, "Source node ID: %d. Source: %s %d", sourceNodeID, attrs.source, attrs.num)
+	}
+	targetNode, ok := net.Nodes[gmns.NodeID(targetNodeID)]
+	if !ok {
+		return nil, errors.Wrapf(ErrNodeNotFound, "Target node ID: %d. Source: %s %d", targetNodeID, attrs.source, attrs.num)
+	}
+	geom := orb.LineString{sourceNode.Geom(), targetNode.Geom()}
+	if lineGeom, ok := attrs.geom.(orb.LineString); ok && len(lineGeom) > 1 {
+		geom = lineGeom
+	} else if geomStr := attrs.value("geometry"); geomStr != "" {
+		geom, err = wkt.UnmarshalLineString(geomStr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Can't parse geometry on %s %d", attrs.source, attrs.num)
+		}
+	}
+	lengthMeters, err := attrs.floatOr("length", geo.LengthHaversine(geom))
+	if err != nil {
+		return nil, err
+	}
+	lanesNum, err := attrs.intOr("lanes", 1)
+	if err != nil {
+		return nil, err
+	}
+	linkType := types.NewLinkTypeFrom(attrs.value("link_type"))
+	freeSpeed, err := attrs.floatOr("free_speed", types.NewSpeedDefault(linkType))
+	if err != nil {
+		return nil, err
+	}
+	maxSpeed, err := attrs.floatOr("max_speed", freeSpeed)
+	if err != nil {
+		return nil, err
+	}
+	capacity, err := attrs.intOr("capacity", types.NewCapacityDefault(linkType))
+	if err != nil {
+		return nil, err
+	}
+	osmWayID, err := attrs.intOr("osm_way_id", -1)
+	if err != nil {
+		return nil, err
+	}
+	sourceOSMNodeID, err := attrs.intOr("from_osm_node_id", int(sourceNode.OSMNode()))
+	if err != nil {
+		return nil, err
+	}
+	targetOSMNodeID, err := attrs.intOr("to_osm_node_id", int(targetNode.OSMNode()))
+	if err != nil {
+		return nil, err
+	}
+	pockets := TurnPockets{}
+	pockets.Left.Lanes, err = attrs.intOr("left_pocket_lanes", 0)
+	if err != nil {
+		return nil, err
+	}
+	pockets.Left.Length, err = attrs.floatOr("left_pocket_length", 0)
+	if err != nil {
+		return nil, err
+	}
+	pockets.Right.Lanes, err = attrs.intOr("right_pocket_lanes", 0)
+	if err != nil {
+		return nil, err
+	}
+	pockets.Right.Length, err = attrs.floatOr("right_pocket_length", 0)
+	if err != nil {
+		return nil, err
+	}
+	allowedAgentTypes := []types.AgentType{}
+	for _, use := range strings.Split(attrs.value("allowed_uses"), ",") {
+		if agentType := types.NewAgentTypeFrom(use); agentType != types.AGENT_UNDEFINED {
+			allowedAgentTypes = append(allowedAgentTypes, agentType)
+		}
+	}
+	turnLanes := []types.TurnDirection{}
+	if turnLanesStr := attrs.value("turn_lanes"); turnLanesStr != "" {
+		for _, turn := range strings.Split(turnLanesStr, "|") {
+			turnLanes = append(turnLanes, types.NewTurnDirectionFrom(turn))
+		}
+	}
+	laneChanges := []types.LaneChange{}
+	if laneChangesStr := attrs.value("change_lanes"); laneChangesStr != "" {
+		for _, laneChange := range strings.Split(laneChangesStr, "|") {
+			laneChanges = append(laneChanges, types.NewLaneChangeFrom(laneChange))
+		}
+	}
+	wasBidirectional := false
+	if wasBidirectionalStr := attrs.value("was_bidirectional"); wasBidirectionalStr != "" {
+		wasBidirectional, err = strconv.ParseBool(wasBidirectionalStr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Can't parse 'was_bidirectional' on %s %d", attrs.source, attrs.num)
+		}
+	}
+	link := NewLinkFrom(
+		gmns.LinkID(linkID),
+		gmns.NodeID(sourceNodeID),
+		gmns.NodeID(targetNodeID),
+		WithLinkName(attrs.value("name")),
+		WithOSMWayID(osm.WayID(osmWayID)),
+		WithSourceOSMNodeID(osm.NodeID(sourceOSMNodeID)),
+		WithTargetOSMNodeID(osm.NodeID(targetOSMNodeID)),
+		WithLineGeom(geom),
+		WithLineGeomEuclidean(geomath.LineToEuclidean(geom)),
+		WithLengthMeters(lengthMeters),
+		WithLanesNum(lanesNum),
+		WithFreeSpeed(freeSpeed),
+		WithMaxSpeed(maxSpeed),
+		WithCapacity(capacity),
+		WithLinkClass(types.NewLinkClassFrom(attrs.value("link_class"))),
+		WithLinkConnectionType(types.NewLinkConnectionTypeFrom(attrs.value("is_link"))),
+		WithLinkType(linkType),
+		WithLinkControlType(types.NewControlTypeFrom(attrs.value("ctrl_type"))),
+		WithAllowedAgentTypes(allowedAgentTypes),
+		WithBidirectionalSource(wasBidirectional),
+		WithTurnLanes(turnLanes),
+		WithLaneChanges(laneChanges),
+	)
+	WithLanesInfo(NewLanesInfoWithPockets(link, pockets))(link)
+	return link, nil
+}
+
+// csvTable reads rows of CSV table with named columns
+type csvTable struct {
+	name    string
+	reader  *csv.Reader
+	columns map[string]int
+	num     int
+}
+
+// newCSVTable reads header of the table and checks that required columns are present
+func newCSVTable(r io.Reader, name string, required []string) (*csvTable, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't read %s header", name)
+	}
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))] = i
+	}
+	for _, column := range required {
+		if _, ok := columns[column]; !ok {
+			return nil, errors.Wrapf(ErrCSVColumnAbsent, "Table: %s. Column: '%s'", name, column)
+		}
+	}
+	return &csvTable{
+		name:    name,
+		reader:  reader,
+		columns: columns,
+		num:     1,
+	}, nil
+}
+
+// next returns the next row of the table. Returns io.EOF when there are no rows left
+func (table *csvTable) next() (*attributes, error) {
+	record, err := table.reader.Read()
+	if err == io.EOF {
+		return nil, err
+	}
+	table.num++
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't read %s row %d", table.name, table.num)
+	}
+	return newCSVAttributes(table, record), nil
+}
+
+// attributes are named values of the network element: row of CSV table or properties of GeoJSON feature
+type attributes struct {
+	// Source of the attributes for error messages: "nodes row", "links row", "feature"
+	source string
+	num    int
+	values map[string]string
+	// Geometry of the element if it is given separately from values (GeoJSON)
+	geom orb.Geometry
+}
+
+// newCSVAttributes returns attributes of the record of the table
+func newCSVAttributes(table *csvTable, record []string) *attributes {
+	values := make(map[string]string, len(table.columns))
+	for column, idx := range table.columns {
+		if idx < len(record) {
+			values[column] = strings.TrimSpace(record[idx])
+		}
+	}
+	return &attributes{
+		source: table.name + " row",
+		num:    table.num,
+		values: values,
+	}
+}
+
+// value returns value of the attribute or empty string if there is no such attribute
+func (attrs *attributes) value(name string) string {
+	return attrs.values[name]
+}
+
+// int returns integer value of the required attribute
+func (attrs *attributes) int(name string) (int, error) {
+	value, err := strconv.Atoi(attrs.value(name))
+	if err != nil {
+		return 0, errors.Wrapf(err, "Can't parse '%s' on %s %d", name, attrs.source, attrs.num)
+	}
+	return value, nil
+}
+
+// intOr returns integer value of the optional attribute or the default one if the value is empty
+func (attrs *attributes) intOr(name string, defaultValue int) (int, error) {
+	if attrs.value(name) == "" {
+		return defaultValue, nil
+	}
+	return attrs.int(name)
+}
+
+// float returns float value of the required attribute
+func (attrs *attributes) float(name string) (float64, error) {
+	value, err := strconv.ParseFloat(attrs.value(name), 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Can't parse '%s' on %s %d", name, attrs.source, attrs.num)
+	}
+	return value, nil
+}
+
+// floatOr returns float value of the optional attribute or the default one if the value is empty
+func (attrs *attributes) floatOr(name string, defaultValue float64) (float64, error) {
+	if attrs.value(name) == "" {
+		return defaultValue, nil
+	}
+	return attrs.float(name)
+}
+
+// formatFloat returns the shortest representation of the float
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
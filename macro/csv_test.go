@@ -0,0 +1,94 @@
+package macro
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetCSV(t *testing.T) {
+	nodes := "node_id,name,osm_node_id,ctrl_type,x_coord,y_coord\n" +
+		"1,A,101,signal,37.6,55.75\n" +
+		"2,,,,37.601,55.75\n"
+	links := "link_id,from_node_id,to_node_id,lanes,free_speed,link_type,allowed_uses,turn_lanes,left_pocket_lanes,left_pocket_length,geometry\n" +
+		"7,1,2,2,40,secondary,\"auto,bike\",left|through|through;right,1,20,\"LINESTRING(37.6 55.75,37.6005 55.7501,37.601 55.75)\"\n" +
+		"8,2,1,1,,primary,,,,,\n"
+	net := NewNet()
+	assert.NoError(t, ReadNodesCSV(strings.NewReader(nodes), net))
+	assert.NoError(t, ReadLinksCSV(strings.NewReader(links), net))
+	assert.Empty(t, net.Validate())
+
+	assert.Equal(t, "A", net.Nodes[1].Name())
+	assert.Equal(t, types.CONTROL_TYPE_IS_SIGNAL, net.Nodes[1].ControlType())
+	assert.Equal(t, []int{7}, toInts(net.Nodes[1].OutcomingLinks()))
+	link := net.Links[7]
+	assert.Len(t, link.Geom(), 3)
+	assert.Equal(t, types.LINK_SECONDARY, link.LinkType())
+	assert.Equal(t, []types.AgentType{types.AGENT_AUTO, types.AGENT_BIKE}, link.AllowedAgentTypes())
+	assert.Len(t, link.TurnLanes(), 3)
+	assert.Equal(t, TurnPockets{Left: TurnPocket{Lanes: 1, Length: 20}}, link.TurnPockets())
+	assert.Equal(t, types.NewSpeedDefault(types.LINK_PRIMARY), net.Links[8].FreeSpeed(), "Default free speed is expected for empty value")
+	assert.InDelta(t, 62.65, net.Links[8].LengthMeters(), 0.01, "Length is expected to be computed from geometry")
+
+	// Written tables are read back into the same tables
+	var nodesBuf, linksBuf bytes.Buffer
+	assert.NoError(t, WriteNodesCSV(&nodesBuf, net))
+	assert.NoError(t, WriteLinksCSV(&linksBuf, net))
+	netCopy := NewNet()
+	assert.NoError(t, ReadNodesCSV(bytes.NewReader(nodesBuf.Bytes()), netCopy))
+	assert.NoError(t, ReadLinksCSV(bytes.NewReader(linksBuf.Bytes()), netCopy))
+	var nodesCopyBuf, linksCopyBuf bytes.Buffer
+	assert.NoError(t, WriteNodesCSV(&nodesCopyBuf, netCopy))
+	assert.NoError(t, WriteLinksCSV(&linksCopyBuf, netCopy))
+	assert.Equal(t, nodesBuf.String(), nodesCopyBuf.String())
+	assert.Equal(t, linksBuf.String(), linksCopyBuf.String())
+
+	// The same for GeoJSON passed through encoding
+	data, err := json.Marshal(net.GeoFeatureCollection())
+	assert.NoError(t, err)
+	fc, err := geojson.UnmarshalFeatureCollection(data)
+	assert.NoError(t, err)
+	netGeo, err := NewNetFromGeoFeatureCollection(fc)
+	assert.NoError(t, err)
+	linksCopyBuf.Reset()
+	assert.NoError(t, WriteLinksCSV(&linksCopyBuf, netGeo))
+	assert.Equal(t, linksBuf.String(), linksCopyBuf.String())
+
+	err = ReadLinksCSV(strings.NewReader("link_id,from_node_id,to_node_id\n9,1,3\n"), net)
+	assert.ErrorIs(t, err, ErrNodeNotFound)
+	err = ReadLinksCSV(strings.NewReader("link_id,from_node_id,to_node_id\n7,1,2\n"), net)
+	assert.ErrorIs(t, err, ErrLinkExists)
+	err = ReadNodesCSV(strings.NewReader("node_id,x_coord\n3,37.6\n"), net)
+	assert.ErrorIs(t, err, ErrCSVColumnAbsent)
+	_, err = NewNetFromGeoFeatureCollection(&geojson.FeatureCollection{Features: []*geojson.Feature{geojson.NewFeature(orb.Polygon{})}})
+	assert.ErrorIs(t, err, ErrGeometryNotSupported)
+}
+
+func TestValidate(t *testing.T) {
+	net := NewNet()
+	nodes := "node_id,x_coord,y_coord\n1,37.6,55.75\n2,37.601,55.75\n"
+	links := "link_id,from_node_id,to_node_id,lanes,free_speed\n7,1,2,0,60\n8,2,2,1,60\n"
+	assert.NoError(t, ReadNodesCSV(strings.NewReader(nodes), net))
+	assert.NoError(t, ReadLinksCSV(strings.NewReader(links), net))
+	net.Nodes[1].removeOutcomingLink(7)
+	problems := net.Validate()
+	assert.Len(t, problems, 4)
+	assert.ErrorIs(t, problems[0], ErrLinkNotListed)
+	assert.ErrorIs(t, problems[1], ErrLinkNoLanes)
+	assert.ErrorIs(t, problems[2], ErrLinkLoop)
+	assert.ErrorIs(t, problems[3], ErrLinkBadLength)
+}
+
+func toInts[T ~int | ~int64](values []T) []int {
+	result := make([]int, len(values))
+	for i, value := range values {
+		result[i] = int(value)
+	}
+	return result
+}
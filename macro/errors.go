@@ -3,6 +3,20 @@ package macro
 import "fmt"
 
 var (
-	ErrLinkNotFound = fmt.Errorf("link not found")
-	ErrNodeNotFound = fmt.Errorf("node not found")
+	ErrLinkNotFound         = fmt.Errorf("link not found")
+	ErrNodeNotFound         = fmt.Errorf("node not found")
+	ErrLinkExists           = fmt.Errorf("link already exists")
+	ErrNodeExists           = fmt.Errorf("node already exists")
+	ErrLinkNotIncident      = fmt.Errorf("link is not incident to node")
+	ErrNotPassThrough       = fmt.Errorf("node is not a pass-through one")
+	ErrSplitOutOfRange      = fmt.Errorf("split position is out of link range")
+	ErrSplitEmptyGeom       = fmt.Errorf("can't split link with empty geometry")
+	ErrCSVColumnAbsent      = fmt.Errorf("required CSV column is absent")
+	ErrGeometryNotSupported = fmt.Errorf("geometry type is not supported")
+	ErrLinkNotListed        = fmt.Errorf("link is not listed in links of its node")
+	ErrLinkNoLanes          = fmt.Errorf("link has no lanes")
+	ErrLinkBadLength        = fmt.Errorf("link length is not positive")
+	ErrLinkBadGeom          = fmt.Errorf("link geometry has less than two points")
+	ErrLinkBadSpeed         = fmt.Errorf("link free speed is not positive")
+	ErrLinkLoop             = fmt.Errorf("link starts and ends at the same node")
 )
@@ -1,9 +1,15 @@
 package macro
 
 import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/paulmach/orb"
 	"github.com/paulmach/orb/geojson"
+	"github.com/pkg/errors"
 )
 
 // GeoFeature returns GeoJSON LineString feature for the given link
@@ -27,7 +33,24 @@ func (link *Link) GeoFeature() *geojson.Feature {
 	}
 	f.Properties["allowed_agent_types"] = strings.Join(allowedAgentTypesStrs, ",")
 	f.Properties["was_bidirectional"] = link.WasBidirectional()
+	turnLanes := link.TurnLanes()
+	turnLanesStrs := make([]string, len(turnLanes))
+	for i, turn := range turnLanes {
+		turnLanesStrs[i] = turn.String()
+	}
+	f.Properties["turn_lanes"] = strings.Join(turnLanesStrs, "|")
+	laneChanges := link.LaneChanges()
+	laneChangesStrs := make([]string, len(laneChanges))
+	for i, laneChange := range laneChanges {
+		laneChangesStrs[i] = laneChange.String()
+	}
+	f.Properties["change_lanes"] = strings.Join(laneChangesStrs, "|")
 	f.Properties["lanes"] = link.LanesNum()
+	pockets := link.TurnPockets()
+	f.Properties["left_pocket_lanes"] = pockets.Left.Lanes
+	f.Properties["left_pocket_length"] = pockets.Left.Length
+	f.Properties["right_pocket_lanes"] = pockets.Right.Lanes
+	f.Properties["right_pocket_length"] = pockets.Right.Length
 	f.Properties["max_speed"] = link.MaxSpeed()
 	f.Properties["free_speed"] = link.FreeSpeed()
 	f.Properties["capacity"] = link.Capacity()
@@ -65,3 +88,116 @@ func (net *Net) GeoFeatureCollection() *geojson.FeatureCollection {
 	}
 	return fc
 }
+
+// Properties of GeoJSON features which are named differently from columns of CSV tables
+var geoPropertiesAliases = map[string]string{
+	"source_node":         "from_node_id",
+	"target_node":         "to_node_id",
+	"source_osm_node_id":  "from_osm_node_id",
+	"target_osm_node_id":  "to_osm_node_id",
+	"control_type":        "ctrl_type",
+	"allowed_agent_types": "allowed_uses",
+	"length_meters":       "length",
+}
+
+// NewNetFromGeoFeatureCollection returns macroscopic network built from GeoJSON FeatureCollection with properties as GeoFeature() of nodes and links produces.
+// Point features are treated as nodes and LineString features as links. Properties absent in features are filled the same way as ReadNodesCSV and ReadLinksCSV do
+func NewNetFromGeoFeatureCollection(fc *geojson.FeatureCollection) (*Net, error) {
+	net := NewNet()
+	linksAttrs := []*attributes{}
+	for i, f := range fc.Features {
+		attrs, err := newGeoAttributes(f, i)
+		if err != nil {
+			return nil, err
+		}
+		switch f.Geometry.(type) {
+		case orb.Point:
+			attrs.values["node_id"] = attrs.values["id"]
+			node, err := attrs.node()
+			if err != nil {
+				return nil, err
+			}
+			err = net.addReadNode(node, attrs)
+			if err != nil {
+				return nil, err
+			}
+		case orb.LineString:
+			attrs.values["link_id"] = attrs.values["id"]
+			linksAttrs = append(linksAttrs, attrs)
+		case nil:
+			return nil, errors.Wrapf(ErrGeometryNotSupported, "Geometry: null. Source: %s %d", attrs.source, attrs.num)
+		default:
+			return nil, errors.Wrapf(ErrGeometryNotSupported, "Geometry: %s. Source: %s %d", f.Geometry.GeoJSONType(), attrs.source, attrs.num)
+		}
+	}
+	// Links are added after nodes since features could go in any order
+	for _, attrs := range linksAttrs {
+		link, err := attrs.link(net)
+		if err != nil {
+			return nil, err
+		}
+		err = net.addReadLink(link, attrs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, node := range net.Nodes {
+		sort.Slice(node.incomingLinks, func(i, j int) bool {
+			return node.incomingLinks[i] < node.incomingLinks[j]
+		})
+		sort.Slice(node.outcomingLinks, func(i, j int) bool {
+			return node.outcomingLinks[i] < node.outcomingLinks[j]
+		})
+	}
+	return net, nil
+}
+
+// newGeoAttributes returns attributes of the feature with properties converted to strings. Identifier of the feature is used if there is no "id" property
+func newGeoAttributes(f *geojson.Feature, num int) (*attributes, error) {
+	attrs := &attributes{
+		source: "feature",
+		num:    num,
+		values: make(map[string]string, len(f.Properties)+1),
+		geom:   f.Geometry,
+	}
+	if f.ID != nil {
+		value, err := geoPropertyString(f.ID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Can't parse identifier of feature %d", num)
+		}
+		attrs.values["id"] = value
+	}
+	for key, property := range f.Properties {
+		value, err := geoPropertyString(property)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Can't parse '%s' of feature %d", key, num)
+		}
+		if alias, ok := geoPropertiesAliases[key]; ok {
+			key = alias
+		}
+		attrs.values[key] = value
+	}
+	return attrs, nil
+}
+
+// geoPropertyString converts value of GeoJSON property to string. Numbers and booleans (including named types) are supported
+func geoPropertyString(property any) (string, error) {
+	if property == nil {
+		return "", nil
+	}
+	value := reflect.ValueOf(property)
+	switch value.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(value.String()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return formatFloat(value.Float()), nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", property)
+	}
+}
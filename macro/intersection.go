@@ -0,0 +1,340 @@
+package macro
+
+import (
+	"math"
+	"sort"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/LdDl/go-gmns/utils/geomath"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Default max distance [meters] between nodes of the same intersection
+	INTERSECTION_DISTANCE_DEFAULT = 20.0
+	// Approximate length of one degree of latitude [meters]
+	metersPerDegree = 111320.0
+)
+
+// ConsolidationOptions contains options for intersections consolidation
+type ConsolidationOptions struct {
+	// Max distance [meters] between two nodes to treat them as parts of the same intersection
+	DistanceThreshold float64
+	// Only signalized nodes are considered as parts of intersections
+	SignalizedOnly bool
+	// Replace every found intersection with the single node
+	Collapse bool
+}
+
+// DefaultConsolidationOptions returns default options for intersections consolidation
+func DefaultConsolidationOptions() ConsolidationOptions {
+	return ConsolidationOptions{
+		DistanceThreshold: INTERSECTION_DISTANCE_DEFAULT,
+		SignalizedOnly:    false,
+		Collapse:          false,
+	}
+}
+
+// Intersection is a group of macroscopic nodes forming the single complex junction (e.g. crossing of dual carriageways)
+type Intersection struct {
+	ID int
+	// Sorted identifiers of the grouped nodes
+	Nodes []gmns.NodeID
+	// Identifier of the node which the group has been collapsed into. Equals "-1" if the group has not been collapsed
+	CollapsedNode gmns.NodeID
+	// Identifiers of the links between grouped nodes. Those links are removed during collapse
+	RemovedLinks []gmns.LinkID
+	// Links which became parallel duplicates after collapse (the same source and target nodes) are merged into the link with the smallest identifier.
+	// Maps identifier of the removed duplicate to identifier of the kept link
+	MergedLinks map[gmns.LinkID]gmns.LinkID
+}
+
+// ConsolidateIntersections groups nodes of complex junctions and assigns common intersection identifier to every node in a group.
+// If Collapse option is set then every group is replaced by the single node (see CollapseIntersection).
+// Nodes which already have intersection identifier are grouped by it in the first place.
+// Network is left untouched if an error is returned
+func (net *Net) ConsolidateIntersections(opts ...ConsolidationOptions) ([]*Intersection, error) {
+	options := DefaultConsolidationOptions()
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	intersections := net.ClusterIntersections(options.DistanceThreshold, options.SignalizedOnly)
+	if options.Collapse {
+		// Every group is checked before the first collapse, so the network is either fully consolidated or left untouched
+		for _, intersection := range intersections {
+			if err := net.checkIntersection(intersection); err != nil {
+				return nil, errors.Wrapf(err, "Can't collapse intersection %d", intersection.ID)
+			}
+		}
+	}
+	for _, intersection := range intersections {
+		for _, nodeID := range intersection.Nodes {
+			WithIntersectionID(intersection.ID)(net.Nodes[nodeID])
+		}
+	}
+	if !options.Collapse {
+		return intersections, nil
+	}
+	for _, intersection := range intersections {
+		err := net.CollapseIntersection(intersection)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Can't collapse intersection %d", intersection.ID)
+		}
+	}
+	return intersections, nil
+}
+
+// ClusterIntersections finds groups of nodes which are closer than distanceThreshold [meters] to each other.
+// It does not modify the network. Only junction nodes (three or more adjacent nodes) are considered, or only signalized nodes if signalizedOnly is set.
+// Nodes sharing the same predefined intersection identifier always fall into the same group.
+func (net *Net) ClusterIntersections(distanceThreshold float64, signalizedOnly bool) []*Intersection {
+	candidates := make([]*Node, 0)
+	maxIntersectionID := -1
+	for _, node := range net.Nodes {
+		if node.intersectionID > maxIntersectionID {
+			maxIntersectionID = node.intersectionID
+		}
+		if net.isIntersectionCandidate(node, signalizedOnly) {
+			candidates = append(candidates, node)
+		}
+	}
+	// Sort by longitude to sweep through candidates
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].geom.Lon() == candidates[j].geom.Lon() {
+			return candidates[i].ID < candidates[j].ID
+		}
+		return candidates[i].geom.Lon() < candidates[j].geom.Lon()
+	})
+
+	parents := make([]int, len(candidates))
+	for i := range parents {
+		parents[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parents[i] != i {
+			parents[i] = find(parents[i])
+		}
+		return parents[i]
+	}
+	union := func(i, j int) {
+		rootI, rootJ := find(i), find(j)
+		if rootI != rootJ {
+			parents[rootJ] = rootI
+		}
+	}
+
+	predefined := make(map[int]int)
+	for i, node := range candidates {
+		if node.intersectionID < 0 {
+			continue
+		}
+		if first, ok := predefined[node.intersectionID]; ok {
+			union(first, i)
+			continue
+		}
+		predefined[node.intersectionID] = i
+	}
+	for i := range candidates {
+		pt := candidates[i].geom
+		window := distanceThreshold / (metersPerDegree * math.Max(math.Cos(pt.Lat()*math.Pi/180.0), 1e-6))
+		for j := i + 1; j < len(candidates) && candidates[j].geom.Lon()-pt.Lon() <= window; j++ {
+			if geo.DistanceHaversine(pt, candidates[j].geom) <= distanceThreshold {
+				union(i, j)
+			}
+		}
+	}
+
+	groups := make(map[int][]gmns.NodeID)
+	for i, node := range candidates {
+		root := find(i)
+		groups[root] = append(groups[root], node.ID)
+	}
+	intersections := make([]*Intersection, 0, len(groups))
+	for _, nodes := range groups {
+		if len(nodes) < 2 {
+			continue
+		}
+		sort.Slice(nodes, func(i, j int) bool {
+			return nodes[i] < nodes[j]
+		})
+		intersections = append(intersections, &Intersection{
+			ID:            -1,
+			Nodes:         nodes,
+			CollapsedNode: -1,
+			RemovedLinks:  make([]gmns.LinkID, 0),
+			MergedLinks:   make(map[gmns.LinkID]gmns.LinkID),
+		})
+	}
+	sort.Slice(intersections, func(i, j int) bool {
+		return intersections[i].Nodes[0] < intersections[j].Nodes[0]
+	})
+	// Keep predefined identifiers, enumerate the rest
+	for _, intersection := range intersections {
+		for _, nodeID := range intersection.Nodes {
+			predefinedID := net.Nodes[nodeID].intersectionID
+			if predefinedID >= 0 && (intersection.ID < 0 || predefinedID < intersection.ID) {
+				intersection.ID = predefinedID
+			}
+		}
+		if intersection.ID < 0 {
+			maxIntersectionID++
+			intersection.ID = maxIntersectionID
+		}
+	}
+	return intersections
+}
+
+// isIntersectionCandidate checks whether the given node could be a part of complex intersection
+func (net *Net) isIntersectionCandidate(node *Node, signalizedOnly bool) bool {
+	if node.boundaryType != types.BOUNDARY_NONE || node.isCentroid {
+		return false
+	}
+	if signalizedOnly {
+		return node.controlType == types.CONTROL_TYPE_IS_SIGNAL
+	}
+	if node.intersectionID >= 0 {
+		return true
+	}
+	neighbours := make(map[gmns.NodeID]struct{})
+	for _, linkID := range node.incomingLinks {
+		if link, ok := net.Links[linkID]; ok {
+			neighbours[link.sourceNodeID] = struct{}{}
+		}
+	}
+	for _, linkID := range node.outcomingLinks {
+		if link, ok := net.Links[linkID]; ok {
+			neighbours[link.targetNodeID] = struct{}{}
+		}
+	}
+	return len(neighbours) >= 3
+}
+
+// CollapseIntersection replaces nodes of the given intersection with the single node placed at their centroid.
+// The node with the smallest identifier is kept, the others are removed. Links between grouped nodes are removed too,
+// while links entering or leaving the intersection are re-attached to the kept node and their geometries are extended to its new position.
+// Re-attached links having the same source and target nodes are merged into the one with the smallest identifier (attributes of the kept link are not changed).
+// Kept node becomes signalized if any of grouped nodes is signalized. Network is left untouched if an error is returned
+func (net *Net) CollapseIntersection(intersection *Intersection) error {
+	if len(intersection.Nodes) == 0 {
+		return nil
+	}
+	members := make(map[gmns.NodeID]struct{}, len(intersection.Nodes))
+	centroid := orb.Point{0, 0}
+	controlType := types.CONTROL_TYPE_NOT_SIGNAL
+	if err := net.checkIntersection(intersection); err != nil {
+		return err
+	}
+	for _, nodeID := range intersection.Nodes {
+		node := net.Nodes[nodeID]
+		members[nodeID] = struct{}{}
+		centroid[0] += node.geom.Lon()
+		centroid[1] += node.geom.Lat()
+		if node.controlType == types.CONTROL_TYPE_IS_SIGNAL {
+			controlType = types.CONTROL_TYPE_IS_SIGNAL
+		}
+	}
+	centroid[0] /= float64(len(intersection.Nodes))
+	centroid[1] /= float64(len(intersection.Nodes))
+
+	// Collect every link incident to the intersection
+	incidentLinks := make(map[gmns.LinkID]struct{})
+	for _, nodeID := range intersection.Nodes {
+		node := net.Nodes[nodeID]
+		for _, linkID := range node.incomingLinks {
+			incidentLinks[linkID] = struct{}{}
+		}
+		for _, linkID := range node.outcomingLinks {
+			incidentLinks[linkID] = struct{}{}
+		}
+	}
+	sortedLinks := make([]gmns.LinkID, 0, len(incidentLinks))
+	for linkID := range incidentLinks {
+		sortedLinks = append(sortedLinks, linkID)
+	}
+	sort.Slice(sortedLinks, func(i, j int) bool {
+		return sortedLinks[i] < sortedLinks[j]
+	})
+
+	keptNode := net.Nodes[intersection.Nodes[0]]
+	incomingLinks := make([]gmns.LinkID, 0)
+	outcomingLinks := make([]gmns.LinkID, 0)
+	// Kept link for every pair of source and target nodes of re-attached links
+	pairs := make(map[[2]gmns.NodeID]gmns.LinkID)
+	for _, linkID := range sortedLinks {
+		link := net.Links[linkID]
+		_, sourceInside := members[link.sourceNodeID]
+		_, targetInside := members[link.targetNodeID]
+		if sourceInside && targetInside {
+			delete(net.Links, linkID)
+			intersection.RemovedLinks = append(intersection.RemovedLinks, linkID)
+			continue
+		}
+		source, target := link.sourceNodeID, link.targetNodeID
+		if sourceInside {
+			source = keptNode.ID
+		}
+		if targetInside {
+			target = keptNode.ID
+		}
+		if keptLinkID, ok := pairs[[2]gmns.NodeID{source, target}]; ok {
+			// Outer end of the duplicate stays in the network, so the duplicate is removed from its lists
+			if sourceInside {
+				if targetNode, ok := net.Nodes[link.targetNodeID]; ok {
+					targetNode.removeIncomingLink(linkID)
+				}
+			} else if sourceNode, ok := net.Nodes[link.sourceNodeID]; ok {
+				sourceNode.removeOutcomingLink(linkID)
+			}
+			delete(net.Links, linkID)
+			if intersection.MergedLinks == nil {
+				intersection.MergedLinks = make(map[gmns.LinkID]gmns.LinkID)
+			}
+			intersection.MergedLinks[linkID] = keptLinkID
+			continue
+		}
+		pairs[[2]gmns.NodeID{source, target}] = linkID
+		if sourceInside {
+			link.sourceNodeID = keptNode.ID
+			link.sourceOsmNodeID = keptNode.osmNodeID
+			link.extendUpstream(centroid)
+			outcomingLinks = append(outcomingLinks, linkID)
+		}
+		if targetInside {
+			link.targetNodeID = keptNode.ID
+			link.targetOsmNodeID = keptNode.osmNodeID
+			link.extendDownstream(centroid)
+			incomingLinks = append(incomingLinks, linkID)
+		}
+	}
+	for _, nodeID := range intersection.Nodes[1:] {
+		delete(net.Nodes, nodeID)
+	}
+	keptNode.incomingLinks = incomingLinks
+	keptNode.outcomingLinks = outcomingLinks
+	keptNode.geom = centroid
+	keptNode.geomEuclidean = geomath.PointToEuclidean(centroid)
+	keptNode.controlType = controlType
+	keptNode.intersectionID = intersection.ID
+	intersection.CollapsedNode = keptNode.ID
+	return nil
+}
+
+// checkIntersection checks that every node of the given intersection and every link incident to those nodes exist in the network
+func (net *Net) checkIntersection(intersection *Intersection) error {
+	for _, nodeID := range intersection.Nodes {
+		node, ok := net.Nodes[nodeID]
+		if !ok {
+			return errors.Wrapf(ErrNodeNotFound, "Node ID: %d", nodeID)
+		}
+		for _, linkID := range append(append([]gmns.LinkID{}, node.incomingLinks...), node.outcomingLinks...) {
+			if _, ok := net.Links[linkID]; !ok {
+				return errors.Wrapf(ErrLinkNotFound, "Link ID: %d", linkID)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package macro
+
+import (
+	"testing"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/utils/geomath"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+// testNet returns network with nodes at the given points and straight one-lane links between the given pairs of nodes. Links are numbered in order of pairs
+func testNet(t *testing.T, points map[gmns.NodeID]orb.Point, pairs [][2]gmns.NodeID) *Net {
+	net := NewNet()
+	for id, pt := range points {
+		assert.NoError(t, net.AddNode(NewNodeFrom(id, WithPointGeom(pt), WithPointGeomEuclidean(geomath.PointToEuclidean(pt)))))
+	}
+	for i, pair := range pairs {
+		geom := orb.LineString{points[pair[0]], points[pair[1]]}
+		link := NewLinkFrom(gmns.LinkID(i), pair[0], pair[1],
+			WithLineGeom(geom),
+			WithLineGeomEuclidean(geomath.LineToEuclidean(geom)),
+			WithLengthMeters(geo.LengthHaversine(geom)),
+			WithLanesNum(1),
+		)
+		link.lanesInfo = NewLanesInfo(link)
+		assert.NoError(t, net.AddLink(link))
+	}
+	return net
+}
+
+// dualCarriagewayNet returns network where nodes 1 and 2 form the single junction: they are connected by links 0 and 1,
+// node 8 is connected to both of them (links 8 and 9 become parallel after collapse)
+func dualCarriagewayNet(t *testing.T) *Net {
+	points := map[gmns.NodeID]orb.Point{
+		1: {37.6, 55.75},
+		2: {37.6001, 55.75},
+		3: {37.599, 55.75},
+		4: {37.601, 55.75},
+		5: {37.6, 55.749},
+		6: {37.6001, 55.749},
+		8: {37.60005, 55.751},
+	}
+	return testNet(t, points, [][2]gmns.NodeID{
+		{1, 2}, {2, 1}, // Inside the junction
+		{3, 1}, {1, 3},
+		{2, 4}, {4, 2},
+		{5, 1},
+		{2, 6},
+		{8, 1}, {8, 2},
+	})
+}
+
+func TestClusterIntersections(t *testing.T) {
+	net := dualCarriagewayNet(t)
+	intersections := net.ClusterIntersections(20, false)
+	assert.Len(t, intersections, 1)
+	assert.Equal(t, []gmns.NodeID{1, 2}, intersections[0].Nodes)
+	assert.Equal(t, 0, intersections[0].ID, "Intersections should be enumerated from zero")
+	assert.Equal(t, gmns.NodeID(-1), intersections[0].CollapsedNode)
+	assert.Len(t, net.Nodes, 7, "Clustering should not modify network")
+
+	assert.Len(t, net.ClusterIntersections(1, false), 0, "Nodes are farther than threshold")
+	assert.Len(t, net.ClusterIntersections(20, true), 0, "There are no signalized nodes")
+
+	WithIntersectionID(5)(net.Nodes[2])
+	intersections = net.ClusterIntersections(20, false)
+	assert.Len(t, intersections, 1)
+	assert.Equal(t, 5, intersections[0].ID, "Predefined intersection identifier should be kept")
+}
+
+func TestCollapseIntersection(t *testing.T) {
+	net := dualCarriagewayNet(t)
+	lengthBefore := net.Links[2].lengthMeters
+	intersections, err := net.ConsolidateIntersections(ConsolidationOptions{DistanceThreshold: 20, Collapse: true})
+	assert.NoError(t, err)
+	assert.Len(t, intersections, 1)
+	intersection := intersections[0]
+	assert.Equal(t, gmns.NodeID(1), intersection.CollapsedNode, "Node with the smallest identifier should be kept")
+	assert.Equal(t, []gmns.LinkID{0, 1}, intersection.RemovedLinks)
+	assert.Equal(t, map[gmns.LinkID]gmns.LinkID{9: 8}, intersection.MergedLinks, "Parallel duplicate should be merged into the link with the smallest identifier")
+
+	assert.Len(t, net.Nodes, 6)
+	assert.Len(t, net.Links, 7)
+	kept := net.Nodes[1]
+	assert.InDelta(t, 37.60005, kept.geom.Lon(), 1e-9, "Kept node should be placed at the centroid")
+	assert.Equal(t, 0, kept.intersectionID)
+	assert.ElementsMatch(t, []gmns.LinkID{2, 5, 6, 8}, kept.incomingLinks)
+	assert.ElementsMatch(t, []gmns.LinkID{3, 4, 7}, kept.outcomingLinks)
+	assert.Equal(t, []gmns.LinkID{8}, net.Nodes[8].outcomingLinks, "Merged duplicate should be removed from its outer node")
+	for _, linkID := range []gmns.LinkID{4, 5, 7} {
+		link := net.Links[linkID]
+		assert.True(t, link.sourceNodeID == 1 || link.targetNodeID == 1, "Link %d should be re-attached to the kept node", linkID)
+	}
+	assert.Equal(t, kept.geom, net.Links[2].geom[len(net.Links[2].geom)-1], "Geometry should be extended to the kept node")
+	assert.Greater(t, net.Links[2].lengthMeters, lengthBefore)
+	for _, node := range net.Nodes {
+		for _, linkID := range append(append([]gmns.LinkID{}, node.incomingLinks...), node.outcomingLinks...) {
+			_, ok := net.Links[linkID]
+			assert.True(t, ok, "Node %d refers to missing link %d", node.ID, linkID)
+		}
+	}
+}
+
+func TestCollapseIntersectionMissingLink(t *testing.T) {
+	net := dualCarriagewayNet(t)
+	// Link 7 is listed by node 2 but is absent in the network
+	delete(net.Links, 7)
+	geomBefore := net.Nodes[1].geom
+	err := net.CollapseIntersection(&Intersection{ID: 0, Nodes: []gmns.NodeID{1, 2}, CollapsedNode: -1})
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+	assert.Len(t, net.Nodes, 7, "Network should be left untouched")
+	assert.Len(t, net.Links, 9, "Network should be left untouched")
+	assert.Equal(t, geomBefore, net.Nodes[1].geom)
+	assert.Equal(t, gmns.NodeID(2), net.Links[4].sourceNodeID)
+
+	err = net.CollapseIntersection(&Intersection{ID: 0, Nodes: []gmns.NodeID{1, 100}, CollapsedNode: -1})
+	assert.ErrorIs(t, err, ErrNodeNotFound)
+}
+
+func TestConsolidateIntersectionsMissingLink(t *testing.T) {
+	// Two copies of the dual carriageway junction: nodes 1, 2 and nodes 11, 12
+	points := make(map[gmns.NodeID]orb.Point)
+	pairs := make([][2]gmns.NodeID, 0)
+	for _, offset := range []gmns.NodeID{0, 10} {
+		for id, pt := range map[gmns.NodeID]orb.Point{1: {37.6, 55.75}, 2: {37.6001, 55.75}, 3: {37.599, 55.75}, 4: {37.601, 55.75}, 5: {37.6, 55.749}, 6: {37.6001, 55.749}} {
+			points[id+offset] = orb.Point{pt.Lon() + float64(offset)*0.001, pt.Lat()}
+		}
+		for _, pair := range [][2]gmns.NodeID{{1, 2}, {2, 1}, {3, 1}, {1, 3}, {2, 4}, {4, 2}, {5, 1}, {2, 6}} {
+			pairs = append(pairs, [2]gmns.NodeID{pair[0] + offset, pair[1] + offset})
+		}
+	}
+	net := testNet(t, points, pairs)
+	// Link 13 (from node 14 to node 12) is listed by the second junction but is absent in the network
+	delete(net.Links, 13)
+	_, err := net.ConsolidateIntersections(ConsolidationOptions{DistanceThreshold: 20, Collapse: true})
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+	assert.Len(t, net.Nodes, 12, "Network should be left untouched")
+	assert.Len(t, net.Links, 15, "Network should be left untouched")
+	for _, node := range net.Nodes {
+		assert.Equal(t, -1, node.intersectionID, "Intersection identifier of node %d should not be assigned", node.ID)
+	}
+}
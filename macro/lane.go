@@ -51,6 +51,19 @@ func NewLanesInfo(link *Link) LanesInfo {
 	return lanesInfo
 }
 
+// Clone returns deep copy of the lanes information
+func (lanesInfo LanesInfo) Clone() LanesInfo {
+	clone := LanesInfo{
+		LanesList:         make([]int, len(lanesInfo.LanesList)),
+		LanesChange:       make([][2]int, len(lanesInfo.LanesChange)),
+		LanesChangePoints: make([]float64, len(lanesInfo.LanesChangePoints)),
+	}
+	copy(clone.LanesList, lanesInfo.LanesList)
+	copy(clone.LanesChange, lanesInfo.LanesChange)
+	copy(clone.LanesChangePoints, lanesInfo.LanesChangePoints)
+	return clone
+}
+
 func laneIndices(lanes int, lanesChangeLeft int, lanesChangeRight int) []int {
 	if lanes < lanesChangeLeft || lanes < lanesChangeRight {
 		return make([]int, 0)
@@ -79,3 +92,105 @@ func laneIndices(lanes int, lanesChangeLeft int, lanesChangeRight int) []int {
 	}
 	return laneIndices
 }
+
+// extendLanesInfo returns copy of the given lanes information where the first lanes segment is prolonged by upstreamDelta meters
+// and the last one is prolonged by downstreamDelta meters (e.g. after geometry of the link has been extended on either end)
+func extendLanesInfo(lanesInfo LanesInfo, upstreamDelta, downstreamDelta float64) LanesInfo {
+	ans := LanesInfo{
+		LanesList:         make([]int, len(lanesInfo.LanesList)),
+		LanesChange:       make([][2]int, len(lanesInfo.LanesChange)),
+		LanesChangePoints: make([]float64, len(lanesInfo.LanesChangePoints)),
+	}
+	copy(ans.LanesList, lanesInfo.LanesList)
+	copy(ans.LanesChange, lanesInfo.LanesChange)
+	copy(ans.LanesChangePoints, lanesInfo.LanesChangePoints)
+	if len(ans.LanesChangePoints) == 0 {
+		return ans
+	}
+	for i := 1; i < len(ans.LanesChangePoints); i++ {
+		ans.LanesChangePoints[i] += upstreamDelta
+	}
+	ans.LanesChangePoints[len(ans.LanesChangePoints)-1] += downstreamDelta
+	return ans
+}
+
+// concatLanesInfo returns lanes information for the link which is concatenation of two consecutive links.
+// Lanes change points of the second link are shifted by length of the first one. If lanes on both sides of the junction point are the same
+// then corresponding segments are joined into the single one.
+// Returns empty lanes information if any of given ones is empty.
+func concatLanesInfo(first LanesInfo, second LanesInfo) LanesInfo {
+	if len(first.LanesChangePoints) == 0 || len(second.LanesChangePoints) == 0 {
+		return LanesInfo{}
+	}
+	ans := LanesInfo{
+		LanesList:         make([]int, 0, len(first.LanesList)+len(second.LanesList)),
+		LanesChange:       make([][2]int, 0, len(first.LanesChange)+len(second.LanesChange)),
+		LanesChangePoints: make([]float64, 0, len(first.LanesChangePoints)+len(second.LanesChangePoints)-1),
+	}
+	shift := first.LanesChangePoints[len(first.LanesChangePoints)-1]
+	ans.LanesList = append(ans.LanesList, first.LanesList...)
+	ans.LanesChange = append(ans.LanesChange, first.LanesChange...)
+	ans.LanesChangePoints = append(ans.LanesChangePoints, first.LanesChangePoints...)
+	secondLanesList := second.LanesList
+	secondLanesChange := second.LanesChange
+	junctionIdx := len(ans.LanesChangePoints) - 1
+	canJoin := len(first.LanesList) > 0 && len(secondLanesList) > 0 &&
+		len(first.LanesChange) > 0 && len(secondLanesChange) > 0 &&
+		first.LanesList[len(first.LanesList)-1] == secondLanesList[0] &&
+		first.LanesChange[len(first.LanesChange)-1] == secondLanesChange[0]
+	if canJoin {
+		// Junction point is not a change point anymore
+		ans.LanesChangePoints = ans.LanesChangePoints[:junctionIdx]
+		secondLanesList = secondLanesList[1:]
+		secondLanesChange = secondLanesChange[1:]
+	}
+	ans.LanesList = append(ans.LanesList, secondLanesList...)
+	ans.LanesChange = append(ans.LanesChange, secondLanesChange...)
+	for _, point := range second.LanesChangePoints[1:] {
+		ans.LanesChangePoints = append(ans.LanesChangePoints, point+shift)
+	}
+	return ans
+}
+
+// splitLanesInfo splits lanes information at the given fraction [0; 1] of the link length.
+// Lanes segment containing the split position is shared by both parts. Change points of the downstream part start from zero.
+func splitLanesInfo(lanesInfo LanesInfo, fraction float64) (LanesInfo, LanesInfo) {
+	upstream := LanesInfo{
+		LanesList:         make([]int, 0),
+		LanesChange:       make([][2]int, 0),
+		LanesChangePoints: make([]float64, 0),
+	}
+	downstream := LanesInfo{
+		LanesList:         make([]int, 0),
+		LanesChange:       make([][2]int, 0),
+		LanesChangePoints: make([]float64, 0),
+	}
+	if len(lanesInfo.LanesChangePoints) == 0 {
+		return upstream, downstream
+	}
+	cut := fraction * lanesInfo.LanesChangePoints[len(lanesInfo.LanesChangePoints)-1]
+	for i := 0; i < len(lanesInfo.LanesChangePoints)-1 && i < len(lanesInfo.LanesList); i++ {
+		start, end := lanesInfo.LanesChangePoints[i], lanesInfo.LanesChangePoints[i+1]
+		lanesChange := [2]int{0, 0}
+		if i < len(lanesInfo.LanesChange) {
+			lanesChange = lanesInfo.LanesChange[i]
+		}
+		if start < cut {
+			if len(upstream.LanesChangePoints) == 0 {
+				upstream.LanesChangePoints = append(upstream.LanesChangePoints, start)
+			}
+			upstream.LanesChangePoints = append(upstream.LanesChangePoints, min(end, cut))
+			upstream.LanesList = append(upstream.LanesList, lanesInfo.LanesList[i])
+			upstream.LanesChange = append(upstream.LanesChange, lanesChange)
+		}
+		if end > cut {
+			if len(downstream.LanesChangePoints) == 0 {
+				downstream.LanesChangePoints = append(downstream.LanesChangePoints, max(start, cut)-cut)
+			}
+			downstream.LanesChangePoints = append(downstream.LanesChangePoints, end-cut)
+			downstream.LanesList = append(downstream.LanesList, lanesInfo.LanesList[i])
+			downstream.LanesChange = append(downstream.LanesChange, lanesChange)
+		}
+	}
+	return upstream, downstream
+}
@@ -0,0 +1,71 @@
+package macro
+
+import (
+	"sort"
+
+	"github.com/LdDl/go-gmns/gmns"
+)
+
+// Lanes returns per-lane attributes of the link sorted by lane number. Empty if there are no attributes. Warning: returning object is a slice.
+func (link *Link) Lanes() []gmns.Lane {
+	return link.lanes
+}
+
+// Lane returns attributes for the given lane number
+func (link *Link) Lane(laneNum int) (gmns.Lane, bool) {
+	for _, lane := range link.lanes {
+		if lane.LaneNum == laneNum {
+			return lane, true
+		}
+	}
+	return gmns.Lane{}, false
+}
+
+// SegmentLanes returns attributes for every lane of the given lanes segment ordered from the inner lane to the outer one.
+// Lanes without attributes get default ones. Returns nil if the link has no per-lane attributes at all
+func (link *Link) SegmentLanes(segmentIdx int) []gmns.Lane {
+	if len(link.lanes) == 0 || segmentIdx < 0 || segmentIdx >= len(link.lanesInfo.LanesChange) {
+		return nil
+	}
+	lanesChange := link.lanesInfo.LanesChange[segmentIdx]
+	indices := laneIndices(link.lanesNum, lanesChange[0], lanesChange[1])
+	ans := make([]gmns.Lane, len(indices))
+	for i, laneNum := range indices {
+		lane, ok := link.Lane(laneNum)
+		if !ok {
+			lane = gmns.NewLane(link.ID, laneNum)
+		}
+		ans[i] = lane.Clone()
+	}
+	return ans
+}
+
+// WithLanes sets per-lane attributes for the link. Warning: it does copy argument
+func WithLanes(lanes []gmns.Lane) func(*Link) {
+	return func(link *Link) {
+		link.lanes = make([]gmns.Lane, len(lanes))
+		for i := range lanes {
+			link.lanes[i] = lanes[i].Clone()
+			link.lanes[i].LinkID = link.ID
+		}
+		sort.SliceStable(link.lanes, func(i, j int) bool {
+			return link.lanes[i].LaneNum < link.lanes[j].LaneNum
+		})
+	}
+}
+
+// Lanes returns per-lane attributes of every link in the network sorted by link identifier and lane number
+func (net *Net) Lanes() []gmns.Lane {
+	linksIDs := make([]gmns.LinkID, 0, len(net.Links))
+	for linkID := range net.Links {
+		linksIDs = append(linksIDs, linkID)
+	}
+	sort.Slice(linksIDs, func(i, j int) bool {
+		return linksIDs[i] < linksIDs[j]
+	})
+	ans := []gmns.Lane{}
+	for _, linkID := range linksIDs {
+		ans = append(ans, net.Links[linkID].lanes...)
+	}
+	return ans
+}
@@ -0,0 +1,72 @@
+package macro
+
+import (
+	"strings"
+
+	"github.com/LdDl/go-gmns/gmns/types"
+)
+
+// ParseLaneChanges parses value of OSM "change:lanes" tag into lane change permissions ordered by lanes numbering (the same way as in ParseTurnLanes)
+func ParseLaneChanges(changeLanes string, drivingSide types.DrivingSide) []types.LaneChange {
+	changeLanes = strings.TrimSpace(changeLanes)
+	if changeLanes == "" {
+		return []types.LaneChange{}
+	}
+	lanesStr := strings.Split(changeLanes, "|")
+	lanes := make([]types.LaneChange, len(lanesStr))
+	for i, laneStr := range lanesStr {
+		laneIdx := i
+		if drivingSide == types.DRIVING_SIDE_LEFT {
+			laneIdx = len(lanesStr) - 1 - i
+		}
+		lanes[laneIdx] = types.NewLaneChangeFrom(laneStr)
+	}
+	return lanes
+}
+
+// LaneChangesFromTags returns lane change permissions for the link from OSM tags values.
+// Direction specific tag ("change:lanes:forward" for the link along the way and "change:lanes:backward" for the opposite one) is preferred over "change:lanes".
+// If there are no per-lane tags then value of "change" tag (e.g. "no" on bridges and in tunnels) is applied to every lane of the link
+func LaneChangesFromTags(change, changeLanes, changeLanesForward, changeLanesBackward string, lanesNum int, forward bool, drivingSide types.DrivingSide) []types.LaneChange {
+	value := changeLanesBackward
+	if forward {
+		value = changeLanesForward
+	}
+	if strings.TrimSpace(value) == "" {
+		value = changeLanes
+	}
+	if strings.TrimSpace(value) != "" {
+		return ParseLaneChanges(value, drivingSide)
+	}
+	if strings.TrimSpace(change) == "" || lanesNum <= 0 {
+		return []types.LaneChange{}
+	}
+	lanes := make([]types.LaneChange, lanesNum)
+	for i := range lanes {
+		lanes[i] = types.NewLaneChangeFrom(change)
+	}
+	return lanes
+}
+
+// LaneChange returns lane change permission for the given lane number. Lanes without permissions (including pockets) allow both directions
+func (link *Link) LaneChange(laneNum int) types.LaneChange {
+	if laneNum < 1 || laneNum > len(link.laneChanges) {
+		return types.LANE_CHANGE_BOTH
+	}
+	return link.laneChanges[laneNum-1]
+}
+
+// SegmentLaneChanges returns lane change permissions for every lane of the given lanes segment ordered from the inner lane to the outer one.
+// Returns nil if the link has no lane change permissions at all
+func (link *Link) SegmentLaneChanges(segmentIdx int) []types.LaneChange {
+	if len(link.laneChanges) == 0 || segmentIdx < 0 || segmentIdx >= len(link.lanesInfo.LanesChange) {
+		return nil
+	}
+	lanesChange := link.lanesInfo.LanesChange[segmentIdx]
+	indices := laneIndices(link.lanesNum, lanesChange[0], lanesChange[1])
+	ans := make([]types.LaneChange, len(indices))
+	for i, laneNum := range indices {
+		ans[i] = link.LaneChange(laneNum)
+	}
+	return ans
+}
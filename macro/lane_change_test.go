@@ -0,0 +1,30 @@
+package macro
+
+import (
+	"testing"
+
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLaneChangesFromTags(t *testing.T) {
+	laneChanges := LaneChangesFromTags("", "not_left|no|yes", "", "", 3, true, types.DRIVING_SIDE_RIGHT)
+	assert.Equal(t, []types.LaneChange{types.LANE_CHANGE_RIGHT, types.LANE_CHANGE_NONE, types.LANE_CHANGE_BOTH}, laneChanges)
+
+	// Lanes are reversed for left-hand traffic
+	laneChanges = LaneChangesFromTags("", "not_left|no|yes", "", "", 3, true, types.DRIVING_SIDE_LEFT)
+	assert.Equal(t, []types.LaneChange{types.LANE_CHANGE_BOTH, types.LANE_CHANGE_NONE, types.LANE_CHANGE_RIGHT}, laneChanges)
+
+	// Direction specific tag is preferred, "change" tag is applied to every lane
+	laneChanges = LaneChangesFromTags("", "no|no", "", "only_left|yes", 2, false, types.DRIVING_SIDE_RIGHT)
+	assert.Equal(t, []types.LaneChange{types.LANE_CHANGE_LEFT, types.LANE_CHANGE_BOTH}, laneChanges)
+	laneChanges = LaneChangesFromTags("no", "", "", "", 2, true, types.DRIVING_SIDE_RIGHT)
+	assert.Equal(t, []types.LaneChange{types.LANE_CHANGE_NONE, types.LANE_CHANGE_NONE}, laneChanges)
+	assert.Empty(t, LaneChangesFromTags("", "", "", "", 2, true, types.DRIVING_SIDE_RIGHT))
+
+	// Pocket lanes allow both directions
+	link := NewLinkFrom(1, 1, 2, WithLengthMeters(100), WithLanesNum(2), WithLaneChanges([]types.LaneChange{types.LANE_CHANGE_NONE, types.LANE_CHANGE_LEFT}))
+	WithLanesInfo(NewLanesInfoWithPockets(link, TurnPockets{Left: TurnPocket{Lanes: 1, Length: 30}}))(link)
+	assert.Equal(t, []types.LaneChange{types.LANE_CHANGE_NONE, types.LANE_CHANGE_LEFT}, link.SegmentLaneChanges(0))
+	assert.Equal(t, []types.LaneChange{types.LANE_CHANGE_BOTH, types.LANE_CHANGE_NONE, types.LANE_CHANGE_LEFT}, link.SegmentLaneChanges(1))
+}
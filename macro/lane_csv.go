@@ -0,0 +1,149 @@
+package macro
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/pkg/errors"
+)
+
+var lanesCSVHeader = []string{"lane_id", "link_id", "lane_num", "allowed_uses", "designation", "width"}
+
+// WriteLanesCSV writes per-lane attributes of the network as GMNS lane table (lane.csv).
+// Lanes without identifier (or with identifier used by preceding lane) get unique ones following the maximum identifier; attributes of links are not changed
+func WriteLanesCSV(w io.Writer, net *Net) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(lanesCSVHeader)
+	if err != nil {
+		return errors.Wrap(err, "Can't write lanes header")
+	}
+	lanes := net.Lanes()
+	uniqueLanesIDs(lanes)
+	for _, lane := range lanes {
+		allowedUses := make([]string, len(lane.AllowedAgentTypes))
+		for i, agentType := range lane.AllowedAgentTypes {
+			allowedUses[i] = agentType.String()
+		}
+		width := ""
+		if lane.Width > 0 {
+			width = strconv.FormatFloat(lane.Width, 'f', -1, 64)
+		}
+		err = writer.Write([]string{
+			fmt.Sprintf("%d", lane.ID),
+			fmt.Sprintf("%d", lane.LinkID),
+			fmt.Sprintf("%d", lane.LaneNum),
+			strings.Join(allowedUses, ","),
+			lane.Designation.String(),
+			width,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Can't write lane %d of link %d", lane.LaneNum, lane.LinkID)
+		}
+	}
+	writer.Flush()
+	return errors.Wrap(writer.Error(), "Can't flush lanes")
+}
+
+// uniqueLanesIDs assigns identifiers to lanes without identifier or with duplicate one. New identifiers follow the maximum existing one in order of lanes
+func uniqueLanesIDs(lanes []gmns.Lane) {
+	nextID := gmns.LaneID(0)
+	for _, lane := range lanes {
+		if lane.ID >= nextID {
+			nextID = lane.ID + 1
+		}
+	}
+	used := make(map[gmns.LaneID]struct{}, len(lanes))
+	for i := range lanes {
+		if _, ok := used[lanes[i].ID]; lanes[i].ID < 0 || ok {
+			lanes[i].ID = nextID
+			nextID++
+		}
+		used[lanes[i].ID] = struct{}{}
+	}
+}
+
+// ReadLanesCSV reads GMNS lane table (lane.csv) and attaches per-lane attributes to the links of the network.
+// Columns "link_id" and "lane_num" are required, the rest ones are optional. Existing attributes of the mentioned links are replaced
+func ReadLanesCSV(r io.Reader, net *Net) error {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return errors.Wrap(err, "Can't read lanes header")
+	}
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, required := range []string{"link_id", "lane_num"} {
+		if _, ok := columns[required]; !ok {
+			return errors.Wrapf(ErrCSVColumnAbsent, "Column: '%s'", required)
+		}
+	}
+	value := func(record []string, column string) string {
+		idx, ok := columns[column]
+		if !ok || idx >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[idx])
+	}
+
+	lanesByLink := make(map[gmns.LinkID][]gmns.Lane)
+	linksOrder := []gmns.LinkID{}
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Can't read lanes row %d", row)
+		}
+		linkID, err := strconv.Atoi(value(record, "link_id"))
+		if err != nil {
+			return errors.Wrapf(err, "Can't parse link ID on row %d", row)
+		}
+		if _, ok := net.Links[gmns.LinkID(linkID)]; !ok {
+			return errors.Wrapf(ErrLinkNotFound, "Link ID: %d. Row: %d", linkID, row)
+		}
+		laneNum, err := strconv.Atoi(value(record, "lane_num"))
+		if err != nil {
+			return errors.Wrapf(err, "Can't parse lane number on row %d", row)
+		}
+		lane := gmns.NewLane(gmns.LinkID(linkID), laneNum)
+		if laneIDStr := value(record, "lane_id"); laneIDStr != "" {
+			laneID, err := strconv.Atoi(laneIDStr)
+			if err != nil {
+				return errors.Wrapf(err, "Can't parse lane ID on row %d", row)
+			}
+			lane.ID = gmns.LaneID(laneID)
+		}
+		if widthStr := value(record, "width"); widthStr != "" {
+			lane.Width, err = strconv.ParseFloat(widthStr, 64)
+			if err != nil {
+				return errors.Wrapf(err, "Can't parse lane width on row %d", row)
+			}
+		}
+		if allowedUses := value(record, "allowed_uses"); allowedUses != "" {
+			for _, use := range strings.Split(allowedUses, ",") {
+				agentType := types.NewAgentTypeFrom(use)
+				if agentType == types.AGENT_UNDEFINED {
+					continue
+				}
+				lane.AllowedAgentTypes = append(lane.AllowedAgentTypes, agentType)
+			}
+		}
+		lane.Designation = types.NewLaneDesignationFrom(value(record, "designation"))
+		if _, ok := lanesByLink[lane.LinkID]; !ok {
+			linksOrder = append(linksOrder, lane.LinkID)
+		}
+		lanesByLink[lane.LinkID] = append(lanesByLink[lane.LinkID], lane)
+	}
+	for _, linkID := range linksOrder {
+		WithLanes(lanesByLink[linkID])(net.Links[linkID])
+	}
+	return nil
+}
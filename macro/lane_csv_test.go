@@ -0,0 +1,92 @@
+package macro
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLanesCSV(t *testing.T) {
+	net := NewNet()
+	link := NewLinkFrom(7, 1, 2, WithLengthMeters(100), WithLanesNum(2))
+	WithLanesInfo(NewLanesInfoWithPockets(link, TurnPockets{Left: TurnPocket{Lanes: 1, Length: 30}}))(link)
+	net.Links[link.ID] = link
+
+	input := "lane_id,link_id,lane_num,allowed_uses,designation,width\n" +
+		"3,7,2,,general,3.25\n" +
+		"1,7,-1,,general,3\n" +
+		"2,7,1,\"auto,bike\",bus,\n"
+	err := ReadLanesCSV(strings.NewReader(input), net)
+	assert.NoError(t, err)
+	lanes := link.Lanes()
+	assert.Len(t, lanes, 3)
+	assert.Equal(t, []int{-1, 1, 2}, []int{lanes[0].LaneNum, lanes[1].LaneNum, lanes[2].LaneNum}, "Lanes should be sorted by lane number")
+	assert.Equal(t, []types.AgentType{types.AGENT_AUTO, types.AGENT_BIKE}, lanes[1].AllowedAgentTypes)
+	assert.Equal(t, types.LANE_DESIGNATION_BUS, lanes[1].Designation)
+	assert.Equal(t, 3.5, lanes[1].WidthOrDefault(3.5), "Default width is expected for lane without width")
+
+	// Pocket lane exists on the downstream segment only
+	assert.Len(t, link.SegmentLanes(0), 2)
+	downstreamLanes := link.SegmentLanes(1)
+	assert.Len(t, downstreamLanes, 3)
+	assert.Equal(t, 3.0, downstreamLanes[0].Width)
+
+	var buf bytes.Buffer
+	err = WriteLanesCSV(&buf, net)
+	assert.NoError(t, err)
+	expected := "lane_id,link_id,lane_num,allowed_uses,designation,width\n" +
+		"1,7,-1,,general,3\n" +
+		"2,7,1,\"auto,bike\",bus,\n" +
+		"3,7,2,,general,3.25\n"
+	assert.Equal(t, expected, buf.String())
+
+	err = ReadLanesCSV(strings.NewReader("link_id,lane_num\n8,1\n"), net)
+	assert.ErrorIs(t, err, ErrLinkNotFound)
+	err = ReadLanesCSV(strings.NewReader("link_id,width\n7,3\n"), net)
+	assert.ErrorIs(t, err, ErrCSVColumnAbsent)
+}
+
+func TestLanesCSVWithoutIDs(t *testing.T) {
+	net := NewNet()
+	for _, linkID := range []gmns.LinkID{1, 2} {
+		link := NewLinkFrom(linkID, 1, 2, WithLengthMeters(100), WithLanesNum(3))
+		WithLanesInfo(NewLanesInfo(link))(link)
+		WithLanes(ReservedLanesFromTags(linkID, "designated||designated", "", "", types.DRIVING_SIDE_RIGHT))(link)
+		net.Links[linkID] = link
+	}
+	lanes := net.Lanes()
+	assert.Len(t, lanes, 4)
+	// Lane with explicit identifier keeps it
+	net.Links[2].lanes[0].ID = 10
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteLanesCSV(&buf, net))
+	expected := "lane_id,link_id,lane_num,allowed_uses,designation,width\n" +
+		"11,1,1,bus,bus,\n" +
+		"12,1,3,bus,bus,\n" +
+		"10,2,1,bus,bus,\n" +
+		"13,2,3,bus,bus,\n"
+	assert.Equal(t, expected, buf.String())
+	assert.Equal(t, gmns.LaneID(-1), net.Links[1].lanes[0].ID, "Writing should not change the network")
+
+	// Round trip
+	read := NewNet()
+	for _, linkID := range []gmns.LinkID{1, 2} {
+		link := NewLinkFrom(linkID, 1, 2, WithLengthMeters(100), WithLanesNum(3))
+		WithLanesInfo(NewLanesInfo(link))(link)
+		read.Links[linkID] = link
+	}
+	assert.NoError(t, ReadLanesCSV(strings.NewReader(buf.String()), read))
+	ids := []gmns.LaneID{}
+	for _, lane := range read.Lanes() {
+		ids = append(ids, lane.ID)
+	}
+	assert.Equal(t, []gmns.LaneID{11, 12, 10, 13}, ids)
+	var again bytes.Buffer
+	assert.NoError(t, WriteLanesCSV(&again, read))
+	assert.Equal(t, buf.String(), again.String())
+}
@@ -3,7 +3,9 @@ package macro
 import (
 	"github.com/LdDl/go-gmns/gmns"
 	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/LdDl/go-gmns/utils/geomath"
 	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
 	"github.com/paulmach/osm"
 )
 
@@ -14,6 +16,9 @@ type Link struct {
 	geom               orb.LineString
 	geomEuclidean      orb.LineString
 	allowedAgentTypes  []types.AgentType
+	turnLanes          []types.TurnDirection
+	laneChanges        []types.LaneChange
+	lanes              []gmns.Lane
 	lengthMeters       float64
 	freeSpeed          float64
 	maxSpeed           float64
@@ -40,6 +45,9 @@ func NewLinkFrom(id gmns.LinkID, sourceNodeID, targetNodeID gmns.NodeID, options
 		geom:               orb.LineString{},
 		geomEuclidean:      orb.LineString{},
 		allowedAgentTypes:  []types.AgentType{},
+		turnLanes:          []types.TurnDirection{},
+		laneChanges:        []types.LaneChange{},
+		lanes:              []gmns.Lane{},
 		lengthMeters:       -1,
 		freeSpeed:          -1,
 		maxSpeed:           -1,
@@ -139,6 +147,16 @@ func (link *Link) AllowedAgentTypes() []types.AgentType {
 	return link.allowedAgentTypes
 }
 
+// TurnLanes returns turn designations for lanes on the downstream end of the link ordered by lanes numbering (see GetOutcomingLaneIndices). Empty if there are no designations. Warning: returning object is a slice.
+func (link *Link) TurnLanes() []types.TurnDirection {
+	return link.turnLanes
+}
+
+// LaneChanges returns lane change permissions ordered by lanes numbering (see GetOutcomingLaneIndices). Empty if lane changes are not restricted. Warning: returning object is a slice.
+func (link *Link) LaneChanges() []types.LaneChange {
+	return link.laneChanges
+}
+
 // LengthMeters returns length of the underlying geometry [WGS84]. Outputs "-1" if it was not set.
 func (link *Link) LengthMeters() float64 {
 	return link.lengthMeters
@@ -250,6 +268,22 @@ func WithAllowedAgentTypes(allowedAgentTypes []types.AgentType) func(*Link) {
 	}
 }
 
+// WithTurnLanes sets turn designations for lanes on the downstream end of the link. Warning: it does copy argument
+func WithTurnLanes(turnLanes []types.TurnDirection) func(*Link) {
+	return func(link *Link) {
+		link.turnLanes = make([]types.TurnDirection, len(turnLanes))
+		copy(link.turnLanes, turnLanes)
+	}
+}
+
+// WithLaneChanges sets lane change permissions for lanes of the link. Warning: it does copy argument
+func WithLaneChanges(laneChanges []types.LaneChange) func(*Link) {
+	return func(link *Link) {
+		link.laneChanges = make([]types.LaneChange, len(laneChanges))
+		copy(link.laneChanges, laneChanges)
+	}
+}
+
 // WithLengthMeters sets underlying geometry [WGS84] length in meters. Warning: it should be called explicitly after setting geometry [WGS84]
 func WithLengthMeters(lengthMeters float64) func(*Link) {
 	return func(link *Link) {
@@ -354,3 +388,57 @@ func WithBidirectionalSource(wasBidirectional bool) func(*Link) {
 		link.wasBidirectional = wasBidirectional
 	}
 }
+
+// extendUpstream prepends given point to the geometry of the link. Euclidean geometry, length and lanes information are updated accordingly
+func (link *Link) extendUpstream(pt orb.Point) {
+	if len(link.geom) == 0 || link.geom[0].Equal(pt) {
+		return
+	}
+	extra := geo.DistanceHaversine(pt, link.geom[0])
+	link.geom = append(orb.LineString{pt}, link.geom...)
+	link.geomEuclidean = geomath.LineToEuclidean(link.geom)
+	if link.lengthMeters >= 0 {
+		link.lengthMeters += extra
+	}
+	link.lanesInfo = extendLanesInfo(link.lanesInfo, extra, 0)
+}
+
+// extendDownstream appends given point to the geometry of the link. Euclidean geometry, length and lanes information are updated accordingly
+func (link *Link) extendDownstream(pt orb.Point) {
+	if len(link.geom) == 0 || link.geom[len(link.geom)-1].Equal(pt) {
+		return
+	}
+	extra := geo.DistanceHaversine(link.geom[len(link.geom)-1], pt)
+	link.geom = append(link.geom.Clone(), pt)
+	link.geomEuclidean = geomath.LineToEuclidean(link.geom)
+	if link.lengthMeters >= 0 {
+		link.lengthMeters += extra
+	}
+	link.lanesInfo = extendLanesInfo(link.lanesInfo, 0, extra)
+}
+
+// clone returns deep copy of the link with the given identifier
+func (link *Link) clone(id gmns.LinkID) *Link {
+	newLink := *link
+	newLink.ID = id
+	newLink.geom = link.geom.Clone()
+	newLink.geomEuclidean = link.geomEuclidean.Clone()
+	newLink.allowedAgentTypes = make([]types.AgentType, len(link.allowedAgentTypes))
+	copy(newLink.allowedAgentTypes, link.allowedAgentTypes)
+	newLink.turnLanes = make([]types.TurnDirection, len(link.turnLanes))
+	copy(newLink.turnLanes, link.turnLanes)
+	newLink.laneChanges = make([]types.LaneChange, len(link.laneChanges))
+	copy(newLink.laneChanges, link.laneChanges)
+	newLink.lanes = make([]gmns.Lane, len(link.lanes))
+	for i := range link.lanes {
+		newLink.lanes[i] = link.lanes[i].Clone()
+		newLink.lanes[i].LinkID = id
+	}
+	newLink.lanesInfo = extendLanesInfo(link.lanesInfo, 0, 0)
+	return &newLink
+}
+
+// Clone returns deep copy of the link
+func (link *Link) Clone() *Link {
+	return link.clone(link.ID)
+}
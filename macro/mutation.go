@@ -0,0 +1,189 @@
+package macro
+
+import (
+	"sort"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/movement"
+	"github.com/pkg/errors"
+)
+
+// syncCounters sets identifiers counters next to the max identifiers of nodes and links
+func (net *Net) syncCounters() {
+	if net.countersSynced {
+		return
+	}
+	for nodeID := range net.Nodes {
+		if nodeID >= net.maxNodeID {
+			net.maxNodeID = nodeID + 1
+		}
+	}
+	for linkID := range net.Links {
+		if linkID >= net.maxLinkID {
+			net.maxLinkID = linkID + 1
+		}
+	}
+	net.countersSynced = true
+}
+
+// MaxNodeID returns the current value of nodes identifiers counter: next allocated identifier is not less than it
+func (net *Net) MaxNodeID() gmns.NodeID {
+	net.syncCounters()
+	return net.maxNodeID
+}
+
+// MaxLinkID returns the current value of links identifiers counter: next allocated identifier is not less than it
+func (net *Net) MaxLinkID() gmns.LinkID {
+	net.syncCounters()
+	return net.maxLinkID
+}
+
+// SetMaxNodeID sets the nodes identifiers counter
+func (net *Net) SetMaxNodeID(id gmns.NodeID) {
+	net.syncCounters()
+	net.maxNodeID = id
+}
+
+// SetMaxLinkID sets the links identifiers counter
+func (net *Net) SetMaxLinkID(id gmns.LinkID) {
+	net.syncCounters()
+	net.maxLinkID = id
+}
+
+// NewNodeID returns identifier which is not used by any node of the network and reserves it
+func (net *Net) NewNodeID() gmns.NodeID {
+	net.syncCounters()
+	for {
+		if _, ok := net.Nodes[net.maxNodeID]; !ok {
+			break
+		}
+		net.maxNodeID++
+	}
+	id := net.maxNodeID
+	net.maxNodeID++
+	return id
+}
+
+// NewLinkID returns identifier which is not used by any link of the network and reserves it
+func (net *Net) NewLinkID() gmns.LinkID {
+	net.syncCounters()
+	for {
+		if _, ok := net.Links[net.maxLinkID]; !ok {
+			break
+		}
+		net.maxLinkID++
+	}
+	id := net.maxLinkID
+	net.maxLinkID++
+	return id
+}
+
+// AddNode adds the node to the network. Links referenced by the node must exist already and must be incident to it
+func (net *Net) AddNode(node *Node) error {
+	if _, ok := net.Nodes[node.ID]; ok {
+		return errors.Wrapf(ErrNodeExists, "Node ID: %d", node.ID)
+	}
+	for _, linkID := range node.incomingLinks {
+		link, ok := net.Links[linkID]
+		if !ok {
+			return errors.Wrapf(ErrLinkNotFound, "Incoming link ID: %d", linkID)
+		}
+		if link.targetNodeID != node.ID {
+			return errors.Wrapf(ErrLinkNotIncident, "Incoming link ID: %d. Node ID: %d", linkID, node.ID)
+		}
+	}
+	for _, linkID := range node.outcomingLinks {
+		link, ok := net.Links[linkID]
+		if !ok {
+			return errors.Wrapf(ErrLinkNotFound, "Outcoming link ID: %d", linkID)
+		}
+		if link.sourceNodeID != node.ID {
+			return errors.Wrapf(ErrLinkNotIncident, "Outcoming link ID: %d. Node ID: %d", linkID, node.ID)
+		}
+	}
+	net.Nodes[node.ID] = node
+	if node.ID >= net.maxNodeID {
+		net.maxNodeID = node.ID + 1
+	}
+	return nil
+}
+
+// AddLink adds the link to the network. Source and target nodes must exist already: the link is appended to their lists of outcoming and incoming links
+func (net *Net) AddLink(link *Link) error {
+	if _, ok := net.Links[link.ID]; ok {
+		return errors.Wrapf(ErrLinkExists, "Link ID: %d", link.ID)
+	}
+	sourceNode, ok := net.Nodes[link.sourceNodeID]
+	if !ok {
+		return errors.Wrapf(ErrNodeNotFound, "Source node ID: %d", link.sourceNodeID)
+	}
+	targetNode, ok := net.Nodes[link.targetNodeID]
+	if !ok {
+		return errors.Wrapf(ErrNodeNotFound, "Target node ID: %d", link.targetNodeID)
+	}
+	net.Links[link.ID] = link
+	sourceNode.outcomingLinks = append(sourceNode.outcomingLinks, link.ID)
+	targetNode.incomingLinks = append(targetNode.incomingLinks, link.ID)
+	if link.ID >= net.maxLinkID {
+		net.maxLinkID = link.ID + 1
+	}
+	return nil
+}
+
+// DeleteLink removes the link from the network and from lists of its source and target nodes.
+// If movements storages are provided then movements going through the link are removed from them too
+func (net *Net) DeleteLink(linkID gmns.LinkID, movements ...movement.MovementsStorage) error {
+	link, ok := net.Links[linkID]
+	if !ok {
+		return errors.Wrapf(ErrLinkNotFound, "Link ID: %d", linkID)
+	}
+	if sourceNode, ok := net.Nodes[link.sourceNodeID]; ok {
+		sourceNode.removeOutcomingLink(linkID)
+	}
+	if targetNode, ok := net.Nodes[link.targetNodeID]; ok {
+		targetNode.removeIncomingLink(linkID)
+	}
+	delete(net.Links, linkID)
+	for _, storage := range movements {
+		for mvmtID, mvmt := range storage {
+			if mvmt.IncomeMacroLink() == linkID || mvmt.OutcomeMacroLink() == linkID {
+				delete(storage, mvmtID)
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteNode removes the node and every link incident to it from the network.
+// If movements storages are provided then movements at the node and movements going through removed links are removed from them too
+func (net *Net) DeleteNode(nodeID gmns.NodeID, movements ...movement.MovementsStorage) error {
+	node, ok := net.Nodes[nodeID]
+	if !ok {
+		return errors.Wrapf(ErrNodeNotFound, "Node ID: %d", nodeID)
+	}
+	incidentLinks := make([]gmns.LinkID, 0, len(node.incomingLinks)+len(node.outcomingLinks))
+	incidentLinks = append(incidentLinks, node.incomingLinks...)
+	incidentLinks = append(incidentLinks, node.outcomingLinks...)
+	sort.Slice(incidentLinks, func(i, j int) bool {
+		return incidentLinks[i] < incidentLinks[j]
+	})
+	for _, linkID := range incidentLinks {
+		// Loops are listed twice
+		if _, ok := net.Links[linkID]; !ok {
+			continue
+		}
+		err := net.DeleteLink(linkID, movements...)
+		if err != nil {
+			return errors.Wrapf(err, "Can't delete link incident to node %d", nodeID)
+		}
+	}
+	delete(net.Nodes, nodeID)
+	for _, storage := range movements {
+		for mvmtID, mvmt := range storage {
+			if mvmt.MacroNode() == nodeID {
+				delete(storage, mvmtID)
+			}
+		}
+	}
+	return nil
+}
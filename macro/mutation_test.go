@@ -0,0 +1,81 @@
+package macro
+
+import (
+	"testing"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/movement"
+	"github.com/paulmach/orb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewIDs(t *testing.T) {
+	net := NewNet()
+	// Elements put to the maps directly are taken into account
+	net.Nodes[1] = NewNodeFrom(1)
+	net.Nodes[5] = NewNodeFrom(5)
+	net.Links[3] = NewLinkFrom(3, 1, 5)
+	assert.Equal(t, gmns.NodeID(6), net.NewNodeID())
+	assert.Equal(t, gmns.NodeID(7), net.NewNodeID(), "Allocated identifier should be reserved")
+	assert.Equal(t, gmns.LinkID(4), net.NewLinkID())
+	assert.Equal(t, gmns.LinkID(5), net.MaxLinkID())
+
+	net.SetMaxNodeID(100)
+	assert.Equal(t, gmns.NodeID(100), net.NewNodeID())
+	net.SetMaxLinkID(0)
+	net.Links[0] = NewLinkFrom(0, 1, 5)
+	assert.Equal(t, gmns.LinkID(1), net.NewLinkID(), "Used identifiers should be skipped")
+}
+
+func TestAddNodeLink(t *testing.T) {
+	net := testNet(t, map[gmns.NodeID]orb.Point{1: {37.6, 55.75}, 2: {37.601, 55.75}}, [][2]gmns.NodeID{{1, 2}})
+
+	assert.ErrorIs(t, net.AddNode(NewNodeFrom(1)), ErrNodeExists)
+	assert.ErrorIs(t, net.AddNode(NewNodeFrom(3, WithIncomingLinks(42))), ErrLinkNotFound)
+	assert.ErrorIs(t, net.AddNode(NewNodeFrom(3, WithIncomingLinks(0))), ErrLinkNotIncident, "Link 0 ends at node 2")
+	assert.ErrorIs(t, net.AddNode(NewNodeFrom(3, WithOutcomingLinks(0))), ErrLinkNotIncident, "Link 0 starts at node 1")
+	_, ok := net.Nodes[3]
+	assert.False(t, ok, "Rejected node should not be added")
+	assert.NoError(t, net.AddNode(NewNodeFrom(3)))
+	assert.Equal(t, gmns.NodeID(4), net.NewNodeID())
+
+	assert.ErrorIs(t, net.AddLink(NewLinkFrom(0, 1, 2)), ErrLinkExists)
+	assert.ErrorIs(t, net.AddLink(NewLinkFrom(10, 1, 42)), ErrNodeNotFound)
+	assert.ErrorIs(t, net.AddLink(NewLinkFrom(10, 42, 1)), ErrNodeNotFound)
+	assert.Len(t, net.Links, 1)
+	assert.NoError(t, net.AddLink(NewLinkFrom(10, 2, 3)))
+	assert.Equal(t, []gmns.LinkID{10}, net.Nodes[2].outcomingLinks)
+	assert.Equal(t, []gmns.LinkID{10}, net.Nodes[3].incomingLinks)
+	assert.Equal(t, gmns.LinkID(11), net.NewLinkID())
+}
+
+func TestDeleteCascades(t *testing.T) {
+	points := map[gmns.NodeID]orb.Point{
+		1: {37.6, 55.75},
+		2: {37.601, 55.75},
+		3: {37.602, 55.75},
+		4: {37.601, 55.751},
+	}
+	net := testNet(t, points, [][2]gmns.NodeID{{1, 2}, {2, 3}, {2, 4}, {4, 2}})
+	movements := movement.NewMovementsStorage()
+	movements[0] = movement.NewMovement(0, 2, 0, 1, movement.MOVEMENT_EBT, movement.MOVEMENT_TYPE_THRU)
+	movements[1] = movement.NewMovement(1, 2, 0, 2, movement.MOVEMENT_EBL, movement.MOVEMENT_TYPE_LEFT)
+	movements[2] = movement.NewMovement(2, 2, 3, 1, movement.MOVEMENT_SBR, movement.MOVEMENT_TYPE_RIGHT)
+
+	assert.ErrorIs(t, net.DeleteLink(42), ErrLinkNotFound)
+	assert.NoError(t, net.DeleteLink(2, movements))
+	assert.Len(t, net.Links, 3)
+	assert.Equal(t, []gmns.LinkID{1}, net.Nodes[2].outcomingLinks, "Link should be removed from its source node")
+	assert.Len(t, net.Nodes[4].incomingLinks, 0, "Link should be removed from its target node")
+	_, ok := movements[1]
+	assert.False(t, ok, "Movement through the removed link should be removed")
+	assert.Len(t, movements, 2)
+
+	assert.ErrorIs(t, net.DeleteNode(42), ErrNodeNotFound)
+	assert.NoError(t, net.DeleteNode(2, movements))
+	assert.Len(t, net.Nodes, 3)
+	assert.Len(t, net.Links, 0, "Every link incident to the node should be removed")
+	assert.Len(t, net.Nodes[1].outcomingLinks, 0)
+	assert.Len(t, net.Nodes[4].outcomingLinks, 0)
+	assert.Len(t, movements, 0, "Movements at the node should be removed")
+}
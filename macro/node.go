@@ -229,3 +229,34 @@ func WithPointGeomEuclidean(geomEuclidean orb.Point) func(*Node) {
 		node.geomEuclidean = geomEuclidean
 	}
 }
+
+// removeIncomingLink removes given link identifier from the set of incoming links
+func (node *Node) removeIncomingLink(linkID gmns.LinkID) {
+	node.incomingLinks = removeLinkID(node.incomingLinks, linkID)
+}
+
+// removeOutcomingLink removes given link identifier from the set of outcoming links
+func (node *Node) removeOutcomingLink(linkID gmns.LinkID) {
+	node.outcomingLinks = removeLinkID(node.outcomingLinks, linkID)
+}
+
+// removeLinkID removes every occurrence of the given identifier from the slice. Order of remaining elements is preserved
+func removeLinkID(linksIDs []gmns.LinkID, linkID gmns.LinkID) []gmns.LinkID {
+	ans := linksIDs[:0]
+	for _, id := range linksIDs {
+		if id != linkID {
+			ans = append(ans, id)
+		}
+	}
+	return ans
+}
+
+// Clone returns deep copy of the node
+func (node *Node) Clone() *Node {
+	newNode := *node
+	newNode.incomingLinks = make([]gmns.LinkID, len(node.incomingLinks))
+	copy(newNode.incomingLinks, node.incomingLinks)
+	newNode.outcomingLinks = make([]gmns.LinkID, len(node.outcomingLinks))
+	copy(newNode.outcomingLinks, node.outcomingLinks)
+	return &newNode
+}
@@ -0,0 +1,162 @@
+package macro
+
+import (
+	"sort"
+
+	"github.com/LdDl/go-gmns/gmns/types"
+)
+
+const (
+	// Default length of the turn pocket when it can't be extracted from data (e.g. from OSM tags)
+	TURN_POCKET_LENGTH_DEFAULT = 40.0
+)
+
+// TurnPocket is auxiliary lanes added at the downstream end of the link
+type TurnPocket struct {
+	// Number of auxiliary lanes
+	Lanes int
+	// Length of the pocket in meters counted from the downstream end of the link
+	Length float64
+}
+
+// TurnPockets is set of auxiliary lanes for both sides of the link.
+// Sides follow lanes numbering: Left pocket is next to the lane 1 (the inner one), so for left-hand traffic it is the one physically on the right.
+type TurnPockets struct {
+	Left  TurnPocket
+	Right TurnPocket
+}
+
+// NewLanesInfoWithPockets creates lanes information for the link with the given turn pockets.
+// Link is split into segments at pockets start points, so mesoscopic links are split there and the auxiliary lanes are generated on the downstream segments.
+// Pockets with no lanes or non-positive length are ignored. Pockets longer than the link cover the whole link.
+func NewLanesInfoWithPockets(link *Link, pockets TurnPockets) LanesInfo {
+	lengthMeters := link.LengthMeters()
+	left := pockets.Left.normalized(lengthMeters)
+	right := pockets.Right.normalized(lengthMeters)
+	if left.Lanes == 0 && right.Lanes == 0 {
+		return NewLanesInfo(link)
+	}
+	lanesChangePointsTemp := []float64{0.0, lengthMeters}
+	if left.Lanes > 0 {
+		lanesChangePointsTemp = append(lanesChangePointsTemp, lengthMeters-left.Length)
+	}
+	if right.Lanes > 0 {
+		lanesChangePointsTemp = append(lanesChangePointsTemp, lengthMeters-right.Length)
+	}
+	sort.Float64s(lanesChangePointsTemp)
+	lanesInfo := LanesInfo{
+		LanesList:         make([]int, 0),
+		LanesChange:       make([][2]int, 0),
+		LanesChangePoints: make([]float64, 0, len(lanesChangePointsTemp)),
+	}
+	for _, point := range lanesChangePointsTemp {
+		pointsNum := len(lanesInfo.LanesChangePoints)
+		if pointsNum > 0 && point-lanesInfo.LanesChangePoints[pointsNum-1] < resolution {
+			// Too short segment: pocket start is snapped to the previous point, but end of the link is kept as is
+			if point == lengthMeters && pointsNum > 1 {
+				lanesInfo.LanesChangePoints[pointsNum-1] = lengthMeters
+			}
+			continue
+		}
+		lanesInfo.LanesChangePoints = append(lanesInfo.LanesChangePoints, point)
+	}
+	if len(lanesInfo.LanesChangePoints) < 2 {
+		lanesInfo.LanesChangePoints = []float64{0.0, lengthMeters}
+	}
+	for i := 0; i < len(lanesInfo.LanesChangePoints)-1; i++ {
+		distanceToEnd := lengthMeters - lanesInfo.LanesChangePoints[i]
+		lanesChange := [2]int{0, 0}
+		if left.Lanes > 0 && distanceToEnd <= left.Length+resolution {
+			lanesChange[0] = left.Lanes
+		}
+		if right.Lanes > 0 && distanceToEnd <= right.Length+resolution {
+			lanesChange[1] = right.Lanes
+		}
+		lanesInfo.LanesList = append(lanesInfo.LanesList, link.LanesNum()+lanesChange[0]+lanesChange[1])
+		lanesInfo.LanesChange = append(lanesInfo.LanesChange, lanesChange)
+	}
+	return lanesInfo
+}
+
+// normalized returns pocket with lanes number and length fitted into the link of the given length
+func (pocket TurnPocket) normalized(lengthMeters float64) TurnPocket {
+	if pocket.Lanes <= 0 || pocket.Length <= 0 {
+		return TurnPocket{}
+	}
+	return TurnPocket{
+		Lanes:  pocket.Lanes,
+		Length: min(pocket.Length, lengthMeters),
+	}
+}
+
+// TurnPockets returns turn pockets of the link extracted from its lanes information.
+// Only positive lanes changes on the downstream end of the link are treated as pockets.
+func (link *Link) TurnPockets() TurnPockets {
+	pockets := TurnPockets{}
+	lanesInfo := link.lanesInfo
+	segmentsNum := min(len(lanesInfo.LanesChange), len(lanesInfo.LanesChangePoints)-1)
+	if segmentsNum <= 0 {
+		return pockets
+	}
+	lengthMeters := lanesInfo.LanesChangePoints[segmentsNum]
+	for side := 0; side < 2; side++ {
+		lanes := lanesInfo.LanesChange[segmentsNum-1][side]
+		if lanes <= 0 {
+			continue
+		}
+		startIdx := segmentsNum - 1
+		for startIdx > 0 && lanesInfo.LanesChange[startIdx-1][side] == lanes {
+			startIdx--
+		}
+		pocket := TurnPocket{
+			Lanes:  lanes,
+			Length: lengthMeters - lanesInfo.LanesChangePoints[startIdx],
+		}
+		if side == 0 {
+			pockets.Left = pocket
+		} else {
+			pockets.Right = pocket
+		}
+	}
+	return pockets
+}
+
+// TurnPocketsFromTurnLanes estimates turn pockets from the value of OSM "turn:lanes" tag (or its ":forward"/":backward" variants).
+// Lanes described by the tag above the given number of lanes are treated as pockets: exclusive turn lanes on the edges of the road become pockets
+// of the given length. E.g. "left|through|through;right" with 2 lanes gives the single left pocket lane.
+// OSM lists lanes from left to right, so for left-hand traffic pockets sides are mirrored to follow lanes numbering.
+func TurnPocketsFromTurnLanes(turnLanes string, lanesNum int, length float64, drivingSide types.DrivingSide) TurnPockets {
+	pockets := TurnPockets{}
+	lanes := parseTurnDirections(turnLanes)
+	extra := len(lanes) - lanesNum
+	if lanesNum <= 0 || extra <= 0 {
+		return pockets
+	}
+	physicalLeft := 0
+	for physicalLeft < len(lanes) && isExclusiveTurn(lanes[physicalLeft], types.TURN_ANY_LEFT) {
+		physicalLeft++
+	}
+	physicalRight := 0
+	for physicalRight < len(lanes)-physicalLeft && isExclusiveTurn(lanes[len(lanes)-1-physicalRight], types.TURN_ANY_RIGHT) {
+		physicalRight++
+	}
+	innerSide, outerSide := physicalLeft, physicalRight
+	if drivingSide == types.DRIVING_SIDE_LEFT {
+		innerSide, outerSide = physicalRight, physicalLeft
+	}
+	// Turns across the opposite traffic are more likely to have dedicated pocket
+	innerLanes := min(innerSide, extra)
+	outerLanes := min(outerSide, extra-innerLanes)
+	if innerLanes > 0 {
+		pockets.Left = TurnPocket{Lanes: innerLanes, Length: length}
+	}
+	if outerLanes > 0 {
+		pockets.Right = TurnPocket{Lanes: outerLanes, Length: length}
+	}
+	return pockets
+}
+
+// isExclusiveTurn checks whether lane is marked and every its designation is one of the given ones
+func isExclusiveTurn(turn types.TurnDirection, allowed types.TurnDirection) bool {
+	return turn != types.TURN_NONE && turn&^allowed == 0
+}
@@ -0,0 +1,41 @@
+package macro
+
+import (
+	"testing"
+
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLanesInfoWithPockets(t *testing.T) {
+	link := NewLinkFrom(1, 1, 2, WithLengthMeters(200), WithLanesNum(2))
+	pockets := TurnPockets{
+		Left:  TurnPocket{Lanes: 1, Length: 60},
+		Right: TurnPocket{Lanes: 1, Length: 30},
+	}
+	lanesInfo := NewLanesInfoWithPockets(link, pockets)
+	assert.Equal(t, []float64{0, 140, 170, 200}, lanesInfo.LanesChangePoints, "Wrong lanes change points")
+	assert.Equal(t, []int{2, 3, 4}, lanesInfo.LanesList, "Wrong lanes list")
+	assert.Equal(t, [][2]int{{0, 0}, {1, 0}, {1, 1}}, lanesInfo.LanesChange, "Wrong lanes change")
+
+	WithLanesInfo(lanesInfo)(link)
+	assert.Equal(t, pockets, link.TurnPockets(), "Pockets should be restored from lanes information")
+	assert.Equal(t, []int{-1, 1, 2, 3}, link.GetOutcomingLaneIndices(), "Wrong outcoming lanes indices")
+	assert.Equal(t, 2, link.GetIncomingLanes(), "Pockets should not affect upstream end")
+	assert.Equal(t, 4, link.GetOutcomingLanes(), "Pockets should be counted on downstream end")
+}
+
+func TestTurnPocketsFromTurnLanes(t *testing.T) {
+	pockets := TurnPocketsFromTurnLanes("left|through|through;right", 2, 50, types.DRIVING_SIDE_RIGHT)
+	assert.Equal(t, TurnPockets{Left: TurnPocket{Lanes: 1, Length: 50}}, pockets)
+
+	pockets = TurnPocketsFromTurnLanes("left|through|through|right", 2, 50, types.DRIVING_SIDE_RIGHT)
+	assert.Equal(t, TurnPockets{Left: TurnPocket{Lanes: 1, Length: 50}, Right: TurnPocket{Lanes: 1, Length: 50}}, pockets)
+
+	// Right turn crosses opposite traffic for left-hand traffic, so it takes the inner pocket first
+	pockets = TurnPocketsFromTurnLanes("left|through|right", 2, 50, types.DRIVING_SIDE_LEFT)
+	assert.Equal(t, TurnPockets{Left: TurnPocket{Lanes: 1, Length: 50}}, pockets)
+
+	pockets = TurnPocketsFromTurnLanes("left|through", 2, 50, types.DRIVING_SIDE_RIGHT)
+	assert.Equal(t, TurnPockets{}, pockets, "No pockets are expected when there are no extra lanes")
+}
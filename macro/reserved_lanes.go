@@ -0,0 +1,79 @@
+package macro
+
+import (
+	"strings"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/gmns/types"
+)
+
+// ReservedLanesFromTags returns attributes for the lanes reserved for specific agent types from values of OSM "bus:lanes", "psv:lanes" and "hov:lanes" tags.
+// Only "designated" lanes are considered as reserved ones. Lanes are ordered the same way as in ParseTurnLanes. Output contains reserved lanes only
+func ReservedLanesFromTags(linkID gmns.LinkID, busLanes, psvLanes, hovLanes string, drivingSide types.DrivingSide) []gmns.Lane {
+	reservations := []struct {
+		value       string
+		agentTypes  []types.AgentType
+		designation types.LaneDesignation
+	}{
+		{busLanes, []types.AgentType{types.AGENT_BUS}, types.LANE_DESIGNATION_BUS},
+		{psvLanes, []types.AgentType{types.AGENT_BUS, types.AGENT_TAXI}, types.LANE_DESIGNATION_BUS},
+		{hovLanes, []types.AgentType{types.AGENT_HOV}, types.LANE_DESIGNATION_HOV},
+	}
+	lanesByNum := make(map[int]*gmns.Lane)
+	maxLaneNum := 0
+	for _, reservation := range reservations {
+		for laneNum, access := range parseLanesAccess(reservation.value, drivingSide) {
+			if access != "designated" {
+				continue
+			}
+			lane, ok := lanesByNum[laneNum]
+			if !ok {
+				newLane := gmns.NewLane(linkID, laneNum)
+				newLane.Designation = reservation.designation
+				lane = &newLane
+				lanesByNum[laneNum] = lane
+			}
+			for _, agentType := range reservation.agentTypes {
+				if !containsAgentType(lane.AllowedAgentTypes, agentType) {
+					lane.AllowedAgentTypes = append(lane.AllowedAgentTypes, agentType)
+				}
+			}
+			maxLaneNum = max(maxLaneNum, laneNum)
+		}
+	}
+	ans := make([]gmns.Lane, 0, len(lanesByNum))
+	for laneNum := 1; laneNum <= maxLaneNum; laneNum++ {
+		if lane, ok := lanesByNum[laneNum]; ok {
+			ans = append(ans, *lane)
+		}
+	}
+	return ans
+}
+
+// parseLanesAccess splits value of OSM "*:lanes" access tag into lowercased values mapped to lanes numbers (starting from 1)
+func parseLanesAccess(value string, drivingSide types.DrivingSide) map[int]string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return map[int]string{}
+	}
+	valuesStr := strings.Split(value, "|")
+	ans := make(map[int]string, len(valuesStr))
+	for i, valueStr := range valuesStr {
+		laneNum := i + 1
+		if drivingSide == types.DRIVING_SIDE_LEFT {
+			laneNum = len(valuesStr) - i
+		}
+		ans[laneNum] = strings.ToLower(strings.TrimSpace(valueStr))
+	}
+	return ans
+}
+
+// containsAgentType checks whether the given agent type is in the list
+func containsAgentType(agentTypes []types.AgentType, agentType types.AgentType) bool {
+	for _, a := range agentTypes {
+		if a == agentType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package macro
+
+import (
+	"testing"
+
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReservedLanesFromTags(t *testing.T) {
+	lanes := ReservedLanesFromTags(5, "yes|yes|designated", "", "designated||", types.DRIVING_SIDE_RIGHT)
+	assert.Len(t, lanes, 2)
+	assert.Equal(t, 1, lanes[0].LaneNum)
+	assert.Equal(t, types.LANE_DESIGNATION_HOV, lanes[0].Designation)
+	assert.Equal(t, []types.AgentType{types.AGENT_HOV}, lanes[0].AllowedAgentTypes)
+	assert.Equal(t, 3, lanes[1].LaneNum)
+	assert.Equal(t, types.LANE_DESIGNATION_BUS, lanes[1].Designation)
+	assert.Equal(t, []types.AgentType{types.AGENT_BUS}, lanes[1].AllowedAgentTypes)
+
+	// Lanes are reversed for left-hand traffic; PSV lane is shared by buses and taxis
+	lanes = ReservedLanesFromTags(5, "", "designated|yes", "", types.DRIVING_SIDE_LEFT)
+	assert.Len(t, lanes, 1)
+	assert.Equal(t, 2, lanes[0].LaneNum)
+	assert.Equal(t, []types.AgentType{types.AGENT_BUS, types.AGENT_TAXI}, lanes[0].AllowedAgentTypes)
+}
@@ -0,0 +1,298 @@
+package macro
+
+import (
+	"sort"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/pkg/errors"
+)
+
+// Simplification contains results of the degree-2 nodes simplification
+type Simplification struct {
+	// Removed link identifier -> identifier of the link which it has been merged into
+	MergedLinks map[gmns.LinkID]gmns.LinkID
+	// Sorted identifiers of the removed pass-through nodes
+	RemovedNodes []gmns.NodeID
+}
+
+// Simplify merges consecutive links through pass-through nodes: nodes with exactly one incoming and one outcoming link per direction.
+// Links are merged only if they have the same link type, lanes, speeds, allowed agent types and names.
+// Signalized, boundary, zone, POI and intersection nodes are never removed.
+// The upstream link keeps its identifier, while geometry, length and lanes information of the downstream link are appended to it.
+func (net *Net) Simplify() (*Simplification, error) {
+	result := &Simplification{
+		MergedLinks:  make(map[gmns.LinkID]gmns.LinkID),
+		RemovedNodes: make([]gmns.NodeID, 0),
+	}
+	nodesIDs := make([]gmns.NodeID, 0, len(net.Nodes))
+	for nodeID := range net.Nodes {
+		nodesIDs = append(nodesIDs, nodeID)
+	}
+	sort.Slice(nodesIDs, func(i, j int) bool {
+		return nodesIDs[i] < nodesIDs[j]
+	})
+	for _, nodeID := range nodesIDs {
+		if !isSimplificationCandidate(net.Nodes[nodeID]) {
+			continue
+		}
+		merged, err := net.mergeLinksAtNode(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		if len(merged) == 0 {
+			continue
+		}
+		for removedID, keptID := range merged {
+			result.MergedLinks[removedID] = keptID
+		}
+		result.RemovedNodes = append(result.RemovedNodes, nodeID)
+	}
+	// Keep mapping flat: links merged into the removed one are parts of the final upstream link
+	for removedID := range result.MergedLinks {
+		finalID := result.MergedLinks[removedID]
+		for {
+			keptID, ok := result.MergedLinks[finalID]
+			if !ok {
+				break
+			}
+			finalID = keptID
+		}
+		// Compress the path so following lookups are short
+		for linkID := removedID; linkID != finalID; {
+			keptID := result.MergedLinks[linkID]
+			result.MergedLinks[linkID] = finalID
+			linkID = keptID
+		}
+	}
+	return result, nil
+}
+
+// MergeLinksAtNode merges links passing through the given node and removes the node (see Simplify).
+// Returns mapping from the removed link identifiers to identifiers of links which they have been merged into.
+func (net *Net) MergeLinksAtNode(nodeID gmns.NodeID) (map[gmns.LinkID]gmns.LinkID, error) {
+	node, ok := net.Nodes[nodeID]
+	if !ok {
+		return nil, errors.Wrapf(ErrNodeNotFound, "Node ID: %d", nodeID)
+	}
+	if !isSimplificationCandidate(node) {
+		return nil, errors.Wrapf(ErrNotPassThrough, "Node ID: %d", nodeID)
+	}
+	merged, err := net.mergeLinksAtNode(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if len(merged) == 0 {
+		return nil, errors.Wrapf(ErrNotPassThrough, "Node ID: %d", nodeID)
+	}
+	return merged, nil
+}
+
+// mergeLinksAtNode merges links passing through the given node if possible. Returns empty mapping if links can't be merged
+func (net *Net) mergeLinksAtNode(nodeID gmns.NodeID) (map[gmns.LinkID]gmns.LinkID, error) {
+	node := net.Nodes[nodeID]
+	pairs, err := net.passThroughPairs(node)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't find pass-through links for node %d", nodeID)
+	}
+	merged := make(map[gmns.LinkID]gmns.LinkID, len(pairs))
+	if len(pairs) == 0 {
+		return merged, nil
+	}
+	for _, pair := range pairs {
+		err = net.mergeLinks(pair[0], pair[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Can't merge link %d into link %d", pair[1].ID, pair[0].ID)
+		}
+		merged[pair[1].ID] = pair[0].ID
+	}
+	delete(net.Nodes, nodeID)
+	return merged, nil
+}
+
+// isSimplificationCandidate checks whether the given node could be removed by simplification
+func isSimplificationCandidate(node *Node) bool {
+	if node.controlType == types.CONTROL_TYPE_IS_SIGNAL || node.boundaryType != types.BOUNDARY_NONE || node.isCentroid {
+		return false
+	}
+	if node.zoneID >= 0 || node.poiID >= 0 || node.intersectionID >= 0 {
+		return false
+	}
+	return (len(node.incomingLinks) == 1 && len(node.outcomingLinks) == 1) || (len(node.incomingLinks) == 2 && len(node.outcomingLinks) == 2)
+}
+
+// passThroughPairs returns pairs of [incoming, outcoming] links which could be merged through the given node.
+// Returns empty slice if the node is not a pass-through one or if any of pairs can't be merged.
+func (net *Net) passThroughPairs(node *Node) ([][2]*Link, error) {
+	incoming := make([]*Link, 0, len(node.incomingLinks))
+	for _, linkID := range node.incomingLinks {
+		link, ok := net.Links[linkID]
+		if !ok {
+			return nil, errors.Wrapf(ErrLinkNotFound, "Link ID: %d", linkID)
+		}
+		incoming = append(incoming, link)
+	}
+	outcoming := make([]*Link, 0, len(node.outcomingLinks))
+	for _, linkID := range node.outcomingLinks {
+		link, ok := net.Links[linkID]
+		if !ok {
+			return nil, errors.Wrapf(ErrLinkNotFound, "Link ID: %d", linkID)
+		}
+		outcoming = append(outcoming, link)
+	}
+	for _, link := range incoming {
+		// Loops are kept as is
+		if link.sourceNodeID == node.ID {
+			return nil, nil
+		}
+	}
+	pairs := make([][2]*Link, 0, len(incoming))
+	switch len(incoming) {
+	case 1:
+		// Dead ends are kept as is
+		if incoming[0].sourceNodeID == outcoming[0].targetNodeID {
+			return nil, nil
+		}
+		pairs = append(pairs, [2]*Link{incoming[0], outcoming[0]})
+	case 2:
+		// Bidirectional road: every incoming link continues to the node which is not its source
+		if incoming[0].sourceNodeID == incoming[1].sourceNodeID {
+			return nil, nil
+		}
+		for _, inLink := range incoming {
+			var next *Link
+			for _, outLink := range outcoming {
+				if outLink.targetNodeID != inLink.sourceNodeID {
+					continue
+				}
+				for _, candidate := range outcoming {
+					if candidate != outLink {
+						next = candidate
+					}
+				}
+			}
+			if next == nil || next.targetNodeID == inLink.sourceNodeID || next.targetNodeID == node.ID {
+				return nil, nil
+			}
+			pairs = append(pairs, [2]*Link{inLink, next})
+		}
+	default:
+		return nil, nil
+	}
+	for _, pair := range pairs {
+		if !canMergeLinks(pair[0], pair[1]) {
+			return nil, nil
+		}
+	}
+	return pairs, nil
+}
+
+// canMergeLinks checks whether two consecutive links have the same attributes
+func canMergeLinks(first, second *Link) bool {
+	if first.linkType != second.linkType || first.name != second.name {
+		return false
+	}
+	if first.lanesNum != second.lanesNum || first.GetOutcomingLanes() != second.GetIncomingLanes() {
+		return false
+	}
+	if first.freeSpeed != second.freeSpeed || first.maxSpeed != second.maxSpeed {
+		return false
+	}
+	if first.wasBidirectional != second.wasBidirectional {
+		return false
+	}
+	if len(first.turnLanes) != 0 {
+		// Turn designations would be lost in the middle of the merged link
+		return false
+	}
+	if !sameLanes(first.lanes, second.lanes) {
+		return false
+	}
+	if !sameLaneChanges(first.laneChanges, second.laneChanges) {
+		return false
+	}
+	return sameAgentTypes(first.allowedAgentTypes, second.allowedAgentTypes)
+}
+
+// sameLanes checks whether two links have the same per-lane attributes regardless of lanes identifiers
+func sameLanes(first, second []gmns.Lane) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for i := range first {
+		if first[i].LaneNum != second[i].LaneNum || first[i].Width != second[i].Width || first[i].Designation != second[i].Designation {
+			return false
+		}
+		if !sameAgentTypes(first[i].AllowedAgentTypes, second[i].AllowedAgentTypes) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameLaneChanges checks whether two lists of lane change permissions are equal
+func sameLaneChanges(first, second []types.LaneChange) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sameAgentTypes checks whether two sets of agent types are equal regardless of order
+func sameAgentTypes(first, second []types.AgentType) bool {
+	firstSet := make(map[types.AgentType]struct{}, len(first))
+	for _, agentType := range first {
+		firstSet[agentType] = struct{}{}
+	}
+	secondSet := make(map[types.AgentType]struct{}, len(second))
+	for _, agentType := range second {
+		if _, ok := firstSet[agentType]; !ok {
+			return false
+		}
+		secondSet[agentType] = struct{}{}
+	}
+	return len(firstSet) == len(secondSet)
+}
+
+// mergeLinks appends the second link to the first one and removes the second link from the network.
+// Shared node lists are not updated for the node between links since it is expected to be removed.
+func (net *Net) mergeLinks(first, second *Link) error {
+	targetNode, ok := net.Nodes[second.targetNodeID]
+	if !ok {
+		return errors.Wrapf(ErrNodeNotFound, "Node ID: %d", second.targetNodeID)
+	}
+	geom := first.geom.Clone()
+	secondGeom := second.geom
+	if len(geom) > 0 && len(secondGeom) > 0 && geom[len(geom)-1].Equal(secondGeom[0]) {
+		secondGeom = secondGeom[1:]
+	}
+	first.geom = append(geom, secondGeom...)
+	geomEuclidean := first.geomEuclidean.Clone()
+	secondGeomEuclidean := second.geomEuclidean
+	if len(geomEuclidean) > 0 && len(secondGeomEuclidean) > 0 && geomEuclidean[len(geomEuclidean)-1].Equal(secondGeomEuclidean[0]) {
+		secondGeomEuclidean = secondGeomEuclidean[1:]
+	}
+	first.geomEuclidean = append(geomEuclidean, secondGeomEuclidean...)
+	if first.lengthMeters >= 0 && second.lengthMeters >= 0 {
+		first.lengthMeters += second.lengthMeters
+	} else {
+		first.lengthMeters = -1
+	}
+	first.lanesInfo = concatLanesInfo(first.lanesInfo, second.lanesInfo)
+	first.turnLanes = second.turnLanes
+	first.targetNodeID = second.targetNodeID
+	first.targetOsmNodeID = second.targetOsmNodeID
+	first.controlType = second.controlType
+	for i, linkID := range targetNode.incomingLinks {
+		if linkID == second.ID {
+			targetNode.incomingLinks[i] = first.ID
+		}
+	}
+	delete(net.Links, second.ID)
+	return nil
+}
@@ -0,0 +1,104 @@
+package macro
+
+import (
+	"testing"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/paulmach/orb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimplifyTwoWayChain(t *testing.T) {
+	points := map[gmns.NodeID]orb.Point{
+		1: {37.6, 55.75},
+		2: {37.601, 55.75},
+		3: {37.602, 55.7505},
+		4: {37.603, 55.75},
+	}
+	net := testNet(t, points, [][2]gmns.NodeID{
+		{1, 2}, {2, 1},
+		{2, 3}, {3, 2},
+		{3, 4}, {4, 3},
+	})
+	forwardLength := net.Links[0].lengthMeters + net.Links[2].lengthMeters + net.Links[4].lengthMeters
+
+	result, err := net.Simplify()
+	assert.NoError(t, err)
+	assert.Equal(t, []gmns.NodeID{2, 3}, result.RemovedNodes)
+	assert.Equal(t, map[gmns.LinkID]gmns.LinkID{2: 0, 4: 0, 3: 5, 1: 5}, result.MergedLinks, "Mapping should point to the final links")
+	assert.Len(t, net.Nodes, 2)
+	assert.Len(t, net.Links, 2)
+
+	forward, backward := net.Links[0], net.Links[5]
+	assert.Equal(t, gmns.NodeID(1), forward.sourceNodeID)
+	assert.Equal(t, gmns.NodeID(4), forward.targetNodeID)
+	assert.Equal(t, gmns.NodeID(4), backward.sourceNodeID)
+	assert.Equal(t, gmns.NodeID(1), backward.targetNodeID)
+	assert.Equal(t, orb.LineString{points[1], points[2], points[3], points[4]}, forward.geom, "Shared points should not be duplicated")
+	assert.InDelta(t, forwardLength, forward.lengthMeters, 1e-6)
+	assert.Equal(t, []int{1}, forward.lanesInfo.LanesList, "Equal lanes should be joined into the single segment")
+	assert.InDelta(t, forwardLength, forward.lanesInfo.LanesChangePoints[len(forward.lanesInfo.LanesChangePoints)-1], 1e-6)
+	assert.Equal(t, []gmns.LinkID{5}, net.Nodes[1].incomingLinks)
+	assert.Equal(t, []gmns.LinkID{0}, net.Nodes[4].incomingLinks)
+
+	// Nothing to simplify anymore: both nodes are dead ends
+	result, err = net.Simplify()
+	assert.NoError(t, err)
+	assert.Len(t, result.MergedLinks, 0)
+}
+
+func TestSimplifyDifferentLanes(t *testing.T) {
+	points := map[gmns.NodeID]orb.Point{
+		1: {37.6, 55.75},
+		2: {37.601, 55.75},
+		3: {37.602, 55.75},
+	}
+	net := testNet(t, points, [][2]gmns.NodeID{{1, 2}, {2, 3}})
+	link := net.Links[1]
+	WithLanesNum(2)(link)
+	link.lanesInfo = NewLanesInfo(link)
+
+	result, err := net.Simplify()
+	assert.NoError(t, err)
+	assert.Len(t, result.MergedLinks, 0, "Links with different lanes should not be merged")
+	assert.Len(t, result.RemovedNodes, 0)
+	_, err = net.MergeLinksAtNode(2)
+	assert.ErrorIs(t, err, ErrNotPassThrough)
+
+	WithLanesNum(1)(link)
+	link.lanesInfo = NewLanesInfo(link)
+	merged, err := net.MergeLinksAtNode(2)
+	assert.NoError(t, err)
+	assert.Equal(t, map[gmns.LinkID]gmns.LinkID{1: 0}, merged)
+	_, ok := net.Nodes[2]
+	assert.False(t, ok, "Pass-through node should be removed")
+}
+
+func TestConcatLanesInfo(t *testing.T) {
+	first := LanesInfo{
+		LanesList:         []int{2, 3},
+		LanesChange:       [][2]int{{0, 0}, {0, 1}},
+		LanesChangePoints: []float64{0, 50, 100},
+	}
+	// The same lanes at the junction point are joined
+	joined := concatLanesInfo(first, LanesInfo{
+		LanesList:         []int{3, 2},
+		LanesChange:       [][2]int{{0, 1}, {0, 0}},
+		LanesChangePoints: []float64{0, 30, 60},
+	})
+	assert.Equal(t, []int{2, 3, 2}, joined.LanesList)
+	assert.Equal(t, [][2]int{{0, 0}, {0, 1}, {0, 0}}, joined.LanesChange)
+	assert.Equal(t, []float64{0, 50, 130, 160}, joined.LanesChangePoints)
+
+	// Different lanes at the junction point keep the change point
+	separate := concatLanesInfo(first, LanesInfo{
+		LanesList:         []int{2},
+		LanesChange:       [][2]int{{0, 0}},
+		LanesChangePoints: []float64{0, 40},
+	})
+	assert.Equal(t, []int{2, 3, 2}, separate.LanesList)
+	assert.Equal(t, [][2]int{{0, 0}, {0, 1}, {0, 0}}, separate.LanesChange)
+	assert.Equal(t, []float64{0, 50, 100, 140}, separate.LanesChangePoints)
+
+	assert.Equal(t, LanesInfo{}, concatLanesInfo(first, LanesInfo{}), "Empty lanes information should give empty result")
+}
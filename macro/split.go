@@ -0,0 +1,198 @@
+package macro
+
+import (
+	"math"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/LdDl/go-gmns/utils/geomath"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/paulmach/osm"
+	"github.com/pkg/errors"
+)
+
+// LinkSplit contains results of the link split
+type LinkSplit struct {
+	// Identifier of the created node
+	NodeID gmns.NodeID
+	// Upstream part of the split link keeps identifier of the original link
+	Upstream   gmns.LinkID
+	Downstream gmns.LinkID
+	// Parts of the opposite link. Equal "-1" if the opposite link has not been split
+	OppositeUpstream   gmns.LinkID
+	OppositeDownstream gmns.LinkID
+}
+
+// SplitLink inserts new node into the link at the given distance [meters] from its source node along the geometry.
+// The link is cut into two links: the upstream one keeps the original identifier, the downstream one gets the new identifier.
+// If splitOpposite is set and the link has been produced from the bidirectional road then the opposite link is split at the same node too.
+// Network is left untouched if an error is returned
+func (net *Net) SplitLink(linkID gmns.LinkID, distance float64, splitOpposite bool) (*LinkSplit, error) {
+	link, ok := net.Links[linkID]
+	if !ok {
+		return nil, errors.Wrapf(ErrLinkNotFound, "Link ID: %d", linkID)
+	}
+	if len(link.geom) < 2 {
+		return nil, errors.Wrapf(ErrSplitEmptyGeom, "Link ID: %d", linkID)
+	}
+	geomLength := geo.LengthHaversine(link.geom)
+	if distance <= 0 || distance >= geomLength {
+		return nil, errors.Wrapf(ErrSplitOutOfRange, "Link ID: %d. Distance: %f. Length: %f", linkID, distance, geomLength)
+	}
+	var opposite *Link
+	if splitOpposite && link.wasBidirectional {
+		opposite = net.findOppositeLink(link)
+		if opposite != nil && len(opposite.geom) < 2 {
+			opposite = nil
+		}
+	}
+	// Check everything the cuts depend on, so a failure leaves the network untouched
+	if _, ok := net.Nodes[link.targetNodeID]; !ok {
+		return nil, errors.Wrapf(ErrNodeNotFound, "Can't cut link %d: target node ID: %d", linkID, link.targetNodeID)
+	}
+	if opposite != nil {
+		if _, ok := net.Nodes[opposite.targetNodeID]; !ok {
+			return nil, errors.Wrapf(ErrNodeNotFound, "Can't cut opposite link %d: target node ID: %d", opposite.ID, opposite.targetNodeID)
+		}
+	}
+
+	splitPoint, _ := geo.PointAtDistanceAlongLine(link.geom, distance)
+	node := NewNodeFrom(
+		net.NewNodeID(),
+		WithPointGeom(splitPoint),
+		WithPointGeomEuclidean(geomath.PointToEuclidean(splitPoint)),
+	)
+	result := &LinkSplit{
+		NodeID:             node.ID,
+		Upstream:           link.ID,
+		Downstream:         -1,
+		OppositeUpstream:   -1,
+		OppositeDownstream: -1,
+	}
+	downstream, err := net.cutLink(link, distance/geomLength, node)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't cut link %d", linkID)
+	}
+	result.Downstream = downstream.ID
+	if opposite != nil {
+		oppositeDownstream, err := net.cutLink(opposite, 1.0-distance/geomLength, node)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Can't cut opposite link %d", opposite.ID)
+		}
+		result.OppositeUpstream = opposite.ID
+		result.OppositeDownstream = oppositeDownstream.ID
+	}
+	// Node is inserted after both cuts, so it is never left orphaned
+	net.Nodes[node.ID] = node
+	return result, nil
+}
+
+// SplitLinkAtPoint splits the link at the point of its geometry which is the nearest to the given one (see SplitLink)
+func (net *Net) SplitLinkAtPoint(linkID gmns.LinkID, pt orb.Point, splitOpposite bool) (*LinkSplit, error) {
+	link, ok := net.Links[linkID]
+	if !ok {
+		return nil, errors.Wrapf(ErrLinkNotFound, "Link ID: %d", linkID)
+	}
+	if len(link.geom) < 2 {
+		return nil, errors.Wrapf(ErrSplitEmptyGeom, "Link ID: %d", linkID)
+	}
+	return net.SplitLink(linkID, projectOnLine(link.geom, pt), splitOpposite)
+}
+
+// cutLink cuts the link at the given fraction [0; 1] of its length. The link becomes the upstream part ending at the given node,
+// while the downstream part is added to the network as the new link starting at the given node.
+// Geometries of both parts are snapped to the node.
+func (net *Net) cutLink(link *Link, fraction float64, node *Node) (*Link, error) {
+	targetNode, ok := net.Nodes[link.targetNodeID]
+	if !ok {
+		return nil, errors.Wrapf(ErrNodeNotFound, "Node ID: %d", link.targetNodeID)
+	}
+	geomLength := geo.LengthHaversine(link.geom)
+	upstreamGeom := geomath.SubstringHaversine(link.geom, 0, fraction*geomLength)
+	downstreamGeom := geomath.SubstringHaversine(link.geom, fraction*geomLength, geomLength)
+	upstreamGeom[0] = link.geom[0]
+	upstreamGeom[len(upstreamGeom)-1] = node.geom
+	downstreamGeom[0] = node.geom
+	downstreamGeom[len(downstreamGeom)-1] = link.geom[len(link.geom)-1]
+	upstreamLanes, downstreamLanes := splitLanesInfo(link.lanesInfo, fraction)
+
+	downstream := link.clone(net.NewLinkID())
+	downstream.geom = downstreamGeom
+	downstream.geomEuclidean = geomath.LineToEuclidean(downstreamGeom)
+	downstream.lanesInfo = downstreamLanes
+	downstream.sourceNodeID = node.ID
+	downstream.sourceOsmNodeID = osm.NodeID(-1)
+
+	link.geom = upstreamGeom
+	link.geomEuclidean = geomath.LineToEuclidean(upstreamGeom)
+	link.lanesInfo = upstreamLanes
+	link.turnLanes = []types.TurnDirection{} // Turn designations are kept by the downstream part only
+	link.targetNodeID = node.ID
+	link.targetOsmNodeID = osm.NodeID(-1)
+	link.controlType = types.CONTROL_TYPE_NOT_SIGNAL
+	if link.lengthMeters >= 0 {
+		downstream.lengthMeters = (1.0 - fraction) * link.lengthMeters
+		link.lengthMeters = fraction * link.lengthMeters
+	}
+
+	for i, linkID := range targetNode.incomingLinks {
+		if linkID == link.ID {
+			targetNode.incomingLinks[i] = downstream.ID
+		}
+	}
+	node.incomingLinks = append(node.incomingLinks, link.ID)
+	node.outcomingLinks = append(node.outcomingLinks, downstream.ID)
+	net.Links[downstream.ID] = downstream
+	return downstream, nil
+}
+
+// findOppositeLink returns link of the opposite direction for the link produced from bidirectional road.
+// Link with the same OSM way is preferred. Returns nil if there is no such link
+func (net *Net) findOppositeLink(link *Link) *Link {
+	targetNode, ok := net.Nodes[link.targetNodeID]
+	if !ok {
+		return nil
+	}
+	var opposite *Link
+	for _, linkID := range targetNode.outcomingLinks {
+		candidate, ok := net.Links[linkID]
+		if !ok || candidate.ID == link.ID || !candidate.wasBidirectional || candidate.targetNodeID != link.sourceNodeID {
+			continue
+		}
+		if candidate.osmWayID == link.osmWayID {
+			return candidate
+		}
+		if opposite == nil || candidate.ID < opposite.ID {
+			opposite = candidate
+		}
+	}
+	return opposite
+}
+
+// projectOnLine returns distance [meters] along the line to the point of the line which is the nearest to the given point.
+// Projection is done in Euclidean space, while the distance is measured with haversine formula
+func projectOnLine(line orb.LineString, pt orb.Point) float64 {
+	lineEuclidean := geomath.LineToEuclidean(line)
+	ptEuclidean := geomath.PointToEuclidean(pt)
+	bestDistance := math.Inf(1)
+	bestAlong := 0.0
+	passed := 0.0
+	for i := 1; i < len(lineEuclidean); i++ {
+		a, b := lineEuclidean[i-1], lineEuclidean[i]
+		dx, dy := b[0]-a[0], b[1]-a[1]
+		t := 0.0
+		if segmentSquared := dx*dx + dy*dy; segmentSquared > 0 {
+			t = ((ptEuclidean[0]-a[0])*dx + (ptEuclidean[1]-a[1])*dy) / segmentSquared
+			t = math.Max(0, math.Min(1, t))
+		}
+		px, py := a[0]+t*dx-ptEuclidean[0], a[1]+t*dy-ptEuclidean[1]
+		segmentLength := geo.DistanceHaversine(line[i-1], line[i])
+		if distance := px*px + py*py; distance < bestDistance {
+			bestDistance = distance
+			bestAlong = passed + t*segmentLength
+		}
+		passed += segmentLength
+	}
+	return bestAlong
+}
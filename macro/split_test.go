@@ -0,0 +1,90 @@
+package macro
+
+import (
+	"testing"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/utils/geomath"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitLink(t *testing.T) {
+	net := NewNet()
+	points := []orb.Point{{37.6, 55.75}, {37.601, 55.7505}, {37.603, 55.7505}}
+	net.Nodes[1] = NewNodeFrom(1, WithPointGeom(points[0]), WithOutcomingLinks(10), WithIncomingLinks(11))
+	net.Nodes[2] = NewNodeFrom(2, WithPointGeom(points[2]), WithOutcomingLinks(11), WithIncomingLinks(10))
+	forwardGeom := orb.LineString(points)
+	backwardGeom := forwardGeom.Clone()
+	backwardGeom.Reverse()
+	for _, link := range []*Link{
+		NewLinkFrom(10, 1, 2, WithLineGeom(forwardGeom), WithLengthMeters(geo.LengthHaversine(forwardGeom)), WithLanesNum(2), WithBidirectionalSource(true)),
+		NewLinkFrom(11, 2, 1, WithLineGeom(backwardGeom), WithLengthMeters(geo.LengthHaversine(backwardGeom)), WithLanesNum(2), WithBidirectionalSource(true)),
+	} {
+		link.geomEuclidean = geomath.LineToEuclidean(link.geom)
+		link.lanesInfo = NewLanesInfo(link)
+		net.Links[link.ID] = link
+	}
+	totalLength := net.Links[10].lengthMeters
+
+	split, err := net.SplitLink(10, 50, true)
+	assert.NoError(t, err)
+	assert.Equal(t, gmns.NodeID(3), split.NodeID, "Wrong new node ID")
+	assert.Equal(t, gmns.LinkID(10), split.Upstream, "Upstream part should keep original ID")
+	assert.Equal(t, gmns.LinkID(12), split.Downstream, "Wrong downstream part ID")
+	assert.Equal(t, gmns.LinkID(11), split.OppositeUpstream, "Wrong opposite upstream part ID")
+	assert.Equal(t, gmns.LinkID(13), split.OppositeDownstream, "Wrong opposite downstream part ID")
+	assert.Len(t, net.Links, 4)
+
+	upstream, downstream := net.Links[10], net.Links[12]
+	assert.InDelta(t, 50, upstream.lengthMeters, 1e-6, "Wrong upstream length")
+	assert.InDelta(t, totalLength-50, downstream.lengthMeters, 1e-6, "Wrong downstream length")
+	assert.Equal(t, upstream.geom[len(upstream.geom)-1], downstream.geom[0], "Parts should share the split point")
+	assert.Equal(t, net.Nodes[3].geom, downstream.geom[0], "Node should be placed at the split point")
+	assert.Equal(t, points[1], downstream.geom[1], "Intermediate points should be kept")
+	assert.InDelta(t, 50, upstream.lanesInfo.LanesChangePoints[len(upstream.lanesInfo.LanesChangePoints)-1], 1e-6, "Wrong upstream lanes info")
+	assert.InDelta(t, totalLength-50, downstream.lanesInfo.LanesChangePoints[len(downstream.lanesInfo.LanesChangePoints)-1], 1e-6, "Wrong downstream lanes info")
+
+	assert.Equal(t, []gmns.LinkID{10, 11}, net.Nodes[3].incomingLinks, "Wrong incoming links of the new node")
+	assert.Equal(t, []gmns.LinkID{12, 13}, net.Nodes[3].outcomingLinks, "Wrong outcoming links of the new node")
+	assert.Equal(t, []gmns.LinkID{12}, net.Nodes[2].incomingLinks, "Wrong incoming links of the target node")
+	assert.Equal(t, []gmns.LinkID{13}, net.Nodes[1].incomingLinks, "Wrong incoming links of the opposite target node")
+	assert.Equal(t, gmns.NodeID(1), net.Links[13].targetNodeID, "Wrong target of the opposite downstream part")
+
+	_, err = net.SplitLink(10, 1000, false)
+	assert.ErrorIs(t, err, ErrSplitOutOfRange)
+
+	// Split near the intermediate point of the downstream part
+	split, err = net.SplitLinkAtPoint(12, orb.Point{37.601, 55.7506}, false)
+	assert.NoError(t, err)
+	assert.InDelta(t, points[1].Lon(), net.Nodes[split.NodeID].geom.Lon(), 1e-6, "Wrong projection on the link")
+	assert.Equal(t, gmns.LinkID(-1), split.OppositeUpstream, "Opposite link should not be split")
+}
+
+func TestSplitLinkFailure(t *testing.T) {
+	points := map[gmns.NodeID]orb.Point{
+		1: {37.6, 55.75},
+		2: {37.602, 55.75},
+	}
+	net := testNet(t, points, [][2]gmns.NodeID{{1, 2}, {2, 1}})
+	for _, link := range net.Links {
+		link.wasBidirectional = true
+	}
+	// The opposite link ends at the missing node, so its cut is impossible
+	delete(net.Nodes, 1)
+	geomBefore := net.Links[0].geom.Clone()
+	_, err := net.SplitLink(0, 50, true)
+	assert.ErrorIs(t, err, ErrNodeNotFound)
+	assert.Len(t, net.Nodes, 1, "New node should not be left in the network")
+	assert.Len(t, net.Links, 2, "Links should not be cut")
+	assert.Equal(t, geomBefore, net.Links[0].geom)
+	assert.Equal(t, gmns.NodeID(2), net.Links[0].targetNodeID)
+	assert.Equal(t, []gmns.LinkID{0}, net.Nodes[2].incomingLinks)
+
+	// Missing target node of the link itself
+	_, err = net.SplitLink(1, 50, false)
+	assert.ErrorIs(t, err, ErrNodeNotFound)
+	assert.Len(t, net.Nodes, 1)
+	assert.Len(t, net.Links, 2)
+}
@@ -0,0 +1,56 @@
+package macro
+
+import (
+	"strings"
+
+	"github.com/LdDl/go-gmns/gmns/types"
+)
+
+// ParseTurnLanes parses value of OSM "turn:lanes" tag into turn designations ordered by lanes numbering.
+// OSM lists lanes from left to right, so for left-hand traffic lanes are reversed (lane 1 is the rightmost one).
+func ParseTurnLanes(turnLanes string, drivingSide types.DrivingSide) []types.TurnDirection {
+	lanes := parseTurnDirections(turnLanes)
+	if drivingSide == types.DRIVING_SIDE_LEFT {
+		for i, j := 0, len(lanes)-1; i < j; i, j = i+1, j-1 {
+			lanes[i], lanes[j] = lanes[j], lanes[i]
+		}
+	}
+	return lanes
+}
+
+// TurnLanesFromTags returns turn designations for the link from OSM tags values.
+// Direction specific tag ("turn:lanes:forward" for the link along the way and "turn:lanes:backward" for the opposite one) is preferred over "turn:lanes"
+func TurnLanesFromTags(turnLanes, turnLanesForward, turnLanesBackward string, forward bool, drivingSide types.DrivingSide) []types.TurnDirection {
+	value := turnLanesBackward
+	if forward {
+		value = turnLanesForward
+	}
+	if strings.TrimSpace(value) == "" {
+		value = turnLanes
+	}
+	return ParseTurnLanes(value, drivingSide)
+}
+
+// parseTurnDirections splits value of OSM "turn:lanes" tag into the list of lanes designations as is (from left to right)
+func parseTurnDirections(turnLanes string) []types.TurnDirection {
+	turnLanes = strings.TrimSpace(turnLanes)
+	if turnLanes == "" {
+		return []types.TurnDirection{}
+	}
+	lanesStr := strings.Split(turnLanes, "|")
+	lanes := make([]types.TurnDirection, len(lanesStr))
+	for i, laneStr := range lanesStr {
+		lanes[i] = types.NewTurnDirectionFrom(laneStr)
+	}
+	return lanes
+}
+
+// hasTurnDesignations checks whether at least one lane has marking
+func hasTurnDesignations(turnLanes []types.TurnDirection) bool {
+	for _, turn := range turnLanes {
+		if turn != types.TURN_NONE {
+			return true
+		}
+	}
+	return false
+}
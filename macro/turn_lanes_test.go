@@ -0,0 +1,54 @@
+package macro
+
+import (
+	"testing"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/paulmach/orb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTurnLanes(t *testing.T) {
+	turnLanes := ParseTurnLanes("left|through|Through;right|", types.DRIVING_SIDE_RIGHT)
+	expected := []types.TurnDirection{types.TURN_LEFT, types.TURN_THROUGH, types.TURN_THROUGH | types.TURN_RIGHT, types.TURN_NONE}
+	assert.Equal(t, expected, turnLanes, "Wrong turn designations")
+	assert.Equal(t, "through;right", turnLanes[2].String(), "Wrong designations string")
+	assert.Equal(t, "none", turnLanes[3].String(), "Wrong designations string for unmarked lane")
+
+	// Lane 1 is the rightmost one for left-hand traffic
+	turnLanes = ParseTurnLanes("left|through;right", types.DRIVING_SIDE_LEFT)
+	assert.Equal(t, []types.TurnDirection{types.TURN_THROUGH | types.TURN_RIGHT, types.TURN_LEFT}, turnLanes, "Wrong turn designations for left-hand traffic")
+
+	turnLanes = TurnLanesFromTags("through|right", "left|through", "", true, types.DRIVING_SIDE_RIGHT)
+	assert.Equal(t, []types.TurnDirection{types.TURN_LEFT, types.TURN_THROUGH}, turnLanes, "Forward tag should be preferred")
+	turnLanes = TurnLanesFromTags("through|right", "left|through", "", false, types.DRIVING_SIDE_RIGHT)
+	assert.Equal(t, []types.TurnDirection{types.TURN_THROUGH, types.TURN_RIGHT}, turnLanes, "Common tag should be used when backward one is absent")
+}
+
+func TestGenerateIntersectionsConnectionsWithTurnLanes(t *testing.T) {
+	newTestLink := func(id gmns.LinkID, geom orb.LineString) *Link {
+		link := NewLinkFrom(id, 0, 0, WithLineGeomEuclidean(geom), WithLengthMeters(100), WithLanesNum(2))
+		link.lanesInfo = NewLanesInfo(link)
+		return link
+	}
+	incomingLink := newTestLink(1, orb.LineString{{0, -100}, {0, 0}})
+	leftLink := newTestLink(2, orb.LineString{{0, 0}, {-100, 0}})
+	thruLink := newTestLink(3, orb.LineString{{0, 0}, {0, 100}})
+	rightLink := newTestLink(4, orb.LineString{{0, 0}, {100, 0}})
+	outcomingLinks := []*Link{thruLink, leftLink, rightLink}
+
+	// Shared through/right lane
+	WithTurnLanes([]types.TurnDirection{types.TURN_LEFT | types.TURN_THROUGH, types.TURN_THROUGH | types.TURN_RIGHT})(incomingLink)
+	connections := GenerateIntersectionsConnections(incomingLink, outcomingLinks)
+	assert.Equal(t, []ConnectionPair{{0, 1}, {0, 1}}, connections[0], "Through movement should use both lanes")
+	assert.Equal(t, []ConnectionPair{{0, 0}, {0, 0}}, connections[1], "Left movement should use the first lane")
+	assert.Equal(t, []ConnectionPair{{1, 1}, {1, 1}}, connections[2], "Right movement should use the second lane")
+
+	// Unmarked lane serves movements without designated lanes
+	WithTurnLanes([]types.TurnDirection{types.TURN_LEFT, types.TURN_NONE})(incomingLink)
+	connections = GenerateIntersectionsConnections(incomingLink, outcomingLinks)
+	assert.Equal(t, []ConnectionPair{{1, 1}, {1, 1}}, connections[0], "Through movement should use unmarked lane")
+	assert.Equal(t, []ConnectionPair{{0, 0}, {0, 0}}, connections[1], "Left movement should use the first lane")
+	assert.Equal(t, []ConnectionPair{{1, 1}, {1, 1}}, connections[2], "Right movement should use unmarked lane")
+}
@@ -0,0 +1,90 @@
+package macro
+
+import (
+	"sort"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/pkg/errors"
+)
+
+// Validate checks consistency of the network: links refer to existing nodes and are listed in their links, nodes list existing incident links,
+// links have lanes, positive length and free speed and geometry of two points at least. Returns all found problems sorted by nodes and links identifiers
+func (net *Net) Validate() []error {
+	problems := []error{}
+	nodesIDs := make([]gmns.NodeID, 0, len(net.Nodes))
+	for nodeID := range net.Nodes {
+		nodesIDs = append(nodesIDs, nodeID)
+	}
+	sort.Slice(nodesIDs, func(i, j int) bool {
+		return nodesIDs[i] < nodesIDs[j]
+	})
+	for _, nodeID := range nodesIDs {
+		node := net.Nodes[nodeID]
+		for _, linkID := range node.IncomingLinks() {
+			link, ok := net.Links[linkID]
+			if !ok {
+				problems = append(problems, errors.Wrapf(ErrLinkNotFound, "Node ID: %d. Incoming link ID: %d", nodeID, linkID))
+				continue
+			}
+			if link.TargetNode() != nodeID {
+				problems = append(problems, errors.Wrapf(ErrLinkNotIncident, "Node ID: %d. Incoming link ID: %d", nodeID, linkID))
+			}
+		}
+		for _, linkID := range node.OutcomingLinks() {
+			link, ok := net.Links[linkID]
+			if !ok {
+				problems = append(problems, errors.Wrapf(ErrLinkNotFound, "Node ID: %d. Outcoming link ID: %d", nodeID, linkID))
+				continue
+			}
+			if link.SourceNode() != nodeID {
+				problems = append(problems, errors.Wrapf(ErrLinkNotIncident, "Node ID: %d. Outcoming link ID: %d", nodeID, linkID))
+			}
+		}
+	}
+	linksIDs := make([]gmns.LinkID, 0, len(net.Links))
+	for linkID := range net.Links {
+		linksIDs = append(linksIDs, linkID)
+	}
+	sort.Slice(linksIDs, func(i, j int) bool {
+		return linksIDs[i] < linksIDs[j]
+	})
+	for _, linkID := range linksIDs {
+		link := net.Links[linkID]
+		if sourceNode, ok := net.Nodes[link.SourceNode()]; !ok {
+			problems = append(problems, errors.Wrapf(ErrNodeNotFound, "Link ID: %d. Source node ID: %d", linkID, link.SourceNode()))
+		} else if !containsLink(sourceNode.OutcomingLinks(), linkID) {
+			problems = append(problems, errors.Wrapf(ErrLinkNotListed, "Link ID: %d. Source node ID: %d", linkID, link.SourceNode()))
+		}
+		if targetNode, ok := net.Nodes[link.TargetNode()]; !ok {
+			problems = append(problems, errors.Wrapf(ErrNodeNotFound, "Link ID: %d. Target node ID: %d", linkID, link.TargetNode()))
+		} else if !containsLink(targetNode.IncomingLinks(), linkID) {
+			problems = append(problems, errors.Wrapf(ErrLinkNotListed, "Link ID: %d. Target node ID: %d", linkID, link.TargetNode()))
+		}
+		if link.SourceNode() == link.TargetNode() {
+			problems = append(problems, errors.Wrapf(ErrLinkLoop, "Link ID: %d. Node ID: %d", linkID, link.SourceNode()))
+		}
+		if link.LanesNum() <= 0 {
+			problems = append(problems, errors.Wrapf(ErrLinkNoLanes, "Link ID: %d. Lanes: %d", linkID, link.LanesNum()))
+		}
+		if link.LengthMeters() <= 0 {
+			problems = append(problems, errors.Wrapf(ErrLinkBadLength, "Link ID: %d. Length: %f", linkID, link.LengthMeters()))
+		}
+		if link.FreeSpeed() <= 0 {
+			problems = append(problems, errors.Wrapf(ErrLinkBadSpeed, "Link ID: %d. Free speed: %f", linkID, link.FreeSpeed()))
+		}
+		if len(link.Geom()) < 2 {
+			problems = append(problems, errors.Wrapf(ErrLinkBadGeom, "Link ID: %d. Points: %d", linkID, len(link.Geom())))
+		}
+	}
+	return problems
+}
+
+// containsLink checks if the link is in the list
+func containsLink(linksIDs []gmns.LinkID, linkID gmns.LinkID) bool {
+	for _, id := range linksIDs {
+		if id == linkID {
+			return true
+		}
+	}
+	return false
+}
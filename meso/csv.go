@@ -0,0 +1,102 @@
+package meso
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/paulmach/orb/encoding/wkt"
+	"github.com/pkg/errors"
+)
+
+var nodesCSVHeader = []string{"node_id", "zone_id", "macro_node_id", "macro_link_id", "activity_link_type", "boundary_type", "x_coord", "y_coord"}
+
+var linksCSVHeader = []string{"link_id", "from_node_id", "to_node_id", "macro_node_id", "macro_link_id", "segment_idx", "link_type", "ctrl_type", "mvmt_id", "mvmt_txt_id", "allowed_uses", "lanes", "free_speed", "capacity", "length", "geometry"}
+
+// WriteNodesCSV writes nodes of the network as node table (node.csv) sorted by identifiers
+func WriteNodesCSV(w io.Writer, net *Net) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(nodesCSVHeader)
+	if err != nil {
+		return errors.Wrap(err, "Can't write nodes header")
+	}
+	nodesIDs := make([]gmns.NodeID, 0, len(net.Nodes))
+	for nodeID := range net.Nodes {
+		nodesIDs = append(nodesIDs, nodeID)
+	}
+	sort.Slice(nodesIDs, func(i, j int) bool {
+		return nodesIDs[i] < nodesIDs[j]
+	})
+	for _, nodeID := range nodesIDs {
+		node := net.Nodes[nodeID]
+		err = writer.Write([]string{
+			strconv.Itoa(int(node.ID)),
+			strconv.Itoa(int(node.MacroZone())),
+			strconv.Itoa(int(node.MacroNode())),
+			strconv.Itoa(int(node.MacroLink())),
+			node.ActivityLinkType().String(),
+			node.BoundaryType().String(),
+			formatFloat(node.Geom().X()),
+			formatFloat(node.Geom().Y()),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Can't write node %d", node.ID)
+		}
+	}
+	writer.Flush()
+	return errors.Wrap(writer.Error(), "Can't flush nodes")
+}
+
+// WriteLinksCSV writes links of the network as link table (link.csv) sorted by identifiers. Geometry is written as WKT
+func WriteLinksCSV(w io.Writer, net *Net) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(linksCSVHeader)
+	if err != nil {
+		return errors.Wrap(err, "Can't write links header")
+	}
+	linksIDs := make([]gmns.LinkID, 0, len(net.Links))
+	for linkID := range net.Links {
+		linksIDs = append(linksIDs, linkID)
+	}
+	sort.Slice(linksIDs, func(i, j int) bool {
+		return linksIDs[i] < linksIDs[j]
+	})
+	for _, linkID := range linksIDs {
+		link := net.Links[linkID]
+		allowedUses := make([]string, len(link.AllowedAgentTypes()))
+		for i, agentType := range link.AllowedAgentTypes() {
+			allowedUses[i] = agentType.String()
+		}
+		err = writer.Write([]string{
+			strconv.Itoa(int(link.ID)),
+			strconv.Itoa(int(link.SourceNode())),
+			strconv.Itoa(int(link.TargetNode())),
+			strconv.Itoa(int(link.MacroNode())),
+			strconv.Itoa(int(link.MacroLink())),
+			strconv.Itoa(link.SegmentIdx()),
+			link.LinkType().String(),
+			link.ControlType().String(),
+			strconv.Itoa(int(link.Movement())),
+			link.MvmtTextID().String(),
+			strings.Join(allowedUses, ","),
+			strconv.Itoa(link.LanesNum()),
+			formatFloat(link.FreeSpeed()),
+			strconv.Itoa(link.Capacity()),
+			formatFloat(link.LengthMeters()),
+			wkt.MarshalString(link.Geom()),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Can't write link %d", link.ID)
+		}
+	}
+	writer.Flush()
+	return errors.Wrap(writer.Error(), "Can't flush links")
+}
+
+// formatFloat returns the shortest representation of the float
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
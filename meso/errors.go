@@ -3,6 +3,9 @@ package meso
 import "fmt"
 
 var (
-	ErrLinkNotFound = fmt.Errorf("link not found")
-	ErrNodeNotFound = fmt.Errorf("node not found")
+	ErrLinkNotFound    = fmt.Errorf("link not found")
+	ErrNodeNotFound    = fmt.Errorf("node not found")
+	ErrLinkExists      = fmt.Errorf("link already exists")
+	ErrNodeExists      = fmt.Errorf("node already exists")
+	ErrLinkNotIncident = fmt.Errorf("link is not incident to node")
 )
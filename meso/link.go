@@ -36,11 +36,13 @@ type Link struct {
 	movementOutcomeLaneStartSeqID int
 
 	/* Inherited from paret data parameters */
-	controlType       types.ControlType // Inherited from macroscopic node
-	linkType          types.LinkType    // Inherited either from macroscopic link or from first incoming incident edge in macroscopic node
-	freeSpeed         float64           // Inherited either from macroscopic link or from first incoming incident edge in macroscopic node
-	capacity          int               // Inherited either from macroscopic link or from first incoming incident edge in macroscopic node
-	allowedAgentTypes []types.AgentType // Inherited either from macroscopic link or from first incoming incident edge in macroscopic node
+	controlType       types.ControlType  // Inherited from macroscopic node
+	linkType          types.LinkType     // Inherited either from macroscopic link or from first incoming incident edge in macroscopic node
+	freeSpeed         float64            // Inherited either from macroscopic link or from first incoming incident edge in macroscopic node
+	capacity          int                // Inherited either from macroscopic link or from first incoming incident edge in macroscopic node
+	allowedAgentTypes []types.AgentType  // Inherited either from macroscopic link or from first incoming incident edge in macroscopic node
+	lanes             []gmns.Lane        // Inherited from macroscopic link segment. Ordered from the inner lane to the outer one
+	laneChanges       []types.LaneChange // Inherited from macroscopic link segment. Ordered from the inner lane to the outer one
 }
 
 func NewLinkFrom(id gmns.LinkID, sourceNodeID, targetNodeID gmns.NodeID, options ...func(*Link)) *Link {
@@ -68,6 +70,8 @@ func NewLinkFrom(id gmns.LinkID, sourceNodeID, targetNodeID gmns.NodeID, options
 		freeSpeed:         0.0,
 		capacity:          0,
 		allowedAgentTypes: []types.AgentType{},
+		lanes:             []gmns.Lane{},
+		laneChanges:       []types.LaneChange{},
 	}
 	for _, option := range options {
 		option(newLink)
@@ -184,6 +188,24 @@ func (link *Link) AllowedAgentTypes() []types.AgentType {
 	return link.allowedAgentTypes
 }
 
+// Lanes returns per-lane attributes ordered from the inner lane to the outer one. Empty if parent macroscopic link has no per-lane attributes. Warning: returning object is a slice.
+func (link *Link) Lanes() []gmns.Lane {
+	return link.lanes
+}
+
+// LaneChanges returns lane change permissions ordered from the inner lane to the outer one. Empty if lane changes are not restricted. Warning: returning object is a slice.
+func (link *Link) LaneChanges() []types.LaneChange {
+	return link.laneChanges
+}
+
+// LaneChange returns lane change permission for the given lane (1-indexed). Lanes without permissions allow both directions
+func (link *Link) LaneChange(laneID int) types.LaneChange {
+	if laneID < 1 || laneID > len(link.laneChanges) {
+		return types.LANE_CHANGE_BOTH
+	}
+	return link.laneChanges[laneID-1]
+}
+
 // WithLineGeom sets geometry [WGS84] for the link. Warning: it does not copy the given slice.
 func WithLineGeom(geom orb.LineString) func(*Link) {
 	return func(link *Link) {
@@ -338,3 +360,21 @@ func WithAllowedAgentTypes(allowedAgentTypes []types.AgentType) func(*Link) {
 		copy(link.allowedAgentTypes, allowedAgentTypes)
 	}
 }
+
+// WithLanes sets per-lane attributes for the link. Warning: it does copy argument
+func WithLanes(lanes []gmns.Lane) func(*Link) {
+	return func(link *Link) {
+		link.lanes = make([]gmns.Lane, len(lanes))
+		for i := range lanes {
+			link.lanes[i] = lanes[i].Clone()
+		}
+	}
+}
+
+// WithLaneChanges sets lane change permissions for the link. Warning: it does copy argument
+func WithLaneChanges(laneChanges []types.LaneChange) func(*Link) {
+	return func(link *Link) {
+		link.laneChanges = make([]types.LaneChange, len(laneChanges))
+		copy(link.laneChanges, laneChanges)
+	}
+}
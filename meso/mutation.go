@@ -0,0 +1,237 @@
+package meso
+
+import (
+	"sort"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/elliotchance/orderedmap"
+	"github.com/pkg/errors"
+)
+
+// syncCounters sets identifiers counters next to the max identifiers of nodes and links
+func (net *Net) syncCounters() {
+	if net.countersSynced {
+		return
+	}
+	for nodeID := range net.Nodes {
+		if nodeID >= net.maxNodeID {
+			net.maxNodeID = nodeID + 1
+		}
+	}
+	for linkID := range net.Links {
+		if linkID >= net.maxLinkID {
+			net.maxLinkID = linkID + 1
+		}
+	}
+	net.countersSynced = true
+}
+
+// NewNodeID returns identifier which is not used by any node of the network and reserves it
+func (net *Net) NewNodeID() gmns.NodeID {
+	net.syncCounters()
+	for {
+		if _, ok := net.Nodes[net.maxNodeID]; !ok {
+			break
+		}
+		net.maxNodeID++
+	}
+	id := net.maxNodeID
+	net.maxNodeID++
+	return id
+}
+
+// NewLinkID returns identifier which is not used by any link of the network and reserves it
+func (net *Net) NewLinkID() gmns.LinkID {
+	net.syncCounters()
+	for {
+		if _, ok := net.Links[net.maxLinkID]; !ok {
+			break
+		}
+		net.maxLinkID++
+	}
+	id := net.maxLinkID
+	net.maxLinkID++
+	return id
+}
+
+// AddNode adds the node to the network. Links referenced by the node must exist already and must be incident to it
+func (net *Net) AddNode(node *Node) error {
+	if _, ok := net.Nodes[node.ID]; ok {
+		return errors.Wrapf(ErrNodeExists, "Node ID: %d", node.ID)
+	}
+	for _, key := range node.incomingLinks.Keys() {
+		linkID := key.(gmns.LinkID)
+		link, ok := net.Links[linkID]
+		if !ok {
+			return errors.Wrapf(ErrLinkNotFound, "Incoming link ID: %d", linkID)
+		}
+		if link.targetNodeID != node.ID {
+			return errors.Wrapf(ErrLinkNotIncident, "Incoming link ID: %d. Node ID: %d", linkID, node.ID)
+		}
+	}
+	for _, key := range node.outcomingLinks.Keys() {
+		linkID := key.(gmns.LinkID)
+		link, ok := net.Links[linkID]
+		if !ok {
+			return errors.Wrapf(ErrLinkNotFound, "Outcoming link ID: %d", linkID)
+		}
+		if link.sourceNodeID != node.ID {
+			return errors.Wrapf(ErrLinkNotIncident, "Outcoming link ID: %d. Node ID: %d", linkID, node.ID)
+		}
+	}
+	net.Nodes[node.ID] = node
+	if node.ID >= net.maxNodeID {
+		net.maxNodeID = node.ID + 1
+	}
+	return nil
+}
+
+// AddLink adds the link to the network. Source and target nodes must exist already: the link is appended to their sets of outcoming and incoming links
+func (net *Net) AddLink(link *Link) error {
+	if _, ok := net.Links[link.ID]; ok {
+		return errors.Wrapf(ErrLinkExists, "Link ID: %d", link.ID)
+	}
+	sourceNode, ok := net.Nodes[link.sourceNodeID]
+	if !ok {
+		return errors.Wrapf(ErrNodeNotFound, "Source node ID: %d", link.sourceNodeID)
+	}
+	targetNode, ok := net.Nodes[link.targetNodeID]
+	if !ok {
+		return errors.Wrapf(ErrNodeNotFound, "Target node ID: %d", link.targetNodeID)
+	}
+	net.Links[link.ID] = link
+	WithOutcomingLinks(link.ID)(sourceNode)
+	WithIncomingLinks(link.ID)(targetNode)
+	if link.ID >= net.maxLinkID {
+		net.maxLinkID = link.ID + 1
+	}
+	return nil
+}
+
+// DeleteLink removes the link from the network and from sets of its source and target nodes.
+// Connection links which refer to the removed link as to the income or outcome one are removed too
+func (net *Net) DeleteLink(linkID gmns.LinkID) error {
+	link, ok := net.Links[linkID]
+	if !ok {
+		return errors.Wrapf(ErrLinkNotFound, "Link ID: %d", linkID)
+	}
+	if sourceNode, ok := net.Nodes[link.sourceNodeID]; ok {
+		sourceNode.outcomingLinks.Delete(linkID)
+	}
+	if targetNode, ok := net.Nodes[link.targetNodeID]; ok {
+		targetNode.incomingLinks.Delete(linkID)
+	}
+	delete(net.Links, linkID)
+	if link.isConnection {
+		return nil
+	}
+	dependent := make([]gmns.LinkID, 0)
+	for connectionID, connection := range net.Links {
+		if connection.isConnection && (connection.movementMesoLinkIncome == linkID || connection.movementMesoLinkOutcome == linkID) {
+			dependent = append(dependent, connectionID)
+		}
+	}
+	sort.Slice(dependent, func(i, j int) bool {
+		return dependent[i] < dependent[j]
+	})
+	for _, connectionID := range dependent {
+		err := net.DeleteLink(connectionID)
+		if err != nil {
+			return errors.Wrapf(err, "Can't delete connection link dependent on link %d", linkID)
+		}
+	}
+	return nil
+}
+
+// DeleteNode removes the node and every link incident to it from the network
+func (net *Net) DeleteNode(nodeID gmns.NodeID) error {
+	node, ok := net.Nodes[nodeID]
+	if !ok {
+		return errors.Wrapf(ErrNodeNotFound, "Node ID: %d", nodeID)
+	}
+	incidentLinks := make([]gmns.LinkID, 0, node.incomingLinks.Len()+node.outcomingLinks.Len())
+	for _, key := range node.incomingLinks.Keys() {
+		incidentLinks = append(incidentLinks, key.(gmns.LinkID))
+	}
+	for _, key := range node.outcomingLinks.Keys() {
+		incidentLinks = append(incidentLinks, key.(gmns.LinkID))
+	}
+	sort.Slice(incidentLinks, func(i, j int) bool {
+		return incidentLinks[i] < incidentLinks[j]
+	})
+	for _, linkID := range incidentLinks {
+		// Loops are listed twice and connection links could be removed already as dependent ones
+		if _, ok := net.Links[linkID]; !ok {
+			continue
+		}
+		err := net.DeleteLink(linkID)
+		if err != nil {
+			return errors.Wrapf(err, "Can't delete link incident to node %d", nodeID)
+		}
+	}
+	delete(net.Nodes, nodeID)
+	return nil
+}
+
+// Renumber replaces identifiers of nodes and links according to the given mappings. References between elements (end nodes of links,
+// incident links of nodes, income and outcome links of connections) are updated too. Elements absent in the mappings keep their identifiers.
+// Network is left untouched if the resulting identifiers are not unique
+func (net *Net) Renumber(nodesIDs map[gmns.NodeID]gmns.NodeID, linksIDs map[gmns.LinkID]gmns.LinkID) error {
+	nodeID := func(id gmns.NodeID) gmns.NodeID {
+		if newID, ok := nodesIDs[id]; ok {
+			return newID
+		}
+		return id
+	}
+	linkID := func(id gmns.LinkID) gmns.LinkID {
+		if newID, ok := linksIDs[id]; ok {
+			return newID
+		}
+		return id
+	}
+	renumberedNodes := make(map[gmns.NodeID]*Node, len(net.Nodes))
+	for id, node := range net.Nodes {
+		newID := nodeID(id)
+		if _, ok := renumberedNodes[newID]; ok {
+			return errors.Wrapf(ErrNodeExists, "Node ID: %d", newID)
+		}
+		renumberedNodes[newID] = node
+	}
+	renumberedLinks := make(map[gmns.LinkID]*Link, len(net.Links))
+	for id, link := range net.Links {
+		newID := linkID(id)
+		if _, ok := renumberedLinks[newID]; ok {
+			return errors.Wrapf(ErrLinkExists, "Link ID: %d", newID)
+		}
+		renumberedLinks[newID] = link
+	}
+	for id, node := range renumberedNodes {
+		node.ID = id
+		node.incomingLinks = renumberLinksIDs(node.incomingLinks, linkID)
+		node.outcomingLinks = renumberLinksIDs(node.outcomingLinks, linkID)
+	}
+	for id, link := range renumberedLinks {
+		link.ID = id
+		link.sourceNodeID = nodeID(link.sourceNodeID)
+		link.targetNodeID = nodeID(link.targetNodeID)
+		if link.isConnection {
+			link.movementMesoLinkIncome = linkID(link.movementMesoLinkIncome)
+			link.movementMesoLinkOutcome = linkID(link.movementMesoLinkOutcome)
+		}
+	}
+	net.Nodes = renumberedNodes
+	net.Links = renumberedLinks
+	net.maxNodeID = 0
+	net.maxLinkID = 0
+	net.countersSynced = false
+	return nil
+}
+
+// renumberLinksIDs returns copy of the ordered set of links identifiers with every identifier replaced by the given function
+func renumberLinksIDs(linksIDs *orderedmap.OrderedMap, linkID func(gmns.LinkID) gmns.LinkID) *orderedmap.OrderedMap {
+	renumbered := orderedmap.NewOrderedMap()
+	for el := linksIDs.Front(); el != nil; el = el.Next() {
+		renumbered.Set(linkID(el.Key.(gmns.LinkID)), el.Value)
+	}
+	return renumbered
+}
@@ -0,0 +1,71 @@
+package meso
+
+import (
+	"testing"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/stretchr/testify/assert"
+)
+
+// chainNet returns network 1 -> 2 -> 3 of base links 10 and 11 joined by connection link 12 between nodes 4 and 5 placed at node 2
+func chainNet(t *testing.T) *Net {
+	net := NewNet()
+	for _, id := range []gmns.NodeID{1, 2, 3, 4, 5} {
+		assert.NoError(t, net.AddNode(NewNodeFrom(id)))
+	}
+	assert.NoError(t, net.AddLink(NewLinkFrom(10, 1, 4)))
+	assert.NoError(t, net.AddLink(NewLinkFrom(11, 5, 3)))
+	assert.NoError(t, net.AddLink(NewLinkFrom(12, 4, 5, WithIsConnection(true), WithMovementMesoLinkIncome(10), WithMovementMesoLinkOutcome(11))))
+	return net
+}
+
+func TestNewIDs(t *testing.T) {
+	net := NewNet()
+	net.Nodes[3] = NewNodeFrom(3)
+	net.Links[7] = NewLinkFrom(7, 3, 3)
+	assert.Equal(t, gmns.NodeID(4), net.NewNodeID(), "Elements put to the maps directly should be taken into account")
+	assert.Equal(t, gmns.NodeID(5), net.NewNodeID())
+	assert.Equal(t, gmns.LinkID(8), net.NewLinkID())
+	net.Links[9] = NewLinkFrom(9, 3, 3)
+	assert.Equal(t, gmns.LinkID(10), net.NewLinkID(), "Used identifiers should be skipped")
+}
+
+func TestAddNodeLink(t *testing.T) {
+	net := chainNet(t)
+	assert.ErrorIs(t, net.AddNode(NewNodeFrom(1)), ErrNodeExists)
+	assert.ErrorIs(t, net.AddNode(NewNodeFrom(6, WithOutcomingLinks(42))), ErrLinkNotFound)
+	assert.ErrorIs(t, net.AddNode(NewNodeFrom(6, WithIncomingLinks(10))), ErrLinkNotIncident)
+	assert.Len(t, net.Nodes, 5)
+
+	assert.ErrorIs(t, net.AddLink(NewLinkFrom(10, 1, 2)), ErrLinkExists)
+	assert.ErrorIs(t, net.AddLink(NewLinkFrom(13, 1, 42)), ErrNodeNotFound)
+	assert.Len(t, net.Links, 3)
+	assert.NoError(t, net.AddLink(NewLinkFrom(13, 3, 2)))
+	assert.Equal(t, []interface{}{gmns.LinkID(13)}, net.Nodes[3].OutcomingLinks().Keys())
+	assert.Equal(t, []interface{}{gmns.LinkID(13)}, net.Nodes[2].IncomingLinks().Keys())
+	assert.Equal(t, gmns.LinkID(14), net.NewLinkID())
+}
+
+func TestDeleteCascades(t *testing.T) {
+	net := chainNet(t)
+	assert.ErrorIs(t, net.DeleteLink(42), ErrLinkNotFound)
+	assert.NoError(t, net.DeleteLink(11))
+	_, ok := net.Links[12]
+	assert.False(t, ok, "Connection link referring to the removed link should be removed")
+	assert.Len(t, net.Links, 1)
+	assert.Equal(t, 0, net.Nodes[4].OutcomingLinks().Len())
+	assert.Equal(t, 0, net.Nodes[5].OutcomingLinks().Len())
+
+	net = chainNet(t)
+	assert.NoError(t, net.DeleteLink(12))
+	assert.Len(t, net.Links, 2, "Removal of connection link should not affect base links")
+
+	net = chainNet(t)
+	assert.ErrorIs(t, net.DeleteNode(42), ErrNodeNotFound)
+	assert.NoError(t, net.DeleteNode(4))
+	assert.Len(t, net.Nodes, 4)
+	assert.Len(t, net.Links, 1, "Incident links and dependent connection links should be removed")
+	_, ok = net.Links[11]
+	assert.True(t, ok)
+	assert.Equal(t, 0, net.Nodes[1].OutcomingLinks().Len())
+}
@@ -8,12 +8,19 @@ import (
 type Net struct {
 	Nodes map[gmns.NodeID]*Node
 	Links map[gmns.LinkID]*Link
+
+	maxNodeID gmns.NodeID
+	maxLinkID gmns.LinkID
+	// Nodes and links could be put to the maps directly, so counters are synchronized with maps before the first allocation
+	countersSynced bool
 }
 
 // NewNet returns pointer to the new macroscopic road network data
 func NewNet() *Net {
 	return &Net{
-		Nodes: make(map[gmns.NodeID]*Node),
-		Links: make(map[gmns.LinkID]*Link),
+		Nodes:     make(map[gmns.NodeID]*Node),
+		Links:     make(map[gmns.LinkID]*Link),
+		maxNodeID: 0,
+		maxLinkID: 0,
 	}
 }
@@ -5,4 +5,6 @@ import "fmt"
 var (
 	ErrLinkNotFound = fmt.Errorf("link not found")
 	ErrNodeNotFound = fmt.Errorf("node not found")
+	ErrLinkExists   = fmt.Errorf("link already exists")
+	ErrNodeExists   = fmt.Errorf("node already exists")
 )
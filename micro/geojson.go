@@ -18,6 +18,9 @@ func (link *Link) GeoFeature() *geojson.Feature {
 	f.Properties["macro_node_id"] = link.MacroNode()
 	f.Properties["cell_type"] = link.CellType().String()
 	f.Properties["lane_id"] = link.LaneID()
+	f.Properties["lane_width"] = link.LaneWidth()
+	f.Properties["lane_designation"] = link.LaneDesignation().String()
+	f.Properties["cell_length"] = link.CellLength()
 	f.Properties["is_first_movement_cell"] = link.IsFirstMovementCell()
 	f.Properties["movement_composite_type"] = link.MovementCompositeType().String()
 	f.Properties["additional_travel_cost"] = link.AdditionalTravelCost()
@@ -25,8 +25,13 @@ type Link struct {
 	macroNodeID gmns.NodeID
 
 	// Cell properties
-	cellType types.CellType
-	laneID   int // Lane number this cell belongs to
+	cellType   types.CellType
+	laneID     int     // Lane number this cell belongs to
+	cellLength float64 // Length of the cell along the lane [meters]
+
+	// Lane properties
+	laneWidth       float64
+	laneDesignation types.LaneDesignation
 
 	// Movement properties
 	isFirstMovementCell   bool
@@ -55,6 +60,9 @@ func NewLinkFrom(id gmns.LinkID, sourceNodeID, targetNodeID gmns.NodeID, options
 		macroNodeID:           -1,
 		cellType:              types.CELL_FORWARD,
 		laneID:                0,
+		cellLength:            -1,
+		laneWidth:             -1,
+		laneDesignation:       types.LANE_DESIGNATION_GENERAL,
 		isFirstMovementCell:   false,
 		movementCompositeType: movement.MOVEMENT_UNDEFINED,
 		additionalTravelCost:  0.0,
@@ -120,6 +128,21 @@ func (link *Link) LaneID() int {
 	return link.laneID
 }
 
+// LaneWidth returns width of the lane this cell belongs to. Outputs "-1" if it was not set.
+func (link *Link) LaneWidth() float64 {
+	return link.laneWidth
+}
+
+// CellLength returns length of the cell along the lane [meters] as it has been planned by cells sizing policy. Negative value means that it is unknown
+func (link *Link) CellLength() float64 {
+	return link.cellLength
+}
+
+// LaneDesignation returns special purpose of the lane this cell belongs to
+func (link *Link) LaneDesignation() types.LaneDesignation {
+	return link.laneDesignation
+}
+
 // IsFirstMovementCell returns true if this is the first cell in a movement
 func (link *Link) IsFirstMovementCell() bool {
 	return link.isFirstMovementCell
@@ -219,6 +242,27 @@ func WithCellType(cellType types.CellType) func(*Link) {
 	}
 }
 
+// WithLaneWidth sets width of the lane
+func WithLaneWidth(laneWidth float64) func(*Link) {
+	return func(link *Link) {
+		link.laneWidth = laneWidth
+	}
+}
+
+// WithCellLength sets length of the cell along the lane [meters]
+func WithCellLength(cellLength float64) func(*Link) {
+	return func(link *Link) {
+		link.cellLength = cellLength
+	}
+}
+
+// WithLaneDesignation sets special purpose of the lane
+func WithLaneDesignation(laneDesignation types.LaneDesignation) func(*Link) {
+	return func(link *Link) {
+		link.laneDesignation = laneDesignation
+	}
+}
+
 // WithLaneID sets the lane number
 func WithLaneID(laneID int) func(*Link) {
 	return func(link *Link) {
@@ -2,6 +2,8 @@ package micro
 
 import (
 	"github.com/LdDl/go-gmns/gmns"
+	"github.com/elliotchance/orderedmap"
+	"github.com/pkg/errors"
 )
 
 // Net is representation of a microscopic road network with links and nodes
@@ -68,3 +70,92 @@ func (net *Net) DeleteNode(nodeID gmns.NodeID) {
 func (net *Net) DeleteLink(linkID gmns.LinkID) {
 	delete(net.Links, linkID)
 }
+
+// Append moves nodes and links of the other network into the network shifting their identifiers (and references between them) by the current maximum identifiers.
+// It gives the same identifiers as if elements of the other network were added via MaxNodeID/MaxLinkID allocation right after existing ones. The other network should not be used afterwards
+func (net *Net) Append(other *Net) {
+	nodeOffset := net.maxNodeID
+	linkOffset := net.maxLinkID
+	for _, node := range other.Nodes {
+		node.ID += nodeOffset
+		node.incomingLinks = shiftLinksIDs(node.incomingLinks, linkOffset)
+		node.outcomingLinks = shiftLinksIDs(node.outcomingLinks, linkOffset)
+		net.Nodes[node.ID] = node
+	}
+	for _, link := range other.Links {
+		link.ID += linkOffset
+		link.sourceNodeID += nodeOffset
+		link.targetNodeID += nodeOffset
+		net.Links[link.ID] = link
+	}
+	net.maxNodeID = nodeOffset + other.maxNodeID
+	net.maxLinkID = linkOffset + other.maxLinkID
+}
+
+// shiftLinksIDs returns copy of the ordered set of links identifiers with every identifier shifted by the given offset
+func shiftLinksIDs(linksIDs *orderedmap.OrderedMap, offset gmns.LinkID) *orderedmap.OrderedMap {
+	shifted := orderedmap.NewOrderedMap()
+	for el := linksIDs.Front(); el != nil; el = el.Next() {
+		shifted.Set(el.Key.(gmns.LinkID)+offset, el.Value)
+	}
+	return shifted
+}
+
+// Renumber replaces identifiers of nodes and links according to the given mappings. End nodes of links and incident links of nodes are updated too.
+// Elements absent in the mappings keep their identifiers. Maximum identifiers are updated to the new ones. Network is left untouched if the resulting identifiers are not unique
+func (net *Net) Renumber(nodesIDs map[gmns.NodeID]gmns.NodeID, linksIDs map[gmns.LinkID]gmns.LinkID) error {
+	nodeID := func(id gmns.NodeID) gmns.NodeID {
+		if newID, ok := nodesIDs[id]; ok {
+			return newID
+		}
+		return id
+	}
+	linkID := func(id gmns.LinkID) gmns.LinkID {
+		if newID, ok := linksIDs[id]; ok {
+			return newID
+		}
+		return id
+	}
+	renumberedNodes := make(map[gmns.NodeID]*Node, len(net.Nodes))
+	for id, node := range net.Nodes {
+		newID := nodeID(id)
+		if _, ok := renumberedNodes[newID]; ok {
+			return errors.Wrapf(ErrNodeExists, "Node ID: %d", newID)
+		}
+		renumberedNodes[newID] = node
+	}
+	renumberedLinks := make(map[gmns.LinkID]*Link, len(net.Links))
+	for id, link := range net.Links {
+		newID := linkID(id)
+		if _, ok := renumberedLinks[newID]; ok {
+			return errors.Wrapf(ErrLinkExists, "Link ID: %d", newID)
+		}
+		renumberedLinks[newID] = link
+	}
+	net.Nodes = make(map[gmns.NodeID]*Node, len(renumberedNodes))
+	net.Links = make(map[gmns.LinkID]*Link, len(renumberedLinks))
+	net.maxNodeID = 0
+	net.maxLinkID = 0
+	for id, node := range renumberedNodes {
+		node.ID = id
+		node.incomingLinks = renumberLinksIDs(node.incomingLinks, linkID)
+		node.outcomingLinks = renumberLinksIDs(node.outcomingLinks, linkID)
+		net.AddNode(node)
+	}
+	for id, link := range renumberedLinks {
+		link.ID = id
+		link.sourceNodeID = nodeID(link.sourceNodeID)
+		link.targetNodeID = nodeID(link.targetNodeID)
+		net.AddLink(link)
+	}
+	return nil
+}
+
+// renumberLinksIDs returns copy of the ordered set of links identifiers with every identifier replaced by the given function
+func renumberLinksIDs(linksIDs *orderedmap.OrderedMap, linkID func(gmns.LinkID) gmns.LinkID) *orderedmap.OrderedMap {
+	renumbered := orderedmap.NewOrderedMap()
+	for el := linksIDs.Front(); el != nil; el = el.Next() {
+		renumbered.Set(linkID(el.Key.(gmns.LinkID)), el.Value)
+	}
+	return renumbered
+}
@@ -0,0 +1,63 @@
+package movement
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/paulmach/orb/encoding/wkt"
+	"github.com/pkg/errors"
+)
+
+var movementsCSVHeader = []string{"mvmt_id", "node_id", "osm_node_id", "name", "ib_link_id", "start_ib_lane", "end_ib_lane", "ob_link_id", "start_ob_lane", "end_ob_lane", "lanes", "from_osm_node_id", "to_osm_node_id", "type", "ctrl_type", "mvmt_txt_id", "allowed_uses", "geometry"}
+
+// WriteMovementsCSV writes movements as GMNS movement table (movement.csv) sorted by identifiers. Geometry is written as WKT
+func WriteMovementsCSV(w io.Writer, mvmts MovementsStorage) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(movementsCSVHeader)
+	if err != nil {
+		return errors.Wrap(err, "Can't write movements header")
+	}
+	mvmtsIDs := make([]gmns.MovementID, 0, len(mvmts))
+	for mvmtID := range mvmts {
+		mvmtsIDs = append(mvmtsIDs, mvmtID)
+	}
+	sort.Slice(mvmtsIDs, func(i, j int) bool {
+		return mvmtsIDs[i] < mvmtsIDs[j]
+	})
+	for _, mvmtID := range mvmtsIDs {
+		mvmt := mvmts[mvmtID]
+		allowedUses := make([]string, len(mvmt.AllowedAgentTypes()))
+		for i, agentType := range mvmt.AllowedAgentTypes() {
+			allowedUses[i] = agentType.String()
+		}
+		err = writer.Write([]string{
+			strconv.Itoa(int(mvmt.ID)),
+			strconv.Itoa(int(mvmt.MacroNode())),
+			strconv.FormatInt(int64(mvmt.OSMNode()), 10),
+			mvmt.Name(),
+			strconv.Itoa(int(mvmt.IncomeMacroLink())),
+			strconv.Itoa(mvmt.IncomeLaneStart()),
+			strconv.Itoa(mvmt.IncomeLaneEnd()),
+			strconv.Itoa(int(mvmt.OutcomeMacroLink())),
+			strconv.Itoa(mvmt.OutcomeLaneStart()),
+			strconv.Itoa(mvmt.OutcomeLaneEnd()),
+			strconv.Itoa(mvmt.LanesNum()),
+			strconv.FormatInt(int64(mvmt.OSMNodeSource()), 10),
+			strconv.FormatInt(int64(mvmt.OSMNodeTarget()), 10),
+			mvmt.Type().String(),
+			mvmt.ControlType().String(),
+			mvmt.MvmtTextID().String(),
+			strings.Join(allowedUses, ","),
+			wkt.MarshalString(mvmt.Geom()),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Can't write movement %d", mvmt.ID)
+		}
+	}
+	writer.Flush()
+	return errors.Wrap(writer.Error(), "Can't flush movements")
+}
@@ -4,4 +4,5 @@ import "fmt"
 
 var (
 	ErrMvmtNotFound = fmt.Errorf("movement not found")
+	ErrMvmtExists   = fmt.Errorf("movement already exists")
 )
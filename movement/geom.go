@@ -3,6 +3,7 @@ package movement
 import (
 	"math"
 
+	"github.com/LdDl/go-gmns/gmns/types"
 	"github.com/paulmach/orb"
 	"github.com/paulmach/orb/geo"
 )
@@ -16,7 +17,14 @@ const (
 // obLine - The line with coordinates in EPSG:3857. Line represents target of movement;
 // Returns one of corresponding values: NBL, NBT, NBR, NBU, SBL, SBT, SBR, SBU, EBL, EBT, EBR, EBU, WBL, WBT, WBR, WBU along with corresponding movement with possible values: thru, right, left, uturn;
 // Warning: use it for Euclidean space only (or EPSG:3857).
+// Right-hand traffic is assumed (see FindMovementTypeForSide).
 func FindMovementType(ibLine orb.LineString, obLine orb.LineString) (MovementCompositeType, MovementType) {
+	return FindMovementTypeForSide(ibLine, obLine, types.DRIVING_SIDE_RIGHT)
+}
+
+// FindMovementTypeForSide is the same as FindMovementType, but takes driving side into account.
+// U-turns are made across the opposite traffic: for right-hand traffic the sharpest left turns are U-turns, for left-hand traffic the sharpest right ones are.
+func FindMovementTypeForSide(ibLine orb.LineString, obLine orb.LineString, drivingSide types.DrivingSide) (MovementCompositeType, MovementType) {
 	startIB, endIB := ibLine[0], ibLine[len(ibLine)-1]
 	endOB := obLine[len(obLine)-1]
 
@@ -38,11 +46,21 @@ func FindMovementType(ibLine orb.LineString, obLine orb.LineString) (MovementCom
 
 	angleDiff := angleOB - angleIB
 
-	if angleDiff <= -1*math.Pi { // '<=' instead of '<' because of floating point number precision
-		angleDiff += 2 * math.Pi
-	}
-	if angleDiff > math.Pi {
-		angleDiff -= 2 * math.Pi
+	if drivingSide == types.DRIVING_SIDE_LEFT {
+		// Keep angle in [-Pi; Pi) so full reverse is treated as turn to the right
+		if angleDiff < -1*math.Pi {
+			angleDiff += 2 * math.Pi
+		}
+		if angleDiff >= math.Pi { // '>=' instead of '>' because of floating point number precision
+			angleDiff -= 2 * math.Pi
+		}
+	} else {
+		if angleDiff <= -1*math.Pi { // '<=' instead of '<' because of floating point number precision
+			angleDiff += 2 * math.Pi
+		}
+		if angleDiff > math.Pi {
+			angleDiff -= 2 * math.Pi
+		}
 	}
 
 	var movementShortType MovementShortType
@@ -50,6 +68,17 @@ func FindMovementType(ibLine orb.LineString, obLine orb.LineString) (MovementCom
 	if -0.25*math.Pi <= angleDiff && angleDiff <= 0.25*math.Pi {
 		movementShortType = MOVEMENT_SHORT_TYPE_THRU
 		movementType = MOVEMENT_TYPE_THRU
+	} else if drivingSide == types.DRIVING_SIDE_LEFT {
+		if angleDiff > 0.25*math.Pi {
+			movementShortType = MOVEMENT_SHORT_TYPE_LEFT
+			movementType = MOVEMENT_TYPE_LEFT
+		} else if angleDiff >= -0.75*math.Pi {
+			movementShortType = MOVEMENT_SHORT_TYPE_RIGHT
+			movementType = MOVEMENT_TYPE_RIGHT
+		} else {
+			movementShortType = MOVEMENT_SHORT_TYPE_U_TURN
+			movementType = MOVEMENT_TYPE_U_TURN
+		}
 	} else if angleDiff < -0.25*math.Pi {
 		movementShortType = MOVEMENT_SHORT_TYPE_RIGHT
 		movementType = MOVEMENT_TYPE_RIGHT
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/LdDl/go-gmns/gmns/types"
 	"github.com/LdDl/go-gmns/utils/geomath"
 	"github.com/paulmach/orb"
 	"github.com/stretchr/testify/assert"
@@ -143,6 +144,35 @@ func TestFindMovementType(t *testing.T) {
 	assert.Equal(t, expectedMovementType, ansMovementType, "Wrong movement type")
 }
 
+func TestFindMovementTypeForSide(t *testing.T) {
+	// Northbound inbound line in Euclidean space
+	givenInboundLine := orb.LineString{{0, 0}, {0, 100}}
+	// Sharp turn to the right-back
+	givenOutboundLine := orb.LineString{{0, 100}, {60, 0}}
+	ansMovementTextID, ansMovementType := FindMovementTypeForSide(givenInboundLine, givenOutboundLine, types.DRIVING_SIDE_RIGHT)
+	assert.Equal(t, MOVEMENT_NBR, ansMovementTextID, "Wrong movement text ID for right-hand traffic")
+	assert.Equal(t, MOVEMENT_TYPE_RIGHT, ansMovementType, "Wrong movement type for right-hand traffic")
+	ansMovementTextID, ansMovementType = FindMovementTypeForSide(givenInboundLine, givenOutboundLine, types.DRIVING_SIDE_LEFT)
+	assert.Equal(t, MOVEMENT_NBU, ansMovementTextID, "Wrong movement text ID for left-hand traffic")
+	assert.Equal(t, MOVEMENT_TYPE_U_TURN, ansMovementType, "Wrong movement type for left-hand traffic")
+
+	// Sharp turn to the left-back
+	givenOutboundLine = orb.LineString{{0, 100}, {-60, 0}}
+	ansMovementTextID, ansMovementType = FindMovementTypeForSide(givenInboundLine, givenOutboundLine, types.DRIVING_SIDE_RIGHT)
+	assert.Equal(t, MOVEMENT_NBU, ansMovementTextID, "Wrong movement text ID for right-hand traffic")
+	assert.Equal(t, MOVEMENT_TYPE_U_TURN, ansMovementType, "Wrong movement type for right-hand traffic")
+	ansMovementTextID, ansMovementType = FindMovementTypeForSide(givenInboundLine, givenOutboundLine, types.DRIVING_SIDE_LEFT)
+	assert.Equal(t, MOVEMENT_NBL, ansMovementTextID, "Wrong movement text ID for left-hand traffic")
+	assert.Equal(t, MOVEMENT_TYPE_LEFT, ansMovementType, "Wrong movement type for left-hand traffic")
+
+	// Full reverse is U-turn for both sides
+	givenOutboundLine = orb.LineString{{0, 100}, {0, 0}}
+	_, ansMovementType = FindMovementTypeForSide(givenInboundLine, givenOutboundLine, types.DRIVING_SIDE_RIGHT)
+	assert.Equal(t, MOVEMENT_TYPE_U_TURN, ansMovementType, "Wrong movement type for right-hand traffic")
+	_, ansMovementType = FindMovementTypeForSide(givenInboundLine, givenOutboundLine, types.DRIVING_SIDE_LEFT)
+	assert.Equal(t, MOVEMENT_TYPE_U_TURN, ansMovementType, "Wrong movement type for left-hand traffic")
+}
+
 func TestFindMovementGeom(t *testing.T) {
 	precision := 10e-8
 
@@ -7,28 +7,39 @@ import (
 	"github.com/LdDl/go-gmns/gmns/types"
 	"github.com/paulmach/orb"
 	"github.com/paulmach/osm"
+	"github.com/pkg/errors"
 )
 
-type autoInc struct {
-	sync.Mutex
-	id gmns.MovementID
+// IDAllocator gives identifiers for new movements. It is safe for concurrent use, independent allocators do not affect each other
+type IDAllocator struct {
+	mu   sync.Mutex
+	next gmns.MovementID
 }
 
-func (a *autoInc) ID() (id gmns.MovementID) {
-	a.Lock()
-	defer a.Unlock()
+// NewIDAllocator returns allocator giving identifiers sequentially starting from the given one.
+// Use MovementsStorage.NextID() as the start to append movements to the existing (e.g. imported) storage
+func NewIDAllocator(start gmns.MovementID) *IDAllocator {
+	return &IDAllocator{next: start}
+}
 
-	id = a.id
-	a.id++
-	return
+// Next returns the next identifier and reserves it
+func (ids *IDAllocator) Next() gmns.MovementID {
+	ids.mu.Lock()
+	defer ids.mu.Unlock()
+	id := ids.next
+	ids.next++
+	return id
 }
 
 var (
-	ai autoInc
+	defaultIDs = NewIDAllocator(0)
 )
 
+// GenMovementID returns the next identifier of the package-level allocator shared by the whole process.
+//
+// Deprecated: identifiers are interleaved between independent storages and never reset. Use IDAllocator instead
 func GenMovementID() gmns.MovementID {
-	return ai.ID()
+	return defaultIDs.Next()
 }
 
 // MovementsStorage is storage for the movements
@@ -39,6 +50,45 @@ func NewMovementsStorage() MovementsStorage {
 	return make(MovementsStorage)
 }
 
+// NextID returns identifier next to the maximum identifier of movements in the storage. Zero is returned for the empty storage
+func (mvmts MovementsStorage) NextID() gmns.MovementID {
+	next := gmns.MovementID(0)
+	for id := range mvmts {
+		if id >= next {
+			next = id + 1
+		}
+	}
+	return next
+}
+
+// Renumber replaces identifiers of movements according to the given mapping. Movements absent in the mapping keep their identifiers.
+// Storage is left untouched if the resulting identifiers are not unique
+func (mvmts MovementsStorage) Renumber(ids map[gmns.MovementID]gmns.MovementID) error {
+	renumbered := make(map[gmns.MovementID]struct{}, len(mvmts))
+	for id := range mvmts {
+		newID, ok := ids[id]
+		if !ok {
+			newID = id
+		}
+		if _, ok := renumbered[newID]; ok {
+			return errors.Wrapf(ErrMvmtExists, "Movement ID: %d", newID)
+		}
+		renumbered[newID] = struct{}{}
+	}
+	moved := make([]*Movement, 0, len(ids))
+	for id, mvmt := range mvmts {
+		if newID, ok := ids[id]; ok && newID != id {
+			moved = append(moved, mvmt)
+			delete(mvmts, id)
+		}
+	}
+	for _, mvmt := range moved {
+		mvmt.ID = ids[mvmt.ID]
+		mvmts[mvmt.ID] = mvmt
+	}
+	return nil
+}
+
 // Movement represents maneuver between some road parts
 type Movement struct {
 	name              string
@@ -341,3 +391,13 @@ func WithLanesNum(lanesNum int) func(*Movement) {
 		mvmt.lanesNum = lanesNum
 	}
 }
+
+// Clone returns deep copy of the movement
+func (mvmt *Movement) Clone() *Movement {
+	newMovement := *mvmt
+	newMovement.geom = mvmt.geom.Clone()
+	newMovement.geomEuclidean = mvmt.geomEuclidean.Clone()
+	newMovement.allowedAgentTypes = make([]types.AgentType, len(mvmt.allowedAgentTypes))
+	copy(newMovement.allowedAgentTypes, mvmt.allowedAgentTypes)
+	return &newMovement
+}
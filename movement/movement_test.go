@@ -0,0 +1,45 @@
+package movement
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIDAllocator(t *testing.T) {
+	first := NewIDAllocator(0)
+	second := NewIDAllocator(0)
+	assert.Equal(t, gmns.MovementID(0), first.Next())
+	assert.Equal(t, gmns.MovementID(0), second.Next(), "Independent allocators should not affect each other")
+
+	storage := NewMovementsStorage()
+	assert.Equal(t, gmns.MovementID(0), storage.NextID())
+	storage[10] = NewMovement(10, 1, 2, 3, MOVEMENT_NBT, MOVEMENT_TYPE_THRU)
+	storage[4] = NewMovement(4, 1, 2, 3, MOVEMENT_NBT, MOVEMENT_TYPE_THRU)
+	seeded := NewIDAllocator(storage.NextID())
+	assert.Equal(t, gmns.MovementID(11), seeded.Next())
+
+	const workers, perWorker = 8, 100
+	ids := make([][]gmns.MovementID, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				ids[w] = append(ids[w], seeded.Next())
+			}
+		}(w)
+	}
+	wg.Wait()
+	unique := make(map[gmns.MovementID]struct{}, workers*perWorker)
+	for w := range ids {
+		for _, id := range ids[w] {
+			unique[id] = struct{}{}
+		}
+	}
+	assert.Len(t, unique, workers*perWorker)
+	assert.Equal(t, gmns.MovementID(12+workers*perWorker), seeded.Next())
+}
@@ -0,0 +1,205 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+)
+
+// section is rendering-agnostic representation of the network level summary
+type section struct {
+	title   string
+	metrics [][2]string
+	tables  []table
+}
+
+// table is list of rows with the header
+type table struct {
+	title  string
+	header []string
+	rows   [][]string
+}
+
+// WriteJSON writes the summary as JSON
+func WriteJSON(w io.Writer, stats *Stats) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(stats)
+	if err != nil {
+		return errors.Wrap(err, "Can't encode stats")
+	}
+	return nil
+}
+
+// WriteText writes the summary as plain text with aligned columns
+func WriteText(w io.Writer, stats *Stats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, sec := range stats.sections() {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintln(tw, sec.title)
+		for _, metric := range sec.metrics {
+			fmt.Fprintf(tw, "  %s:\t%s\n", metric[0], metric[1])
+		}
+		for _, tbl := range sec.tables {
+			if len(tbl.rows) == 0 {
+				continue
+			}
+			fmt.Fprintf(tw, "  %s:\n", tbl.title)
+			fmt.Fprintf(tw, "    %s\n", strings.Join(tbl.header, "\t"))
+			for _, row := range tbl.rows {
+				fmt.Fprintf(tw, "    %s\n", strings.Join(row, "\t"))
+			}
+		}
+	}
+	return errors.Wrap(tw.Flush(), "Can't write stats")
+}
+
+// WriteMarkdown writes the summary as Markdown: a heading and tables for each network level
+func WriteMarkdown(w io.Writer, stats *Stats) error {
+	var sb strings.Builder
+	for i, sec := range stats.sections() {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "## %s\n\n", sec.title)
+		sb.WriteString("| Metric | Value |\n|---|---:|\n")
+		for _, metric := range sec.metrics {
+			fmt.Fprintf(&sb, "| %s | %s |\n", metric[0], metric[1])
+		}
+		for _, tbl := range sec.tables {
+			if len(tbl.rows) == 0 {
+				continue
+			}
+			fmt.Fprintf(&sb, "\n### %s\n\n", strings.ToUpper(tbl.title[:1])+tbl.title[1:])
+			fmt.Fprintf(&sb, "| %s |\n", strings.Join(tbl.header, " | "))
+			sb.WriteString("|---" + strings.Repeat("|---:", len(tbl.header)-1) + "|\n")
+			for _, row := range tbl.rows {
+				fmt.Fprintf(&sb, "| %s |\n", strings.Join(row, " | "))
+			}
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return errors.Wrap(err, "Can't write stats")
+}
+
+// sections returns summaries of computed network levels
+func (stats *Stats) sections() []section {
+	sections := []section{}
+	if stats.Macro != nil {
+		sections = append(sections, section{
+			title: "Macroscopic network",
+			metrics: [][2]string{
+				{"nodes", strconv.Itoa(stats.Macro.Nodes)},
+				{"links", strconv.Itoa(stats.Macro.Links)},
+				{"length, km", formatKm(stats.Macro.LengthKm)},
+				{"lane-km", formatKm(stats.Macro.LaneKm)},
+				{"signalized nodes", strconv.Itoa(stats.Macro.SignalizedNodes)},
+			},
+			tables: []table{
+				linkTypesTable(stats.Macro.LinkTypes),
+				boundaryNodesTable(stats.Macro.BoundaryNodes),
+				agentTypesTable(stats.Macro.AgentTypes, true),
+			},
+		})
+	}
+	if stats.Movements != nil {
+		rows := make([][]string, len(stats.Movements.MovementTypes))
+		for i, item := range stats.Movements.MovementTypes {
+			rows[i] = []string{item.MovementType, strconv.Itoa(item.Movements)}
+		}
+		sections = append(sections, section{
+			title: "Movements",
+			metrics: [][2]string{
+				{"movements", strconv.Itoa(stats.Movements.Movements)},
+			},
+			tables: []table{
+				{title: "movements by type", header: []string{"Movement type", "Movements"}, rows: rows},
+			},
+		})
+	}
+	if stats.Meso != nil {
+		sections = append(sections, section{
+			title: "Mesoscopic network",
+			metrics: [][2]string{
+				{"nodes", strconv.Itoa(stats.Meso.Nodes)},
+				{"links", strconv.Itoa(stats.Meso.Links)},
+				{"connection links", strconv.Itoa(stats.Meso.ConnectionLinks)},
+				{"lane-km", formatKm(stats.Meso.LaneKm)},
+			},
+			tables: []table{
+				linkTypesTable(stats.Meso.LinkTypes),
+				boundaryNodesTable(stats.Meso.BoundaryNodes),
+				agentTypesTable(stats.Meso.AgentTypes, true),
+			},
+		})
+	}
+	if stats.Micro != nil {
+		rows := make([][]string, len(stats.Micro.CellTypes))
+		for i, item := range stats.Micro.CellTypes {
+			rows[i] = []string{item.CellType, strconv.Itoa(item.Links)}
+		}
+		sections = append(sections, section{
+			title: "Microscopic network",
+			metrics: [][2]string{
+				{"nodes", strconv.Itoa(stats.Micro.Nodes)},
+				{"links", strconv.Itoa(stats.Micro.Links)},
+				{"meso links", strconv.Itoa(stats.Micro.MesoLinks)},
+				{"avg cells per meso link", strconv.FormatFloat(stats.Micro.AvgCellsPerMesoLink, 'f', 2, 64)},
+			},
+			tables: []table{
+				{title: "cells by type", header: []string{"Cell type", "Links"}, rows: rows},
+				boundaryNodesTable(stats.Micro.BoundaryNodes),
+				agentTypesTable(stats.Micro.AgentTypes, false),
+			},
+		})
+	}
+	return sections
+}
+
+// linkTypesTable returns table of lane-km by link types
+func linkTypesTable(items []LinkTypeStats) table {
+	rows := make([][]string, len(items))
+	for i, item := range items {
+		rows[i] = []string{item.LinkType, strconv.Itoa(item.Links), formatKm(item.LaneKm)}
+	}
+	return table{title: "lane-km by link type", header: []string{"Link type", "Links", "Lane-km"}, rows: rows}
+}
+
+// boundaryNodesTable returns table of nodes by boundary types
+func boundaryNodesTable(items []BoundaryTypeStats) table {
+	rows := make([][]string, len(items))
+	for i, item := range items {
+		rows[i] = []string{item.BoundaryType, strconv.Itoa(item.Nodes)}
+	}
+	return table{title: "boundary nodes", header: []string{"Boundary type", "Nodes"}, rows: rows}
+}
+
+// agentTypesTable returns table of agent types coverage. Lane-km column is omitted if withLaneKm is not set
+func agentTypesTable(items []AgentTypeCoverage, withLaneKm bool) table {
+	tbl := table{title: "agent types coverage", header: []string{"Agent type", "Links", "Share"}}
+	if withLaneKm {
+		tbl.header = []string{"Agent type", "Links", "Lane-km", "Share"}
+	}
+	tbl.rows = make([][]string, len(items))
+	for i, item := range items {
+		share := strconv.FormatFloat(item.Share*100, 'f', 1, 64) + "%"
+		if withLaneKm {
+			tbl.rows[i] = []string{item.AgentType, strconv.Itoa(item.Links), formatKm(item.LaneKm), share}
+		} else {
+			tbl.rows[i] = []string{item.AgentType, strconv.Itoa(item.Links), share}
+		}
+	}
+	return tbl
+}
+
+// formatKm returns kilometers with meters precision
+func formatKm(km float64) string {
+	return strconv.FormatFloat(km, 'f', 3, 64)
+}
@@ -0,0 +1,328 @@
+// Package stats computes summaries of networks for scenario reviews: lane-km by link types, signalized and boundary nodes,
+// movements by types, cells per mesoscopic link and agent types coverage.
+package stats
+
+import (
+	"sort"
+
+	"github.com/LdDl/go-gmns/gmns"
+	"github.com/LdDl/go-gmns/gmns/types"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/LdDl/go-gmns/meso"
+	"github.com/LdDl/go-gmns/micro"
+	"github.com/LdDl/go-gmns/movement"
+)
+
+// Stats is summary of networks. Levels which have not been computed are nil
+type Stats struct {
+	Macro     *MacroStats     `json:"macro,omitempty"`
+	Movements *MovementsStats `json:"movements,omitempty"`
+	Meso      *MesoStats      `json:"meso,omitempty"`
+	Micro     *MicroStats     `json:"micro,omitempty"`
+}
+
+// LinkTypeStats is summary of links of the same link type
+type LinkTypeStats struct {
+	LinkType string  `json:"link_type"`
+	Links    int     `json:"links"`
+	LaneKm   float64 `json:"lane_km"`
+}
+
+// AgentTypeCoverage is summary of links allowed for the agent type
+type AgentTypeCoverage struct {
+	AgentType string `json:"agent_type"`
+	Links     int    `json:"links"`
+	// Lane-km of links allowed for the agent type. Zero for microscopic network
+	LaneKm float64 `json:"lane_km"`
+	// Share of links allowed for the agent type: by lane-km for macroscopic and mesoscopic networks, by number of cells for microscopic network
+	Share float64 `json:"share"`
+}
+
+// BoundaryTypeStats is number of nodes of the boundary type
+type BoundaryTypeStats struct {
+	BoundaryType string `json:"boundary_type"`
+	Nodes        int    `json:"nodes"`
+}
+
+// MovementTypeStats is number of movements of the movement type
+type MovementTypeStats struct {
+	MovementType string `json:"movement_type"`
+	Movements    int    `json:"movements"`
+}
+
+// CellTypeStats is number of microscopic links of the cell type
+type CellTypeStats struct {
+	CellType string `json:"cell_type"`
+	Links    int    `json:"links"`
+}
+
+// MacroStats is summary of macroscopic network
+type MacroStats struct {
+	Nodes           int     `json:"nodes"`
+	Links           int     `json:"links"`
+	LengthKm        float64 `json:"length_km"`
+	LaneKm          float64 `json:"lane_km"`
+	SignalizedNodes int     `json:"signalized_nodes"`
+	// Lane-km by link types ordered as types.LinkType constants
+	LinkTypes []LinkTypeStats `json:"link_types"`
+	// Nodes by boundary types except types.BOUNDARY_NONE
+	BoundaryNodes []BoundaryTypeStats `json:"boundary_nodes"`
+	AgentTypes    []AgentTypeCoverage `json:"agent_types"`
+}
+
+// MovementsStats is summary of movements
+type MovementsStats struct {
+	Movements     int                 `json:"movements"`
+	MovementTypes []MovementTypeStats `json:"movement_types"`
+}
+
+// MesoStats is summary of mesoscopic network
+type MesoStats struct {
+	Nodes int `json:"nodes"`
+	Links int `json:"links"`
+	// Links connecting incoming and outcoming links at macroscopic nodes
+	ConnectionLinks int `json:"connection_links"`
+	// Lane-km of links except connection ones
+	LaneKm        float64             `json:"lane_km"`
+	LinkTypes     []LinkTypeStats     `json:"link_types"`
+	BoundaryNodes []BoundaryTypeStats `json:"boundary_nodes"`
+	AgentTypes    []AgentTypeCoverage `json:"agent_types"`
+}
+
+// MicroStats is summary of microscopic network
+type MicroStats struct {
+	Nodes int `json:"nodes"`
+	Links int `json:"links"`
+	// Number of mesoscopic links having cells
+	MesoLinks int `json:"meso_links"`
+	// Average number of cells (of every type) per mesoscopic link
+	AvgCellsPerMesoLink float64             `json:"avg_cells_per_meso_link"`
+	CellTypes           []CellTypeStats     `json:"cell_types"`
+	BoundaryNodes       []BoundaryTypeStats `json:"boundary_nodes"`
+	AgentTypes          []AgentTypeCoverage `json:"agent_types"`
+}
+
+// Compute returns summary of the given networks. Any of them could be nil: its level is skipped then
+func Compute(macroNet *macro.Net, movements movement.MovementsStorage, mesoNet *meso.Net, microNet *micro.Net) *Stats {
+	stats := &Stats{}
+	if macroNet != nil {
+		stats.Macro = ComputeMacro(macroNet)
+	}
+	if movements != nil {
+		stats.Movements = ComputeMovements(movements)
+	}
+	if mesoNet != nil {
+		stats.Meso = ComputeMeso(mesoNet)
+	}
+	if microNet != nil {
+		stats.Micro = ComputeMicro(microNet)
+	}
+	return stats
+}
+
+// ComputeMacro returns summary of macroscopic network
+func ComputeMacro(net *macro.Net) *MacroStats {
+	stats := &MacroStats{
+		Nodes: len(net.Nodes),
+		Links: len(net.Links),
+	}
+	boundaries := map[types.BoundaryType]int{}
+	for _, node := range net.Nodes {
+		if node.ControlType() == types.CONTROL_TYPE_IS_SIGNAL {
+			stats.SignalizedNodes++
+		}
+		boundaries[node.BoundaryType()]++
+	}
+	linkTypes := map[types.LinkType]*LinkTypeStats{}
+	coverage := map[types.AgentType]*AgentTypeCoverage{}
+	for _, link := range net.Links {
+		lengthKm := link.LengthMeters() / 1000.0
+		laneKm := float64(link.LanesNum()) * lengthKm
+		stats.LengthKm += lengthKm
+		stats.LaneKm += laneKm
+		addLinkType(linkTypes, link.LinkType(), laneKm)
+		addAgentTypes(coverage, link.AllowedAgentTypes(), laneKm)
+	}
+	stats.LinkTypes = linkTypesStats(linkTypes)
+	stats.BoundaryNodes = boundaryNodesStats(boundaries)
+	stats.AgentTypes = agentTypesCoverage(coverage, stats.LaneKm, stats.Links)
+	return stats
+}
+
+// ComputeMovements returns summary of movements
+func ComputeMovements(movements movement.MovementsStorage) *MovementsStats {
+	stats := &MovementsStats{
+		Movements: len(movements),
+	}
+	byType := map[movement.MovementType]int{}
+	for _, mvmt := range movements {
+		byType[mvmt.Type()]++
+	}
+	mvmtTypes := make([]movement.MovementType, 0, len(byType))
+	for mvmtType := range byType {
+		mvmtTypes = append(mvmtTypes, mvmtType)
+	}
+	sort.Slice(mvmtTypes, func(i, j int) bool {
+		return mvmtTypes[i] < mvmtTypes[j]
+	})
+	stats.MovementTypes = make([]MovementTypeStats, len(mvmtTypes))
+	for i, mvmtType := range mvmtTypes {
+		stats.MovementTypes[i] = MovementTypeStats{
+			MovementType: mvmtType.String(),
+			Movements:    byType[mvmtType],
+		}
+	}
+	return stats
+}
+
+// ComputeMeso returns summary of mesoscopic network
+func ComputeMeso(net *meso.Net) *MesoStats {
+	stats := &MesoStats{
+		Nodes: len(net.Nodes),
+		Links: len(net.Links),
+	}
+	boundaries := map[types.BoundaryType]int{}
+	for _, node := range net.Nodes {
+		boundaries[node.BoundaryType()]++
+	}
+	linkTypes := map[types.LinkType]*LinkTypeStats{}
+	coverage := map[types.AgentType]*AgentTypeCoverage{}
+	for _, link := range net.Links {
+		if link.IsConnection() {
+			stats.ConnectionLinks++
+			continue
+		}
+		laneKm := float64(link.LanesNum()) * link.LengthMeters() / 1000.0
+		stats.LaneKm += laneKm
+		addLinkType(linkTypes, link.LinkType(), laneKm)
+		addAgentTypes(coverage, link.AllowedAgentTypes(), laneKm)
+	}
+	stats.LinkTypes = linkTypesStats(linkTypes)
+	stats.BoundaryNodes = boundaryNodesStats(boundaries)
+	stats.AgentTypes = agentTypesCoverage(coverage, stats.LaneKm, stats.Links-stats.ConnectionLinks)
+	return stats
+}
+
+// ComputeMicro returns summary of microscopic network
+func ComputeMicro(net *micro.Net) *MicroStats {
+	stats := &MicroStats{
+		Nodes: len(net.Nodes),
+		Links: len(net.Links),
+	}
+	boundaries := map[types.BoundaryType]int{}
+	for _, node := range net.Nodes {
+		boundaries[node.BoundaryType()]++
+	}
+	cellTypes := map[types.CellType]int{}
+	coverage := map[types.AgentType]*AgentTypeCoverage{}
+	mesoLinks := map[gmns.LinkID]struct{}{}
+	for _, link := range net.Links {
+		cellTypes[link.CellType()]++
+		mesoLinks[link.MesoLink()] = struct{}{}
+		addAgentTypes(coverage, link.AllowedAgentTypes(), 0)
+	}
+	stats.MesoLinks = len(mesoLinks)
+	if stats.MesoLinks > 0 {
+		stats.AvgCellsPerMesoLink = float64(stats.Links) / float64(stats.MesoLinks)
+	}
+	keys := make([]types.CellType, 0, len(cellTypes))
+	for cellType := range cellTypes {
+		keys = append(keys, cellType)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	stats.CellTypes = make([]CellTypeStats, len(keys))
+	for i, cellType := range keys {
+		stats.CellTypes[i] = CellTypeStats{
+			CellType: cellType.String(),
+			Links:    cellTypes[cellType],
+		}
+	}
+	stats.BoundaryNodes = boundaryNodesStats(boundaries)
+	stats.AgentTypes = agentTypesCoverage(coverage, 0, stats.Links)
+	return stats
+}
+
+// addLinkType accumulates lane-km of the link of the given type
+func addLinkType(linkTypes map[types.LinkType]*LinkTypeStats, linkType types.LinkType, laneKm float64) {
+	item, ok := linkTypes[linkType]
+	if !ok {
+		item = &LinkTypeStats{LinkType: linkType.String()}
+		linkTypes[linkType] = item
+	}
+	item.Links++
+	item.LaneKm += laneKm
+}
+
+// addAgentTypes accumulates lane-km of the link allowed for the given agent types
+func addAgentTypes(coverage map[types.AgentType]*AgentTypeCoverage, agentTypes []types.AgentType, laneKm float64) {
+	for _, agentType := range agentTypes {
+		item, ok := coverage[agentType]
+		if !ok {
+			item = &AgentTypeCoverage{AgentType: agentType.String()}
+			coverage[agentType] = item
+		}
+		item.Links++
+		item.LaneKm += laneKm
+	}
+}
+
+// linkTypesStats returns lane-km by link types ordered as types.LinkType constants
+func linkTypesStats(linkTypes map[types.LinkType]*LinkTypeStats) []LinkTypeStats {
+	keys := make([]types.LinkType, 0, len(linkTypes))
+	for linkType := range linkTypes {
+		keys = append(keys, linkType)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	result := make([]LinkTypeStats, len(keys))
+	for i, linkType := range keys {
+		result[i] = *linkTypes[linkType]
+	}
+	return result
+}
+
+// boundaryNodesStats returns number of nodes by boundary types except types.BOUNDARY_NONE ordered as types.BoundaryType constants
+func boundaryNodesStats(boundaries map[types.BoundaryType]int) []BoundaryTypeStats {
+	keys := make([]types.BoundaryType, 0, len(boundaries))
+	for boundaryType := range boundaries {
+		if boundaryType != types.BOUNDARY_NONE {
+			keys = append(keys, boundaryType)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	result := make([]BoundaryTypeStats, len(keys))
+	for i, boundaryType := range keys {
+		result[i] = BoundaryTypeStats{
+			BoundaryType: boundaryType.String(),
+			Nodes:        boundaries[boundaryType],
+		}
+	}
+	return result
+}
+
+// agentTypesCoverage returns coverage ordered as types.AgentType constants. Share is computed by lane-km if total lane-km is positive or by number of links otherwise
+func agentTypesCoverage(coverage map[types.AgentType]*AgentTypeCoverage, totalLaneKm float64, totalLinks int) []AgentTypeCoverage {
+	keys := make([]types.AgentType, 0, len(coverage))
+	for agentType := range coverage {
+		keys = append(keys, agentType)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	result := make([]AgentTypeCoverage, len(keys))
+	for i, agentType := range keys {
+		item := *coverage[agentType]
+		if totalLaneKm > 0 {
+			item.Share = item.LaneKm / totalLaneKm
+		} else if totalLinks > 0 {
+			item.Share = float64(item.Links) / float64(totalLinks)
+		}
+		result[i] = item
+	}
+	return result
+}
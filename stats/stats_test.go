@@ -0,0 +1,92 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/LdDl/go-gmns/generators"
+	"github.com/LdDl/go-gmns/macro"
+	"github.com/stretchr/testify/assert"
+)
+
+// crossNet returns signalized intersection of two bidirectional roads: primary one with 2 lanes and residential one with 1 lane
+func crossNet(t *testing.T) *macro.Net {
+	nodes := "node_id,ctrl_type,boundary_type,x_coord,y_coord\n" +
+		"0,signal,none,37.6,55.75\n" +
+		"1,,income_outcome,37.599,55.75\n" +
+		"2,,income_outcome,37.601,55.75\n" +
+		"3,,income_outcome,37.6,55.749\n" +
+		"4,,income_outcome,37.6,55.751\n"
+	links := "link_id,from_node_id,to_node_id,lanes,free_speed,link_type,allowed_uses,length\n" +
+		"1,1,0,2,60,primary,\"auto,bike\",100\n" +
+		"2,0,1,2,60,primary,\"auto,bike\",100\n" +
+		"3,2,0,2,60,primary,\"auto,bike\",100\n" +
+		"4,0,2,2,60,primary,\"auto,bike\",100\n" +
+		"5,3,0,1,30,residential,auto,100\n" +
+		"6,0,3,1,30,residential,auto,100\n" +
+		"7,4,0,1,30,residential,auto,100\n" +
+		"8,0,4,1,30,residential,auto,100\n"
+	net := macro.NewNet()
+	assert.NoError(t, macro.ReadNodesCSV(strings.NewReader(nodes), net))
+	assert.NoError(t, macro.ReadLinksCSV(strings.NewReader(links), net))
+	return net
+}
+
+func TestCompute(t *testing.T) {
+	macroNet := crossNet(t)
+	movements, err := generators.GenerateMovements(macroNet)
+	assert.NoError(t, err)
+	mesoNet, err := generators.GenerateMesoscopic(macroNet, movements)
+	assert.NoError(t, err)
+	microNet, err := generators.GenerateMicroscopic(macroNet, mesoNet, movements)
+	assert.NoError(t, err)
+	stats := Compute(macroNet, movements, mesoNet, microNet)
+
+	assert.Equal(t, 5, stats.Macro.Nodes)
+	assert.Equal(t, 1, stats.Macro.SignalizedNodes)
+	assert.InDelta(t, 1.2, stats.Macro.LaneKm, 1e-9)
+	assert.Equal(t, []LinkTypeStats{{"primary", 4, 0.8}, {"residential", 4, 0.4}}, roundLinkTypes(stats.Macro.LinkTypes))
+	assert.Equal(t, []BoundaryTypeStats{{"income_outcome", 4}}, stats.Macro.BoundaryNodes)
+	assert.Len(t, stats.Macro.AgentTypes, 2)
+	assert.Equal(t, "auto", stats.Macro.AgentTypes[0].AgentType)
+	assert.InDelta(t, 1.0, stats.Macro.AgentTypes[0].Share, 1e-9)
+	assert.InDelta(t, 2.0/3.0, stats.Macro.AgentTypes[1].Share, 1e-9, "Bikes are allowed on primary links only")
+
+	total := 0
+	for _, item := range stats.Movements.MovementTypes {
+		total += item.Movements
+	}
+	assert.Equal(t, len(movements), stats.Movements.Movements)
+	assert.Equal(t, stats.Movements.Movements, total)
+	assert.Equal(t, len(mesoNet.Links), stats.Meso.Links)
+	assert.Greater(t, stats.Meso.ConnectionLinks, 0)
+	assert.Greater(t, stats.Micro.AvgCellsPerMesoLink, 1.0)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteText(&buf, stats))
+	assert.Contains(t, buf.String(), "Macroscopic network\n")
+	assert.Contains(t, buf.String(), "signalized nodes:")
+	buf.Reset()
+	assert.NoError(t, WriteMarkdown(&buf, stats))
+	assert.Contains(t, buf.String(), "## Movements\n")
+	assert.Contains(t, buf.String(), "| Link type | Links | Lane-km |\n|---|---:|---:|\n| primary | 4 | 0.800 |\n")
+	buf.Reset()
+	assert.NoError(t, WriteJSON(&buf, stats))
+	decoded := &Stats{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), decoded))
+	assert.Equal(t, stats.Micro, decoded.Micro)
+
+	// Levels which are not computed are skipped
+	buf.Reset()
+	assert.NoError(t, WriteText(&buf, Compute(macroNet, nil, nil, nil)))
+	assert.NotContains(t, buf.String(), "Movements")
+}
+
+func roundLinkTypes(items []LinkTypeStats) []LinkTypeStats {
+	for i := range items {
+		items[i].LaneKm = float64(int(items[i].LaneKm*1000+0.5)) / 1000
+	}
+	return items
+}